@@ -0,0 +1,157 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/pipeline"
+)
+
+// Mux is an http.Handler serving the admin endpoints for registered
+// consumers. See the package documentation for the endpoints.
+type Mux struct {
+	// Supervisor, if non-nil, is reported on /pipeline and any failed
+	// component makes /health unhealthy.
+	Supervisor *pipeline.Supervisor
+
+	mux *http.ServeMux
+
+	mu        sync.Mutex
+	consumers map[string]*consumer
+}
+
+type consumer struct {
+	stream *events.Stream
+	tracer *events.Tracer
+}
+
+// ConsumerStatus is reported for each consumer on /consumers.
+type ConsumerStatus struct {
+	Name    string             `json:"name"`
+	Offset  *events.Offset     `json:"offset,omitempty"`
+	Filters []*events.Filter   `json:"filters,omitempty"`
+	Subset  *events.Subset     `json:"subset,omitempty"`
+	Stats   events.StreamStats `json:"stats"`
+	Tracing bool               `json:"tracing"`
+	Labels  gobyairship.Labels `json:"labels,omitempty"`
+}
+
+// NewMux creates a Mux with no consumers.
+func NewMux() *Mux {
+	m := &Mux{mux: http.NewServeMux(), consumers: map[string]*consumer{}}
+	m.mux.HandleFunc("/health", m.health)
+	m.mux.HandleFunc("/consumers", m.list)
+	m.mux.HandleFunc("/consumers/", m.trace)
+	m.mux.HandleFunc("/pipeline", m.pipeline)
+	return m
+}
+
+// Add a named consumer, replacing any existing consumer with the same name.
+// tracer may be nil if the consumer doesn't sample raw events.
+func (m *Mux) Add(name string, s *events.Stream, tracer *events.Tracer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.consumers[name] = &consumer{stream: s, tracer: tracer}
+}
+
+// Remove a named consumer.
+func (m *Mux) Remove(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.consumers, name)
+}
+
+// ServeHTTP implements http.Handler.
+func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+// Consumers returns the status of every consumer sorted by name.
+func (m *Mux) Consumers() []ConsumerStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.consumers))
+	for name := range m.consumers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]ConsumerStatus, 0, len(names))
+	for _, name := range names {
+		c := m.consumers[name]
+		st := ConsumerStatus{
+			Name:    name,
+			Filters: c.stream.Filters(),
+			Subset:  c.stream.Subset(),
+			Stats:   c.stream.Stats(),
+			Tracing: c.tracer != nil,
+			Labels:  c.stream.Labels(),
+		}
+		if offset, ok := c.stream.Offset(); ok {
+			o := events.Offset(offset)
+			st.Offset = &o
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+func (m *Mux) health(w http.ResponseWriter, r *http.Request) {
+	if m.Supervisor != nil {
+		if failed := failures(m.Supervisor.Status(), ""); len(failed) > 0 {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"ok": false, "failed": failed})
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
+
+// failures returns the paths of failed components in a supervision tree.
+func failures(statuses []pipeline.Status, prefix string) []string {
+	var failed []string
+	for _, st := range statuses {
+		if st.State == pipeline.StateFailed {
+			failed = append(failed, prefix+st.Name)
+		}
+		failed = append(failed, failures(st.Children, prefix+st.Name+"/")...)
+	}
+	return failed
+}
+
+func (m *Mux) list(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, m.Consumers())
+}
+
+func (m *Mux) trace(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/consumers/"), "/")
+	if len(parts) != 2 || parts[1] != "trace" {
+		http.NotFound(w, r)
+		return
+	}
+	m.mu.Lock()
+	c, ok := m.consumers[parts[0]]
+	m.mu.Unlock()
+	if !ok || c.tracer == nil {
+		http.NotFound(w, r)
+		return
+	}
+	c.tracer.ServeHTTP(w, r)
+}
+
+func (m *Mux) pipeline(w http.ResponseWriter, r *http.Request) {
+	if m.Supervisor == nil {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, m.Supervisor.Status())
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
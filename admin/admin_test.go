@@ -0,0 +1,103 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/admin"
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/pipeline"
+	"github.com/lytics/gobyairship/uatest"
+)
+
+// urlClient sends all requests to a fixed URL.
+type urlClient struct {
+	c   *gobyairship.Client
+	url string
+}
+
+func (c *urlClient) Post(_ string, body interface{}, extra http.Header) (*http.Response, error) {
+	return c.c.Post(c.url, body, extra)
+}
+
+func get(t *testing.T, h http.Handler, path string, v interface{}) int {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	if v != nil && w.Code == 200 {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("Invalid JSON from %s: %v", path, err)
+		}
+	}
+	return w.Code
+}
+
+func TestMux(t *testing.T) {
+	t.Parallel()
+	srv := uatest.NewServer()
+	defer srv.Close()
+	now := time.Now()
+	srv.Add(&events.Event{ID: "a", Type: events.TypeClose, Offset: 7, Occurred: now, Processed: now, Body: []byte("{}")})
+
+	tracer := events.NewTracer(1, 10)
+	s, err := events.NewStream(context.Background(), &urlClient{gobyairship.NewClient("", ""), srv.EventsURL()}, events.StreamConfig{
+		Start:   events.StartFirst,
+		Filters: []*events.Filter{{Types: []events.Type{events.TypeClose}}},
+		Options: []events.Option{events.Trace(tracer)},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer s.Close()
+	<-s.Events()
+
+	m := admin.NewMux()
+	m.Add("closes", s, tracer)
+	m.Add("untraced", s, nil)
+
+	if code := get(t, m, "/health", nil); code != 200 {
+		t.Errorf("Expected healthy but received %d", code)
+	}
+
+	// Offset is recorded just after delivery
+	var consumers []admin.ConsumerStatus
+	deadline := time.Now().Add(3 * time.Second)
+	for get(t, m, "/consumers", &consumers); consumers[0].Offset == nil && time.Now().Before(deadline); get(t, m, "/consumers", &consumers) {
+		time.Sleep(time.Millisecond)
+	}
+	if len(consumers) != 2 || consumers[0].Name != "closes" {
+		t.Fatalf("Unexpected consumers: %#v", consumers)
+	}
+	c := consumers[0]
+	if c.Offset == nil || *c.Offset != 7 || len(c.Filters) != 1 || c.Stats.Delivered != 1 || !c.Tracing {
+		t.Errorf("Unexpected consumer status: %#v", c)
+	}
+
+	var recs []events.TraceRecord
+	if code := get(t, m, "/consumers/closes/trace", &recs); code != 200 || len(recs) != 1 {
+		t.Errorf("Expected 1 trace record but received %d: %v", code, recs)
+	}
+	for _, path := range []string{"/consumers/untraced/trace", "/consumers/missing/trace", "/consumers/closes", "/pipeline"} {
+		if code := get(t, m, path, nil); code != 404 {
+			t.Errorf("Expected 404 for %s but received %d", path, code)
+		}
+	}
+
+	// Failed pipelines are unhealthy
+	sup := pipeline.NewSupervisor("root", pipeline.Escalate)
+	sup.Add("broken", pipeline.ComponentFunc(func(context.Context) error { return errors.New("broken") }))
+	sup.Run(context.Background())
+	m.Supervisor = sup
+	if code := get(t, m, "/health", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected unhealthy but received %d", code)
+	}
+	var statuses []pipeline.Status
+	if code := get(t, m, "/pipeline", &statuses); code != 200 || len(statuses) != 1 || statuses[0].State != pipeline.StateFailed {
+		t.Errorf("Unexpected pipeline status %d: %#v", code, statuses)
+	}
+}
@@ -0,0 +1,30 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package provides an HTTP handler exposing the health and state of
+// running event consumers to operators.
+//
+// Mount a Mux under a prefix of an existing server:
+//
+//	m := admin.NewMux()
+//	m.Add("opens", stream, tracer)
+//	http.Handle("/debug/ua/", http.StripPrefix("/debug/ua", m))
+//
+// The Mux serves:
+//
+//	/health                 200 if healthy, 503 if a pipeline component failed
+//	/consumers              offsets, filters, and stats of every consumer
+//	/consumers/<name>/trace raw events sampled by the consumer's Tracer
+//	/pipeline               status of the Supervisor, if set
+package admin
@@ -0,0 +1,118 @@
+package audience
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Selector chooses a set of devices.
+type Selector interface {
+	json.Marshaler
+
+	// Validate returns nil if the selector is valid or an error if there's an
+	// issue which the API would reject.
+	Validate() error
+}
+
+// All selects every device. It may not be combined with other selectors.
+var All Selector = all{}
+
+type all struct{}
+
+func (all) MarshalJSON() ([]byte, error) { return []byte(`"all"`), nil }
+func (all) Validate() error              { return nil }
+
+// atom selects devices by a single identifier such as {"tag": "vip"}.
+type atom struct {
+	key   string
+	value string
+
+	// group of a tag, if any
+	group string
+}
+
+func (a *atom) MarshalJSON() ([]byte, error) {
+	m := map[string]string{a.key: a.value}
+	if a.group != "" {
+		m["group"] = a.group
+	}
+	return json.Marshal(m)
+}
+
+func (a *atom) Validate() error {
+	if a.value == "" {
+		return fmt.Errorf("empty %s", a.key)
+	}
+	return nil
+}
+
+// Tag selects devices with a tag in the device tag group.
+func Tag(tag string) Selector { return &atom{key: "tag", value: tag} }
+
+// TagGroup selects devices with a tag in a tag group.
+func TagGroup(group, tag string) Selector { return &atom{key: "tag", value: tag, group: group} }
+
+// NamedUser selects the devices of a named user.
+func NamedUser(id string) Selector { return &atom{key: "named_user", value: id} }
+
+// Alias selects devices by alias.
+func Alias(alias string) Selector { return &atom{key: "alias", value: alias} }
+
+// Segment selects devices in a segment by its ID.
+func Segment(id string) Selector { return &atom{key: "segment", value: id} }
+
+// StaticList selects devices in a static list by its name.
+func StaticList(name string) Selector { return &atom{key: "static_list", value: name} }
+
+// IOSChannel selects an iOS device by its channel ID.
+func IOSChannel(id string) Selector { return &atom{key: "ios_channel", value: id} }
+
+// AndroidChannel selects an Android device by its channel ID.
+func AndroidChannel(id string) Selector { return &atom{key: "android_channel", value: id} }
+
+// AmazonChannel selects an Amazon device by its channel ID.
+func AmazonChannel(id string) Selector { return &atom{key: "amazon_channel", value: id} }
+
+// Channel selects a web, email, or SMS channel by its ID.
+func Channel(id string) Selector { return &atom{key: "channel", value: id} }
+
+// compound combines selectors with a boolean operator.
+type compound struct {
+	op        string
+	selectors []Selector
+}
+
+func (c *compound) MarshalJSON() ([]byte, error) {
+	if c.op == "not" && len(c.selectors) == 1 {
+		return json.Marshal(map[string]Selector{c.op: c.selectors[0]})
+	}
+	return json.Marshal(map[string][]Selector{c.op: c.selectors})
+}
+
+func (c *compound) Validate() error {
+	if len(c.selectors) == 0 {
+		return fmt.Errorf("%s without selectors", c.op)
+	}
+	for i, s := range c.selectors {
+		if s == nil {
+			return fmt.Errorf("nil selector %d in %s", i, c.op)
+		}
+		if _, ok := s.(all); ok {
+			return errors.New("all may not be combined with other selectors")
+		}
+		if err := s.Validate(); err != nil {
+			return fmt.Errorf("invalid selector %d in %s: %v", i, c.op, err)
+		}
+	}
+	return nil
+}
+
+// And selects devices matching every selector.
+func And(selectors ...Selector) Selector { return &compound{op: "and", selectors: selectors} }
+
+// Or selects devices matching any selector.
+func Or(selectors ...Selector) Selector { return &compound{op: "or", selectors: selectors} }
+
+// Not selects devices which don't match a selector.
+func Not(selector Selector) Selector { return &compound{op: "not", selectors: []Selector{selector}} }
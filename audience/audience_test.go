@@ -0,0 +1,53 @@
+package audience_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lytics/gobyairship/audience"
+)
+
+func TestSelectors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		sel      audience.Selector
+		expected string
+	}{
+		{audience.All, `"all"`},
+		{audience.Tag("vip"), `{"tag":"vip"}`},
+		{audience.TagGroup("loyalty", "gold"), `{"group":"loyalty","tag":"gold"}`},
+		{audience.NamedUser("u1"), `{"named_user":"u1"}`},
+		{audience.Segment("s1"), `{"segment":"s1"}`},
+		{audience.IOSChannel("c1"), `{"ios_channel":"c1"}`},
+		{audience.Not(audience.Tag("lapsed")), `{"not":{"tag":"lapsed"}}`},
+		{
+			audience.And(audience.Tag("vip"), audience.Or(audience.NamedUser("u1"), audience.Channel("c2"))),
+			`{"and":[{"tag":"vip"},{"or":[{"named_user":"u1"},{"channel":"c2"}]}]}`,
+		},
+	}
+	for _, tc := range tests {
+		if err := tc.sel.Validate(); err != nil {
+			t.Errorf("Unexpected error validating %s: %v", tc.expected, err)
+		}
+		buf, err := json.Marshal(tc.sel)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(buf) != tc.expected {
+			t.Errorf("Expected %s but found %s", tc.expected, buf)
+		}
+	}
+
+	invalid := map[string]audience.Selector{
+		"empty tag":       audience.Tag(""),
+		"empty and":       audience.And(),
+		"nil not":         audience.Not(nil),
+		"nested all":      audience.Or(audience.Tag("vip"), audience.All),
+		"invalid nesting": audience.And(audience.Not(audience.NamedUser(""))),
+	}
+	for name, sel := range invalid {
+		if err := sel.Validate(); err == nil {
+			t.Errorf("Expected an error with %s", name)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package builds audience selectors which choose the devices a push,
+// create-and-send, or experiment is sent to. Selectors marshal to the JSON
+// the Urban Airship API expects and compose with And, Or, and Not:
+//
+//	sel := audience.And(
+//		audience.Tag("vip"),
+//		audience.Not(audience.TagGroup("loyalty", "lapsed")),
+//	)
+//
+// This package only depends on the standard library.
+package audience
@@ -0,0 +1,74 @@
+package gobyairship
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff determines how long to wait before retrying a failed operation such
+// as a request or a reconnect. Implementations must be safe for concurrent
+// use.
+type Backoff interface {
+	// Next returns how long to wait before the given attempt. Attempts start
+	// at 1 for the first retry; last is the previous wait or 0.
+	Next(attempt int, last time.Duration) time.Duration
+}
+
+// BackoffFunc adapts a function to the Backoff interface.
+type BackoffFunc func(attempt int, last time.Duration) time.Duration
+
+// Next calls f(attempt, last).
+func (f BackoffFunc) Next(attempt int, last time.Duration) time.Duration { return f(attempt, last) }
+
+// ConstantBackoff always waits the same duration.
+type ConstantBackoff time.Duration
+
+// Next returns b.
+func (b ConstantBackoff) Next(int, time.Duration) time.Duration { return time.Duration(b) }
+
+// ExponentialBackoff waits Min before the first attempt and multiplies the
+// wait by Factor (default 2) for each subsequent attempt up to Max.
+type ExponentialBackoff struct {
+	Min    time.Duration
+	Max    time.Duration
+	Factor float64
+}
+
+// Next returns the exponential wait for attempt.
+func (b ExponentialBackoff) Next(attempt int, _ time.Duration) time.Duration {
+	factor := b.Factor
+	if factor <= 1 {
+		factor = 2
+	}
+	d := float64(b.Min)
+	for i := 1; i < attempt; i++ {
+		d *= factor
+		if b.Max > 0 && d >= float64(b.Max) {
+			return b.Max
+		}
+	}
+	return time.Duration(d)
+}
+
+// DecorrelatedJitter waits a random duration between Base and three times the
+// last wait, capped at Max. Randomizing waits keeps many clients from
+// retrying in lockstep.
+type DecorrelatedJitter struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Next returns a random wait for attempt.
+func (b DecorrelatedJitter) Next(_ int, last time.Duration) time.Duration {
+	if last < b.Base {
+		last = b.Base
+	}
+	d := b.Base
+	if span := int64(last*3 - b.Base); span > 0 {
+		d += time.Duration(rand.Int63n(span))
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	return d
+}
@@ -0,0 +1,39 @@
+package gobyairship_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/lytics/gobyairship"
+)
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+	exp := ExponentialBackoff{Min: time.Second, Max: 10 * time.Second}
+	for attempt, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		if d := exp.Next(attempt+1, 0); d != expected {
+			t.Errorf("Attempt %d: expected %s but found %s", attempt+1, expected, d)
+		}
+	}
+	if d := (ExponentialBackoff{Min: time.Second, Factor: 3}).Next(3, 0); d != 9*time.Second {
+		t.Errorf("Expected factor of 3 to wait 9s but found %s", d)
+	}
+	if d := ConstantBackoff(time.Second).Next(10, 0); d != time.Second {
+		t.Errorf("Expected constant backoff but found %s", d)
+	}
+
+	jitter := DecorrelatedJitter{Base: time.Second, Max: 20 * time.Second}
+	last := time.Duration(0)
+	for attempt := 1; attempt < 100; attempt++ {
+		d := jitter.Next(attempt, last)
+		if d < time.Second || d > 20*time.Second || (last > 0 && d > last*3) {
+			t.Fatalf("Attempt %d: wait %s out of bounds (last %s)", attempt, d, last)
+		}
+		last = d
+	}
+
+	var b Backoff = BackoffFunc(func(attempt int, _ time.Duration) time.Duration { return time.Duration(attempt) })
+	if d := b.Next(5, 0); d != 5 {
+		t.Errorf("Expected custom backoff but found %s", d)
+	}
+}
@@ -0,0 +1,79 @@
+package gobyairship
+
+import (
+	"bytes"
+	"io"
+)
+
+// bodyFunc returns a request body and its length for each attempt of a
+// request. A length of -1 means the length is unknown.
+type bodyFunc func() (io.Reader, int64, error)
+
+func bytesBody(buf []byte) bodyFunc {
+	if len(buf) == 0 {
+		return nil
+	}
+	return func() (io.Reader, int64, error) {
+		return bytes.NewReader(buf), int64(len(buf)), nil
+	}
+}
+
+// readerBody returns r on the first attempt and rewinds it on subsequent
+// attempts if it's an io.Seeker.
+func readerBody(r io.Reader) bodyFunc {
+	seeker, _ := r.(io.Seeker)
+	var start int64
+	if seeker != nil {
+		var err error
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			seeker = nil
+		}
+	}
+	first := true
+	return func() (io.Reader, int64, error) {
+		if !first {
+			if seeker == nil {
+				return nil, 0, ErrBodyNotReplayable
+			}
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, 0, err
+			}
+		}
+		first = false
+		return r, length(r), nil
+	}
+}
+
+func funcBody(f func() (io.Reader, error)) bodyFunc {
+	return func() (io.Reader, int64, error) {
+		r, err := f()
+		if err != nil {
+			return nil, 0, err
+		}
+		return r, length(r), nil
+	}
+}
+
+// length returns the number of unread bytes in r or -1 if it's unknown.
+func length(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface {
+		Len() int
+	}:
+		return int64(v.Len())
+	case io.Seeker:
+		cur, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		end, err := v.Seek(0, io.SeekEnd)
+		if err != nil {
+			return -1
+		}
+		if _, err := v.Seek(cur, io.SeekStart); err != nil {
+			return -1
+		}
+		return end - cur
+	}
+	return -1
+}
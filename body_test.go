@@ -0,0 +1,90 @@
+package gobyairship_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/lytics/gobyairship"
+)
+
+// TestPostReader ensures streamed bodies are resent on redirects when
+// possible.
+func TestPostReader(t *testing.T) {
+	t.Parallel()
+
+	type req struct {
+		body          string
+		contentType   string
+		contentLength int64
+	}
+	reqs := []req{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		reqs = append(reqs, req{string(body), r.Header.Get("Content-Type"), r.ContentLength})
+		if len(reqs)%2 == 1 {
+			w.Header().Set("Set-Cookie", "c")
+			w.WriteHeader(307)
+		}
+	}))
+	defer ts.Close()
+	c := NewClient("", "")
+
+	const csv = "ios,abc\nandroid,def\n"
+	resp, err := c.PostReader(ts.URL, strings.NewReader(csv), "text/csv")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	expected := req{csv, "text/csv", int64(len(csv))}
+	if len(reqs) != 2 || reqs[0] != expected || reqs[1] != expected {
+		t.Errorf("Expected body to be sent twice with length: %#v", reqs)
+	}
+
+	// Unseekable readers cannot be resent
+	reqs = reqs[:0]
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte(csv))
+		pw.Close()
+	}()
+	if _, err := c.PostReader(ts.URL, pr, "text/csv"); err != ErrBodyNotReplayable {
+		t.Errorf("Expected ErrBodyNotReplayable but received: %v", err)
+	}
+
+	// But a factory can create a new reader for each attempt
+	reqs = reqs[:0]
+	calls := 0
+	resp, err = c.PostReaderFunc(ts.URL, func() (io.Reader, error) {
+		calls++
+		return bytes.NewBufferString(csv), nil
+	}, "text/csv")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if calls != 2 || len(reqs) != 2 || reqs[1] != expected {
+		t.Errorf("Expected factory to be called for each attempt: calls=%d %#v", calls, reqs)
+	}
+
+	// Canceled requests aren't sent
+	reqs = reqs[:0]
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.PostReaderContext(ctx, ts.URL, strings.NewReader(csv), "text/csv"); err == nil {
+		t.Errorf("Expected an error posting with a canceled context")
+	}
+	if _, err := c.PostReaderFuncContext(ctx, ts.URL, func() (io.Reader, error) {
+		return bytes.NewBufferString(csv), nil
+	}, "text/csv"); err == nil {
+		t.Errorf("Expected an error posting with a canceled context")
+	}
+	if len(reqs) != 0 {
+		t.Errorf("Expected no requests but found %#v", reqs)
+	}
+}
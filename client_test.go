@@ -2,12 +2,14 @@ package gobyairship_test
 
 import (
 	"compress/gzip"
+	"context"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	. "github.com/lytics/gobyairship"
 )
@@ -154,3 +156,228 @@ func TestGzip(t *testing.T) {
 		t.Fatalf("Read %d bytes; expected to read %d. Error: %v", n, sz, err)
 	}
 }
+
+// TestVersion ensures the Client requests its API version in the Accept header
+// and that requests may override it.
+func TestVersion(t *testing.T) {
+	t.Parallel()
+
+	accept := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept <- r.Header.Get("Accept")
+	}))
+	defer ts.Close()
+
+	c := NewClient("", "")
+	post := func(extra http.Header) string {
+		resp, err := c.Post(ts.URL, nil, extra)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		return <-accept
+	}
+
+	if h := post(nil); h != Accept(MediaTypeJSON, DefaultVersion) {
+		t.Errorf("Unexpected default Accept header: %q", h)
+	}
+	c.Version = 4
+	if h := post(nil); h != "application/vnd.urbanairship+json;version=4;" {
+		t.Errorf("Unexpected Accept header for version 4: %q", h)
+	}
+	if h := post(http.Header{"Accept": {Accept(MediaTypeJSON, 5)}}); h != Accept(MediaTypeJSON, 5) {
+		t.Errorf("Expected per-request Accept header to override version: %q", h)
+	}
+}
+
+// TestIdempotent ensures idempotency keys are generated and reused across
+// redirects.
+func TestIdempotent(t *testing.T) {
+	t.Parallel()
+
+	keys := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(IdempotencyHeader))
+		if len(keys)%2 == 1 {
+			w.WriteHeader(307)
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient("", "")
+	c.Idempotent = true
+	for i := 0; i < 2; i++ {
+		resp, err := c.Post(ts.URL, []byte("{}"), nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if len(keys) != 4 {
+		t.Fatalf("Expected 4 requests but received %d", len(keys))
+	}
+	if keys[0] == "" || keys[0] != keys[1] || keys[2] != keys[3] {
+		t.Errorf("Expected keys to be reused across redirects: %v", keys)
+	}
+	if keys[0] == keys[2] {
+		t.Errorf("Expected a new key per Post: %v", keys)
+	}
+
+	// Caller supplied keys are used as is
+	keys = keys[:0]
+	resp, err := c.Post(ts.URL, []byte("{}"), http.Header{IdempotencyHeader: {"mykey"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if len(keys) != 2 || keys[0] != "mykey" || keys[1] != "mykey" {
+		t.Errorf("Expected caller's key to be used: %v", keys)
+	}
+}
+
+// TestLimiter ensures the Client's Limiter caps in-flight requests until
+// response bodies are closed.
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	c := NewClient("", "")
+	c.Limiter = NewLimiter(1)
+	waits := make(chan time.Duration, 10)
+	c.Limiter.OnWait = func(d time.Duration) { waits <- d }
+
+	resp, err := c.Post(ts.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := c.Limiter.InFlight(); n != 1 {
+		t.Errorf("Expected 1 in-flight request but found %d", n)
+	}
+	if _, err := c.Post(ts.URL, nil, nil); err != ErrLimited {
+		t.Fatalf("Expected ErrLimited but received: %v", err)
+	}
+
+	// Queued requests wait for the slot to be released
+	c.Limiter.Queue = true
+	done := make(chan error)
+	go func() {
+		resp, err := c.Post(ts.URL, nil, nil)
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+	<-waits
+	time.Sleep(10 * time.Millisecond)
+	resp.Body.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error from queued request: %v", err)
+	}
+	if d := <-waits; d < 10*time.Millisecond {
+		t.Errorf("Expected queued request to wait at least 10ms but waited %s", d)
+	}
+	if n := c.Limiter.InFlight(); n != 0 {
+		t.Errorf("Expected no in-flight requests but found %d", n)
+	}
+}
+
+// TestLimiterContext ensures queued requests stop waiting for a slot when
+// their context is done or their timeout expires.
+func TestLimiterContext(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	c := NewClient("", "")
+	c.Limiter = NewLimiter(1)
+	c.Limiter.Queue = true
+	resp, err := c.Post(ts.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.PostContext(ctx, ts.URL, nil, nil); err != context.Canceled {
+		t.Errorf("Expected context.Canceled but received: %v", err)
+	}
+
+	c.Timeouts = &TimeoutPolicy{Default: 10 * time.Millisecond}
+	if _, err := c.Post(ts.URL, nil, nil); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded but received: %v", err)
+	}
+	if n := c.Limiter.InFlight(); n != 1 {
+		t.Errorf("Expected 1 in-flight request but found %d", n)
+	}
+}
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("X-UA-Appkey") != "key" {
+			w.WriteHeader(400)
+			return
+		}
+		if r.Header.Get(IdempotencyHeader) != "" {
+			t.Errorf("GETs should not have idempotency keys")
+		}
+		if r.Header.Get("Cookie") == "" {
+			w.Header().Add("Set-Cookie", "testcookie")
+			w.WriteHeader(307)
+			return
+		}
+		w.Write([]byte(r.Header.Get("X-Extra")))
+	}))
+	defer ts.Close()
+
+	c := NewClient("key", "token")
+	c.Idempotent = true
+	resp, err := c.Get(ts.URL, http.Header{"X-Extra": {"ok"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || string(body) != "ok" {
+		t.Errorf("Unexpected response %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestPutDelete(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get(IdempotencyHeader) != "" {
+			w.WriteHeader(400)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("Cookie") == "" {
+			w.Header().Add("Set-Cookie", "testcookie")
+			w.WriteHeader(307)
+			return
+		}
+		w.Write([]byte(r.Method + " " + string(body)))
+	}))
+	defer ts.Close()
+
+	c := NewClient("key", "token")
+	c.Idempotent = true
+	check := func(resp *http.Response, err error, expected string) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != 200 || string(body) != expected {
+			t.Errorf("Expected %q but found %d: %s", expected, resp.StatusCode, body)
+		}
+	}
+	resp, err := c.Put(ts.URL, map[string]int{"a": 1}, nil)
+	check(resp, err, `PUT {"a":1}`)
+	resp, err = c.Delete(ts.URL, nil)
+	check(resp, err, "DELETE ")
+}
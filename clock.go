@@ -0,0 +1,18 @@
+package gobyairship
+
+import "time"
+
+// Clock tells time. Clients and helpers built on them use a Clock for all
+// time-based behavior so tests can control time instead of sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock is the default Clock and uses the time package.
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
@@ -0,0 +1,34 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// Command uaconnect helps teams get started consuming Urban Airship's Event
+// API with this library.
+//
+// The init subcommand scaffolds a ready-to-run consumer service:
+//
+//	uaconnect init -dir myconsumer -checkpoint file -sink file
+//
+// It writes a config.json and a main.go which streams events with
+// events.NewStream, batches them into a sinks.Sink, checkpoints the offset of
+// each stored batch, runs under a pipeline.Supervisor, and optionally serves
+// the admin endpoints. Existing files are never overwritten.
+//
+// The export subcommand writes events of one type which occurred in a date
+// range to CSV, such as for a spreadsheet of a day's sends:
+//
+//	UA_APP_KEY=<key> UA_ACCESS_TOKEN=<token> uaconnect export -type SEND -since 2017-04-25 -until 2017-04-26 -o sends.csv
+//
+// See the events/csv package for the columns of each type. Add other body
+// fields with -fields.
+package main
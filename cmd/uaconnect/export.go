@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/events/csv"
+)
+
+// parseTime parses a date such as 2017-04-25 or an RFC 3339 time.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func exportCmd(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	appKey := fs.String("app-key", os.Getenv("UA_APP_KEY"), "app key, defaults to $UA_APP_KEY")
+	typ := fs.String("type", "", "event type to export such as SEND or OPEN; empty exports every type")
+	since := fs.String("since", "", "export events which occurred at or after this date or RFC 3339 time")
+	until := fs.String("until", "", "export events which occurred before this date or RFC 3339 time; defaults to now")
+	fields := fs.String("fields", "", "comma separated body fields to add as columns, such as triggering_push.push_id")
+	out := fs.String("o", "", "file to write to instead of stdout")
+	fs.Parse(args)
+
+	fail := func(err error) {
+		fmt.Fprintf(os.Stderr, "uaconnect: %v\n", err)
+		os.Exit(1)
+	}
+	if *since == "" {
+		fail(fmt.Errorf("-since is required"))
+	}
+	start, err := parseTime(*since)
+	if err != nil {
+		fail(fmt.Errorf("invalid -since: %v", err))
+	}
+	end := time.Now()
+	if *until != "" {
+		if end, err = parseTime(*until); err != nil {
+			fail(fmt.Errorf("invalid -until: %v", err))
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fail(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	x := csv.NewExporter(w, events.Type(*typ))
+	x.Since, x.Until = start, end
+	for _, p := range strings.Split(*fields, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			x.Columns = append(x.Columns, csv.Field(p))
+		}
+	}
+
+	var filters []*events.Filter
+	if *typ != "" {
+		filters = append(filters, &events.Filter{Types: []events.Type{events.Type(*typ)}})
+	}
+	client := gobyairship.NewClient(*appKey, os.Getenv("UA_ACCESS_TOKEN"))
+	resp, err := events.FetchSince(client, start, nil, filters...)
+	if err != nil {
+		fail(err)
+	}
+	n, err := x.Export(resp)
+	if err != nil {
+		fail(err)
+	}
+	fmt.Fprintf(os.Stderr, "exported %d events\n", n)
+}
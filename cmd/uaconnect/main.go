@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `usage: uaconnect <command> [flags]
+
+Commands:
+  init    scaffold a consumer service
+  export  export events to CSV
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "init":
+		initCmd(os.Args[2:])
+	case "export":
+		exportCmd(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func initCmd(args []string) {
+	s := Scaffold{}
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	fs.StringVar(&s.Dir, "dir", ".", "directory to write the service to")
+	fs.StringVar(&s.Checkpoint, "checkpoint", CheckpointFile, "checkpoint store: file or memory")
+	fs.StringVar(&s.Sink, "sink", SinkStdout, "event sink: stdout or file")
+	fs.Parse(args)
+
+	files, err := s.Write()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "uaconnect: %v\n", err)
+		os.Exit(1)
+	}
+	for _, f := range files {
+		fmt.Println("wrote", f)
+	}
+	fmt.Printf("\nSet app_key in config.json, then run:\n\n  cd %s\n  go build -o consumer && UA_ACCESS_TOKEN=<token> ./consumer -config config.json\n", s.Dir)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// Checkpoint stores.
+const (
+	CheckpointFile   = "file"
+	CheckpointMemory = "memory"
+)
+
+// Sinks.
+const (
+	SinkStdout = "stdout"
+	SinkFile   = "file"
+)
+
+// Scaffold describes a consumer service to generate.
+type Scaffold struct {
+	// Dir the service's files are written to. Created if it doesn't exist.
+	Dir string
+
+	// Checkpoint is CheckpointFile or CheckpointMemory.
+	Checkpoint string
+
+	// Sink is SinkStdout or SinkFile.
+	Sink string
+}
+
+// Validate returns an error if the Scaffold has an unknown Checkpoint or
+// Sink.
+func (s *Scaffold) Validate() error {
+	if s.Checkpoint != CheckpointFile && s.Checkpoint != CheckpointMemory {
+		return fmt.Errorf("checkpoint must be %q or %q", CheckpointFile, CheckpointMemory)
+	}
+	if s.Sink != SinkStdout && s.Sink != SinkFile {
+		return fmt.Errorf("sink must be %q or %q", SinkStdout, SinkFile)
+	}
+	return nil
+}
+
+// Write the service's files and returns their paths. An error is returned
+// before anything is written if any of the files already exist.
+func (s *Scaffold) Write() ([]string, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	files := []struct {
+		name  string
+		tmpl  *template.Template
+		gofmt bool
+	}{
+		{"config.json", configTemplate, false},
+		{"main.go", mainTemplate, true},
+	}
+	contents := make([][]byte, len(files))
+	paths := make([]string, len(files))
+	for i, f := range files {
+		buf := &bytes.Buffer{}
+		if err := f.tmpl.Execute(buf, s); err != nil {
+			return nil, err
+		}
+		contents[i] = buf.Bytes()
+		if f.gofmt {
+			src, err := format.Source(contents[i])
+			if err != nil {
+				return nil, fmt.Errorf("generated invalid %s: %v", f.name, err)
+			}
+			contents[i] = src
+		}
+		paths[i] = filepath.Join(s.Dir, f.name)
+		if _, err := os.Stat(paths[i]); err == nil {
+			return nil, fmt.Errorf("%s already exists", paths[i])
+		}
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return nil, err
+	}
+	for i, path := range paths {
+		if err := ioutil.WriteFile(path, contents[i], 0644); err != nil {
+			return paths[:i], err
+		}
+	}
+	return paths, nil
+}
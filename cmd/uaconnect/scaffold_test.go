@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScaffold(t *testing.T) {
+	t.Parallel()
+	tmp, err := ioutil.TempDir("", "uaconnect")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, checkpoint := range []string{CheckpointFile, CheckpointMemory} {
+		for _, sink := range []string{SinkStdout, SinkFile} {
+			s := Scaffold{Dir: filepath.Join(tmp, checkpoint+"-"+sink), Checkpoint: checkpoint, Sink: sink}
+			files, err := s.Write()
+			if err != nil {
+				t.Fatalf("%s/%s: Unexpected error: %v", checkpoint, sink, err)
+			}
+			if len(files) != 2 {
+				t.Fatalf("%s/%s: Expected 2 files but found %v", checkpoint, sink, files)
+			}
+
+			buf, err := ioutil.ReadFile(filepath.Join(s.Dir, "config.json"))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			cfg := map[string]interface{}{}
+			if err := json.Unmarshal(buf, &cfg); err != nil {
+				t.Fatalf("%s/%s: Invalid config.json: %v\n%s", checkpoint, sink, err, buf)
+			}
+			if _, ok := cfg["checkpoint"]; ok != (checkpoint == CheckpointFile) {
+				t.Errorf("%s/%s: Unexpected checkpoint config: %v", checkpoint, sink, cfg)
+			}
+			if _, ok := cfg["output"]; ok != (sink == SinkFile) {
+				t.Errorf("%s/%s: Unexpected output config: %v", checkpoint, sink, cfg)
+			}
+
+			buf, err = ioutil.ReadFile(filepath.Join(s.Dir, "main.go"))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Contains(string(buf), "NewFileCheckpointer") != (checkpoint == CheckpointFile) {
+				t.Errorf("%s/%s: main.go uses the wrong checkpointer", checkpoint, sink)
+			}
+
+			// Existing files are not overwritten
+			if _, err := s.Write(); err == nil {
+				t.Errorf("%s/%s: Expected an error overwriting files", checkpoint, sink)
+			}
+		}
+	}
+}
+
+func TestScaffoldInvalid(t *testing.T) {
+	t.Parallel()
+	invalid := []Scaffold{
+		{Dir: ".", Checkpoint: "redis", Sink: SinkStdout},
+		{Dir: ".", Checkpoint: CheckpointFile, Sink: "kafka"},
+	}
+	for _, s := range invalid {
+		if _, err := s.Write(); err == nil {
+			t.Errorf("Expected an error for %+v", s)
+		}
+	}
+}
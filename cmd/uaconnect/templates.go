@@ -0,0 +1,207 @@
+package main
+
+import "text/template"
+
+var configTemplate = template.Must(template.New("config.json").Parse(`{
+  "app_key": "",
+  "start": "EARLIEST",
+{{- if eq .Checkpoint "file"}}
+  "checkpoint": "offset.checkpoint",
+{{- end}}
+{{- if eq .Sink "file"}}
+  "output": "events.ndjson",
+{{- end}}
+  "batch_size": 500,
+  "batch_linger": "1s",
+  "admin": "localhost:8080"
+}
+`))
+
+var mainTemplate = template.Must(template.New("main.go").Parse(`// Command consumer stores events from Urban Airship's Event API.
+//
+// Generated by uaconnect init.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/admin"
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/pipeline"
+	"github.com/lytics/gobyairship/sinks"
+)
+
+// Config is loaded from the file given by -config.
+type Config struct {
+	// AppKey of the Urban Airship app. The access token is read from the
+	// UA_ACCESS_TOKEN environment variable.
+	AppKey string ` + "`json:\"app_key\"`" + `
+
+	// Start is EARLIEST or LATEST and is used until an offset is checkpointed.
+	Start events.Start ` + "`json:\"start\"`" + `
+{{if eq .Checkpoint "file"}}
+	// Checkpoint is the file the offset of the last stored event is saved to.
+	Checkpoint string ` + "`json:\"checkpoint\"`" + `
+{{end}}
+{{- if eq .Sink "file"}}
+	// Output is the file events are appended to as newline-delimited JSON.
+	Output string ` + "`json:\"output\"`" + `
+{{end}}
+	// BatchSize and BatchLinger control how many events are stored at once
+	// and how long a partial batch waits for more events.
+	BatchSize   int    ` + "`json:\"batch_size\"`" + `
+	BatchLinger string ` + "`json:\"batch_linger\"`" + `
+
+	// Admin is the address the admin endpoints are served on. Disabled if
+	// empty.
+	Admin string ` + "`json:\"admin\"`" + `
+}
+
+func main() {
+	path := flag.String("config", "config.json", "path to the config file")
+	flag.Parse()
+
+	buf, err := ioutil.ReadFile(*path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg := &Config{}
+	if err := json.Unmarshal(buf, cfg); err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
+	linger, err := time.ParseDuration(cfg.BatchLinger)
+	if err != nil {
+		log.Fatalf("invalid batch_linger: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		cancel()
+	}()
+{{if eq .Checkpoint "file"}}
+	checkpointer := events.NewFileCheckpointer(cfg.Checkpoint)
+{{- else}}
+	// Offsets are only kept in memory so restarting the process starts over
+	// from cfg.Start.
+	checkpointer := &events.MemoryCheckpointer{}
+{{- end}}
+	client := gobyairship.NewClient(cfg.AppKey, os.Getenv("UA_ACCESS_TOKEN"))
+	mux := admin.NewMux()
+	mux.Supervisor = pipeline.NewSupervisor("consumer", pipeline.OneForOne)
+	mux.Supervisor.Add("events", pipeline.ComponentFunc(func(ctx context.Context) error {
+		return consume(ctx, cfg, client, checkpointer, linger, mux)
+	}))
+	if cfg.Admin != "" {
+		go func() { log.Println(http.ListenAndServe(cfg.Admin, mux)) }()
+	}
+	if err := mux.Supervisor.Run(ctx); err != nil && err != context.Canceled {
+		log.Fatal(err)
+	}
+}
+
+// consume streams events into the sink, checkpointing the offset of each
+// batch once it's stored, until ctx is done.
+func consume(ctx context.Context, cfg *Config, client *gobyairship.Client, checkpointer events.Checkpointer, linger time.Duration, mux *admin.Mux) error {
+	sink, err := newSink(cfg)
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+
+	// The Stream's own checkpointing saves delivered offsets. Offsets are
+	// saved here instead so they're only saved once events are stored.
+	scfg := events.StreamConfig{Start: cfg.Start, OnError: func(err error) { log.Println(err) }}
+	offset, ok, err := checkpointer.Load()
+	if err != nil {
+		return err
+	}
+	if ok {
+		scfg.Start = events.StartOffset
+		scfg.Offset = &offset
+	}
+	stream, err := events.NewStream(ctx, client, scfg)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	mux.Add("events", stream, nil)
+	defer mux.Remove("events")
+
+	for batch := range events.Batch(stream.Events(), cfg.BatchSize, linger) {
+		// Process events here before storing them.
+		if err := sink.Write(batch); err != nil {
+			return err
+		}
+		if err := sink.Flush(); err != nil {
+			return err
+		}
+		if err := checkpointer.Save(batch[len(batch)-1].Offset); err != nil {
+			return err
+		}
+	}
+	return stream.Err()
+}
+
+// ndjsonSink is a sinks.Sink which writes events as newline-delimited JSON.
+type ndjsonSink struct {
+	mark sinks.Watermark
+	f    *os.File
+	w    *bufio.Writer
+}
+{{if eq .Sink "file"}}
+func newSink(cfg *Config) (sinks.Sink, error) {
+	f, err := os.OpenFile(cfg.Output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &ndjsonSink{f: f, w: bufio.NewWriter(f)}, nil
+}
+{{else}}
+func newSink(cfg *Config) (sinks.Sink, error) {
+	return &ndjsonSink{w: bufio.NewWriter(os.Stdout)}, nil
+}
+{{end}}
+func (s *ndjsonSink) Write(evs []*events.Event) error {
+	enc := json.NewEncoder(s.w)
+	for _, ev := range s.mark.Filter(evs) {
+		if err := enc.Encode(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ndjsonSink) Flush() error {
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	if s.f != nil {
+		return s.f.Sync()
+	}
+	return nil
+}
+
+func (s *ndjsonSink) Close() error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	if s.f != nil {
+		return s.f.Close()
+	}
+	return nil
+}
+`))
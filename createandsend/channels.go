@@ -0,0 +1,161 @@
+package createandsend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lytics/gobyairship/push"
+)
+
+// timeLayout of the API's opt-in times, which are in UTC.
+const timeLayout = "2006-01-02T15:04:05"
+
+// Channel is an inline channel to register and notify: an *EmailChannel,
+// *SMSChannel, or *OpenChannel.
+type Channel interface {
+	json.Marshaler
+
+	// deviceType of the channel; open channels return "open".
+	deviceType() string
+
+	validate(r *Request) error
+}
+
+// inline marshals a channel's fields along with its Fields, which may not
+// replace them.
+func inline(fields map[string]string, extra map[string]interface{}) ([]byte, error) {
+	if err := checkFields(extra); err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{}, len(fields)+len(extra))
+	for k, v := range extra {
+		out[k] = v
+	}
+	for k, v := range fields {
+		if v != "" {
+			out[k] = v
+		}
+	}
+	return json.Marshal(out)
+}
+
+// checkFields returns an error if any of fields is reserved.
+func checkFields(fields map[string]interface{}) error {
+	for k := range fields {
+		if strings.HasPrefix(k, "ua_") {
+			return fmt.Errorf("field %q is reserved", k)
+		}
+	}
+	return nil
+}
+
+// formatTime returns t in the API's layout or "" if it's zero.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(timeLayout)
+}
+
+// EmailChannel is an email address to register and notify.
+type EmailChannel struct {
+	Address string
+
+	// CommercialOptedIn and TransactionalOptedIn are when the address opted
+	// in to each type of email. Commercial email requires
+	// CommercialOptedIn.
+	CommercialOptedIn    time.Time
+	TransactionalOptedIn time.Time
+
+	// Fields are substitutions for the address's personalization, such as
+	// {"first_name": "Ada"}. Names starting with "ua_" are reserved.
+	Fields map[string]interface{}
+}
+
+// MarshalJSON encodes the channel inline with its Fields.
+func (c *EmailChannel) MarshalJSON() ([]byte, error) {
+	return inline(map[string]string{
+		"ua_address":                c.Address,
+		"ua_commercial_opted_in":    formatTime(c.CommercialOptedIn),
+		"ua_transactional_opted_in": formatTime(c.TransactionalOptedIn),
+	}, c.Fields)
+}
+
+func (c *EmailChannel) deviceType() string { return string(push.DeviceEmail) }
+
+func (c *EmailChannel) validate(r *Request) error {
+	if !strings.Contains(c.Address, "@") {
+		return fmt.Errorf("invalid email address %q", c.Address)
+	}
+	if r.Email != nil && r.Email.MessageType == Commercial && c.CommercialOptedIn.IsZero() {
+		return fmt.Errorf("commercial email to %s requires its opt-in time", c.Address)
+	}
+	return checkFields(c.Fields)
+}
+
+// SMSChannel is a phone number to register and notify.
+type SMSChannel struct {
+	// MSISDN is the phone number including its country code, digits only.
+	MSISDN string
+
+	// Sender is the long or short code the message is sent from.
+	Sender string
+
+	// OptedIn is when the number opted in to messages from Sender.
+	OptedIn time.Time
+
+	// Fields are substitutions for the number's personalization. Names
+	// starting with "ua_" are reserved.
+	Fields map[string]interface{}
+}
+
+// MarshalJSON encodes the channel inline with its Fields.
+func (c *SMSChannel) MarshalJSON() ([]byte, error) {
+	return inline(map[string]string{
+		"ua_msisdn":   c.MSISDN,
+		"ua_sender":   c.Sender,
+		"ua_opted_in": formatTime(c.OptedIn),
+	}, c.Fields)
+}
+
+func (c *SMSChannel) deviceType() string { return string(push.DeviceSMS) }
+
+func (c *SMSChannel) validate(*Request) error {
+	if c.MSISDN == "" || strings.Trim(c.MSISDN, "0123456789") != "" {
+		return fmt.Errorf("invalid MSISDN %q: use digits only", c.MSISDN)
+	}
+	if c.Sender == "" {
+		return fmt.Errorf("SMS to %s requires a sender", c.MSISDN)
+	}
+	if c.OptedIn.IsZero() {
+		return fmt.Errorf("SMS to %s requires its opt-in time", c.MSISDN)
+	}
+	return checkFields(c.Fields)
+}
+
+// OpenChannel is an address on an open platform, such as a chat app, to
+// register and notify. The platform is the Request's Open.Platform.
+type OpenChannel struct {
+	Address string
+
+	// Fields are substitutions for the address's personalization. Names
+	// starting with "ua_" are reserved.
+	Fields map[string]interface{}
+}
+
+// MarshalJSON encodes the channel inline with its Fields.
+func (c *OpenChannel) MarshalJSON() ([]byte, error) {
+	return inline(map[string]string{"ua_address": c.Address}, c.Fields)
+}
+
+func (c *OpenChannel) deviceType() string { return "open" }
+
+func (c *OpenChannel) validate(*Request) error {
+	if c.Address == "" {
+		return errors.New("open channel without an address")
+	}
+	return checkFields(c.Fields)
+}
@@ -0,0 +1,222 @@
+package createandsend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lytics/gobyairship/push"
+)
+
+// DefaultURL is the Create and Send API endpoint.
+const DefaultURL = "https://go.urbanairship.com/api/create-and-send"
+
+// MaxChannels is the most channels a single request may notify.
+const MaxChannels = 1000
+
+// Client used to send requests. Usually *gobyairship.Client.
+type Client interface {
+	Post(url string, body interface{}, extra http.Header) (*http.Response, error)
+}
+
+// MessageType of an email, which determines the opt-in it requires.
+type MessageType string
+
+const (
+	Commercial    MessageType = "commercial"
+	Transactional MessageType = "transactional"
+)
+
+// Email is the notification sent to EmailChannels.
+type Email struct {
+	Subject       string      `json:"subject"`
+	HTMLBody      string      `json:"html_body,omitempty"`
+	PlaintextBody string      `json:"plaintext_body,omitempty"`
+	MessageType   MessageType `json:"message_type"`
+	SenderName    string      `json:"sender_name"`
+	SenderAddress string      `json:"sender_address"`
+	ReplyTo       string      `json:"reply_to,omitempty"`
+}
+
+func (e *Email) validate() error {
+	switch {
+	case e.Subject == "":
+		return errors.New("email without a subject")
+	case e.HTMLBody == "" && e.PlaintextBody == "":
+		return errors.New("email without an HTML or plaintext body")
+	case e.MessageType != Commercial && e.MessageType != Transactional:
+		return fmt.Errorf("email message type must be %q or %q", Commercial, Transactional)
+	case e.SenderName == "":
+		return errors.New("email without a sender name")
+	case !strings.Contains(e.SenderAddress, "@"):
+		return fmt.Errorf("invalid sender address %q", e.SenderAddress)
+	}
+	return nil
+}
+
+// SMS is the notification sent to SMSChannels.
+type SMS struct {
+	Alert string `json:"alert"`
+
+	// ShortenLinks replaces links in Alert with short links.
+	ShortenLinks bool `json:"shorten_links,omitempty"`
+}
+
+func (s *SMS) validate() error {
+	if s.Alert == "" {
+		return errors.New("SMS without an alert")
+	}
+	return nil
+}
+
+// Open is the notification sent to OpenChannels on Platform.
+type Open struct {
+	// Platform is the name of the open platform, such as "chat".
+	Platform string `json:"-"`
+
+	Alert   string            `json:"alert"`
+	Title   string            `json:"title,omitempty"`
+	Summary string            `json:"summary,omitempty"`
+	Extra   map[string]string `json:"extra,omitempty"`
+}
+
+func (o *Open) validate() error {
+	switch {
+	case o.Platform == "" || strings.ContainsAny(o.Platform, ": "):
+		return fmt.Errorf("invalid open platform %q", o.Platform)
+	case o.Alert == "":
+		return errors.New("open channel notification without an alert")
+	}
+	return nil
+}
+
+// Request registers Channels and notifies them. Every channel must be the
+// same type and the notification for that type must be set: Email for
+// EmailChannels, SMS for SMSChannels, or Open for OpenChannels.
+type Request struct {
+	Channels []Channel
+
+	Email *Email
+	SMS   *SMS
+	Open  *Open
+}
+
+// deviceType of the request's channels.
+func (r *Request) deviceType() string {
+	if len(r.Channels) == 0 || r.Channels[0] == nil {
+		return ""
+	}
+	t := r.Channels[0].deviceType()
+	if t == "open" && r.Open != nil {
+		t = "open::" + r.Open.Platform
+	}
+	return t
+}
+
+// MarshalJSON encodes the request with its channels as the audience.
+func (r *Request) MarshalJSON() ([]byte, error) {
+	t := r.deviceType()
+	notification := map[string]interface{}{}
+	switch {
+	case t == string(push.DeviceEmail):
+		notification["email"] = r.Email
+	case t == string(push.DeviceSMS):
+		notification["sms"] = r.SMS
+	case strings.HasPrefix(t, "open::"):
+		notification[t] = r.Open
+	}
+	return json.Marshal(map[string]interface{}{
+		"audience":     map[string][]Channel{"create_and_send": r.Channels},
+		"device_types": []string{t},
+		"notification": notification,
+	})
+}
+
+// Validate returns nil if the request is valid or an error if there's an
+// issue which the API would reject.
+func (r *Request) Validate() error {
+	if len(r.Channels) == 0 {
+		return errors.New("no channels")
+	}
+	if len(r.Channels) > MaxChannels {
+		return fmt.Errorf("at most %d channels may be sent to: %d", MaxChannels, len(r.Channels))
+	}
+	set := 0
+	for _, n := range []bool{r.Email != nil, r.SMS != nil, r.Open != nil} {
+		if n {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("specify exactly one of Email, SMS, or Open")
+	}
+
+	var t string
+	for i, c := range r.Channels {
+		if c == nil {
+			return fmt.Errorf("nil channel %d", i)
+		}
+		if i == 0 {
+			t = c.deviceType()
+		} else if c.deviceType() != t {
+			return fmt.Errorf("channel %d is %s but channel 0 is %s: send each type separately", i, c.deviceType(), t)
+		}
+		if err := c.validate(r); err != nil {
+			return fmt.Errorf("invalid channel %d: %v", i, err)
+		}
+	}
+	switch t {
+	case string(push.DeviceEmail):
+		if r.Email == nil {
+			return errors.New("email channels require Email")
+		}
+		return r.Email.validate()
+	case string(push.DeviceSMS):
+		if r.SMS == nil {
+			return errors.New("SMS channels require SMS")
+		}
+		return r.SMS.validate()
+	}
+	if r.Open == nil {
+		return errors.New("open channels require Open")
+	}
+	return r.Open.validate()
+}
+
+// API sends Create and Send requests.
+type API struct {
+	c   Client
+	url string
+}
+
+// New creates an API using DefaultURL.
+func New(c Client) *API {
+	return NewURL(c, DefaultURL)
+}
+
+// NewURL creates an API using the Create and Send API at url.
+func NewURL(c Client, url string) *API {
+	return &API{c: c, url: strings.TrimSuffix(url, "/")}
+}
+
+// Send registers the request's channels and notifies them. Invalid requests
+// return Validate's error without being sent, and a *push.APIError is
+// returned if the request is rejected.
+func (a *API) Send(r *Request) (*push.SendResult, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return push.Post(a.c, a.url, r, nil)
+}
+
+// Validate asks the API to validate the request without sending it, after
+// validating it locally.
+func (a *API) Validate(r *Request) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	_, err := push.Post(a.c, a.url+"/validate", r, nil)
+	return err
+}
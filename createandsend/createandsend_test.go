@@ -0,0 +1,154 @@
+package createandsend_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/createandsend"
+	"github.com/lytics/gobyairship/push"
+)
+
+var optedIn = time.Date(2030, 1, 2, 15, 4, 5, 0, time.UTC)
+
+func email() *createandsend.Email {
+	return &createandsend.Email{
+		Subject:       "Welcome",
+		PlaintextBody: "Hello {{first_name}}",
+		MessageType:   createandsend.Commercial,
+		SenderName:    "Example",
+		SenderAddress: "team@example.com",
+	}
+}
+
+func TestSend(t *testing.T) {
+	t.Parallel()
+	var paths []string
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		buf, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(buf, &body); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		w.Header().Set("X-Request-Id", "req")
+		w.WriteHeader(202)
+		w.Write([]byte(`{"ok":true,"operation_id":"op","push_ids":["p1"]}`))
+	}))
+	defer srv.Close()
+	api := createandsend.NewURL(gobyairship.NewClient("key", "token"), srv.URL+"/api/create-and-send/")
+
+	r := &createandsend.Request{
+		Channels: []createandsend.Channel{&createandsend.EmailChannel{
+			Address:           "ada@example.com",
+			CommercialOptedIn: optedIn,
+			Fields:            map[string]interface{}{"first_name": "Ada"},
+		}},
+		Email: email(),
+	}
+	if err := api.Validate(r); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	res, err := api.Send(r)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if res.OperationID != "op" || len(res.PushIDs) != 1 || res.PushIDs[0] != "p1" {
+		t.Errorf("Unexpected result: %#v", res)
+	}
+	if len(paths) != 2 || paths[0] != "/api/create-and-send/validate" || paths[1] != "/api/create-and-send" {
+		t.Errorf("Expected validate then send requests but found %v", paths)
+	}
+
+	expected := `{"audience":{"create_and_send":[{"first_name":"Ada","ua_address":"ada@example.com","ua_commercial_opted_in":"2030-01-02T15:04:05"}]},` +
+		`"device_types":["email"],` +
+		`"notification":{"email":{"message_type":"commercial","plaintext_body":"Hello {{first_name}}","sender_address":"team@example.com","sender_name":"Example","subject":"Welcome"}}}`
+	if found, _ := json.Marshal(body); string(found) != expected {
+		t.Errorf("Expected %s but found %s", expected, found)
+	}
+}
+
+func TestSendError(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"ok":false,"error":"Invalid sender","error_code":40001}`))
+	}))
+	defer srv.Close()
+	api := createandsend.NewURL(gobyairship.NewClient("key", "token"), srv.URL)
+
+	_, err := api.Send(&createandsend.Request{
+		Channels: []createandsend.Channel{&createandsend.SMSChannel{MSISDN: "15035551234", Sender: "12345", OptedIn: optedIn}},
+		SMS:      &createandsend.SMS{Alert: "Hello"},
+	})
+	if apiErr, ok := err.(*push.APIError); !ok || apiErr.StatusCode != 400 {
+		t.Errorf("Expected a 400 *push.APIError but found %v", err)
+	}
+}
+
+func TestMarshalOpen(t *testing.T) {
+	t.Parallel()
+	r := &createandsend.Request{
+		Channels: []createandsend.Channel{&createandsend.OpenChannel{Address: "ada"}},
+		Open:     &createandsend.Open{Platform: "chat", Alert: "Hello"},
+	}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	buf, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{"audience":{"create_and_send":[{"ua_address":"ada"}]},"device_types":["open::chat"],"notification":{"open::chat":{"alert":"Hello"}}}`
+	if string(buf) != expected {
+		t.Errorf("Expected %s but found %s", expected, buf)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	ada := &createandsend.EmailChannel{Address: "ada@example.com", CommercialOptedIn: optedIn}
+	sms := &createandsend.SMSChannel{MSISDN: "15035551234", Sender: "12345", OptedIn: optedIn}
+	transactional := email()
+	transactional.MessageType = createandsend.Transactional
+	noBody := email()
+	noBody.PlaintextBody = ""
+
+	many := make([]createandsend.Channel, createandsend.MaxChannels+1)
+	for i := range many {
+		many[i] = ada
+	}
+	invalid := map[string]*createandsend.Request{
+		"no channels":           {Email: email()},
+		"too many channels":     {Channels: many, Email: email()},
+		"nil channel":           {Channels: []createandsend.Channel{nil}, Email: email()},
+		"mixed channels":        {Channels: []createandsend.Channel{ada, sms}, Email: email()},
+		"missing notification":  {Channels: []createandsend.Channel{ada}},
+		"two notifications":     {Channels: []createandsend.Channel{ada}, Email: email(), SMS: &createandsend.SMS{Alert: "Hi"}},
+		"wrong notification":    {Channels: []createandsend.Channel{sms}, Email: email()},
+		"email without body":    {Channels: []createandsend.Channel{ada}, Email: noBody},
+		"invalid address":       {Channels: []createandsend.Channel{&createandsend.EmailChannel{Address: "ada"}}, Email: transactional},
+		"commercial no opt-in":  {Channels: []createandsend.Channel{&createandsend.EmailChannel{Address: "ada@example.com"}}, Email: email()},
+		"reserved field":        {Channels: []createandsend.Channel{&createandsend.EmailChannel{Address: "ada@example.com", Fields: map[string]interface{}{"ua_address": "x"}}}, Email: transactional},
+		"sms without opt-in":    {Channels: []createandsend.Channel{&createandsend.SMSChannel{MSISDN: "15035551234", Sender: "12345"}}, SMS: &createandsend.SMS{Alert: "Hi"}},
+		"sms invalid msisdn":    {Channels: []createandsend.Channel{&createandsend.SMSChannel{MSISDN: "+1 503", Sender: "12345", OptedIn: optedIn}}, SMS: &createandsend.SMS{Alert: "Hi"}},
+		"sms without alert":     {Channels: []createandsend.Channel{sms}, SMS: &createandsend.SMS{}},
+		"open without platform": {Channels: []createandsend.Channel{&createandsend.OpenChannel{Address: "ada"}}, Open: &createandsend.Open{Alert: "Hi"}},
+		"open without address":  {Channels: []createandsend.Channel{&createandsend.OpenChannel{}}, Open: &createandsend.Open{Platform: "chat", Alert: "Hi"}},
+	}
+	for name, r := range invalid {
+		if err := r.Validate(); err == nil {
+			t.Errorf("Expected an error with %s", name)
+		}
+	}
+
+	// Transactional email doesn't require the commercial opt-in
+	r := &createandsend.Request{Channels: []createandsend.Channel{&createandsend.EmailChannel{Address: "ada@example.com"}}, Email: transactional}
+	if err := r.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
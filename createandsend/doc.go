@@ -0,0 +1,36 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package registers channels and notifies them in one call with Urban
+// Airship's Create and Send API, for email addresses, SMS numbers, and open
+// channels which may not be registered yet:
+//
+//	api := createandsend.New(client)
+//	result, err := api.Send(&createandsend.Request{
+//		Channels: []createandsend.Channel{
+//			&createandsend.EmailChannel{Address: "ada@example.com", TransactionalOptedIn: now},
+//		},
+//		Email: &createandsend.Email{
+//			Subject:       "Your receipt",
+//			PlaintextBody: "Thanks for your order",
+//			MessageType:   createandsend.Transactional,
+//			SenderName:    "Shop",
+//			SenderAddress: "receipts@shop.example.com",
+//		},
+//	})
+//
+// Requests are validated before they're sent, including the fields each
+// channel type requires. Validate also asks the API to validate a request
+// without sending it.
+package createandsend
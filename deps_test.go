@@ -0,0 +1,127 @@
+package gobyairship_test
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const repo = "github.com/lytics/gobyairship"
+
+// integrations are the only packages allowed to import third-party
+// dependencies. Core packages may not import them.
+var integrations = map[string]bool{
+	"events/prometheus": true,
+	"relay/grpc":        true,
+	"sinks/bigquery":    true,
+	"sinks/gcs":         true,
+	"sinks/s3":          true,
+}
+
+// layers lists the repository packages each core package may import so users
+// of one API, such as the events consumer, never pull in code for another.
+// Packages not listed may import any repository package.
+var layers = map[string][]string{
+	".":             nil,
+	"audience":      nil,
+	"createandsend": {".", "audience", "push"},
+	"events":        {"."},
+	"experiments":   {".", "audience", "push", "schedules"},
+	"pipeline":      {"."},
+	"pipelines":     {".", "audience", "push"},
+	"push":          {".", "audience"},
+	"relay":         {".", "events"},
+	"reports":       {"."},
+	"schedules":     {".", "audience", "push"},
+	"sinks":         {".", "events"},
+}
+
+// packages calls fn for each Go package in the repository with its path
+// relative to the repository root.
+func packages(t *testing.T, fn func(rel string, pkg *build.Package)) {
+	err := filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if name := info.Name(); path != "." && (strings.HasPrefix(name, ".") || name == "testdata") {
+			return filepath.SkipDir
+		}
+		pkg, err := build.ImportDir(path, 0)
+		if err != nil {
+			if _, ok := err.(*build.NoGoError); ok {
+				return nil
+			}
+			return err
+		}
+		fn(filepath.ToSlash(path), pkg)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestDependencies ensures the core packages stay dependency-free so users
+// only pull in third-party code by importing an integration package.
+func TestDependencies(t *testing.T) {
+	packages(t, func(rel string, pkg *build.Package) {
+		if integrations[rel] {
+			return
+		}
+		for _, imp := range pkg.Imports {
+			switch {
+			case imp == repo:
+			case strings.HasPrefix(imp, repo+"/"):
+				if integrations[strings.TrimPrefix(imp, repo+"/")] {
+					t.Errorf("core package %s imports integration %s", rel, imp)
+				}
+			case strings.Contains(strings.SplitN(imp, "/", 2)[0], "."):
+				t.Errorf("core package %s imports third-party package %s", rel, imp)
+			}
+		}
+	})
+}
+
+// TestLayers ensures core packages only import the repository packages they
+// are layered on.
+func TestLayers(t *testing.T) {
+	packages(t, func(rel string, pkg *build.Package) {
+		allowed, ok := layers[rel]
+		if !ok {
+			return
+		}
+		for _, imp := range pkg.Imports {
+			var dep string
+			switch {
+			case imp == repo:
+				dep = "."
+			case strings.HasPrefix(imp, repo+"/"):
+				dep = strings.TrimPrefix(imp, repo+"/")
+			default:
+				continue
+			}
+			found := false
+			for _, a := range allowed {
+				found = found || a == dep
+			}
+			if !found {
+				t.Errorf("package %s may not import %s", rel, imp)
+			}
+		}
+	})
+}
+
+// TestPureGo ensures no package requires cgo so the library cross-compiles
+// with CGO_ENABLED=0 for any platform Go supports.
+func TestPureGo(t *testing.T) {
+	packages(t, func(rel string, pkg *build.Package) {
+		if len(pkg.CgoFiles) > 0 {
+			t.Errorf("package %s uses cgo in %v", rel, pkg.CgoFiles)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package events
+
+import "sort"
+
+// pendingAck is an event delivered by a Stream using ManualAck.
+type pendingAck struct {
+	offset uint64
+	acked  bool
+}
+
+// Ack acknowledges that an event received from a Stream using ManualAck has
+// been processed so its offset may be checkpointed. Events may be
+// acknowledged in any order but a checkpoint never passes an unacknowledged
+// event. Ack must be called before Release and is a no-op for events from
+// elsewhere. Safe for concurrent use.
+func (e *Event) Ack() {
+	// acker isn't cleared so concurrent calls don't race; Stream.ack ignores
+	// events which were already acknowledged
+	if e.acker != nil {
+		e.acker.ack(e.Offset)
+	}
+}
+
+// track an event about to be delivered so it may be acknowledged.
+func (s *Stream) track(offset uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acks = append(s.acks, pendingAck{offset: offset})
+}
+
+// delivered records that the event at offset was delivered.
+func (s *Stream) delivered(offset uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset = &offset
+	s.stats.Delivered++
+	s.stats.LastEvent = s.cfg.Clock.Now()
+	if !s.cfg.ManualAck {
+		s.committedEvents(1)
+	}
+}
+
+// ack marks the event at offset acknowledged.
+func (s *Stream) ack(offset uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.acked(offset) {
+		s.stats.Acked++
+	}
+}
+
+// acked marks the event at offset acknowledged and commits every acknowledged
+// event which isn't preceded by an unacknowledged one. It returns false if the
+// event isn't pending. s.mu must be held.
+func (s *Stream) acked(offset uint64) bool {
+	i := sort.Search(len(s.acks), func(i int) bool { return s.acks[i].offset >= offset })
+	if i == len(s.acks) || s.acks[i].offset != offset || s.acks[i].acked {
+		return false
+	}
+	s.acks[i].acked = true
+	n := 0
+	for n < len(s.acks) && s.acks[n].acked {
+		n++
+	}
+	if n == 0 {
+		return true
+	}
+	committed := s.acks[n-1].offset
+	s.committed = &committed
+	s.acks = s.acks[n:]
+	s.committedEvents(n)
+	return true
+}
+
+// committedEvents counts events committed since the last checkpoint and
+// signals the checkpointer once CheckpointEvery is reached. s.mu must be held.
+func (s *Stream) committedEvents(n int) {
+	if s.cfg.Checkpointer == nil || s.cfg.CheckpointEvery <= 0 {
+		return
+	}
+	s.unsaved += n
+	if s.unsaved < s.cfg.CheckpointEvery {
+		return
+	}
+	s.unsaved = 0
+	select {
+	case s.commit <- struct{}{}:
+	default:
+		// A checkpoint is already pending
+	}
+}
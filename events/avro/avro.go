@@ -0,0 +1,215 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+// Namespace of the records in every schema.
+const Namespace = "gobyairship.events"
+
+// bodies are the types event bodies are decoded into for per-type schemas.
+var bodies = map[events.Type]interface{}{
+	events.TypePush:      events.PushBody{},
+	events.TypeOpen:      events.Open{},
+	events.TypeSend:      events.Send{},
+	events.TypeClose:     events.Close{},
+	events.TypeTagChange: events.TagChange{},
+	events.TypeUninstall: struct{}{},
+	events.TypeFirst: struct {
+		SessionID string `json:"session_id"`
+	}{},
+	events.TypeCustom:                 events.Custom{},
+	events.TypeLocation:               events.Location{},
+	events.TypeRichDelivery:           events.RichEvent{},
+	events.TypeRichRead:               events.RichEvent{},
+	events.TypeRichDelete:             events.RichEvent{},
+	events.TypeInAppMessageDisplay:    events.InAppMessageDisplay{},
+	events.TypeInAppMessageResolution: events.InAppMessageResolution{},
+	events.TypeInAppMessageExpiration: events.InAppMessageExpiration{},
+	events.TypeScreenViewed:           events.ScreenViewed{},
+	events.TypeRegion:                 events.Region{},
+	events.TypeControl:                events.Control{},
+	events.TypeSendAborted:            events.SendAborted{},
+	events.TypeEmailDelivery:          events.Email{},
+	events.TypeEmailOpen:              events.Email{},
+	events.TypeEmailClick:             events.Email{},
+	events.TypeEmailBounce:            events.Email{},
+	events.TypeEmailUnsubscribe:       events.Email{},
+	events.TypeSMSDelivery:            events.SMSDelivery{},
+	events.TypeMobileOriginated:       events.MobileOriginated{},
+	events.TypeSMSOptIn:               events.SMSOpt{},
+	events.TypeSMSOptOut:              events.SMSOpt{},
+	events.TypeWebClick:               events.WebClick{},
+	events.TypeAttributeOperation: struct {
+		Mutations []events.AttributeOperation `json:"mutations"`
+	}{},
+	events.TypeSubscriptionListChange: events.SubscriptionListChange{},
+	events.TypeCompliance:             events.Compliance{},
+}
+
+// envelope holds the fields shared by every event. Offsets above
+// math.MaxInt64 wrap around to negative longs.
+type envelope struct {
+	ID           string         `json:"id"`
+	Type         events.Type    `json:"type"`
+	Offset       uint64         `json:"offset"`
+	OpaqueOffset string         `json:"opaque_offset"`
+	Occurred     time.Time      `json:"occurred"`
+	Processed    time.Time      `json:"processed"`
+	Device       *events.Device `json:"device"`
+}
+
+var envelopeType = reflect.TypeOf(envelope{})
+
+// Types returns the event types ForType supports in sorted order.
+func Types() []events.Type {
+	types := make([]events.Type, 0, len(bodies))
+	for t := range bodies {
+		types = append(types, t)
+	}
+	sort.Sort(typeSlice(types))
+	return types
+}
+
+type typeSlice []events.Type
+
+func (s typeSlice) Len() int           { return len(s) }
+func (s typeSlice) Less(i, j int) bool { return s[i] < s[j] }
+func (s typeSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// Codec encodes events with one schema. Safe for concurrent use.
+type Codec struct {
+	typ      events.Type
+	schema   string
+	envelope encodeFunc
+	body     reflect.Type // nil if the body is encoded as JSON
+	bodyEnc  encodeFunc
+}
+
+// Envelope returns a Codec for events of every type. The body is a nullable
+// string of the body's JSON.
+func Envelope() *Codec {
+	c, err := newCodec("", "Event", nil)
+	if err != nil {
+		// The envelope is fixed so this is a programming error
+		panic(err)
+	}
+	return c
+}
+
+// ForType returns a Codec for events of type t. The body is a record of the
+// body's fields, such as Open for TypeOpen.
+func ForType(t events.Type) (*Codec, error) {
+	body, ok := bodies[t]
+	if !ok {
+		return nil, fmt.Errorf("no schema for event type %q", t)
+	}
+	return newCodec(t, camel(string(t))+"Event", reflect.TypeOf(body))
+}
+
+func newCodec(t events.Type, name string, body reflect.Type) (*Codec, error) {
+	b := newBuilder()
+	var fields []field
+	if err := b.fields(envelopeType, nil, map[string]bool{}, &fields); err != nil {
+		return nil, err
+	}
+	c := &Codec{typ: t, body: body}
+	var bodySchema, bodyDefault interface{}
+	var err error
+	if body == nil {
+		bodySchema, bodyDefault, c.bodyEnc, err = b.build(rawType, "")
+	} else {
+		bodySchema, bodyDefault, c.bodyEnc, err = b.build(body, camel(string(t))+"Body")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := make([]interface{}, 0, len(fields)+1)
+	for _, f := range fields {
+		schemas = append(schemas, f.schema)
+	}
+	schemas = append(schemas, map[string]interface{}{"name": "body", "type": bodySchema, "default": bodyDefault})
+	schema, err := json.Marshal(map[string]interface{}{
+		"type":      "record",
+		"name":      name,
+		"namespace": Namespace,
+		"fields":    schemas,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.schema = string(schema)
+	c.envelope = func(buf []byte, v reflect.Value) ([]byte, error) {
+		var err error
+		for _, f := range fields {
+			if buf, err = f.enc(buf, v.FieldByIndex(f.index)); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	}
+	return c, nil
+}
+
+// Type returns the Codec's event type or an empty string for the envelope.
+func (c *Codec) Type() events.Type { return c.typ }
+
+// Schema returns the Codec's schema as JSON.
+func (c *Codec) Schema() string { return c.schema }
+
+// Marshal returns the Avro binary encoding of ev. Returns events.WrongType if
+// the Codec is for a different type of event.
+func (c *Codec) Marshal(ev *events.Event) ([]byte, error) {
+	return c.Append(make([]byte, 0, len(ev.Body)+128), ev)
+}
+
+// Append appends the Avro binary encoding of ev to b.
+func (c *Codec) Append(b []byte, ev *events.Event) ([]byte, error) {
+	if c.typ != "" && ev.Type != c.typ {
+		return nil, events.WrongType
+	}
+	env := envelope{
+		ID:           ev.ID,
+		Type:         ev.Type,
+		Offset:       ev.Offset,
+		OpaqueOffset: ev.OpaqueOffset,
+		Occurred:     ev.Occurred,
+		Processed:    ev.Processed,
+		Device:       ev.Device,
+	}
+	b, err := c.envelope(b, reflect.ValueOf(env))
+	if err != nil {
+		return nil, err
+	}
+	body := reflect.ValueOf(ev.Body)
+	if c.body != nil {
+		body = reflect.New(c.body)
+		if len(ev.Body) > 0 {
+			if err := json.Unmarshal(ev.Body, body.Interface()); err != nil {
+				return nil, fmt.Errorf("error decoding %s event %s: %v", ev.Type, ev.ID, err)
+			}
+		}
+		body = body.Elem()
+	}
+	if b, err = c.bodyEnc(b, body); err != nil {
+		return nil, fmt.Errorf("error encoding %s event %s: %v", ev.Type, ev.ID, err)
+	}
+	return b, nil
+}
+
+// Frame prefixes an encoded event with the header used by Confluent's schema
+// registry serializers: a zero byte followed by the 4 byte big endian schema
+// ID.
+func Frame(schemaID uint32, b []byte) []byte {
+	framed := make([]byte, 5, 5+len(b))
+	binary.BigEndian.PutUint32(framed[1:], schemaID)
+	return append(framed, b...)
+}
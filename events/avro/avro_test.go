@@ -0,0 +1,279 @@
+package avro_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/events/avro"
+)
+
+// decoder decodes Avro binary data with a parsed schema so tests can check
+// encoded events against their schemas.
+type decoder struct {
+	names map[string]interface{}
+	b     []byte
+}
+
+func decode(t *testing.T, schema string, b []byte) map[string]interface{} {
+	var s interface{}
+	if err := json.Unmarshal([]byte(schema), &s); err != nil {
+		t.Fatalf("Unexpected error parsing schema: %v", err)
+	}
+	d := &decoder{names: map[string]interface{}{}, b: b}
+	d.define(s)
+	v, err := d.value(s)
+	if err != nil {
+		t.Fatalf("Unexpected error decoding: %v", err)
+	}
+	if len(d.b) > 0 {
+		t.Fatalf("Expected all data to be decoded but %d bytes remain", len(d.b))
+	}
+	return v.(map[string]interface{})
+}
+
+// define records the named types in a schema so later uses may refer to them
+// by name.
+func (d *decoder) define(s interface{}) {
+	switch s := s.(type) {
+	case []interface{}:
+		for _, v := range s {
+			d.define(v)
+		}
+	case map[string]interface{}:
+		if s["type"] == "record" {
+			d.names[s["name"].(string)] = s
+			for _, f := range s["fields"].([]interface{}) {
+				d.define(f.(map[string]interface{})["type"])
+			}
+			return
+		}
+		d.define(s["type"])
+		d.define(s["items"])
+		d.define(s["values"])
+	}
+}
+
+func (d *decoder) long() (int64, error) {
+	n, l := binary.Varint(d.b)
+	if l <= 0 {
+		return 0, fmt.Errorf("invalid long")
+	}
+	d.b = d.b[l:]
+	return n, nil
+}
+
+func (d *decoder) bytes() ([]byte, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || int64(len(d.b)) < n {
+		return nil, fmt.Errorf("invalid length %d", n)
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b, nil
+}
+
+func (d *decoder) value(s interface{}) (interface{}, error) {
+	switch s := s.(type) {
+	case string:
+		switch s {
+		case "null":
+			return nil, nil
+		case "boolean":
+			if len(d.b) == 0 {
+				return nil, fmt.Errorf("invalid boolean")
+			}
+			v := d.b[0] == 1
+			d.b = d.b[1:]
+			return v, nil
+		case "long":
+			return d.long()
+		case "double":
+			if len(d.b) < 8 {
+				return nil, fmt.Errorf("invalid double")
+			}
+			v := math.Float64frombits(binary.LittleEndian.Uint64(d.b))
+			d.b = d.b[8:]
+			return v, nil
+		case "string":
+			b, err := d.bytes()
+			return string(b), err
+		case "bytes":
+			return d.bytes()
+		}
+		named, ok := d.names[s]
+		if !ok {
+			return nil, fmt.Errorf("unknown type %q", s)
+		}
+		return d.value(named)
+	case []interface{}:
+		i, err := d.long()
+		if err != nil || i < 0 || int(i) >= len(s) {
+			return nil, fmt.Errorf("invalid union index %d", i)
+		}
+		return d.value(s[i])
+	case map[string]interface{}:
+		switch s["type"] {
+		case "record":
+			rec := map[string]interface{}{}
+			for _, f := range s["fields"].([]interface{}) {
+				f := f.(map[string]interface{})
+				v, err := d.value(f["type"])
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", f["name"], err)
+				}
+				rec[f["name"].(string)] = v
+			}
+			return rec, nil
+		case "array", "map":
+			var items []interface{}
+			m := map[string]interface{}{}
+			for {
+				n, err := d.long()
+				if err != nil {
+					return nil, err
+				}
+				if n == 0 {
+					break
+				}
+				for ; n > 0; n-- {
+					var key []byte
+					if s["type"] == "map" {
+						if key, err = d.bytes(); err != nil {
+							return nil, err
+						}
+					}
+					v, err := d.value(s[map[interface{}]string{"array": "items", "map": "values"}[s["type"]]])
+					if err != nil {
+						return nil, err
+					}
+					items = append(items, v)
+					m[string(key)] = v
+				}
+			}
+			if s["type"] == "map" {
+				return m, nil
+			}
+			return items, nil
+		}
+		return d.value(s["type"])
+	}
+	return nil, fmt.Errorf("invalid schema %v", s)
+}
+
+// fixture returns the events in a file in the events package's testdata.
+func fixture(t *testing.T, name string) []*events.Event {
+	f, err := os.Open(filepath.Join("..", "testdata", name))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer f.Close()
+	var evs []*events.Event
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		ev := &events.Event{}
+		if err := json.Unmarshal(s.Bytes(), ev); err != nil {
+			t.Fatalf("Unexpected error decoding %s: %v", name, err)
+		}
+		evs = append(evs, ev)
+	}
+	return evs
+}
+
+func checkEnvelope(t *testing.T, ev *events.Event, rec map[string]interface{}) {
+	if rec["id"] != ev.ID || rec["type"] != string(ev.Type) || rec["offset"] != int64(ev.Offset) {
+		t.Errorf("Expected %s %s at %d but decoded %v", ev.Type, ev.ID, ev.Offset, rec)
+	}
+	if ms := ev.Processed.UnixNano() / 1e6; rec["processed"] != ms {
+		t.Errorf("Expected processed %d but found %v", ms, rec["processed"])
+	}
+	if (ev.Device == nil) != (rec["device"] == nil) {
+		t.Errorf("Expected device %v but found %v", ev.Device, rec["device"])
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	c := avro.Envelope()
+	for _, ev := range fixture(t, "all.json") {
+		b, err := c.Marshal(ev)
+		if err != nil {
+			t.Fatalf("Unexpected error encoding %s: %v", ev.ID, err)
+		}
+		rec := decode(t, c.Schema(), b)
+		checkEnvelope(t, ev, rec)
+		var expected interface{} = string(ev.Body)
+		if len(ev.Body) == 0 || string(ev.Body) == "null" {
+			expected = nil
+		}
+		if rec["body"] != expected {
+			t.Errorf("Expected body %q but found %v", ev.Body, rec["body"])
+		}
+	}
+}
+
+func TestForType(t *testing.T) {
+	for _, typ := range avro.Types() {
+		c, err := avro.ForType(typ)
+		if err != nil {
+			t.Fatalf("Unexpected error creating %s codec: %v", typ, err)
+		}
+		for _, ev := range fixture(t, strings.ToLower(string(typ))+".json") {
+			b, err := c.Marshal(ev)
+			if err != nil {
+				t.Fatalf("Unexpected error encoding %s: %v", ev.ID, err)
+			}
+			rec := decode(t, c.Schema(), b)
+			checkEnvelope(t, ev, rec)
+			if _, ok := rec["body"].(map[string]interface{}); !ok {
+				t.Errorf("Expected %s body record but found %v", typ, rec["body"])
+			}
+		}
+	}
+
+	c, err := avro.ForType(events.TypeOpen)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ev := &events.Event{
+		ID:   "open",
+		Type: events.TypeOpen,
+		Body: json.RawMessage(`{"session_id":"s","triggering_push":{"push_id":"p"}}`),
+	}
+	b, err := c.Marshal(ev)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	body := decode(t, c.Schema(), b)["body"].(map[string]interface{})
+	if body["session_id"] != "s" || body["last_delivered"] != nil {
+		t.Errorf("Unexpected body: %v", body)
+	}
+	if p := body["triggering_push"].(map[string]interface{}); p["push_id"] != "p" {
+		t.Errorf("Expected triggering push p but found %v", p)
+	}
+
+	ev.Type = events.TypeSend
+	if _, err := c.Marshal(ev); err != events.WrongType {
+		t.Errorf("Expected WrongType but found %v", err)
+	}
+	if _, err := avro.ForType("nope"); err == nil {
+		t.Errorf("Expected error for unknown type")
+	}
+}
+
+func TestFrame(t *testing.T) {
+	b := avro.Frame(0x01020304, []byte{42})
+	if string(b) != "\x00\x01\x02\x03\x04\x2a" {
+		t.Errorf("Unexpected frame: %x", b)
+	}
+}
@@ -0,0 +1,43 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package encodes events in Avro's binary encoding so they can be written
+// to schema-registry-backed Kafka topics with a shared schema.
+//
+// A Codec's schema is either the envelope, with the body kept as a JSON
+// string, for topics carrying every type of event:
+//
+//	c := avro.Envelope()
+//	id, err := registry.Register(subject, c.Schema())
+//	if err != nil {
+//		return err
+//	}
+//	for ev := range s.Events() {
+//		b, err := c.Marshal(ev)
+//		if err != nil {
+//			return err
+//		}
+//		produce(topic, avro.Frame(id, b))
+//	}
+//
+// or a single type of event with its body as a record, for topics per type:
+//
+//	c, err := avro.ForType(events.TypeOpen)
+//
+// Schemas are derived from the body types in the events package, so they
+// change when fields are added there. Adding fields is a backward compatible
+// schema change because every field has a default.
+//
+// This package only depends on the standard library.
+package avro
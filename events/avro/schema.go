@@ -0,0 +1,306 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// encodeFunc appends the Avro binary encoding of v to b.
+type encodeFunc func(b []byte, v reflect.Value) ([]byte, error)
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	numberType = reflect.TypeOf(json.Number(""))
+	rawType    = reflect.TypeOf(json.RawMessage(nil))
+	bytesType  = reflect.TypeOf([]byte(nil))
+)
+
+var (
+	timestamp = map[string]interface{}{"type": "long", "logicalType": "timestamp-millis"}
+	nullable  = func(s interface{}) []interface{} { return []interface{}{"null", s} }
+)
+
+// builder derives Avro schemas and encoders from Go types the way
+// encoding/json would marshal them.
+type builder struct {
+	// names are records already defined in the schema being built. Avro
+	// requires later uses to refer to them by name.
+	names map[string]bool
+}
+
+func newBuilder() *builder { return &builder{names: map[string]bool{}} }
+
+// build returns the schema, default value, and encoder of t.
+func (b *builder) build(t reflect.Type, name string) (interface{}, interface{}, encodeFunc, error) {
+	switch t {
+	case timeType:
+		return timestamp, 0, encodeTime, nil
+	case numberType:
+		return nullable("double"), nil, encodeNumber, nil
+	case rawType:
+		return nullable("string"), nil, encodeRaw, nil
+	case bytesType:
+		return "bytes", "", encodeBytes, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string", "", encodeString, nil
+	case reflect.Bool:
+		return "boolean", false, encodeBool, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "long", 0, encodeInt, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "long", 0, encodeUint, nil
+	case reflect.Float32, reflect.Float64:
+		return "double", 0, encodeFloat, nil
+	case reflect.Ptr:
+		s, _, enc, err := b.build(t.Elem(), name)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return nullable(s), nil, encodeNullable(enc), nil
+	case reflect.Slice:
+		s, _, enc, err := b.build(t.Elem(), name)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": s}, []interface{}{}, encodeArray(enc), nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			break
+		}
+		s, _, enc, err := b.build(t.Elem(), name)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return map[string]interface{}{"type": "map", "values": s}, map[string]interface{}{}, encodeMap(enc), nil
+	case reflect.Struct:
+		return b.record(t, name)
+	}
+	return nil, nil, nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// field of a record and the index path of the Go struct field it encodes.
+type field struct {
+	schema map[string]interface{}
+	index  []int
+	enc    encodeFunc
+}
+
+// record returns the schema of a struct as a record named after the Go type,
+// or name if it's anonymous. Embedded structs without JSON names are
+// flattened into the record like encoding/json does.
+func (b *builder) record(t reflect.Type, name string) (interface{}, interface{}, encodeFunc, error) {
+	if t.Name() != "" {
+		name = t.Name()
+	}
+	var fields []field
+	seen := map[string]bool{}
+	if err := b.fields(t, nil, seen, &fields); err != nil {
+		return nil, nil, nil, err
+	}
+
+	schemas := make([]interface{}, len(fields))
+	def := map[string]interface{}{}
+	for i, f := range fields {
+		schemas[i] = f.schema
+		def[f.schema["name"].(string)] = f.schema["default"]
+	}
+	var schema interface{} = name
+	if !b.names[name] {
+		b.names[name] = true
+		schema = map[string]interface{}{"type": "record", "name": name, "fields": schemas}
+	}
+	enc := func(buf []byte, v reflect.Value) ([]byte, error) {
+		var err error
+		for _, f := range fields {
+			if buf, err = f.enc(buf, v.FieldByIndex(f.index)); err != nil {
+				return nil, fmt.Errorf("%s: %v", f.schema["name"], err)
+			}
+		}
+		return buf, nil
+	}
+	return schema, def, enc, nil
+}
+
+// fields appends the fields of t, then those of its embedded structs, so
+// shallower fields take precedence like they do in encoding/json.
+func (b *builder) fields(t reflect.Type, index []int, seen map[string]bool, fields *[]field) error {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if tag == "-" || sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			embedded = append(embedded, sf)
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		s, def, enc, err := b.build(sf.Type, camel(name))
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		*fields = append(*fields, field{
+			schema: map[string]interface{}{"name": name, "type": s, "default": def},
+			index:  append(append([]int(nil), index...), i),
+			enc:    enc,
+		})
+	}
+	for _, sf := range embedded {
+		if err := b.fields(sf.Type, append(append([]int(nil), index...), sf.Index...), seen, fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// camel converts snake or upper case names such as "ATTRIBUTE_OPERATION" to
+// AttributeOperation for use as record names.
+func camel(s string) string {
+	parts := strings.Split(strings.ToLower(s), "_")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func appendLong(b []byte, n int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutVarint(buf[:], n)]...)
+}
+
+func appendString(b []byte, s string) []byte {
+	return append(appendLong(b, int64(len(s))), s...)
+}
+
+func encodeString(b []byte, v reflect.Value) ([]byte, error) {
+	return appendString(b, v.String()), nil
+}
+
+func encodeBytes(b []byte, v reflect.Value) ([]byte, error) {
+	return append(appendLong(b, int64(v.Len())), v.Bytes()...), nil
+}
+
+func encodeBool(b []byte, v reflect.Value) ([]byte, error) {
+	if v.Bool() {
+		return append(b, 1), nil
+	}
+	return append(b, 0), nil
+}
+
+func encodeInt(b []byte, v reflect.Value) ([]byte, error) {
+	return appendLong(b, v.Int()), nil
+}
+
+// encodeUint encodes unsigned integers as longs. Values above math.MaxInt64,
+// such as very large offsets, wrap around to negative longs.
+func encodeUint(b []byte, v reflect.Value) ([]byte, error) {
+	return appendLong(b, int64(v.Uint())), nil
+}
+
+func encodeFloat(b []byte, v reflect.Value) ([]byte, error) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v.Float()))
+	return append(b, buf[:]...), nil
+}
+
+// encodeTime encodes times as milliseconds since the Unix epoch.
+func encodeTime(b []byte, v reflect.Value) ([]byte, error) {
+	t := v.Interface().(time.Time)
+	if t.IsZero() {
+		return appendLong(b, 0), nil
+	}
+	return appendLong(b, t.UnixNano()/int64(time.Millisecond)), nil
+}
+
+// encodeNumber encodes a json.Number as a nullable double. Empty numbers are
+// null.
+func encodeNumber(b []byte, v reflect.Value) ([]byte, error) {
+	s := v.String()
+	if s == "" {
+		return appendLong(b, 0), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return encodeFloat(appendLong(b, 1), reflect.ValueOf(f))
+}
+
+// encodeRaw encodes raw JSON as a nullable string. Empty and null values are
+// null.
+func encodeRaw(b []byte, v reflect.Value) ([]byte, error) {
+	raw := v.Bytes()
+	if len(raw) == 0 || string(raw) == "null" {
+		return appendLong(b, 0), nil
+	}
+	return append(appendLong(appendLong(b, 1), int64(len(raw))), raw...), nil
+}
+
+// encodeNullable encodes pointers as the union of null and their element.
+func encodeNullable(enc encodeFunc) encodeFunc {
+	return func(b []byte, v reflect.Value) ([]byte, error) {
+		if v.IsNil() {
+			return appendLong(b, 0), nil
+		}
+		return enc(appendLong(b, 1), v.Elem())
+	}
+}
+
+// encodeArray encodes slices as a single block of items.
+func encodeArray(enc encodeFunc) encodeFunc {
+	return func(b []byte, v reflect.Value) ([]byte, error) {
+		var err error
+		if n := v.Len(); n > 0 {
+			b = appendLong(b, int64(n))
+			for i := 0; i < n; i++ {
+				if b, err = enc(b, v.Index(i)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return appendLong(b, 0), nil
+	}
+}
+
+// encodeMap encodes maps as a single block of entries in sorted key order so
+// encoding is deterministic.
+func encodeMap(enc encodeFunc) encodeFunc {
+	return func(b []byte, v reflect.Value) ([]byte, error) {
+		var err error
+		if n := v.Len(); n > 0 {
+			keys := make([]string, 0, n)
+			for _, k := range v.MapKeys() {
+				keys = append(keys, k.String())
+			}
+			sort.Strings(keys)
+			b = appendLong(b, int64(n))
+			for _, k := range keys {
+				b = appendString(b, k)
+				kv := reflect.ValueOf(k).Convert(v.Type().Key())
+				if b, err = enc(b, v.MapIndex(kv)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return appendLong(b, 0), nil
+	}
+}
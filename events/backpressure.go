@@ -0,0 +1,407 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// DropOldest discards the oldest buffered event to make room for each new
+// event when the consumer of Events falls behind. Discarded events are
+// counted by Dropped.
+//
+// By default a Response stops reading from the network while its buffer is
+// full, and Urban Airship drops connections which stay idle for too long.
+// DropOldest and SpillToDisk keep reading instead. With a BufferSize of zero
+// the new event is discarded instead. Only use DropOldest when losing events
+// is preferable to falling behind, such as for real-time dashboards.
+func DropOldest() Option {
+	return func(r *Response) { r.dropOldest = true }
+}
+
+// SpillToDisk writes events to a temporary file in dir when the consumer of
+// Events falls behind and feeds them back in order as the consumer catches
+// up. The file is removed when the Response ends. If dir is empty the default
+// temporary directory is used.
+//
+// The file is used as a ring of at most maxBytes. What happens when an event
+// doesn't fit is determined by SpillFull and defaults to SpillBlock. Events
+// may be compressed with SpillCompression. SpillToDisk takes precedence over
+// DropOldest.
+func SpillToDisk(dir string, maxBytes int64) Option {
+	return func(r *Response) {
+		r.spillDir = dir
+		r.spillMax = maxBytes
+		r.spillEnabled = true
+	}
+}
+
+// SpillPolicy determines what a Response using SpillToDisk does when an event
+// doesn't fit in the spill file.
+type SpillPolicy int
+
+const (
+	// SpillBlock stops reading from the network until the consumer has
+	// drained enough of the file, risking the connection being dropped.
+	SpillBlock SpillPolicy = iota
+
+	// SpillDropOldest discards the oldest spilled events to make room.
+	SpillDropOldest
+
+	// SpillDropNewest discards the event which doesn't fit.
+	SpillDropNewest
+)
+
+// SpillFull sets the policy for events which don't fit in the spill file of
+// SpillToDisk. How often each policy applied is counted by SpillStats.
+func SpillFull(policy SpillPolicy) Option {
+	return func(r *Response) { r.spillPolicy = policy }
+}
+
+// SpillStats counts how often a full spill file was handled by each
+// SpillPolicy.
+type SpillStats struct {
+	// Blocked is the number of events which waited for room.
+	Blocked uint64 `json:"blocked"`
+
+	// DroppedOldest and DroppedNewest are the number of events discarded.
+	DroppedOldest uint64 `json:"dropped_oldest"`
+	DroppedNewest uint64 `json:"dropped_newest"`
+}
+
+// SpillStats returns the counters of the Response's spill file, which are
+// zero without SpillToDisk. Safe for concurrent access.
+func (r *Response) SpillStats() SpillStats {
+	if r.spill == nil {
+		return SpillStats{}
+	}
+	r.spill.mu.Lock()
+	defer r.spill.mu.Unlock()
+	return r.spill.stats
+}
+
+// OnSaturated calls fn from the decoding goroutine when the buffer of events
+// fills up. fn is called once each time the buffer becomes full, not for every
+// event decoded while it stays full, and should return quickly.
+func OnSaturated(fn func()) Option {
+	return func(r *Response) { r.onSaturated = fn }
+}
+
+// Dropped returns the number of events discarded by DropOldest. Safe for
+// concurrent access.
+func (r *Response) Dropped() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dropped
+}
+
+// send an event to the Events chan according to the Response's backpressure
+// options. Returns false if the Response was closed.
+func (r *Response) send(ev *Event) bool {
+	if r.spill != nil && r.spill.pending() {
+		// Keep events in order behind those already spilled
+		return r.spill.push(ev)
+	}
+	if !r.dropOldest && r.spill == nil && r.onSaturated == nil {
+		select {
+		case r.out <- ev:
+			return true
+		case <-r.closed:
+			return false
+		}
+	}
+
+	select {
+	case r.out <- ev:
+		r.saturated = false
+		return true
+	case <-r.closed:
+		return false
+	default:
+	}
+	if !r.saturated {
+		r.saturated = true
+		if r.onSaturated != nil {
+			r.onSaturated()
+		}
+	}
+	switch {
+	case r.spill != nil:
+		return r.spill.push(ev)
+	case r.dropOldest:
+		return r.sendDropping(ev)
+	}
+	select {
+	case r.out <- ev:
+		return true
+	case <-r.closed:
+		return false
+	}
+}
+
+// sendDropping discards buffered events until ev fits in the Events chan.
+func (r *Response) sendDropping(ev *Event) bool {
+	for {
+		select {
+		case r.out <- ev:
+			return true
+		case <-r.closed:
+			return false
+		default:
+		}
+		dropped := ev
+		if cap(r.out) > 0 {
+			select {
+			case dropped = <-r.out:
+			default:
+				// The consumer emptied the buffer; try again
+				continue
+			}
+		}
+		dropped.Release()
+		r.mu.Lock()
+		r.dropped++
+		r.mu.Unlock()
+		if dropped == ev {
+			return true
+		}
+	}
+}
+
+// spill is a bounded on-disk queue of newline delimited events which a pump
+// goroutine feeds to the Events chan in order. The file is a ring: events are
+// written after the newest one, wrapping to the start of the file once there's
+// room before the oldest.
+type spill struct {
+	r      *Response
+	f      *os.File
+	max    int64
+	policy SpillPolicy
+	codec  SpillCodec
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queued  []spilled // unread events, oldest first
+	sending bool      // the pump has read an event it hasn't sent yet
+	wr      int64     // where the next event is written if it fits
+	ended   bool      // no more events will be pushed
+	stopped bool      // the Response was closed
+	stats   SpillStats
+	drained chan struct{}
+}
+
+// spilled is the location and checksum of an event in the file.
+type spilled struct {
+	off, n int64
+	crc    uint32
+}
+
+// crcTable is the CRC-32C table used to checksum spilled events.
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+func newSpill(r *Response) (*spill, error) {
+	f, err := ioutil.TempFile(r.spillDir, "gobyairship-spill-")
+	if err != nil {
+		return nil, err
+	}
+	s := &spill{r: r, f: f, max: r.spillMax, policy: r.spillPolicy, codec: r.spillCodec, drained: make(chan struct{})}
+	s.cond = sync.NewCond(&s.mu)
+	go s.pump()
+	go func() {
+		select {
+		case <-r.closed:
+		case <-s.drained:
+			return
+		}
+		s.mu.Lock()
+		s.stopped = true
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	}()
+	return s, nil
+}
+
+// pending returns true if spilled events have not been sent yet.
+func (s *spill) pending() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queued) > 0 || s.sending
+}
+
+// place returns where an event of n bytes may be written or false if there's
+// no room. An event always fits in an empty file. s.mu must be held.
+func (s *spill) place(n int64) (int64, bool) {
+	if len(s.queued) == 0 {
+		return 0, true
+	}
+	oldest := s.queued[0].off
+	if s.wr > oldest {
+		// Free space is after the newest event and before the oldest
+		if s.wr+n <= s.max {
+			return s.wr, true
+		}
+		return 0, n <= oldest
+	}
+	return s.wr, s.wr+n <= oldest
+}
+
+// dropOldest discards the oldest spilled event. Returns false if there's
+// none. s.mu must be held.
+func (s *spill) dropOldest() bool {
+	if len(s.queued) == 0 {
+		return false
+	}
+	s.queued = s.queued[1:]
+	s.stats.DroppedOldest++
+	if len(s.queued) == 0 {
+		s.wr = 0
+	}
+	return true
+}
+
+// push appends an event to the file, applying the spill policy if it doesn't
+// fit. Returns false if the Response was closed.
+func (s *spill) push(ev *Event) bool {
+	line, err := json.Marshal(ev)
+	ev.Release()
+	if err != nil {
+		s.r.fail(err)
+		return false
+	}
+	line = append(line, '\n')
+	if s.codec != nil {
+		if line, err = s.codec.Encode(nil, line); err != nil {
+			s.r.fail(err)
+			return false
+		}
+	}
+	n := int64(len(line))
+	crc := crc32.Checksum(line, crcTable)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	off, ok := s.place(n)
+	switch {
+	case ok:
+	case s.policy == SpillDropNewest:
+		s.stats.DroppedNewest++
+		return true
+	case s.policy == SpillDropOldest:
+		for !ok && s.dropOldest() {
+			off, ok = s.place(n)
+		}
+	}
+	if !ok && !s.stopped {
+		// An empty file always has room so only SpillBlock waits
+		s.stats.Blocked++
+		for off, ok = s.place(n); !ok && !s.stopped; off, ok = s.place(n) {
+			s.cond.Wait()
+		}
+	}
+	if s.stopped {
+		return false
+	}
+	if _, err := s.f.WriteAt(line, off); err != nil {
+		s.r.fail(err)
+		return false
+	}
+	s.wr = off + n
+	s.queued = append(s.queued, spilled{off: off, n: n, crc: crc})
+	s.cond.Broadcast()
+	return true
+}
+
+// pump sends spilled events until the Response is closed or ends and all
+// spilled events have been sent.
+func (s *spill) pump() {
+	defer close(s.drained)
+	var buf, decoded []byte
+	for {
+		s.mu.Lock()
+		for len(s.queued) == 0 && !s.ended && !s.stopped {
+			s.cond.Wait()
+		}
+		if s.stopped || len(s.queued) == 0 {
+			s.mu.Unlock()
+			return
+		}
+		// Read the oldest event while holding the lock so its space can be
+		// reused as soon as it's off the queue
+		n, off, crc := s.queued[0].n, s.queued[0].off, s.queued[0].crc
+		if int64(cap(buf)) < n {
+			buf = make([]byte, n)
+		}
+		buf = buf[:n]
+		if _, err := s.f.ReadAt(buf, off); err != nil {
+			s.mu.Unlock()
+			s.fail(err)
+			return
+		}
+		s.queued = s.queued[1:]
+		if len(s.queued) == 0 {
+			// Reuse the file from the start once it's drained
+			s.wr = 0
+			s.f.Truncate(0)
+		}
+		s.sending = true
+		s.cond.Broadcast()
+		s.mu.Unlock()
+
+		if crc32.Checksum(buf, crcTable) != crc {
+			s.fail(fmt.Errorf("corrupt spilled event at %d of %s", off, s.f.Name()))
+			return
+		}
+		line := buf
+		if s.codec != nil {
+			var err error
+			if decoded, err = s.codec.Decode(decoded[:0], buf); err != nil {
+				s.fail(err)
+				return
+			}
+			line = decoded
+		}
+		ev := s.r.newEvent()
+		if err := json.Unmarshal(line, ev.wire()); err != nil {
+			ev.Release()
+			s.fail(err)
+			return
+		}
+		select {
+		case s.r.out <- ev:
+		case <-s.r.closed:
+			ev.Release()
+			return
+		}
+
+		s.mu.Lock()
+		s.sending = false
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	}
+}
+
+// fail closes the Response with err. Spilled events are sent after the body
+// has ended, so err replaces io.EOF.
+func (s *spill) fail(err error) {
+	s.r.mu.Lock()
+	if s.r.err == nil || s.r.err == io.EOF {
+		s.r.err = err
+	}
+	s.r.mu.Unlock()
+	s.r.closeErr(nil)
+}
+
+// finish waits for spilled events to be sent and removes the file.
+func (s *spill) finish() {
+	s.mu.Lock()
+	s.ended = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+	<-s.drained
+	s.f.Close()
+	os.Remove(s.f.Name())
+}
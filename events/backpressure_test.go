@@ -0,0 +1,288 @@
+package events_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+// backpressureResponse returns a Response of n CLOSE events with offsets 1
+// through n. If wait is true it waits for every event to be decoded.
+func backpressureResponse(t *testing.T, n int, wait bool, opts ...events.Option) *events.Response {
+	buf := &bytes.Buffer{}
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(buf, `{"id":"%d","type":"CLOSE","offset":"%d","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}`+"\n", i, i)
+	}
+	resp, err := events.NewResponse(&http.Response{StatusCode: 200, Body: ioutil.NopCloser(buf)}, opts...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !wait {
+		return resp
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for resp.Stats().Events < uint64(n) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for events to be decoded: %+v", resp.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return resp
+}
+
+func TestDropOldest(t *testing.T) {
+	t.Parallel()
+	saturated := int32(0)
+	resp := backpressureResponse(t, 100, true, events.BufferSize(2), events.DropOldest(),
+		events.OnSaturated(func() { atomic.AddInt32(&saturated, 1) }))
+	var offsets []uint64
+	for ev := range resp.Events() {
+		offsets = append(offsets, ev.Offset)
+	}
+	if resp.Err() != io.EOF {
+		t.Errorf("Unexpected error: %v", resp.Err())
+	}
+	if len(offsets) != 2 || offsets[0] != 99 || offsets[1] != 100 {
+		t.Errorf("Expected only the newest 2 events but received %v", offsets)
+	}
+	if n := resp.Dropped(); n != 98 {
+		t.Errorf("Expected 98 dropped events but found %d", n)
+	}
+	if n := atomic.LoadInt32(&saturated); n != 1 {
+		t.Errorf("Expected OnSaturated to be called once but it was called %d times", n)
+	}
+}
+
+// countingCodec is a SpillCodec which counts the events it encodes and
+// decodes.
+type countingCodec struct {
+	events.SpillCodec
+	encoded, decoded int32
+}
+
+func (c *countingCodec) Encode(dst, src []byte) ([]byte, error) {
+	atomic.AddInt32(&c.encoded, 1)
+	return c.SpillCodec.Encode(dst, src)
+}
+
+func (c *countingCodec) Decode(dst, src []byte) ([]byte, error) {
+	atomic.AddInt32(&c.decoded, 1)
+	return c.SpillCodec.Decode(dst, src)
+}
+
+func TestSpillToDisk(t *testing.T) {
+	t.Parallel()
+	flate, err := events.NewFlateCodec(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := events.NewFlateCodec(100); err == nil {
+		t.Errorf("Expected an error with an invalid level")
+	}
+	for _, codec := range []events.SpillCodec{nil, flate, &countingCodec{SpillCodec: flate}} {
+		for _, max := range []int64{1 << 20, 1} {
+			dir, err := ioutil.TempDir("", "spill")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			const n = 100
+			opts := []events.Option{events.BufferSize(1), events.SpillToDisk(dir, max), events.PoolEvents()}
+			if codec != nil {
+				opts = append(opts, events.SpillCompression(codec))
+			}
+			// A full spill file blocks decoding so all events can only be decoded
+			// before consuming them if the file is large enough.
+			resp := backpressureResponse(t, n, max > 1, opts...)
+
+			expected := uint64(1)
+			for ev := range resp.Events() {
+				if ev.Offset != expected || ev.ID != fmt.Sprint(expected) {
+					t.Fatalf("Expected event %d but received %s at offset %d", expected, ev.ID, ev.Offset)
+				}
+				ev.Release()
+				expected++
+			}
+			if resp.Err() != io.EOF {
+				t.Errorf("Unexpected error: %v", resp.Err())
+			}
+			if expected != n+1 {
+				t.Errorf("Expected %d events but received %d", n, expected-1)
+			}
+			if fis, _ := ioutil.ReadDir(dir); len(fis) > 0 {
+				t.Errorf("Expected spill file to be removed but found %d files", len(fis))
+			}
+			if st := resp.SpillStats(); (st.Blocked > 0) != (max == 1) || st.DroppedOldest+st.DroppedNewest > 0 {
+				t.Errorf("Unexpected spill stats with max %d: %+v", max, st)
+			}
+			if c, ok := codec.(*countingCodec); ok {
+				enc, dec := atomic.LoadInt32(&c.encoded), atomic.LoadInt32(&c.decoded)
+				if enc == 0 || enc != dec {
+					t.Errorf("Expected every spilled event to be encoded and decoded but found %d and %d", enc, dec)
+				}
+				atomic.StoreInt32(&c.encoded, 0)
+				atomic.StoreInt32(&c.decoded, 0)
+			}
+		}
+	}
+}
+
+func TestSpillCorrupt(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "spill")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	resp := backpressureResponse(t, 100, true, events.BufferSize(1), events.SpillToDisk(dir, 1<<20))
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil || len(fis) != 1 {
+		t.Fatalf("Expected a spill file but found %d: %v", len(fis), err)
+	}
+	// Damage the last spilled event so the events before it are still read
+	f, err := os.OpenFile(dir+"/"+fis[0].Name(), os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := f.WriteAt([]byte("x"), fis[0].Size()-10); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	f.Close()
+
+	received := 0
+	for range resp.Events() {
+		received++
+	}
+	if received == 0 || received >= 100 {
+		t.Errorf("Expected the events before the corrupt one but received %d", received)
+	}
+	if err := resp.Err(); err == nil || err == io.EOF {
+		t.Errorf("Expected an error with a corrupt spill file but found %v", err)
+	}
+}
+
+func TestSpillFull(t *testing.T) {
+	t.Parallel()
+	for _, policy := range []events.SpillPolicy{events.SpillDropOldest, events.SpillDropNewest} {
+		dir, err := ioutil.TempDir("", "spill")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		// The file only holds a few events so most are dropped before the
+		// consumer reads any
+		const n = 100
+		opts := []events.Option{events.BufferSize(1), events.SpillToDisk(dir, 1000), events.SpillFull(policy)}
+		resp := backpressureResponse(t, n, true, opts...)
+
+		var offsets []uint64
+		for ev := range resp.Events() {
+			if len(offsets) > 0 && ev.Offset <= offsets[len(offsets)-1] {
+				t.Fatalf("Expected increasing offsets but received %d after %v", ev.Offset, offsets)
+			}
+			offsets = append(offsets, ev.Offset)
+		}
+		st := resp.SpillStats()
+		if st.Blocked != 0 || uint64(len(offsets))+st.DroppedOldest+st.DroppedNewest != n || len(offsets) > n/2 {
+			t.Errorf("Unexpected stats %+v for %d received events", st, len(offsets))
+		}
+		last := offsets[len(offsets)-1]
+		switch policy {
+		case events.SpillDropOldest:
+			if st.DroppedNewest != 0 || last != n {
+				t.Errorf("Expected the newest events to be kept but received %v", offsets)
+			}
+		case events.SpillDropNewest:
+			if st.DroppedOldest != 0 || last == n {
+				t.Errorf("Expected the oldest events to be kept but received %v", offsets)
+			}
+		}
+	}
+}
+
+func TestSpillDropOldestWhileSending(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "spill")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Events are written one at a time so the pump is blocked sending an
+	// event to the full Events chan while the rest are spilled
+	pr, pw := io.Pipe()
+	resp, err := events.NewResponse(&http.Response{StatusCode: 200, Body: pr},
+		events.BufferSize(1), events.SpillToDisk(dir, 1000), events.SpillFull(events.SpillDropOldest))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	const n = 100
+	go func() {
+		for i := 1; i <= n; i++ {
+			fmt.Fprintf(pw, `{"id":"%d","type":"CLOSE","offset":"%d","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}`+"\n", i, i)
+			if i == 2 {
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+		pw.Close()
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for resp.Stats().Events < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for events to be decoded: %+v", resp.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	var offsets []uint64
+	for ev := range resp.Events() {
+		offsets = append(offsets, ev.Offset)
+	}
+	// The first event is buffered, the second may be held by the pump, and
+	// the file has room for several of the newest
+	if len(offsets) < 6 || offsets[0] != 1 || offsets[len(offsets)-1] != n {
+		t.Fatalf("Expected the first event and the newest events to fit but received %v", offsets)
+	}
+	for i := 3; i < len(offsets); i++ {
+		if offsets[i] != offsets[i-1]+1 {
+			t.Fatalf("Expected only the oldest spilled events to be dropped but received %v", offsets)
+		}
+	}
+	if st := resp.SpillStats(); st.DroppedOldest != n-uint64(len(offsets)) {
+		t.Errorf("Expected %d dropped events but found %+v", n-len(offsets), st)
+	}
+}
+
+// closeRecorder is a response body which records being closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestSpillToDiskError(t *testing.T) {
+	t.Parallel()
+	body := &closeRecorder{Reader: &bytes.Buffer{}}
+	_, err := events.NewResponse(&http.Response{StatusCode: 200, Body: body}, events.SpillToDisk("/nonexistent/spill", 1000))
+	if err == nil {
+		t.Fatalf("Expected an error with a missing spill directory")
+	}
+	if !body.closed {
+		t.Errorf("Expected the response body to be closed")
+	}
+}
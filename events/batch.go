@@ -0,0 +1,76 @@
+package events
+
+import (
+	"time"
+
+	"github.com/lytics/gobyairship"
+)
+
+// Batch groups events from src into batches of up to max events. A partial
+// batch is emitted once linger has passed since its first event was received.
+// The returned chan is closed after the final batch once src is closed.
+//
+// Callers must drain the returned chan or src will stop being read.
+func Batch(src <-chan *Event, max int, linger time.Duration) <-chan []*Event {
+	return batch(src, max, linger, nil, gobyairship.RealClock)
+}
+
+// Batches is like Batch for the Response's events, timing linger with the
+// Response's Clock. Closing the Response closes the returned chan even if it
+// isn't drained.
+func (r *Response) Batches(max int, linger time.Duration) <-chan []*Event {
+	return batch(r.out, max, linger, r.closed, r.clock)
+}
+
+func batch(src <-chan *Event, max int, linger time.Duration, quit <-chan struct{}, clock gobyairship.Clock) <-chan []*Event {
+	if max < 1 {
+		max = 1
+	}
+	out := make(chan []*Event)
+	go func() {
+		defer close(out)
+		var buf []*Event
+		// expired is nil until the batch's first event and abandoned once
+		// it's flushed
+		var expired <-chan time.Time
+		flush := func() bool {
+			expired = nil
+			if len(buf) == 0 {
+				return true
+			}
+			select {
+			case out <- buf:
+				buf = nil
+				return true
+			case <-quit:
+				return false
+			}
+		}
+		for {
+			select {
+			case ev, ok := <-src:
+				if !ok {
+					flush()
+					return
+				}
+				buf = append(buf, ev)
+				if len(buf) >= max {
+					if !flush() {
+						return
+					}
+					continue
+				}
+				if expired == nil {
+					expired = clock.After(linger)
+				}
+			case <-expired:
+				if !flush() {
+					return
+				}
+			case <-quit:
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,69 @@
+package events_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+func TestBatch(t *testing.T) {
+	t.Parallel()
+	src := make(chan *events.Event)
+	batches := events.Batch(src, 3, 20*time.Millisecond)
+
+	// Full batches are emitted immediately
+	go func() {
+		for i := 0; i < 4; i++ {
+			src <- &events.Event{Offset: uint64(i)}
+		}
+	}()
+	if b := <-batches; len(b) != 3 || b[2].Offset != 2 {
+		t.Errorf("Expected full batch of 3 but received %d", len(b))
+	}
+
+	// Partial batches are emitted after lingering
+	start := time.Now()
+	if b := <-batches; len(b) != 1 || b[0].Offset != 3 {
+		t.Errorf("Expected partial batch but received %d", len(b))
+	}
+	if d := time.Since(start); d < 10*time.Millisecond {
+		t.Errorf("Expected partial batch to linger but received after %s", d)
+	}
+
+	// Remaining events are flushed when the source closes
+	go func() {
+		src <- &events.Event{Offset: 4}
+		close(src)
+	}()
+	if b := <-batches; len(b) != 1 || b[0].Offset != 4 {
+		t.Errorf("Expected final batch but received %d", len(b))
+	}
+	if _, ok := <-batches; ok {
+		t.Errorf("Expected batches to be closed")
+	}
+}
+
+func TestResponseBatches(t *testing.T) {
+	t.Parallel()
+	const line = `{"id":"%d","type":"CLOSE","offset":"%d","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}` + "\n"
+	buf := &bytes.Buffer{}
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(buf, line, i, i)
+	}
+	resp, err := events.NewResponse(&http.Response{StatusCode: 200, Body: ioutil.NopCloser(buf)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sizes := []int{}
+	for b := range resp.Batches(2, time.Hour) {
+		sizes = append(sizes, len(b))
+	}
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[1] != 2 || sizes[2] != 1 {
+		t.Errorf("Expected batches of 2, 2, 1 but received %v", sizes)
+	}
+}
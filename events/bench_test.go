@@ -21,7 +21,29 @@ func (c *memClient) Post(url string, body interface{}, extra http.Header) (*http
 	}, nil
 }
 
-func BenchmarkCloseEvents(b *testing.B) {
+func BenchmarkCloseEvents(b *testing.B) { benchCloseEvents(b) }
+
+func BenchmarkCloseEventsBuffered(b *testing.B) {
+	benchCloseEvents(b, events.BufferSize(1000), events.ReadAhead(1024*1024))
+}
+
+func BenchmarkCloseEventsPooled(b *testing.B) {
+	benchCloseEvents(b, events.PoolEvents())
+}
+
+func BenchmarkCloseEventsParallel(b *testing.B) {
+	benchCloseEvents(b, events.DecodeWorkers(4), events.PoolEvents())
+}
+
+func BenchmarkCloseEventsProjected(b *testing.B) {
+	project, err := events.Project(events.Projection{Body: []string{"session_id"}})
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchCloseEvents(b, project, events.PoolEvents())
+}
+
+func benchCloseEvents(b *testing.B, opts ...events.Option) {
 	// Create 50 MB worth of data
 	const line = `{"id":"4e175876-2ac1-665f-57c5-2f714a45601b","type":"CLOSE","offset":"0","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","device":{"ios_channel":"af545191-d7b1-4b6d-8d33-6cfc4915edf0"},"body":{"session_id":"30f738bd-ecce-9f2b-536b-63e8d5e26aca"}}` + "\n"
 	data := bytes.Repeat([]byte(line), (50*1024*1024)/len(line))
@@ -33,7 +55,7 @@ func BenchmarkCloseEvents(b *testing.B) {
 		c := &memClient{body: ioutil.NopCloser(bytes.NewReader(data))}
 		b.StartTimer()
 
-		resp, err := events.Fetch(c, events.StartOffset, 0, nil)
+		resp, err := events.FetchRequest(c, &events.Request{Start: events.StartFirst}, opts...)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -49,6 +71,7 @@ func BenchmarkCloseEvents(b *testing.B) {
 			if cls.SessionID != "30f738bd-ecce-9f2b-536b-63e8d5e26aca" {
 				b.Fatalf("Unexpected session ID: %s", cls.SessionID)
 			}
+			ev.Release()
 		}
 		b.SetBytes(total)
 	}
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Checkpointer persists the offset of the last processed event so a stream
+// may resume from it after a restart.
+type Checkpointer interface {
+	// Save the offset of the last processed event.
+	Save(offset uint64) error
+
+	// Load the last saved offset. The bool is false if no offset has been
+	// saved.
+	Load() (uint64, bool, error)
+}
+
+// MemoryCheckpointer stores the offset in memory. Useful for tests and
+// consumers which only need to survive reconnects, not restarts.
+type MemoryCheckpointer struct {
+	mu     sync.Mutex
+	offset uint64
+	saved  bool
+}
+
+// Save the offset.
+func (m *MemoryCheckpointer) Save(offset uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.offset = offset
+	m.saved = true
+	return nil
+}
+
+// Load the last saved offset.
+func (m *MemoryCheckpointer) Load() (uint64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.offset, m.saved, nil
+}
+
+// FileCheckpointer stores the offset as text in a file. Saves write a
+// temporary file and rename it over the checkpoint so a crash never leaves a
+// partially written offset.
+type FileCheckpointer struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileCheckpointer creates a Checkpointer backed by the file at path. The
+// file is created on the first Save.
+func NewFileCheckpointer(path string) *FileCheckpointer {
+	return &FileCheckpointer{path: path}
+}
+
+// Save the offset.
+func (f *FileCheckpointer) Save(offset uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(Offset(offset).String() + "\n"); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// Load the last saved offset. A missing file means no offset has been saved.
+func (f *FileCheckpointer) Load() (uint64, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	buf, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	offset, err := ParseOffset(strings.TrimSpace(string(buf)))
+	if err != nil {
+		return 0, false, err
+	}
+	return uint64(offset), true, nil
+}
@@ -0,0 +1,155 @@
+package events_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/uatest"
+)
+
+func TestCheckpointers(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cps := map[string]events.Checkpointer{
+		"memory": &events.MemoryCheckpointer{},
+		"file":   events.NewFileCheckpointer(filepath.Join(dir, "offset")),
+	}
+	for name, cp := range cps {
+		if _, ok, err := cp.Load(); ok || err != nil {
+			t.Errorf("%s: expected no offset but found ok=%t err=%v", name, ok, err)
+		}
+		for _, offset := range []uint64{10, 1<<64 - 1} {
+			if err := cp.Save(offset); err != nil {
+				t.Fatalf("%s: unexpected error saving: %v", name, err)
+			}
+			if o, ok, err := cp.Load(); o != offset || !ok || err != nil {
+				t.Errorf("%s: expected %d but loaded %d ok=%t err=%v", name, offset, o, ok, err)
+			}
+		}
+	}
+
+	// Corrupt checkpoints are errors
+	fn := filepath.Join(dir, "corrupt")
+	ioutil.WriteFile(fn, []byte("nope"), 0644)
+	if _, _, err := events.NewFileCheckpointer(fn).Load(); err == nil {
+		t.Errorf("Expected error loading corrupt checkpoint")
+	}
+}
+
+func TestStreamCheckpoint(t *testing.T) {
+	t.Parallel()
+	srv := uatest.NewServer()
+	defer srv.Close()
+	srv.Add(streamEvent(1), streamEvent(2), streamEvent(3))
+
+	cp := &events.MemoryCheckpointer{}
+	cp.Save(2)
+	c := &urlClient{c: gobyairship.NewClient("", ""), url: srv.EventsURL()}
+	s, err := events.NewStream(context.Background(), c, events.StreamConfig{
+		Start:              events.StartFirst,
+		MinBackoff:         time.Millisecond,
+		Checkpointer:       cp,
+		CheckpointInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	select {
+	case ev := <-s.Events():
+		if ev.Offset != 3 {
+			t.Errorf("Expected to resume after checkpoint but received offset %d", ev.Offset)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timed out waiting for event")
+	}
+
+	// Periodic checkpoint
+	deadline := time.Now().Add(3 * time.Second)
+	for offset, _, _ := cp.Load(); offset != 3; offset, _, _ = cp.Load() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected checkpoint 3 but found %d", offset)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.Close()
+	for range s.Events() {
+	}
+}
+
+func TestStreamManualAck(t *testing.T) {
+	t.Parallel()
+	srv := uatest.NewServer()
+	defer srv.Close()
+	srv.Add(streamEvent(1), streamEvent(2), streamEvent(3), streamEvent(4))
+
+	cp := &events.MemoryCheckpointer{}
+	c := &urlClient{c: gobyairship.NewClient("", ""), url: srv.EventsURL()}
+	s, err := events.NewStream(context.Background(), c, events.StreamConfig{
+		Start:              events.StartFirst,
+		MinBackoff:         time.Millisecond,
+		Checkpointer:       cp,
+		CheckpointInterval: time.Hour,
+		CheckpointEvery:    1,
+		ManualAck:          true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var evs []*events.Event
+	for len(evs) < 4 {
+		select {
+		case ev := <-s.Events():
+			evs = append(evs, ev)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timed out waiting for events")
+		}
+	}
+	waitCheckpoint := func(expected uint64) {
+		deadline := time.Now().Add(3 * time.Second)
+		for offset, _, _ := cp.Load(); offset != expected; offset, _, _ = cp.Load() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected checkpoint %d but found %d", expected, offset)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// Acknowledging out of order doesn't commit past unacknowledged events
+	evs[1].Ack()
+	if offset, ok := s.Committed(); ok {
+		t.Errorf("Expected no committed offset but found %d", offset)
+	}
+	evs[0].Ack()
+	if offset, _ := s.Committed(); offset != 2 {
+		t.Errorf("Expected committed offset 2 but found %d", offset)
+	}
+	waitCheckpoint(2)
+	evs[2].Ack()
+	evs[2].Ack()
+	waitCheckpoint(3)
+
+	// The last event is unacknowledged so it's not checkpointed on close
+	s.Close()
+	for range s.Events() {
+	}
+	if offset, _, _ := cp.Load(); offset != 3 {
+		t.Errorf("Expected checkpoint 3 after close but found %d", offset)
+	}
+	if offset, _ := s.Offset(); offset != 4 {
+		t.Errorf("Expected delivered offset 4 but found %d", offset)
+	}
+	if st := s.Stats(); st.Acked != 3 {
+		t.Errorf("Expected 3 acknowledged events but found %d", st.Acked)
+	}
+}
@@ -0,0 +1,89 @@
+package events_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/events"
+)
+
+// urlClient sends all requests to a fixed URL.
+type urlClient struct {
+	c   *gobyairship.Client
+	url string
+}
+
+func (c *urlClient) Post(_ string, body interface{}, extra http.Header) (*http.Response, error) {
+	return c.c.Post(c.url, body, extra)
+}
+
+func (c *urlClient) PostContext(ctx context.Context, _ string, body interface{}, extra http.Header) (*http.Response, error) {
+	return c.c.PostContext(ctx, c.url, body, extra)
+}
+
+// postOnly hides PostContext from events.Fetch.
+type postOnly struct{ c events.Client }
+
+func (c postOnly) Post(url string, body interface{}, extra http.Header) (*http.Response, error) {
+	return c.c.Post(url, body, extra)
+}
+
+func TestFetchContext(t *testing.T) {
+	t.Parallel()
+	const line = `{"id":"a","type":"CLOSE","offset":"1","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}` + "\n"
+
+	aborted := make(chan bool, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(line))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+			aborted <- true
+		case <-time.After(5 * time.Second):
+			aborted <- false
+		}
+	}))
+	defer ts.Close()
+
+	clients := map[string]events.Client{
+		"context": &urlClient{c: gobyairship.NewClient("", ""), url: ts.URL},
+		"plain":   postOnly{&urlClient{c: gobyairship.NewClient("", ""), url: ts.URL}},
+	}
+	for name, c := range clients {
+		ctx, cancel := context.WithCancel(context.Background())
+		resp, err := events.FetchContext(ctx, c, events.StartFirst, 0, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if ev := <-resp.Events(); ev == nil || ev.ID != "a" {
+			t.Fatalf("%s: expected first event but received %v", name, ev)
+		}
+		cancel()
+
+		select {
+		case _, ok := <-resp.Events():
+			if ok {
+				t.Errorf("%s: unexpected event after cancel", name)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("%s: events chan not closed after cancel", name)
+		}
+		if err := resp.Err(); err != context.Canceled {
+			t.Errorf("%s: expected context.Canceled but found %v", name, err)
+		}
+		if !<-aborted {
+			t.Errorf("%s: HTTP request was not aborted", name)
+		}
+	}
+
+	// Already cancelled contexts fail immediately
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := events.FetchContext(ctx, clients["context"], events.StartFirst, 0, nil); err == nil {
+		t.Errorf("Expected error fetching with a cancelled context")
+	}
+}
@@ -0,0 +1,58 @@
+package csv
+
+import "github.com/lytics/gobyairship/events"
+
+// push fields are shared by events about a push.
+var push = []string{"push_id", "group_id", "variant_id"}
+
+// fields are the body fields DefaultColumns includes for each type.
+var fields = map[events.Type][]string{
+	events.TypePush:                   {"push_id", "group_id"},
+	events.TypeOpen:                   {"session_id", "last_delivered.push_id", "triggering_push.push_id"},
+	events.TypeSend:                   push,
+	events.TypeClose:                  {"session_id"},
+	events.TypeTagChange:              {"add", "remove"},
+	events.TypeUninstall:              nil,
+	events.TypeFirst:                  {"session_id"},
+	events.TypeCustom:                 {"name", "value", "interaction_id", "interaction_type", "session_id"},
+	events.TypeLocation:               {"latitude", "longitude", "foreground"},
+	events.TypeRichDelivery:           append(push, "message_id"),
+	events.TypeRichRead:               append(push, "message_id", "session_id"),
+	events.TypeRichDelete:             append(push, "message_id", "session_id"),
+	events.TypeInAppMessageDisplay:    {"push_id", "group_id", "triggering_push.push_id"},
+	events.TypeInAppMessageResolution: {"push_id", "group_id", "type", "duration", "button_id"},
+	events.TypeInAppMessageExpiration: {"push_id", "group_id", "type"},
+	events.TypeScreenViewed:           {"viewed_screen", "previous_screen", "duration", "session_id"},
+	events.TypeRegion:                 {"region_id", "name", "action", "source"},
+	events.TypeControl:                push,
+	events.TypeSendAborted:            append(push, "reason"),
+	events.TypeEmailDelivery:          append(push, "message_type"),
+	events.TypeEmailOpen:              append(push, "message_type"),
+	events.TypeEmailClick:             append(push, "message_type", "link_url"),
+	events.TypeEmailBounce:            append(push, "message_type", "bounce_reason"),
+	events.TypeEmailUnsubscribe:       append(push, "message_type"),
+	events.TypeSMSDelivery:            {"push_id", "group_id", "delivery_status", "error_code"},
+	events.TypeMobileOriginated:       {"keyword", "inbound_message"},
+	events.TypeSMSOptIn:               {"keyword", "source"},
+	events.TypeSMSOptOut:              {"keyword", "source"},
+	events.TypeWebClick:               {"push_id", "url", "browser_name", "session_id"},
+	events.TypeAttributeOperation:     {"mutations"},
+	events.TypeSubscriptionListChange: {"list_id", "action", "scope"},
+	events.TypeCompliance:             {"action", "request_id", "reason"},
+}
+
+// DefaultColumns returns the columns exported for events of type t: id,
+// occurred, channel, and platform followed by the type's most useful body
+// fields. For an empty or unknown type the event's type and whole body are
+// exported instead of body fields.
+func DefaultColumns(t events.Type) []Column {
+	cols := []Column{ID, Occurred, Channel, Platform}
+	paths, ok := fields[t]
+	if !ok {
+		return append([]Column{ID, Type}, Occurred, Channel, Platform, Body)
+	}
+	for _, p := range paths {
+		cols = append(cols, Field(p))
+	}
+	return cols
+}
@@ -0,0 +1,245 @@
+package csv
+
+import (
+	"bytes"
+	gocsv "encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+// Column of an export. Value returns the column's value for an event whose
+// body has been decoded into body, which is nil if the event has no body.
+type Column struct {
+	Name  string
+	Value func(ev *events.Event, body map[string]interface{}) string
+}
+
+// Columns of the event envelope.
+var (
+	ID        = Column{"id", func(ev *events.Event, _ map[string]interface{}) string { return ev.ID }}
+	Type      = Column{"type", func(ev *events.Event, _ map[string]interface{}) string { return string(ev.Type) }}
+	Offset    = Column{"offset", func(ev *events.Event, _ map[string]interface{}) string { return strconv.FormatUint(ev.Offset, 10) }}
+	Occurred  = Column{"occurred", func(ev *events.Event, _ map[string]interface{}) string { return formatTime(ev.Occurred) }}
+	Processed = Column{"processed", func(ev *events.Event, _ map[string]interface{}) string { return formatTime(ev.Processed) }}
+
+	// Channel is the event's channel ID, or named user ID if it has no
+	// channel.
+	Channel = Column{"channel", func(ev *events.Event, _ map[string]interface{}) string { return channel(ev.Device) }}
+
+	// Platform is the DeviceType of the event's Channel.
+	Platform = Column{"platform", func(ev *events.Event, _ map[string]interface{}) string { return string(ev.Device.Type()) }}
+
+	// NamedUser is the named user ID of the event's device.
+	NamedUser = Column{"named_user", func(ev *events.Event, _ map[string]interface{}) string {
+		if ev.Device == nil {
+			return ""
+		}
+		return ev.Device.NamedUser
+	}}
+
+	// Body is the event's body as JSON.
+	Body = Column{"body", func(ev *events.Event, _ map[string]interface{}) string { return string(ev.Body) }}
+)
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func channel(d *events.Device) string {
+	switch d.Type() {
+	case events.DeviceIOS:
+		return d.IOS
+	case events.DeviceAndroid:
+		return d.Android
+	case events.DeviceAmazon:
+		return d.Amazon
+	case events.DeviceEmail:
+		return d.Email
+	case events.DeviceSMS:
+		return d.SMS
+	case events.DeviceWeb:
+		return d.Web
+	case events.DeviceUser:
+		return d.NamedUser
+	}
+	return ""
+}
+
+// Field creates a Column of the body field at a dotted path such as
+// "triggering_push.push_id". The column is named after the path with
+// underscores instead of dots. Strings and numbers are written as is; other
+// values, such as maps of tags, are written as JSON.
+func Field(path string) Column {
+	keys := strings.Split(path, ".")
+	return Column{
+		Name: strings.Replace(path, ".", "_", -1),
+		Value: func(_ *events.Event, body map[string]interface{}) string {
+			var v interface{} = body
+			for _, k := range keys {
+				m, ok := v.(map[string]interface{})
+				if !ok {
+					return ""
+				}
+				v = m[k]
+			}
+			return format(v)
+		},
+	}
+}
+
+func format(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// Exporter writes events as CSV rows.
+type Exporter struct {
+	// Columns of each row. Set before the first Write.
+	Columns []Column
+
+	// Types, if non-empty, limits the events exported.
+	Types []events.Type
+
+	// Since and Until, if non-zero, limit the events exported to those which
+	// occurred in [Since, Until).
+	Since, Until time.Time
+
+	w      *gocsv.Writer
+	header bool
+}
+
+// NewExporter creates an Exporter which writes events of type t to w with
+// DefaultColumns(t). If t is empty events of every type are exported.
+func NewExporter(w io.Writer, t events.Type) *Exporter {
+	x := &Exporter{Columns: DefaultColumns(t), w: gocsv.NewWriter(w)}
+	if t != "" {
+		x.Types = []events.Type{t}
+	}
+	return x
+}
+
+// match returns true if ev should be exported.
+func (x *Exporter) match(ev *events.Event) bool {
+	if len(x.Types) > 0 {
+		found := false
+		for _, t := range x.Types {
+			if t == ev.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !x.Since.IsZero() && ev.Occurred.Before(x.Since) {
+		return false
+	}
+	if !x.Until.IsZero() && !ev.Occurred.Before(x.Until) {
+		return false
+	}
+	return true
+}
+
+// Write exports matching events, writing the header row first if it hasn't
+// been written. Returns the number of rows written.
+func (x *Exporter) Write(evs []*events.Event) (int, error) {
+	if err := x.writeHeader(); err != nil {
+		return 0, err
+	}
+	n := 0
+	row := make([]string, len(x.Columns))
+	for _, ev := range evs {
+		if !x.match(ev) {
+			continue
+		}
+		var body map[string]interface{}
+		if len(ev.Body) > 0 {
+			dec := json.NewDecoder(bytes.NewReader(ev.Body))
+			dec.UseNumber()
+			// Bodies which aren't objects leave body nil
+			dec.Decode(&body)
+		}
+		for i, c := range x.Columns {
+			row[i] = c.Value(ev, body)
+		}
+		if err := x.w.Write(row); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (x *Exporter) writeHeader() error {
+	if x.header {
+		return nil
+	}
+	x.header = true
+	names := make([]string, len(x.Columns))
+	for i, c := range x.Columns {
+		names[i] = c.Name
+	}
+	return x.w.Write(names)
+}
+
+// Flush writes buffered rows to the underlying io.Writer.
+func (x *Exporter) Flush() error {
+	x.w.Flush()
+	return x.w.Error()
+}
+
+// Export writes matching events from src until it ends or, if Until is set,
+// until an event processed at or after Until arrives, which closes src.
+// Events are streamed in the order they were processed, so events which
+// occurred before Until but were processed after it are not exported. Returns
+// the number of rows written.
+func (x *Exporter) Export(src events.Source) (int, error) {
+	if err := x.writeHeader(); err != nil {
+		src.Close()
+		return 0, err
+	}
+	n := 0
+	for ev := range src.Events() {
+		if !x.Until.IsZero() && !ev.Processed.Before(x.Until) {
+			src.Close()
+			for range src.Events() {
+			}
+			return n, x.Flush()
+		}
+		written, err := x.Write([]*events.Event{ev})
+		n += written
+		if err != nil {
+			src.Close()
+			return n, err
+		}
+	}
+	if err := x.Flush(); err != nil {
+		return n, err
+	}
+	if err := src.Err(); err != nil && err != io.EOF {
+		return n, err
+	}
+	return n, nil
+}
@@ -0,0 +1,105 @@
+package csv_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/events/csv"
+)
+
+// source is an events.Source of a fixed set of events.
+type source struct {
+	c      chan *events.Event
+	closed bool
+}
+
+func newSource(evs ...*events.Event) *source {
+	s := &source{c: make(chan *events.Event, len(evs))}
+	for _, ev := range evs {
+		s.c <- ev
+	}
+	close(s.c)
+	return s
+}
+
+func (s *source) Events() <-chan *events.Event { return s.c }
+func (s *source) Close()                       { s.closed = true }
+func (s *source) Err() error                   { return io.EOF }
+
+var start = time.Date(2017, 4, 25, 0, 0, 0, 0, time.UTC)
+
+func send(id string, hour int, body string) *events.Event {
+	t := start.Add(time.Duration(hour) * time.Hour)
+	return &events.Event{
+		ID:        id,
+		Type:      events.TypeSend,
+		Occurred:  t,
+		Processed: t,
+		Device:    &events.Device{Android: "a-" + id},
+		Body:      json.RawMessage(body),
+	}
+}
+
+func TestExport(t *testing.T) {
+	src := newSource(
+		send("early", -1, `{"push_id":"p0"}`),
+		send("1", 1, `{"push_id":"p1","variant_id":2}`),
+		&events.Event{ID: "open", Type: events.TypeOpen, Occurred: start, Processed: start},
+		send("2", 2, `{"push_id":"p2","group_id":"g"}`),
+		send("late", 24, `{"push_id":"p3"}`),
+		send("never", 25, `{"push_id":"p4"}`),
+	)
+	buf := &bytes.Buffer{}
+	x := csv.NewExporter(buf, events.TypeSend)
+	x.Since, x.Until = start, start.Add(24*time.Hour)
+	n, err := x.Export(src)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 rows but found %d", n)
+	}
+	if !src.closed {
+		t.Errorf("Expected source to be closed after Until")
+	}
+	expected := "id,occurred,channel,platform,push_id,group_id,variant_id\n" +
+		"1,2017-04-25T01:00:00Z,a-1,android,p1,,2\n" +
+		"2,2017-04-25T02:00:00Z,a-2,android,p2,g,\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nFound:\n%s", expected, buf.String())
+	}
+}
+
+func TestColumns(t *testing.T) {
+	ev := &events.Event{
+		ID:   "tags",
+		Type: events.TypeTagChange,
+		Body: json.RawMessage(`{"add":{"group":["a","b"]},"nested":{"n":1.5,"ok":true}}`),
+	}
+	buf := &bytes.Buffer{}
+	x := csv.NewExporter(buf, "")
+	x.Columns = []csv.Column{csv.Type, csv.Channel, csv.Field("add"), csv.Field("nested.n"), csv.Field("nested.ok"), csv.Field("add.group.x")}
+	if _, err := x.Write([]*events.Event{ev}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := x.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "type,channel,add,nested_n,nested_ok,add_group_x\n" +
+		`TAG_CHANGE,,"{""group"":[""a"",""b""]}",1.5,true,` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\nFound:\n%s", expected, buf.String())
+	}
+
+	// Every type has default columns and unknown types export the body
+	if cols := csv.DefaultColumns(events.TypeUninstall); len(cols) != 4 {
+		t.Errorf("Expected 4 columns for uninstalls but found %d", len(cols))
+	}
+	if cols := csv.DefaultColumns("NEW_TYPE"); cols[len(cols)-1].Name != "body" {
+		t.Errorf("Expected body column for unknown types but found %s", cols[len(cols)-1].Name)
+	}
+}
@@ -0,0 +1,33 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package exports events as CSV for people who want a spreadsheet of
+// events rather than a data pipeline, such as the sends or opens of a
+// campaign over a date range.
+//
+// Each event is flattened into a row of Columns. DefaultColumns picks useful
+// columns for each event type, such as the push ID, channel, and platform of
+// SEND events, and Field selects any other body field by its dotted path:
+//
+//	x := csv.NewExporter(os.Stdout, events.TypeSend)
+//	x.Columns = append(x.Columns, csv.Field("triggering_push.push_id"))
+//	x.Since, x.Until = start, end
+//	resp, err := events.FetchSince(client, start, nil, &events.Filter{Types: []events.Type{events.TypeSend}})
+//	if err != nil {
+//		return err
+//	}
+//	n, err := x.Export(resp)
+//
+// The uaconnect command's export subcommand wraps this package.
+package csv
@@ -0,0 +1,142 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lytics/gobyairship"
+)
+
+// DedupeStore records the IDs of events which have been seen. Implement it
+// over a shared store such as Redis (SET NX with an expiry) to deduplicate
+// events across processes.
+type DedupeStore interface {
+	// Add records id for ttl, returning false if it's already recorded.
+	Add(id string, ttl time.Duration) (bool, error)
+}
+
+// dedupeEntry is an ID recorded by a MemoryDedupeStore in the order it was
+// added.
+type dedupeEntry struct {
+	id      string
+	expires time.Time
+}
+
+// MemoryDedupeStore records IDs in memory, forgetting the oldest once more
+// than its size are recorded.
+type MemoryDedupeStore struct {
+	// Clock used to expire IDs. Defaults to gobyairship.RealClock.
+	Clock gobyairship.Clock
+
+	mu      sync.Mutex
+	size    int
+	ids     map[string]time.Time
+	entries []dedupeEntry
+}
+
+// NewMemoryDedupeStore creates a DedupeStore recording at most size IDs. Size
+// defaults to 100,000 if it's not positive.
+func NewMemoryDedupeStore(size int) *MemoryDedupeStore {
+	if size <= 0 {
+		size = 100000
+	}
+	return &MemoryDedupeStore{size: size, ids: map[string]time.Time{}}
+}
+
+// Add records id for ttl, returning false if it's already recorded.
+func (m *MemoryDedupeStore) Add(id string, ttl time.Duration) (bool, error) {
+	clock := m.Clock
+	if clock == nil {
+		clock = gobyairship.RealClock
+	}
+	now := clock.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(now)
+	if expires, ok := m.ids[id]; ok && now.Before(expires) {
+		return false, nil
+	}
+	expires := now.Add(ttl)
+	m.ids[id] = expires
+	m.entries = append(m.entries, dedupeEntry{id: id, expires: expires})
+	for len(m.ids) > m.size {
+		m.pop()
+	}
+	return true, nil
+}
+
+// Len returns the number of recorded IDs.
+func (m *MemoryDedupeStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.ids)
+}
+
+// expire forgets IDs which expired before now. Entries are in the order they
+// were added, which is the order they expire in given the same ttl, so only
+// the oldest are checked. m.mu must be held.
+func (m *MemoryDedupeStore) expire(now time.Time) {
+	for len(m.entries) > 0 && !now.Before(m.entries[0].expires) {
+		m.pop()
+	}
+}
+
+// pop forgets the oldest entry. m.mu must be held.
+func (m *MemoryDedupeStore) pop() {
+	e := m.entries[0]
+	m.entries[0] = dedupeEntry{}
+	m.entries = m.entries[1:]
+	// The ID may have been added again since this entry
+	if m.ids[e.id] == e.expires {
+		delete(m.ids, e.id)
+	}
+}
+
+// DedupeConfig configures Dedupe.
+type DedupeConfig struct {
+	// Window is how long an event's ID is remembered. Defaults to 10 minutes,
+	// which covers redelivery after reconnects.
+	Window time.Duration
+
+	// Store records the IDs. Defaults to a MemoryDedupeStore of Size, which
+	// defaults to 100,000 IDs, using Clock.
+	Store DedupeStore
+	Size  int
+	Clock gobyairship.Clock
+
+	// OnError, if non-nil, is called with errors from the Store. Events are
+	// delivered when the Store fails, preferring duplicates to losing events.
+	OnError func(error)
+}
+
+// Dedupe returns a Transform which drops events whose ID was seen within the
+// window so at-least-once redelivery, such as after a Stream reconnects,
+// isn't processed twice downstream. Events without an ID are never dropped.
+// Dropped events are Released.
+func Dedupe(cfg DedupeConfig) Transform {
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Minute
+	}
+	if cfg.Store == nil {
+		store := NewMemoryDedupeStore(cfg.Size)
+		store.Clock = cfg.Clock
+		cfg.Store = store
+	}
+	return func(ev *Event) (*Event, bool) {
+		if ev.ID == "" {
+			return ev, true
+		}
+		added, err := cfg.Store.Add(ev.ID, cfg.Window)
+		if err != nil {
+			if cfg.OnError != nil {
+				cfg.OnError(err)
+			}
+			return ev, true
+		}
+		if !added {
+			ev.Release()
+			return nil, false
+		}
+		return ev, true
+	}
+}
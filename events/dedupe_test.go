@@ -0,0 +1,89 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/uatest"
+)
+
+func TestMemoryDedupeStore(t *testing.T) {
+	t.Parallel()
+	m := events.NewMemoryDedupeStore(2)
+	add := func(id string, ttl time.Duration, expected bool) {
+		if added, err := m.Add(id, ttl); err != nil || added != expected {
+			t.Errorf("Expected Add(%q) to return %t but found %t (%v)", id, expected, added, err)
+		}
+	}
+	add("a", time.Hour, true)
+	add("a", time.Hour, false)
+	add("b", time.Hour, true)
+
+	// The oldest ID is forgotten once the store is full
+	add("c", time.Hour, true)
+	if n := m.Len(); n != 2 {
+		t.Errorf("Expected 2 IDs but found %d", n)
+	}
+	add("b", time.Hour, false)
+	add("a", time.Hour, true)
+
+	// IDs are forgotten once they expire
+	clock := uatest.NewClock(time.Now())
+	m = events.NewMemoryDedupeStore(0)
+	m.Clock = clock
+	add("a", time.Millisecond, true)
+	clock.Advance(time.Millisecond)
+	add("a", time.Hour, true)
+	add("a", time.Hour, false)
+	if n := m.Len(); n != 1 {
+		t.Errorf("Expected 1 ID but found %d", n)
+	}
+}
+
+type failingDedupeStore struct{}
+
+func (failingDedupeStore) Add(string, time.Duration) (bool, error) {
+	return false, errors.New("unavailable")
+}
+
+func TestDedupe(t *testing.T) {
+	t.Parallel()
+	dedupe := events.Dedupe(events.DedupeConfig{Size: 10})
+	for i, tc := range []struct {
+		id       string
+		expected bool
+	}{
+		{"a", true},
+		{"b", true},
+		{"a", false},
+		{"", true},
+		{"", true},
+		{"b", false},
+	} {
+		ev := &events.Event{ID: tc.id}
+		out, ok := dedupe(ev)
+		if ok != tc.expected {
+			t.Errorf("%d: Expected %q to be delivered %t but found %t", i, tc.id, tc.expected, ok)
+		}
+		if ok && out != ev {
+			t.Errorf("%d: Expected the event to be delivered unchanged", i)
+		}
+	}
+
+	// Events are delivered when the store fails
+	var errs []error
+	dedupe = events.Dedupe(events.DedupeConfig{
+		Store:   failingDedupeStore{},
+		OnError: func(err error) { errs = append(errs, err) },
+	})
+	for i := 0; i < 2; i++ {
+		if _, ok := dedupe(&events.Event{ID: "a"}); !ok {
+			t.Errorf("Expected event to be delivered despite store error")
+		}
+	}
+	if len(errs) != 2 {
+		t.Errorf("Expected 2 errors but found %d", len(errs))
+	}
+}
@@ -2,16 +2,20 @@ package events_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/uatest"
 )
 
 var (
@@ -47,10 +51,14 @@ func (c *fakeClient) Post(url string, body interface{}, extra http.Header) (*htt
 	if !ok {
 		return nil, fmt.Errorf("body is not a Request: %T", body)
 	}
-	if len(req.Filters) == 0 && c.filter != "all" {
-		return nil, fmt.Errorf("expected filter=%q but no filter specified", c.filter)
+	if c.filter == "" {
+		// Fixtures of all types are requested without filters
+		if len(req.Filters) > 0 {
+			return nil, fmt.Errorf("expected no filters but received filter=%v", req.Filters)
+		}
+		return &http.Response{StatusCode: 200, Body: c.data}, nil
 	}
-	if len(req.Filters) > 1 || len(req.Filters[0].Types) != 1 {
+	if len(req.Filters) != 1 || len(req.Filters[0].Types) != 1 {
 		return nil, fmt.Errorf("expected filter=%q but received filter=%v", c.filter, req.Filters)
 	}
 	if req.Filters[0].Types[0] != c.filter {
@@ -73,6 +81,27 @@ var filterTypes = map[string]events.Type{
 	"in_app_message_display":    events.TypeInAppMessageDisplay,
 	"in_app_message_expiration": events.TypeInAppMessageExpiration,
 	"in_app_message_resolution": events.TypeInAppMessageResolution,
+	"screen_viewed":             events.TypeScreenViewed,
+	"region":                    events.TypeRegion,
+	"control":                   events.TypeControl,
+	"send_aborted":              events.TypeSendAborted,
+	"email_delivery":            events.TypeEmailDelivery,
+	"email_open":                events.TypeEmailOpen,
+	"email_click":               events.TypeEmailClick,
+	"email_bounce":              events.TypeEmailBounce,
+	"email_unsubscribe":         events.TypeEmailUnsubscribe,
+	"sms_delivery_report":       events.TypeSMSDelivery,
+	"mobile_originated":         events.TypeMobileOriginated,
+	"sms_opt_in":                events.TypeSMSOptIn,
+	"sms_opt_out":               events.TypeSMSOptOut,
+	"web_click":                 events.TypeWebClick,
+	"custom":                    events.TypeCustom,
+	"attribute_operation":       events.TypeAttributeOperation,
+	"subscription_list_change":  events.TypeSubscriptionListChange,
+	"compliance":                events.TypeCompliance,
+	"rich_delivery":             events.TypeRichDelivery,
+	"rich_read":                 events.TypeRichRead,
+	"rich_delete":               events.TypeRichDelete,
 }
 
 func TestFilterTypes(t *testing.T) {
@@ -82,7 +111,11 @@ func TestFilterTypes(t *testing.T) {
 		fc := newFakeClient(t, fname, ftype)
 
 		offset := uint64(0)
-		resp, err := events.Fetch(fc, events.StartOffset, 0, nil, &events.Filter{Types: []events.Type{ftype}})
+		var filters []*events.Filter
+		if ftype != "" {
+			filters = append(filters, &events.Filter{Types: []events.Type{ftype}})
+		}
+		resp, err := events.Fetch(fc, events.StartOffset, 0, nil, filters...)
 		if err != nil {
 			t.Errorf("Received error fetching %s: %v", fname, err)
 			continue
@@ -134,7 +167,8 @@ func checkEvent(t *testing.T, ft events.Type, ev *events.Event) (ok bool) {
 	}
 
 	if ev.Device != nil {
-		if len(ev.Device.Amazon)+len(ev.Device.Android)+len(ev.Device.IOS)+len(ev.Device.NamedUser) == 0 {
+		d := ev.Device
+		if len(d.Amazon)+len(d.Android)+len(d.IOS)+len(d.NamedUser)+len(d.Email)+len(d.SMS)+len(d.Web) == 0 {
 			t.Error("Device specified but no IDs")
 			ok = false
 		}
@@ -222,11 +256,19 @@ func checkEvent(t *testing.T, ft events.Type, ev *events.Event) (ok bool) {
 			ok = false
 		}
 	case events.TypeRichDelivery, events.TypeRichRead, events.TypeRichDelete:
-		_, err := ev.RichEvent()
+		r, err := ev.RichEvent()
 		if err != nil {
 			t.Error(err)
 			return false
 		}
+		if r.PushID == "" {
+			t.Errorf("%s - Expected a push ID", ev.ID)
+			ok = false
+		}
+		if ev.Type != events.TypeRichDelivery && r.SessionID == "" {
+			t.Errorf("%s - Expected a session ID", ev.ID)
+			ok = false
+		}
 	case events.TypeInAppMessageDisplay:
 		_, err := ev.InAppMessageDisplay()
 		if err != nil {
@@ -245,7 +287,193 @@ func checkEvent(t *testing.T, ft events.Type, ev *events.Event) (ok bool) {
 			t.Error(err)
 			return false
 		}
-	case events.TypeCustom, events.TypeFirst, events.TypeUninstall:
+	case events.TypeScreenViewed:
+		sv, err := ev.ScreenViewed()
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+		if sv.Screen == "" {
+			t.Error("Empty viewed screen")
+			ok = false
+		}
+		if sv.Duration <= 0 {
+			t.Errorf("Invalid screen view duration: %d", sv.Duration)
+			ok = false
+		}
+	case events.TypeRegion:
+		r, err := ev.Region()
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+		if r.RegionID == "" {
+			t.Error("Empty region ID")
+			ok = false
+		}
+		if r.Action != "enter" && r.Action != "exit" {
+			t.Errorf("Invalid region action: %q", r.Action)
+			ok = false
+		}
+	case events.TypeControl:
+		c, err := ev.Control()
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+		if c.PushID == "" {
+			t.Error("Empty push ID")
+			ok = false
+		}
+	case events.TypeSendAborted:
+		sa, err := ev.SendAborted()
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+		if sa.PushID == "" {
+			t.Error("Empty push ID")
+			ok = false
+		}
+		if sa.Reason == "" {
+			t.Error("Empty abort reason")
+			ok = false
+		}
+	case events.TypeEmailDelivery, events.TypeEmailOpen, events.TypeEmailClick, events.TypeEmailBounce, events.TypeEmailUnsubscribe:
+		em, err := ev.Email()
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+		if ev.Device == nil || ev.Device.Email == "" {
+			t.Error("Email event without an email channel")
+			ok = false
+		}
+		if em.MessageType != "commercial" && em.MessageType != "transactional" {
+			t.Errorf("Invalid email message type: %q", em.MessageType)
+			ok = false
+		}
+		if ev.Type == events.TypeEmailClick && em.URL == "" {
+			t.Error("Email click without a URL")
+			ok = false
+		}
+		if ev.Type == events.TypeEmailBounce && em.BounceReason == "" {
+			t.Error("Email bounce without a reason")
+			ok = false
+		}
+	case events.TypeSMSDelivery:
+		d, err := ev.SMSDelivery()
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+		if d.Status != "delivered" && d.Status != "failed" {
+			t.Errorf("Invalid SMS delivery status: %q", d.Status)
+			ok = false
+		}
+		if d.Status == "failed" && d.ErrorCode == "" {
+			t.Error("Failed SMS delivery without an error code")
+			ok = false
+		}
+	case events.TypeMobileOriginated:
+		mo, err := ev.MobileOriginated()
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+		if mo.Message == "" {
+			t.Error("Empty inbound message")
+			ok = false
+		}
+	case events.TypeSMSOptIn, events.TypeSMSOptOut:
+		o, err := ev.SMSOpt()
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+		if o.Source == "" {
+			t.Error("Empty SMS opt source")
+			ok = false
+		}
+	case events.TypeWebClick:
+		c, err := ev.WebClick()
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+		if ev.Device == nil || ev.Device.Web == "" {
+			t.Error("Web click without a web channel")
+			ok = false
+		}
+		if c.PushID == "" {
+			t.Error("Empty push ID")
+			ok = false
+		}
+	case events.TypeCustom:
+		c, err := ev.Custom()
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+		if c.Name == "" {
+			t.Error("Empty custom event name")
+			ok = false
+		}
+		if c.Value != "" {
+			if _, err := c.Value.Float64(); err != nil {
+				t.Errorf("Error getting float form of value: %v", err)
+				ok = false
+			}
+		}
+	case events.TypeAttributeOperation:
+		ops, err := ev.AttributeOperations()
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+		if len(ops) == 0 {
+			t.Error("Attribute operation without any operations")
+			ok = false
+		}
+		for _, op := range ops {
+			if op.Key == "" || op.Timestamp.IsZero() {
+				t.Errorf("Invalid attribute operation: %+v", op)
+				ok = false
+			}
+			if (op.Action == events.AttributeSet) != (len(op.Value) > 0) || (op.Action != events.AttributeSet && op.Action != events.AttributeRemove) {
+				t.Errorf("Invalid attribute operation action %q with value %s", op.Action, op.Value)
+				ok = false
+			}
+		}
+	case events.TypeSubscriptionListChange:
+		c, err := ev.SubscriptionListChange()
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+		if c.ListID == "" {
+			t.Error("Empty subscription list ID")
+			ok = false
+		}
+		if c.Action != events.SubscriptionSubscribe && c.Action != events.SubscriptionUnsubscribe {
+			t.Errorf("Invalid subscription action: %q", c.Action)
+			ok = false
+		}
+	case events.TypeCompliance:
+		c, err := ev.Compliance()
+		if err != nil {
+			t.Error(err)
+			return false
+		}
+		if c.Action != events.ComplianceDelete && c.Action != events.ComplianceAnonymize {
+			t.Errorf("Invalid compliance action: %q", c.Action)
+			ok = false
+		}
+		if ev.Device == nil {
+			t.Error("Compliance event without a device")
+			ok = false
+		}
+	case events.TypeFirst, events.TypeUninstall:
 		// Nothing to do for these events
 	default:
 		t.Errorf("Unsupported type: %v", ev.Type)
@@ -301,12 +529,42 @@ func TestRequestValidate(t *testing.T) {
 	if err == nil || err == failClientErr {
 		t.Errorf("expected error with invalid subset sample")
 	}
+
+	invalidFilters := map[string]*events.Filter{
+		"unknown type":        {Types: []events.Type{events.TypeOpen, "OPENED"}},
+		"unknown device type": {DeviceTypes: []events.DeviceType{"IOS"}},
+		"empty notification":  {Notification: []events.Push{{PushID: "a"}, {}}},
+		"empty device":        {Devices: []events.Device{{}}},
+		"negative latency":    {Latency: -1},
+	}
+	for name, f := range invalidFilters {
+		_, err = events.Fetch(c, events.StartLast, 0, nil, f)
+		if err == nil || err == failClientErr {
+			t.Errorf("expected error with %s", name)
+		}
+	}
+
+	_, err = events.FetchRequest(c, &events.Request{Start: events.StartLast, Filters: []*events.Filter{nil}})
+	if err == nil || err == failClientErr {
+		t.Errorf("expected error with nil filter")
+	}
+
+	valid := &events.Filter{
+		Types:        []events.Type{events.TypeOpen, events.TypeWebClick},
+		DeviceTypes:  []events.DeviceType{events.DeviceIOS, events.DeviceWeb},
+		Notification: []events.Push{{GroupID: "g"}},
+		Devices:      []events.Device{{NamedUser: "u"}},
+		Latency:      1000,
+	}
+	if _, err = events.Fetch(c, events.StartLast, 0, nil, valid); err != failClientErr {
+		t.Errorf("unexpected error with valid filter: %v", err)
+	}
 }
 
 func TestClose(t *testing.T) {
 	t.Parallel()
 	fc := newFakeClient(t, "all", "")
-	resp, err := events.Fetch(fc, events.StartFirst, 0, nil, &events.Filter{Types: []events.Type{""}})
+	resp, err := events.Fetch(fc, events.StartFirst, 0, nil)
 	if err != nil {
 		t.Fatalf("Received error fetching: %v", err)
 	}
@@ -324,3 +582,575 @@ func TestClose(t *testing.T) {
 		t.Error("Close didn't finish soon enough.")
 	}
 }
+
+func TestNormalizeTime(t *testing.T) {
+	t.Parallel()
+	const body = `{"id":"a","type":"CLOSE","offset":"1","occurred":"2015-05-27T13:32:07.729+02:00","processed":"2015-05-27T11:32:07.729Z","body":{}}
+{"id":"b","type":"CLOSE","offset":"2","occurred":"2015-05-27T11:33:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}
+{"id":"c","type":"CLOSE","offset":"3","occurred":"2015-05-27T11:32:10.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}
+`
+	hr := &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(body))}
+	resp, err := events.NewResponse(hr, events.NormalizeTime(time.UTC))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for ev := range resp.Events() {
+		if ev.Occurred.Location() != time.UTC || ev.Processed.Location() != time.UTC {
+			t.Errorf("Expected UTC timestamps for %s but found %s and %s", ev.ID, ev.Occurred.Location(), ev.Processed.Location())
+		}
+	}
+	skew := resp.Skew()
+	if skew.Events != 2 {
+		t.Errorf("Expected 2 skewed events but found %d", skew.Events)
+	}
+	if skew.Max != time.Minute {
+		t.Errorf("Expected max skew of 1m but found %s", skew.Max)
+	}
+}
+
+func TestSkipOlderThan(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	now := time.Date(2017, 4, 25, 9, 0, 0, 0, time.UTC)
+	for i, age := range []time.Duration{3 * time.Hour, 2 * time.Hour, time.Minute, 0} {
+		enc.Encode(&events.Event{ID: fmt.Sprint(i), Type: events.TypeClose, Offset: uint64(i), Occurred: now.Add(-age), Processed: now.Add(-age), Body: []byte("{}")})
+	}
+	hr := &http.Response{StatusCode: 200, Body: ioutil.NopCloser(buf)}
+	resp, err := events.NewResponse(hr, events.SkipOlderThan(time.Hour), events.WithClock(uatest.NewClock(now)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ids := []string{}
+	for ev := range resp.Events() {
+		ids = append(ids, ev.ID)
+	}
+	if len(ids) != 2 || ids[0] != "2" || ids[1] != "3" {
+		t.Errorf("Expected only fresh events but received: %v", ids)
+	}
+	if n := resp.Skipped(); n != 2 {
+		t.Errorf("Expected 2 skipped events but found %d", n)
+	}
+}
+
+func TestReadBatch(t *testing.T) {
+	t.Parallel()
+	pr, pw := io.Pipe()
+	hr := &http.Response{StatusCode: 200, Body: pr}
+	resp, err := events.NewResponse(hr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	const line = `{"id":"%d","type":"CLOSE","offset":"%d","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}` + "\n"
+
+	// Nothing available yet
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if evs, err := resp.ReadBatch(ctx, 10); len(evs) != 0 || err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded but received %d events and %v", len(evs), err)
+	}
+
+	go func() {
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(pw, line, i, i)
+		}
+		pw.Close()
+	}()
+	n := 0
+	for {
+		evs, err := resp.ReadBatch(context.Background(), 2)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(evs) == 0 || len(evs) > 2 {
+			t.Errorf("Expected 1-2 events but received %d", len(evs))
+		}
+		n += len(evs)
+	}
+	if n != 5 {
+		t.Errorf("Expected 5 events but received %d", n)
+	}
+}
+
+func TestMaxIdle(t *testing.T) {
+	t.Parallel()
+	const line = `{"id":"%d","type":"CLOSE","offset":"%d","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}` + "\n"
+	const idle = 50 * time.Millisecond
+
+	// Stalled body
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	resp, err := events.NewResponse(&http.Response{StatusCode: 200, Body: pr}, events.MaxIdle(idle))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	go fmt.Fprintf(pw, line, 1, 1)
+	n := 0
+	for range resp.Events() {
+		n++
+	}
+	if n != 1 || resp.Err() != events.ErrStreamStalled {
+		t.Errorf("Expected 1 event and ErrStreamStalled but found %d and %v", n, resp.Err())
+	}
+
+	// Slow consumers are not stalls
+	pr, pw = io.Pipe()
+	resp, err = events.NewResponse(&http.Response{StatusCode: 200, Body: pr}, events.MaxIdle(idle))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	go func() {
+		for i := 0; i < 20; i++ {
+			fmt.Fprintf(pw, line, i, i)
+		}
+		pw.Close()
+	}()
+	time.Sleep(3 * idle)
+	n = 0
+	for range resp.Events() {
+		n++
+	}
+	if n != 20 || resp.Err() == events.ErrStreamStalled {
+		t.Errorf("Expected 20 events without stalling but found %d and %v", n, resp.Err())
+	}
+
+	// Stalls are timed with the Response's Clock
+	clock := uatest.NewClock(time.Now())
+	pr, pw = io.Pipe()
+	defer pw.Close()
+	resp, err = events.NewResponse(&http.Response{StatusCode: 200, Body: pr}, events.MaxIdle(time.Hour), events.WithClock(clock))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for clock.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(time.Hour)
+	for range resp.Events() {
+	}
+	if err := resp.Err(); err != events.ErrStreamStalled {
+		t.Errorf("Expected ErrStreamStalled but found %v", err)
+	}
+}
+
+func TestBufferSize(t *testing.T) {
+	t.Parallel()
+	const line = `{"id":"%d","type":"CLOSE","offset":"%d","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}` + "\n"
+	tests := []struct {
+		opts []events.Option
+		cap  int
+	}{
+		{nil, events.DefaultBufferSize},
+		{[]events.Option{events.BufferSize(0)}, 0},
+		{[]events.Option{events.BufferSize(100), events.ReadAhead(64)}, 100},
+	}
+	for _, test := range tests {
+		buf := &bytes.Buffer{}
+		for i := 0; i < 20; i++ {
+			fmt.Fprintf(buf, line, i, i)
+		}
+		resp, err := events.NewResponse(&http.Response{StatusCode: 200, Body: ioutil.NopCloser(buf)}, test.opts...)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if n := cap(resp.Events()); n != test.cap {
+			t.Errorf("Expected buffer of %d but found %d", test.cap, n)
+		}
+		n := 0
+		for range resp.Events() {
+			n++
+		}
+		if n != 20 {
+			t.Errorf("Expected 20 events but found %d", n)
+		}
+	}
+}
+
+func TestPoolEvents(t *testing.T) {
+	t.Parallel()
+	const line = `{"id":"%d","type":"CLOSE","offset":"%d","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{"session_id":"s%d"}}` + "\n"
+	buf := &bytes.Buffer{}
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(buf, line, i, i, i)
+	}
+	resp, err := events.NewResponse(&http.Response{StatusCode: 200, Body: ioutil.NopCloser(buf)}, events.PoolEvents())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	i := 0
+	for ev := range resp.Events() {
+		cls, err := ev.Close()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ev.ID != fmt.Sprint(i) || ev.Offset != uint64(i) || cls.SessionID != fmt.Sprintf("s%d", i) {
+			t.Errorf("Unexpected event %d: %s %d %s", i, ev.ID, ev.Offset, cls.SessionID)
+		}
+		ev.Release()
+		i++
+	}
+	if i != 100 {
+		t.Errorf("Expected 100 events but found %d", i)
+	}
+
+	// Releasing unpooled events is a no-op
+	ev := &events.Event{ID: "a"}
+	ev.Release()
+	if ev.ID != "a" {
+		t.Errorf("Unpooled event was reset")
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	t.Parallel()
+	ev := &events.Event{Type: events.TypeCustom, Body: []byte(`{"name":"purchase","properties":{"sku":"abc"}}`)}
+	custom := struct {
+		Name       string            `json:"name"`
+		Properties map[string]string `json:"properties"`
+	}{}
+	if err := ev.DecodeBody(&custom, events.TypeCustom); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if custom.Name != "purchase" || custom.Properties["sku"] != "abc" {
+		t.Errorf("Unexpected body: %#v", custom)
+	}
+	if err := ev.DecodeBody(&custom, events.TypeOpen, events.TypeClose); err != events.WrongType {
+		t.Errorf("Expected WrongType but found %v", err)
+	}
+	var any map[string]interface{}
+	if err := ev.DecodeBody(&any); err != nil || any["name"] != "purchase" {
+		t.Errorf("Unexpected result decoding any type: %v %v", any, err)
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	t.Parallel()
+	const line = `{"id":"%d","type":"CLOSE","offset":"%d","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}` + "\n"
+	body := fmt.Sprintf(line, 1, 1) + "{not json\n" + `{"id":"x","offset":false}` + "\n" + fmt.Sprintf(line, 2, 2)
+	var dead []string
+	hr := &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(body))}
+	resp, err := events.NewResponse(hr, events.DeadLetter(func(raw []byte, err error) {
+		if err == nil {
+			t.Errorf("Expected error for %s", raw)
+		}
+		dead = append(dead, string(bytes.TrimSpace(raw)))
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ids := []string{}
+	for ev := range resp.Events() {
+		ids = append(ids, ev.ID)
+	}
+	if len(ids) != 2 || ids[0] != "1" || ids[1] != "2" {
+		t.Errorf("Expected good events to be streamed but found %v", ids)
+	}
+	if len(dead) != 2 || dead[0] != "{not json" || resp.DeadLettered() != 2 {
+		t.Errorf("Expected 2 dead letters but found %d: %q", resp.DeadLettered(), dead)
+	}
+	if err := resp.Err(); err != io.EOF {
+		t.Errorf("Expected EOF but found %v", err)
+	}
+}
+
+// versionClient records the Accept header of requests.
+type versionClient struct {
+	version int
+	accept  string
+}
+
+func (c *versionClient) APIVersion() int { return c.version }
+
+func (c *versionClient) Post(url string, body interface{}, extra http.Header) (*http.Response, error) {
+	c.accept = extra.Get("Accept")
+	return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(nil))}, nil
+}
+
+func TestVersion(t *testing.T) {
+	t.Parallel()
+	c := &versionClient{version: 3}
+	resp, err := events.FetchRequest(c, &events.Request{Start: events.StartLast})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Close()
+	if c.accept != "application/vnd.urbanairship+x-ndjson;version=3;" {
+		t.Errorf("Unexpected Accept header: %q", c.accept)
+	}
+
+	// Unsupported client and request versions are errors
+	c.version = 99
+	if _, err := events.FetchRequest(c, &events.Request{Start: events.StartLast}); err == nil {
+		t.Errorf("Expected error for unsupported client version")
+	}
+	if _, err := events.FetchRequest(c, &events.Request{Start: events.StartLast, Version: 99}); err == nil {
+		t.Errorf("Expected error for unsupported request version")
+	}
+
+	// Request version overrides client version
+	if _, err := events.FetchRequest(c, &events.Request{Start: events.StartLast, Version: 3}); err != nil {
+		t.Errorf("Unexpected error overriding client version: %v", err)
+	}
+}
+
+func TestProject(t *testing.T) {
+	t.Parallel()
+	const line = `{"id":"a","type":"PUSH_BODY","offset":"7","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z",` +
+		`"device":{"ios_channel":"i","named_user_id":"n"},"body":{"push_id":"p", "payload" : {"large":["x,}","y\\"]}, "nested":{"a":[1,2]},"trimmed":false }}` + "\n"
+
+	tests := []struct {
+		p      events.Projection
+		device *events.Device
+		body   string
+	}{
+		{events.Projection{}, nil, ""},
+		{events.Projection{Device: []string{"ios_channel"}, Body: []string{"push_id", "missing"}}, &events.Device{IOS: "i"}, `{"push_id":"p"}`},
+		{events.Projection{Device: []string{"amazon_channel"}, Body: []string{"trimmed"}}, nil, `{"trimmed":false}`},
+		{events.Projection{Device: []string{"named_user_id", "ios_channel"}, Body: []string{"trimmed", "nested", "push_id"}}, &events.Device{IOS: "i", NamedUser: "n"}, `{"push_id":"p","nested":{"a":[1,2]},"trimmed":false}`},
+	}
+	// Events are projected as they're decoded, from pooled events, and after
+	// being decoded in full to detect schema drift
+	extra := [][]events.Option{nil, {events.PoolEvents()}, {events.ReportSchemaDrift(func(*events.Drift) {})}}
+	for i, test := range tests {
+		for _, opts := range extra {
+			hr := &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(line))}
+			project, err := events.Project(test.p)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			resp, err := events.NewResponse(hr, append(opts, project)...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			ev := <-resp.Events()
+			if ev == nil {
+				t.Fatalf("%d: Expected an event but stream ended: %v", i, resp.Err())
+			}
+			if ev.ID != "a" || ev.Type != events.TypePush || ev.Offset != 7 || ev.Occurred.IsZero() {
+				t.Errorf("%d: Unexpected envelope: %#v", i, ev)
+			}
+			if !ev.Processed.IsZero() {
+				t.Errorf("%d: Expected Processed to be dropped but found %v", i, ev.Processed)
+			}
+			if !reflect.DeepEqual(ev.Device, test.device) {
+				t.Errorf("%d: Expected device %v but found %v", i, test.device, ev.Device)
+			}
+			if string(ev.Body) != test.body {
+				t.Errorf("%d: Expected body %q but found %q", i, test.body, ev.Body)
+			}
+			resp.Close()
+		}
+	}
+
+	if _, err := events.Project(events.Projection{Device: []string{"ios"}}); err == nil {
+		t.Errorf("Expected an error with an unknown device ID")
+	}
+}
+
+func TestResponseStats(t *testing.T) {
+	t.Parallel()
+	const line = `{"id":"%d","type":"%s","offset":"%d","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}` + "\n"
+	body := fmt.Sprintf(line, 1, "OPEN", 5) + fmt.Sprintf(line, 2, "CLOSE", 6) + fmt.Sprintf(line, 3, "OPEN", 9)
+	hr := &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(body))}
+	resp, err := events.NewResponse(hr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s := resp.Stats(); s.Events != 0 || s.Offset != nil || !s.LastEvent.IsZero() || s.SinceLastEvent != 0 {
+		t.Errorf("Unexpected stats before any events: %+v", s)
+	}
+	for range resp.Events() {
+	}
+	s := resp.Stats()
+	if s.Events != 3 || s.Bytes != uint64(len(body)) {
+		t.Errorf("Expected 3 events and %d bytes but found %d and %d", len(body), s.Events, s.Bytes)
+	}
+	if s.Types[events.TypeOpen] != 2 || s.Types[events.TypeClose] != 1 {
+		t.Errorf("Unexpected type counts: %v", s.Types)
+	}
+	if s.Offset == nil || *s.Offset != 9 {
+		t.Errorf("Expected last offset 9 but found %v", s.Offset)
+	}
+	if s.LastEvent.IsZero() || s.SinceLastEvent < 0 {
+		t.Errorf("Unexpected last event time %v (%v ago)", s.LastEvent, s.SinceLastEvent)
+	}
+
+	// Snapshots are copies
+	s.Types[events.TypeOpen] = 100
+	if resp.Stats().Types[events.TypeOpen] != 2 {
+		t.Errorf("Modifying a snapshot modified the Response's stats")
+	}
+}
+
+func TestCustomProperties(t *testing.T) {
+	t.Parallel()
+	ev := &events.Event{
+		Type: events.TypeCustom,
+		Body: []byte(`{"name":"purchase","value":12.5,"properties":{"sku":"A-1","quantity":2,"gift":true}}`),
+	}
+	c, err := ev.Custom()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.Name != "purchase" || c.Value != "12.5" || len(c.Properties) != 3 {
+		t.Errorf("Unexpected custom event: %+v", c)
+	}
+	props := struct {
+		SKU      string `json:"sku"`
+		Quantity int    `json:"quantity"`
+		Gift     bool   `json:"gift"`
+	}{}
+	if err := c.DecodeProperties(&props); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if props.SKU != "A-1" || props.Quantity != 2 || !props.Gift {
+		t.Errorf("Unexpected properties: %+v", props)
+	}
+	if err := c.DecodeProperties(&struct {
+		SKU int `json:"sku"`
+	}{}); err == nil {
+		t.Errorf("Expected an error decoding mismatched properties")
+	}
+
+	if _, err := (&events.Event{Type: events.TypeOpen}).Custom(); err != events.WrongType {
+		t.Errorf("Expected WrongType but found %v", err)
+	}
+}
+
+func TestLag(t *testing.T) {
+	t.Parallel()
+	occurred := time.Date(2017, 5, 1, 12, 0, 0, 0, time.UTC)
+	ev := &events.Event{Occurred: occurred, Processed: occurred.Add(90 * time.Second)}
+	if lag := ev.Lag(); lag != 90*time.Second {
+		t.Errorf("Expected 90s lag but found %v", lag)
+	}
+	ev.Processed = occurred.Add(-time.Second)
+	if lag := ev.Lag(); lag != -time.Second {
+		t.Errorf("Expected -1s lag but found %v", lag)
+	}
+
+	f := events.FilterMaxLatency(1500 * time.Millisecond)
+	f.Types = []events.Type{events.TypeClose}
+	buf, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(buf) != `{"types":["CLOSE"],"latency":1500}` {
+		t.Errorf("Unexpected filter JSON: %s", buf)
+	}
+}
+
+func TestFilterNotification(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		f        *events.Filter
+		expected string
+	}{
+		{events.FilterPush("a", "b"), `{"notification":[{"push_id":"a"},{"push_id":"b"}]}`},
+		{events.FilterGroup("g"), `{"notification":[{"group_id":"g"}]}`},
+	}
+	for _, test := range tests {
+		if err := test.f.Validate(); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		buf, err := json.Marshal(test.f)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(buf) != test.expected {
+			t.Errorf("Expected %s but found %s", test.expected, buf)
+		}
+	}
+	if err := events.FilterPush("").Validate(); err == nil {
+		t.Errorf("Expected an error for an empty push ID")
+	}
+}
+
+func TestFilterDevices(t *testing.T) {
+	t.Parallel()
+	f := events.FilterDevices(
+		events.AmazonChannel("a"),
+		events.AndroidChannel("b"),
+		events.IOSChannel("c"),
+		events.NamedUser("d"),
+		events.EmailChannel("e"),
+		events.SMSChannel("f"),
+		events.WebChannel("g"),
+	)
+	if err := f.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	buf, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{"devices":[{"amazon_channel":"a"},{"android_channel":"b"},{"ios_channel":"c"},{"named_user_id":"d"},` +
+		`{"email_channel":"e"},{"sms_channel":"f"},{"web_channel":"g"}]}`
+	if string(buf) != expected {
+		t.Errorf("Expected %s but found %s", expected, buf)
+	}
+
+	invalid := []events.Device{
+		{},
+		events.IOSChannel(""),
+		{IOS: "c", NamedUser: "d"},
+		{EmailAddress: "user@example.com"},
+	}
+	for _, d := range invalid {
+		if err := d.Validate(); err == nil {
+			t.Errorf("Expected an error for %+v", d)
+		}
+		if err := events.FilterDevices(d).Validate(); err == nil {
+			t.Errorf("Expected a filter error for %+v", d)
+		}
+	}
+}
+
+func TestResponseHeaders(t *testing.T) {
+	t.Parallel()
+	const ct = "application/vnd.urbanairship+x-ndjson;version=3;"
+	hr := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Ua-Operation-Id": {"op-1"}, "Content-Type": {ct}},
+		Body:       ioutil.NopCloser(&bytes.Buffer{}),
+	}
+	resp, err := events.NewResponse(hr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Close()
+	if resp.ID != "op-1" || resp.ContentType != ct {
+		t.Errorf("Unexpected ID %q or ContentType %q", resp.ID, resp.ContentType)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	t.Parallel()
+	hr := &http.Response{
+		StatusCode: 400,
+		Header:     http.Header{"Ua-Operation-Id": {"op-1"}},
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"ok":false,"error":"invalid filter","error_code":40001,"details":{"path":"filters[0]"}}`)),
+	}
+	_, err := events.NewResponse(hr)
+	apiErr, ok := err.(*events.APIError)
+	if !ok {
+		t.Fatalf("Expected *APIError but found %T: %v", err, err)
+	}
+	if apiErr.StatusCode != 400 || apiErr.Message != "invalid filter" || apiErr.Code != 40001 ||
+		apiErr.OperationID != "op-1" || string(apiErr.Details) != `{"path":"filters[0]"}` {
+		t.Errorf("Unexpected error: %#v", apiErr)
+	}
+	if expected := "unexpected non-200 response: 400: invalid filter (operation op-1)"; err.Error() != expected {
+		t.Errorf("Expected %q but found %q", expected, err.Error())
+	}
+
+	// Non-JSON bodies are kept raw
+	hr = &http.Response{StatusCode: 503, Body: ioutil.NopCloser(bytes.NewBufferString("unavailable"))}
+	_, err = events.NewResponse(hr)
+	if apiErr, ok := err.(*events.APIError); !ok || apiErr.StatusCode != 503 || string(apiErr.Body) != "unavailable" {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// Flatten merges the event's envelope and decoded body into a single map with
+// dotted keys, for generic ETL tools and log shippers which don't handle
+// nested records:
+//
+//	id, type, offset, occurred, processed
+//	device.ios_channel, device.named_user_id, ...
+//	body.push_id, body.triggering_push.push_id, ...
+//
+// Offset is a uint64, or the OpaqueOffset string if the event has one, and
+// Occurred and Processed are time.Times. Only non-empty device IDs are
+// included. Body numbers are json.Numbers. Arrays of strings, numbers, and
+// booleans, such as tags, are kept as []interface{} while arrays containing
+// objects or arrays are flattened by index, such as body.mutations.0.key.
+// Bodies which aren't objects are kept whole under body.
+//
+// An error is returned if the body isn't valid JSON.
+func (e *Event) Flatten() (map[string]interface{}, error) {
+	m := map[string]interface{}{
+		"id":        e.ID,
+		"type":      string(e.Type),
+		"occurred":  e.Occurred,
+		"processed": e.Processed,
+	}
+	if e.OpaqueOffset != "" {
+		m["offset"] = e.OpaqueOffset
+	} else {
+		m["offset"] = e.Offset
+	}
+	if d := e.Device; d != nil {
+		for _, id := range []struct{ name, value string }{
+			{"amazon_channel", d.Amazon},
+			{"android_channel", d.Android},
+			{"ios_channel", d.IOS},
+			{"named_user_id", d.NamedUser},
+			{"email_channel", d.Email},
+			{"email_address", d.EmailAddress},
+			{"sms_channel", d.SMS},
+			{"sms_sender", d.SMSSender},
+			{"msisdn", d.MSISDN},
+			{"web_channel", d.Web},
+		} {
+			if id.value != "" {
+				m["device."+id.name] = id.value
+			}
+		}
+	}
+	if len(e.Body) == 0 {
+		return m, nil
+	}
+	var body interface{}
+	dec := json.NewDecoder(bytes.NewReader(e.Body))
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil {
+		return nil, err
+	}
+	if body != nil {
+		flatten(m, "body", body)
+	}
+	return m, nil
+}
+
+// flatten adds v to m under key, recursing into objects and arrays which
+// aren't just scalars.
+func flatten(m map[string]interface{}, key string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, el := range v {
+			flatten(m, key+"."+k, el)
+		}
+		return
+	case []interface{}:
+		for _, el := range v {
+			switch el.(type) {
+			case map[string]interface{}, []interface{}:
+				for i, el := range v {
+					flatten(m, key+"."+strconv.Itoa(i), el)
+				}
+				return
+			}
+		}
+	}
+	m[key] = v
+}
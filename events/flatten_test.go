@@ -0,0 +1,63 @@
+package events_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+func TestFlatten(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2017, 4, 25, 9, 0, 0, 0, time.UTC)
+	ev := &events.Event{
+		ID:        "a",
+		Type:      events.TypeOpen,
+		Offset:    7,
+		Occurred:  now,
+		Processed: now,
+		Device:    &events.Device{IOS: "ios", NamedUser: "kim"},
+		Body:      json.RawMessage(`{"session_id":"s","triggering_push":{"push_id":"p","group_id":null},"tags":["a",1,true],"mutations":[{"key":"k"},[2]],"empty":{}}`),
+	}
+	m, err := ev.Flatten()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]interface{}{
+		"id":                            "a",
+		"type":                          "OPEN",
+		"offset":                        uint64(7),
+		"occurred":                      now,
+		"processed":                     now,
+		"device.ios_channel":            "ios",
+		"device.named_user_id":          "kim",
+		"body.session_id":               "s",
+		"body.triggering_push.push_id":  "p",
+		"body.triggering_push.group_id": nil,
+		"body.tags":                     []interface{}{"a", json.Number("1"), true},
+		"body.mutations.0.key":          "k",
+		"body.mutations.1":              []interface{}{json.Number("2")},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("Expected:\n%v\nFound:\n%v", expected, m)
+	}
+
+	// Opaque offsets, missing devices and bodies, and non-object bodies
+	ev = &events.Event{ID: "b", Type: events.TypeUninstall, OpaqueOffset: "xyz"}
+	if m, err = ev.Flatten(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(m) != 5 || m["offset"] != "xyz" {
+		t.Errorf("Expected only the envelope with an opaque offset but found %v", m)
+	}
+	ev.Body = json.RawMessage(`"text"`)
+	if m, err = ev.Flatten(); err != nil || m["body"] != "text" {
+		t.Errorf("Expected the whole body but found %v (%v)", m["body"], err)
+	}
+	ev.Body = json.RawMessage(`{`)
+	if _, err := ev.Flatten(); err == nil {
+		t.Errorf("Expected error flattening an invalid body")
+	}
+}
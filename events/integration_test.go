@@ -35,7 +35,9 @@ func TestEventsIntegration(t *testing.T) {
 		},
 	}
 
-	resp, err := events.Fetch(client, events.StartFirst, 0, nil, &uaImportFilter)
+	// Stop consuming when there's a 3s pause
+	req := &events.Request{Start: events.StartFirst, Filters: []*events.Filter{&uaImportFilter}}
+	resp, err := events.FetchRequest(client, req, events.MaxIdle(3*time.Second))
 	if err != nil {
 		t.Fatalf("Error fetching events from %s: %v", events.SetURL(""), err)
 	}
@@ -45,32 +47,19 @@ func TestEventsIntegration(t *testing.T) {
 		t.Logf("Invalid/missing response ID: %q", resp.ID)
 	}
 
-	// Consume events for up to 15s or when there's a 3s pause
+	// Consume events for up to 15s or until the stream stalls
 	last := time.Now()
 	events := 0
-	deadline := time.Now().Add(15 * time.Second)
+	deadline := time.AfterFunc(15*time.Second, resp.Close)
+	defer deadline.Stop()
 
-	maxpause := 3 * time.Second
-	timer := time.NewTimer(maxpause)
-	defer timer.Stop()
-
-consume:
-	for time.Now().Before(deadline) {
-		select {
-		case ev, ok := <-resp.Events():
-			if !ok {
-				break consume
-			}
-			events++
-			if !checkEvent(t, ev.Type, ev) {
-				buf, _ := json.MarshalIndent(ev, "", "    ")
-				t.Logf("Event %s not ok: %s", ev.ID, string(buf))
-			}
-			timer.Reset(maxpause)
-			last = time.Now()
-		case <-timer.C:
-			break consume
+	for ev := range resp.Events() {
+		events++
+		if !checkEvent(t, ev.Type, ev) {
+			buf, _ := json.MarshalIndent(ev, "", "    ")
+			t.Logf("Event %s not ok: %s", ev.ID, string(buf))
 		}
+		last = time.Now()
 	}
 	resp.Close()
 
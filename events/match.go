@@ -0,0 +1,132 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MatchFilters returns true if ev matches any of the filters the way the Event
+// API applies them. Filters are unioned, so no filters matches all events.
+// Useful for applying Request filters to events received elsewhere, such as
+// when sharing one connection among several consumers.
+func MatchFilters(filters []*Filter, ev *Event) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, f := range filters {
+		if f.Match(ev) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns true if ev meets every criterion of the Filter. A nil Filter
+// matches all events.
+func (f *Filter) Match(ev *Event) bool {
+	if f == nil {
+		return true
+	}
+	if !matchTypes(f.Types, ev.Type) {
+		return false
+	}
+	if len(f.DeviceTypes) > 0 {
+		dt := ev.Device.Type()
+		found := false
+		for _, t := range f.DeviceTypes {
+			if t == dt {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Devices) > 0 && !matchDevices(f.Devices, ev.Device) {
+		return false
+	}
+	if len(f.Notification) > 0 && !matchNotification(f.Notification, ev) {
+		return false
+	}
+	if f.Latency > 0 && ev.Processed.Sub(ev.Occurred) > time.Duration(f.Latency)*time.Millisecond {
+		return false
+	}
+	return true
+}
+
+// Type returns the DeviceType of the Device's first set identifier or an empty
+// string if the Device is nil or has no identifiers.
+func (d *Device) Type() DeviceType {
+	switch {
+	case d == nil:
+		return ""
+	case d.IOS != "":
+		return DeviceIOS
+	case d.Android != "":
+		return DeviceAndroid
+	case d.Amazon != "":
+		return DeviceAmazon
+	case d.Email != "":
+		return DeviceEmail
+	case d.SMS != "":
+		return DeviceSMS
+	case d.Web != "":
+		return DeviceWeb
+	case d.NamedUser != "":
+		return DeviceUser
+	}
+	return ""
+}
+
+func matchTypes(types []Type, t Type) bool {
+	wildcard := true
+	for _, ft := range types {
+		if ft == "" {
+			// Treat empty types as wildcards
+			continue
+		}
+		wildcard = false
+		if ft == t {
+			return true
+		}
+	}
+	return wildcard
+}
+
+func matchDevices(devices []Device, d *Device) bool {
+	if d == nil {
+		return false
+	}
+	for _, fd := range devices {
+		if (fd.IOS != "" && fd.IOS == d.IOS) ||
+			(fd.Android != "" && fd.Android == d.Android) ||
+			(fd.Amazon != "" && fd.Amazon == d.Amazon) ||
+			(fd.Email != "" && fd.Email == d.Email) ||
+			(fd.SMS != "" && fd.SMS == d.SMS) ||
+			(fd.Web != "" && fd.Web == d.Web) ||
+			(fd.NamedUser != "" && fd.NamedUser == d.NamedUser) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchNotification(pushes []Push, ev *Event) bool {
+	p := Push{}
+	if err := json.Unmarshal(ev.Body, &p); err != nil {
+		return false
+	}
+	for _, fp := range pushes {
+		if fp.PushID != "" && fp.PushID != p.PushID {
+			continue
+		}
+		if fp.GroupID != "" && fp.GroupID != p.GroupID {
+			continue
+		}
+		if fp.PushID != "" || fp.GroupID != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,572 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+// format of a MessagePack value.
+type format int
+
+const (
+	fNil format = iota
+	fBool
+	fInt
+	fUint
+	fFloat
+	fString
+	fBinary
+	fArray
+	fMap
+	fExt
+)
+
+var formatNames = [...]string{"nil", "bool", "int", "uint", "float", "string", "binary", "array", "map", "extension"}
+
+// header of a value: its format and either its scalar value or the length of
+// its contents which follow.
+type header struct {
+	format format
+	b      bool
+	i      int64
+	u      uint64
+	f      float64
+
+	// n is the number of bytes of strings, binaries, and extensions or the
+	// number of elements of arrays and maps.
+	n   int
+	ext int8
+}
+
+// timeExt is the timestamp extension type.
+const timeExt = -1
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+type decoder struct {
+	r byteReader
+}
+
+// Unmarshal decodes the MessagePack value in data into the value pointed to
+// by v, which may be an *events.Event, a typed body such as *events.Send, or
+// any other value supported by Marshal.
+func Unmarshal(data []byte, v interface{}) error {
+	r := bytes.NewReader(data)
+	d := &decoder{r: r}
+	if err := d.decode(v); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if r.Len() > 0 {
+		return fmt.Errorf("msgpack: %d unexpected bytes after value", r.Len())
+	}
+	return nil
+}
+
+func (d *decoder) decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("msgpack: cannot decode into non-pointer %T", v)
+	}
+	h, err := d.header()
+	if err != nil {
+		return err
+	}
+	return d.decodeHeader(h, rv.Elem())
+}
+
+// unexpected returns err unless it's io.EOF, which is unexpected after the
+// first byte of a value.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// big reads an n byte big endian integer.
+func (d *decoder) big(n int) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(d.r, buf[8-n:]); err != nil {
+		return 0, unexpected(err)
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+// header reads the header of the next value. Returns io.EOF if there are no
+// more values.
+func (d *decoder) header() (header, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return header{}, err
+	}
+	switch {
+	case c <= 0x7f:
+		return header{format: fUint, u: uint64(c)}, nil
+	case c >= 0xe0:
+		return header{format: fInt, i: int64(int8(c))}, nil
+	case c&0xf0 == 0x80:
+		return header{format: fMap, n: int(c & 0x0f)}, nil
+	case c&0xf0 == 0x90:
+		return header{format: fArray, n: int(c & 0x0f)}, nil
+	case c&0xe0 == 0xa0:
+		return header{format: fString, n: int(c & 0x1f)}, nil
+	}
+
+	// sized reads a length of n bytes
+	sized := func(f format, n int) (header, error) {
+		l, err := d.big(n)
+		return header{format: f, n: int(l)}, err
+	}
+	ext := func(h header, err error) (header, error) {
+		if err != nil {
+			return h, err
+		}
+		t, err := d.r.ReadByte()
+		h.format, h.ext = fExt, int8(t)
+		return h, unexpected(err)
+	}
+	switch c {
+	case 0xc0:
+		return header{format: fNil}, nil
+	case 0xc2, 0xc3:
+		return header{format: fBool, b: c == 0xc3}, nil
+	case 0xc4, 0xc5, 0xc6:
+		return sized(fBinary, 1<<(c-0xc4))
+	case 0xc7, 0xc8, 0xc9:
+		return ext(sized(fExt, 1<<(c-0xc7)))
+	case 0xca:
+		u, err := d.big(4)
+		return header{format: fFloat, f: float64(math.Float32frombits(uint32(u)))}, err
+	case 0xcb:
+		u, err := d.big(8)
+		return header{format: fFloat, f: math.Float64frombits(u)}, err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.big(1 << (c - 0xcc))
+		return header{format: fUint, u: u}, err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		n := uint(1 << (c - 0xd0))
+		u, err := d.big(int(n))
+		// Sign extend
+		shift := 64 - 8*n
+		return header{format: fInt, i: int64(u<<shift) >> shift}, err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return ext(header{n: 1 << (c - 0xd4)}, nil)
+	case 0xd9, 0xda, 0xdb:
+		return sized(fString, 1<<(c-0xd9))
+	case 0xdc, 0xdd:
+		return sized(fArray, 2<<(c-0xdc))
+	case 0xde, 0xdf:
+		return sized(fMap, 2<<(c-0xde))
+	}
+	return header{}, fmt.Errorf("msgpack: invalid format 0x%x", c)
+}
+
+// bytes reads n bytes. Large values are read in chunks so corrupt lengths
+// fail without allocating them.
+func (d *decoder) bytes(n int) ([]byte, error) {
+	if n <= 4096 {
+		b := make([]byte, n)
+		_, err := io.ReadFull(d.r, b)
+		return b, unexpected(err)
+	}
+	buf := &bytes.Buffer{}
+	if _, err := io.CopyN(buf, d.r, int64(n)); err != nil {
+		return nil, unexpected(err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *decoder) time(h header) (time.Time, error) {
+	b, err := d.bytes(h.n)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var sec, nsec int64
+	switch h.n {
+	case 4:
+		sec = int64(binary.BigEndian.Uint32(b))
+	case 8:
+		v := binary.BigEndian.Uint64(b)
+		sec, nsec = int64(v&(1<<34-1)), int64(v>>34)
+	case 12:
+		nsec, sec = int64(binary.BigEndian.Uint32(b)), int64(binary.BigEndian.Uint64(b[4:]))
+	default:
+		return time.Time{}, fmt.Errorf("msgpack: invalid timestamp length %d", h.n)
+	}
+	return time.Unix(sec, nsec).UTC(), nil
+}
+
+// key reads a map key, which must be a string.
+func (d *decoder) key() (string, error) {
+	h, err := d.header()
+	if err != nil {
+		return "", unexpected(err)
+	}
+	if h.format != fString {
+		return "", fmt.Errorf("msgpack: unsupported %s map key", formatNames[h.format])
+	}
+	b, err := d.bytes(h.n)
+	return string(b), err
+}
+
+// value reads the rest of a value as nil, bool, int64, uint64 (for integers
+// beyond int64), float64, string, []byte, time.Time, []interface{}, or
+// map[string]interface{}.
+func (d *decoder) value(h header) (interface{}, error) {
+	switch h.format {
+	case fNil:
+		return nil, nil
+	case fBool:
+		return h.b, nil
+	case fInt:
+		return h.i, nil
+	case fUint:
+		if h.u <= math.MaxInt64 {
+			return int64(h.u), nil
+		}
+		return h.u, nil
+	case fFloat:
+		return h.f, nil
+	case fString:
+		b, err := d.bytes(h.n)
+		return string(b), err
+	case fBinary:
+		return d.bytes(h.n)
+	case fExt:
+		if h.ext == timeExt {
+			return d.time(h)
+		}
+		if _, err := d.bytes(h.n); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("msgpack: unsupported extension type %d", h.ext)
+	case fArray:
+		arr := make([]interface{}, 0, minLen(h.n))
+		for i := 0; i < h.n; i++ {
+			el, err := d.next()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, el)
+		}
+		return arr, nil
+	}
+	m := make(map[string]interface{}, minLen(h.n))
+	for i := 0; i < h.n; i++ {
+		k, err := d.key()
+		if err != nil {
+			return nil, err
+		}
+		if m[k], err = d.next(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// next reads the next value within an array or map.
+func (d *decoder) next() (interface{}, error) {
+	h, err := d.header()
+	if err != nil {
+		return nil, unexpected(err)
+	}
+	return d.value(h)
+}
+
+// minLen limits the capacity allocated up front for corrupt lengths.
+func minLen(n int) int {
+	if n > 1024 {
+		return 1024
+	}
+	return n
+}
+
+// json reads the rest of a value as JSON. Binaries are base64 strings and
+// times RFC 3339 strings, like encoding/json.
+func (d *decoder) json(h header) (json.RawMessage, error) {
+	v, err := d.value(h)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func (d *decoder) mismatch(h header, v reflect.Value) error {
+	return fmt.Errorf("msgpack: cannot decode %s into %s", formatNames[h.format], v.Type())
+}
+
+func (d *decoder) decodeHeader(h header, v reflect.Value) error {
+	switch v.Type() {
+	case eventType:
+		return d.event(h, v.Addr().Interface().(*events.Event))
+	case rawType:
+		raw, err := d.json(h)
+		if err == nil {
+			v.SetBytes(raw)
+		}
+		return err
+	case timeType:
+		switch {
+		case h.format == fExt && h.ext == timeExt:
+			t, err := d.time(h)
+			if err == nil {
+				v.Set(reflect.ValueOf(t))
+			}
+			return err
+		case h.format == fString:
+			b, err := d.bytes(h.n)
+			if err != nil {
+				return err
+			}
+			t, err := time.Parse(time.RFC3339Nano, string(b))
+			if err == nil {
+				v.Set(reflect.ValueOf(t))
+			}
+			return err
+		case h.format != fNil:
+			return d.mismatch(h, v)
+		}
+	case numberType:
+		switch h.format {
+		case fInt:
+			v.SetString(strconv.FormatInt(h.i, 10))
+			return nil
+		case fUint:
+			v.SetString(strconv.FormatUint(h.u, 10))
+			return nil
+		case fFloat:
+			v.SetString(strconv.FormatFloat(h.f, 'g', -1, 64))
+			return nil
+		}
+	}
+	if h.format == fNil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeHeader(h, v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			break
+		}
+		val, err := d.value(h)
+		if err == nil {
+			v.Set(reflect.ValueOf(&val).Elem())
+		}
+		return err
+	case reflect.Bool:
+		if h.format == fBool {
+			v.SetBool(h.b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := h.i
+		switch {
+		case h.format == fUint && h.u <= math.MaxInt64:
+			i = int64(h.u)
+		case h.format != fInt:
+			return d.mismatch(h, v)
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("msgpack: %d overflows %s", i, v.Type())
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := h.u
+		switch {
+		case h.format == fInt && h.i >= 0:
+			u = uint64(h.i)
+		case h.format != fUint:
+			return d.mismatch(h, v)
+		}
+		if v.OverflowUint(u) {
+			return fmt.Errorf("msgpack: %d overflows %s", u, v.Type())
+		}
+		v.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		switch h.format {
+		case fFloat:
+			v.SetFloat(h.f)
+			return nil
+		case fInt:
+			v.SetFloat(float64(h.i))
+			return nil
+		case fUint:
+			v.SetFloat(float64(h.u))
+			return nil
+		}
+	case reflect.String:
+		if h.format == fString || h.format == fBinary {
+			b, err := d.bytes(h.n)
+			v.SetString(string(b))
+			return err
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 && (h.format == fBinary || h.format == fString) {
+			b, err := d.bytes(h.n)
+			v.SetBytes(b)
+			return err
+		}
+		if h.format != fArray {
+			break
+		}
+		s := reflect.MakeSlice(v.Type(), 0, minLen(h.n))
+		for i := 0; i < h.n; i++ {
+			s = reflect.Append(s, reflect.Zero(v.Type().Elem()))
+			if err := d.decodeNext(s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Array:
+		if h.format != fArray {
+			break
+		}
+		for i := 0; i < h.n; i++ {
+			if i >= v.Len() {
+				if _, err := d.next(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decodeNext(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if h.format != fMap || v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for i := 0; i < h.n; i++ {
+			k, err := d.key()
+			if err != nil {
+				return err
+			}
+			el := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decodeNext(el); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), el)
+		}
+		return nil
+	case reflect.Struct:
+		if h.format != fMap {
+			break
+		}
+		fields := cachedFields(v.Type())
+		for i := 0; i < h.n; i++ {
+			k, err := d.key()
+			if err != nil {
+				return err
+			}
+			f := field(fields, k)
+			if f == nil {
+				// Skip unknown fields
+				if _, err := d.next(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.decodeNext(allocField(v, f.index)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return d.mismatch(h, v)
+}
+
+// decodeNext decodes the next value within an array or map into v.
+func (d *decoder) decodeNext(v reflect.Value) error {
+	h, err := d.header()
+	if err != nil {
+		return unexpected(err)
+	}
+	return d.decodeHeader(h, v)
+}
+
+// allocField returns the field at index, allocating nil embedded pointers.
+func allocField(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// event decodes a map encoded by appendEvent. String offsets are opaque.
+func (d *decoder) event(h header, ev *events.Event) error {
+	if h.format != fMap {
+		return fmt.Errorf("msgpack: cannot decode %s into events.Event", formatNames[h.format])
+	}
+	*ev = events.Event{}
+	v := reflect.ValueOf(ev).Elem()
+	fields := cachedFields(eventType)
+	for i := 0; i < h.n; i++ {
+		k, err := d.key()
+		if err != nil {
+			return err
+		}
+		if k == "offset" {
+			val, err := d.next()
+			if err != nil {
+				return err
+			}
+			switch o := val.(type) {
+			case int64:
+				ev.Offset = uint64(o)
+			case uint64:
+				ev.Offset = o
+			case string:
+				ev.OpaqueOffset = o
+			case nil:
+			default:
+				return fmt.Errorf("msgpack: invalid offset %v", o)
+			}
+			continue
+		}
+		f := field(fields, k)
+		if f == nil {
+			if _, err := d.next(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.decodeNext(allocField(v, f.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package encodes events and their typed bodies with MessagePack, a
+// compact binary encoding of JSON's data model, for passing events between
+// processes such as relay subscribers without re-encoding JSON.
+//
+// Events are maps with the same keys as their JSON encoding. Their bodies are
+// MessagePack maps rather than JSON strings, and their timestamps use the
+// timestamp extension type:
+//
+//	b, err := msgpack.Marshal(ev)
+//	if err != nil {
+//		return err
+//	}
+//	...
+//	ev := &events.Event{}
+//	if err := msgpack.Unmarshal(b, ev); err != nil {
+//		return err
+//	}
+//
+// Decoded bodies are re-encoded as JSON with their keys sorted. Typed bodies
+// such as *events.Send, and other structs, are encoded as maps keyed by their
+// json tags, so they decode with or without the body's original type:
+//
+//	b, err := msgpack.Marshal(send)
+//	...
+//	err = msgpack.Unmarshal(b, &events.Send{})
+//
+// Unlike encoding/json, []byte fields such as PushBody's Payload are binary
+// values rather than base64 strings and JSON numbers are integers or floats.
+// Encoder and Decoder stream values, which are self delimiting.
+//
+// This package only depends on the standard library.
+package msgpack
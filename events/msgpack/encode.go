@@ -0,0 +1,375 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+var (
+	eventType  = reflect.TypeOf(events.Event{})
+	timeType   = reflect.TypeOf(time.Time{})
+	rawType    = reflect.TypeOf(json.RawMessage{})
+	numberType = reflect.TypeOf(json.Number(""))
+	bytesType  = reflect.TypeOf([]byte(nil))
+)
+
+// Marshal returns the MessagePack encoding of v.
+func Marshal(v interface{}) ([]byte, error) {
+	return Append(nil, v)
+}
+
+// Append appends the MessagePack encoding of v to b. Events and structs are
+// encoded as maps keyed by their JSON names; see the package documentation
+// for how other values are encoded.
+func Append(b []byte, v interface{}) ([]byte, error) {
+	return appendValue(b, reflect.ValueOf(v))
+}
+
+func appendNil(b []byte) []byte { return append(b, 0xc0) }
+
+func appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+func appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v < 1<<7:
+		return append(b, byte(v))
+	case v < 1<<8:
+		return append(b, 0xcc, byte(v))
+	case v < 1<<16:
+		return appendBig(append(b, 0xcd), v, 2)
+	case v < 1<<32:
+		return appendBig(append(b, 0xce), v, 4)
+	}
+	return appendBig(append(b, 0xcf), v, 8)
+}
+
+func appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return appendBig(append(b, 0xd1), uint64(v), 2)
+	case v >= math.MinInt32:
+		return appendBig(append(b, 0xd2), uint64(v), 4)
+	}
+	return appendBig(append(b, 0xd3), uint64(v), 8)
+}
+
+// appendBig appends the low n bytes of v in big endian order.
+func appendBig(b []byte, v uint64, n int) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[8-n:]...)
+}
+
+func appendFloat(b []byte, v float64) []byte {
+	return appendBig(append(b, 0xcb), math.Float64bits(v), 8)
+}
+
+// appendLen appends a 16 bit or 32 bit length after the format byte f16 or
+// f16+1 respectively.
+func appendLen(b []byte, n int, f16 byte) []byte {
+	if n < 1<<16 {
+		return appendBig(append(b, f16), uint64(n), 2)
+	}
+	return appendBig(append(b, f16+1), uint64(n), 4)
+}
+
+func appendString(b []byte, s string) []byte {
+	switch {
+	case len(s) < 32:
+		b = append(b, 0xa0|byte(len(s)))
+	case len(s) < 1<<8:
+		b = append(b, 0xd9, byte(len(s)))
+	default:
+		b = appendLen(b, len(s), 0xda)
+	}
+	return append(b, s...)
+}
+
+func appendBinary(b []byte, v []byte) []byte {
+	if len(v) < 1<<8 {
+		b = append(b, 0xc4, byte(len(v)))
+	} else {
+		b = appendLen(b, len(v), 0xc5)
+	}
+	return append(b, v...)
+}
+
+func appendArrayHeader(b []byte, n int) []byte {
+	if n < 16 {
+		return append(b, 0x90|byte(n))
+	}
+	return appendLen(b, n, 0xdc)
+}
+
+func appendMapHeader(b []byte, n int) []byte {
+	if n < 16 {
+		return append(b, 0x80|byte(n))
+	}
+	return appendLen(b, n, 0xde)
+}
+
+// appendTime appends the timestamp extension type in its smallest format.
+func appendTime(b []byte, t time.Time) []byte {
+	sec, nsec := t.Unix(), uint64(t.Nanosecond())
+	if sec >= 0 && sec < 1<<34 {
+		if nsec == 0 && sec < 1<<32 {
+			return appendBig(append(b, 0xd6, 0xff), uint64(sec), 4)
+		}
+		return appendBig(append(b, 0xd7, 0xff), nsec<<34|uint64(sec), 8)
+	}
+	b = appendBig(append(b, 0xc7, 12, 0xff), nsec, 4)
+	return appendBig(b, uint64(sec), 8)
+}
+
+// appendNumber appends a JSON number as an integer if it is one, otherwise
+// as a float.
+func appendNumber(b []byte, n json.Number) ([]byte, error) {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		return appendInt(b, i), nil
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		return appendUint(b, u), nil
+	}
+	f, err := strconv.ParseFloat(string(n), 64)
+	if err != nil {
+		return nil, err
+	}
+	return appendFloat(b, f), nil
+}
+
+// appendJSON appends raw JSON as the equivalent MessagePack value. Empty raw
+// JSON is nil.
+func appendJSON(b []byte, raw json.RawMessage) ([]byte, error) {
+	if len(raw) == 0 {
+		return appendNil(b), nil
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return appendDecoded(b, v)
+}
+
+// appendDecoded appends a value decoded from JSON with UseNumber.
+func appendDecoded(b []byte, v interface{}) ([]byte, error) {
+	var err error
+	switch v := v.(type) {
+	case nil:
+		return appendNil(b), nil
+	case bool:
+		return appendBool(b, v), nil
+	case string:
+		return appendString(b, v), nil
+	case json.Number:
+		return appendNumber(b, v)
+	case []interface{}:
+		b = appendArrayHeader(b, len(v))
+		for _, el := range v {
+			if b, err = appendDecoded(b, el); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b = appendMapHeader(b, len(v))
+		for _, k := range keys {
+			if b, err = appendDecoded(appendString(b, k), v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("msgpack: unexpected JSON value %T", v)
+}
+
+// appendEvent appends an event as a map. Like its JSON encoding the offset is
+// the OpaqueOffset string if it has one. The body is encoded as MessagePack
+// rather than JSON.
+func appendEvent(b []byte, ev *events.Event) ([]byte, error) {
+	n := 6
+	if ev.Device != nil {
+		n++
+	}
+	b = appendMapHeader(b, n)
+	b = appendString(appendString(b, "id"), ev.ID)
+	b = appendString(appendString(b, "type"), string(ev.Type))
+	b = appendString(b, "offset")
+	if ev.OpaqueOffset != "" {
+		b = appendString(b, ev.OpaqueOffset)
+	} else {
+		b = appendUint(b, ev.Offset)
+	}
+	b = appendTime(appendString(b, "occurred"), ev.Occurred)
+	b = appendTime(appendString(b, "processed"), ev.Processed)
+	var err error
+	if b, err = appendJSON(appendString(b, "body"), ev.Body); err != nil {
+		return nil, fmt.Errorf("msgpack: error encoding body of event %s: %v", ev.ID, err)
+	}
+	if ev.Device != nil {
+		if b, err = appendValue(appendString(b, "device"), reflect.ValueOf(ev.Device).Elem()); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func appendValue(b []byte, v reflect.Value) ([]byte, error) {
+	if !v.IsValid() {
+		return appendNil(b), nil
+	}
+	switch v.Type() {
+	case eventType:
+		if v.CanAddr() {
+			return appendEvent(b, v.Addr().Interface().(*events.Event))
+		}
+		ev := v.Interface().(events.Event)
+		return appendEvent(b, &ev)
+	case timeType:
+		return appendTime(b, v.Interface().(time.Time)), nil
+	case rawType:
+		return appendJSON(b, v.Interface().(json.RawMessage))
+	case numberType:
+		if v.String() == "" {
+			return appendNil(b), nil
+		}
+		return appendNumber(b, json.Number(v.String()))
+	case bytesType:
+		if v.IsNil() {
+			return appendNil(b), nil
+		}
+		return appendBinary(b, v.Bytes()), nil
+	}
+
+	var err error
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return appendNil(b), nil
+		}
+		return appendValue(b, v.Elem())
+	case reflect.Bool:
+		return appendBool(b, v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendInt(b, v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return appendUint(b, v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return appendFloat(b, v.Float()), nil
+	case reflect.String:
+		return appendString(b, v.String()), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return appendNil(b), nil
+		}
+		b = appendArrayHeader(b, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if b, err = appendValue(b, v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("msgpack: unsupported map key type %s", v.Type().Key())
+		}
+		if v.IsNil() {
+			return appendNil(b), nil
+		}
+		keys := v.MapKeys()
+		sort.Sort(byString(keys))
+		b = appendMapHeader(b, len(keys))
+		for _, k := range keys {
+			if b, err = appendValue(appendString(b, k.String()), v.MapIndex(k)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Struct:
+		fields := cachedFields(v.Type())
+		var present []*structField
+		for i := range fields {
+			f := &fields[i]
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok || (f.omitEmpty && isEmpty(fv)) {
+				continue
+			}
+			present = append(present, f)
+		}
+		b = appendMapHeader(b, len(present))
+		for _, f := range present {
+			fv, _ := fieldByIndex(v, f.index)
+			if b, err = appendValue(appendString(b, f.name), fv); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type %s", v.Type())
+}
+
+type byString []reflect.Value
+
+func (s byString) Len() int           { return len(s) }
+func (s byString) Less(i, j int) bool { return s[i].String() < s[j].String() }
+func (s byString) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// fieldByIndex returns the field at index, or false if it's in a nil embedded
+// pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmpty reports whether v is empty as defined by encoding/json's omitempty.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package msgpack
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// structField is a field of a struct encoded as a map entry.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var fieldCache struct {
+	sync.RWMutex
+	m map[reflect.Type][]structField
+}
+
+// cachedFields returns the fields of struct type t as encoding/json would
+// encode them: named by their json tags, skipping "-", with the fields of
+// untagged embedded structs promoted unless a shallower field has the same
+// name.
+func cachedFields(t reflect.Type) []structField {
+	fieldCache.RLock()
+	fields, ok := fieldCache.m[t]
+	fieldCache.RUnlock()
+	if ok {
+		return fields
+	}
+
+	type candidate struct {
+		structField
+		depth int
+	}
+	var all []candidate
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts := tag, ""
+			if i := strings.Index(tag, ","); i >= 0 {
+				name, opts = tag[:i], tag[i:]
+			}
+			idx := append(index[:len(index):len(index)], i)
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				walk(ft, idx)
+				continue
+			}
+			if f.PkgPath != "" {
+				// Unexported
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			all = append(all, candidate{structField{name, idx, strings.Contains(opts, ",omitempty")}, len(idx)})
+		}
+	}
+	walk(t, nil)
+
+	depths := map[string]int{}
+	for _, c := range all {
+		if d, ok := depths[c.name]; !ok || c.depth < d {
+			depths[c.name] = c.depth
+		}
+	}
+	for _, c := range all {
+		if depths[c.name] == c.depth {
+			fields = append(fields, c.structField)
+			// Ties at the same depth keep the first field
+			depths[c.name] = -1
+		}
+	}
+
+	fieldCache.Lock()
+	if fieldCache.m == nil {
+		fieldCache.m = map[reflect.Type][]structField{}
+	}
+	fieldCache.m[t] = fields
+	fieldCache.Unlock()
+	return fields
+}
+
+// field returns the field named name, falling back to a case insensitive
+// match like encoding/json.
+func field(fields []structField, name string) *structField {
+	for i := range fields {
+		if fields[i].name == name {
+			return &fields[i]
+		}
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, name) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package msgpack_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/events/msgpack"
+)
+
+// fixture returns the events in a file in the events package's testdata.
+func fixture(t *testing.T, name string) []*events.Event {
+	f, err := os.Open(filepath.Join("..", "testdata", name))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer f.Close()
+	var evs []*events.Event
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		ev := &events.Event{}
+		if err := json.Unmarshal(s.Bytes(), ev); err != nil {
+			t.Fatalf("Unexpected error decoding %s: %v", name, err)
+		}
+		evs = append(evs, ev)
+	}
+	return evs
+}
+
+func TestEncoding(t *testing.T) {
+	tests := []struct {
+		v   interface{}
+		hex string
+	}{
+		{nil, "c0"},
+		{true, "c3"},
+		{127, "7f"},
+		{-32, "e0"},
+		{-33, "d0df"},
+		{256, "cd0100"},
+		{uint64(math.MaxUint64), "cfffffffffffffffff"},
+		{int64(math.MinInt64), "d38000000000000000"},
+		{1.5, "cb3ff8000000000000"},
+		{"abc", "a3616263"},
+		{[]byte{1, 2}, "c4020102"},
+		{[]int{1, 2}, "920102"},
+		{map[string]int{"b": 2, "a": 1}, "82a16101a16202"},
+		{json.RawMessage(`{"a":[1,-1.5,"x",null,true]}`), "81a16195" + "01cbbff8000000000000a178c0c3"},
+		{json.Number("12"), "0c"},
+		{time.Unix(1, 0), "d6ff00000001"},
+		{time.Unix(1, 1), "d7ff0000000400000001"},
+		{time.Unix(-1, 0), "c70cff00000000ffffffffffffffff"},
+	}
+	for _, test := range tests {
+		b, err := msgpack.Marshal(test.v)
+		if err != nil {
+			t.Fatalf("Unexpected error encoding %v: %v", test.v, err)
+		}
+		if h := hex.EncodeToString(b); h != test.hex {
+			t.Errorf("Expected %v to encode as %s but found %s", test.v, test.hex, h)
+		}
+	}
+
+	// Long strings, arrays, and maps use larger headers
+	for _, test := range []struct {
+		v      interface{}
+		prefix string
+	}{
+		{string(make([]byte, 32)), "d920"},
+		{string(make([]byte, 256)), "da0100"},
+		{make([]bool, 16), "dc0010"},
+		{make([]byte, 1<<16), "c600010000"},
+	} {
+		b, err := msgpack.Marshal(test.v)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if h := hex.EncodeToString(b[:len(test.prefix)/2]); h != test.prefix {
+			t.Errorf("Expected prefix %s but found %s", test.prefix, h)
+		}
+		decoded := reflect.New(reflect.TypeOf(test.v))
+		if err := msgpack.Unmarshal(b, decoded.Interface()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(decoded.Elem().Interface(), test.v) {
+			t.Errorf("Expected %T to round trip", test.v)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	for _, name := range []string{"all.json", "custom.json", "attribute_operation.json", "in_app_message_resolution.json"} {
+		for _, ev := range fixture(t, name) {
+			b, err := msgpack.Marshal(ev)
+			if err != nil {
+				t.Fatalf("Unexpected error encoding %s: %v", ev.ID, err)
+			}
+			decoded := &events.Event{}
+			if err := msgpack.Unmarshal(b, decoded); err != nil {
+				t.Fatalf("Unexpected error decoding %s: %v", ev.ID, err)
+			}
+			if decoded.ID != ev.ID || decoded.Type != ev.Type || decoded.Offset != ev.Offset {
+				t.Errorf("Expected %s %s at %d but decoded %+v", ev.Type, ev.ID, ev.Offset, decoded)
+			}
+			if !decoded.Occurred.Equal(ev.Occurred) || !decoded.Processed.Equal(ev.Processed) {
+				t.Errorf("Expected %s to occur at %s but decoded %s", ev.ID, ev.Occurred, decoded.Occurred)
+			}
+			if !reflect.DeepEqual(decoded.Device, ev.Device) {
+				t.Errorf("Expected device %+v but decoded %+v", ev.Device, decoded.Device)
+			}
+			var expected, found interface{}
+			json.Unmarshal(ev.Body, &expected)
+			json.Unmarshal(decoded.Body, &found)
+			if !reflect.DeepEqual(expected, found) {
+				t.Errorf("Expected body %s but decoded %s", ev.Body, decoded.Body)
+			}
+			if len(b) >= len(mustJSON(t, ev)) {
+				t.Errorf("Expected %s to be smaller than its JSON", ev.ID)
+			}
+		}
+	}
+
+	ev := &events.Event{ID: "opaque", Type: events.TypeSend, OpaqueOffset: "abc"}
+	b, err := msgpack.Marshal(ev)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	decoded := &events.Event{}
+	if err := msgpack.Unmarshal(b, decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decoded.OpaqueOffset != "abc" || decoded.Offset != 0 || decoded.Body != nil {
+		t.Errorf("Expected opaque offset without a body but decoded %+v", decoded)
+	}
+	if err := msgpack.Unmarshal(b[:len(b)-1], decoded); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected unexpected EOF decoding a truncated event but found %v", err)
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return b
+}
+
+func TestBodies(t *testing.T) {
+	variant := 3
+	bodies := []interface{}{
+		&events.Send{Push: events.Push{PushID: "p", GroupID: "g"}, VariantID: &variant},
+		&events.PushBody{Push: events.Push{PushID: "p"}, Payload: []byte(`{"audience":"all"}`)},
+		&events.InAppMessageResolution{
+			InAppMessageDisplay: events.InAppMessageDisplay{Push: events.Push{PushID: "p"}, TriggeringPush: events.Push{PushID: "t"}},
+			TimeSent:            time.Date(2017, 4, 25, 9, 0, 0, 5, time.UTC),
+			Type:                "BUTTON_CLICK",
+			Duration:            1500,
+		},
+		&events.Custom{Name: "purchase", Value: json.Number("49.99"), Properties: map[string]json.RawMessage{"sku": json.RawMessage(`"SHOE"`)}},
+		&events.TagChange{Add: map[string][]string{"device": {"a", "b"}}},
+	}
+	for _, body := range bodies {
+		b, err := msgpack.Marshal(body)
+		if err != nil {
+			t.Fatalf("Unexpected error encoding %T: %v", body, err)
+		}
+		decoded := reflect.New(reflect.TypeOf(body).Elem()).Interface()
+		if err := msgpack.Unmarshal(b, decoded); err != nil {
+			t.Fatalf("Unexpected error decoding %T: %v", body, err)
+		}
+		if !reflect.DeepEqual(body, decoded) {
+			t.Errorf("Expected %+v but decoded %+v", body, decoded)
+		}
+	}
+
+	// Bodies of encoded events decode into typed bodies
+	ev := &events.Event{ID: "send", Type: events.TypeSend, Body: json.RawMessage(`{"push_id":"p","variant_id":3,"new":1}`)}
+	b, err := msgpack.Marshal(ev)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var raw struct {
+		Body events.Send `json:"body"`
+	}
+	if err := msgpack.Unmarshal(b, &raw); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if raw.Body.PushID != "p" || raw.Body.VariantID == nil || *raw.Body.VariantID != 3 {
+		t.Errorf("Expected send body but found %+v", raw.Body)
+	}
+
+	var s events.Send
+	if err := msgpack.Unmarshal([]byte{0x81, 0xa7, 'p', 'u', 's', 'h', '_', 'i', 'd', 0x01}, &s); err == nil {
+		t.Errorf("Expected error decoding a numeric push_id")
+	}
+	var small struct{ N int8 }
+	if err := msgpack.Unmarshal([]byte{0x81, 0xa1, 'N', 0xcd, 0x01, 0x00}, &small); err == nil {
+		t.Errorf("Expected error decoding 256 into an int8")
+	}
+}
+
+func TestStream(t *testing.T) {
+	evs := fixture(t, "send.json")
+	buf := &bytes.Buffer{}
+	enc := msgpack.NewEncoder(buf)
+	for _, ev := range evs {
+		if err := enc.Encode(ev); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	dec := msgpack.NewDecoder(bytes.NewReader(buf.Bytes()))
+	n := 0
+	for {
+		ev := &events.Event{}
+		err := dec.Decode(ev)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ev.ID != evs[n].ID {
+			t.Errorf("Expected event %s but read %s", evs[n].ID, ev.ID)
+		}
+		n++
+	}
+	if n != len(evs) {
+		t.Errorf("Expected %d events but read %d", len(evs), n)
+	}
+
+	dec = msgpack.NewDecoder(io.LimitReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()-1)))
+	var err error
+	for err == nil {
+		err = dec.Decode(&events.Event{})
+	}
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected unexpected EOF reading a truncated stream but found %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package msgpack
+
+import (
+	"bufio"
+	"io"
+)
+
+// Encoder writes MessagePack values to a stream. MessagePack values are self
+// delimiting so they're simply concatenated.
+type Encoder struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewEncoder creates an Encoder which writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the MessagePack encoding of v.
+func (e *Encoder) Encode(v interface{}) error {
+	b, err := Append(e.buf[:0], v)
+	if err != nil {
+		return err
+	}
+	e.buf = b
+	_, err = e.w.Write(b)
+	return err
+}
+
+// Decoder reads MessagePack values from a stream.
+type Decoder struct {
+	d decoder
+}
+
+// NewDecoder creates a Decoder which reads from r. The Decoder may read past
+// the values decoded unless r is an io.ByteReader.
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(byteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{d: decoder{r: br}}
+}
+
+// Decode the next value into the value pointed to by v. Returns io.EOF once
+// every value has been read and io.ErrUnexpectedEOF if the last value is
+// truncated.
+func (d *Decoder) Decode(v interface{}) error {
+	return d.d.decode(v)
+}
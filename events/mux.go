@@ -0,0 +1,341 @@
+package events
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Source of events such as a *Response or *Stream.
+type Source interface {
+	Events() <-chan *Event
+	Close()
+	Err() error
+}
+
+// Mux dispatches events to handlers registered for their Type, decoding event
+// bodies for typed handlers. Events without a handler are passed to Default if
+// it's non-nil, otherwise they are ignored.
+//
+// Handlers should be registered before calling Serve.
+type Mux struct {
+	// Default, if non-nil, handles events without a registered handler.
+	Default func(*Event)
+
+	mu       sync.RWMutex
+	handlers map[Type]func(*Event) error
+}
+
+// NewMux creates a Mux with no handlers.
+func NewMux() *Mux {
+	return &Mux{handlers: map[Type]func(*Event) error{}}
+}
+
+// handle registers a handler for types, replacing any existing handlers.
+func (m *Mux) handle(h func(*Event) error, types ...Type) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range types {
+		m.handlers[t] = h
+	}
+}
+
+// Handle registers a handler for raw events of type t. Use it for types
+// without a body method such as TypeUninstall.
+func (m *Mux) Handle(t Type, h func(*Event)) {
+	m.handle(func(ev *Event) error { h(ev); return nil }, t)
+}
+
+// HandlePush registers a handler for PUSH_BODY events.
+func (m *Mux) HandlePush(h func(*PushBody, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.PushBody()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypePush)
+}
+
+// HandleOpen registers a handler for OPEN events.
+func (m *Mux) HandleOpen(h func(*Open, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.Open()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeOpen)
+}
+
+// HandleSend registers a handler for SEND events.
+func (m *Mux) HandleSend(h func(*Send, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.Send()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeSend)
+}
+
+// HandleClose registers a handler for CLOSE events.
+func (m *Mux) HandleClose(h func(*Close, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.Close()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeClose)
+}
+
+// HandleTagChange registers a handler for TAG_CHANGE events.
+func (m *Mux) HandleTagChange(h func(*TagChange, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.TagChange()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeTagChange)
+}
+
+// HandleLocation registers a handler for LOCATION events.
+func (m *Mux) HandleLocation(h func(*Location, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.Location()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeLocation)
+}
+
+// HandleRich registers a handler for RICH_DELIVERY, RICH_READ, and
+// RICH_DELETE events.
+func (m *Mux) HandleRich(h func(*RichEvent, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.RichEvent()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeRichDelivery, TypeRichRead, TypeRichDelete)
+}
+
+// HandleInAppMessageDisplay registers a handler for IN_APP_MESSAGE_DISPLAY
+// events.
+func (m *Mux) HandleInAppMessageDisplay(h func(*InAppMessageDisplay, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.InAppMessageDisplay()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeInAppMessageDisplay)
+}
+
+// HandleInAppMessageResolution registers a handler for
+// IN_APP_MESSAGE_RESOLUTION events.
+func (m *Mux) HandleInAppMessageResolution(h func(*InAppMessageResolution, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.InAppMessageResolution()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeInAppMessageResolution)
+}
+
+// HandleInAppMessageExpiration registers a handler for
+// IN_APP_MESSAGE_EXPIRATION events.
+func (m *Mux) HandleInAppMessageExpiration(h func(*InAppMessageExpiration, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.InAppMessageExpiration()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeInAppMessageExpiration)
+}
+
+// HandleScreenViewed registers a handler for SCREEN_VIEWED events.
+func (m *Mux) HandleScreenViewed(h func(*ScreenViewed, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.ScreenViewed()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeScreenViewed)
+}
+
+// HandleRegion registers a handler for REGION events.
+func (m *Mux) HandleRegion(h func(*Region, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.Region()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeRegion)
+}
+
+// HandleControl registers a handler for CONTROL events.
+func (m *Mux) HandleControl(h func(*Control, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.Control()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeControl)
+}
+
+// HandleSendAborted registers a handler for SEND_ABORTED events.
+func (m *Mux) HandleSendAborted(h func(*SendAborted, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.SendAborted()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeSendAborted)
+}
+
+// HandleEmail registers a handler for EMAIL_DELIVERY, EMAIL_OPEN, EMAIL_CLICK,
+// EMAIL_BOUNCE, and EMAIL_UNSUBSCRIBE events.
+func (m *Mux) HandleEmail(h func(*Email, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.Email()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeEmailDelivery, TypeEmailOpen, TypeEmailClick, TypeEmailBounce, TypeEmailUnsubscribe)
+}
+
+// HandleSMSDelivery registers a handler for SMS_DELIVERY_REPORT events.
+func (m *Mux) HandleSMSDelivery(h func(*SMSDelivery, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.SMSDelivery()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeSMSDelivery)
+}
+
+// HandleMobileOriginated registers a handler for MOBILE_ORIGINATED events.
+func (m *Mux) HandleMobileOriginated(h func(*MobileOriginated, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.MobileOriginated()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeMobileOriginated)
+}
+
+// HandleSMSOpt registers a handler for SMS_OPT_IN and SMS_OPT_OUT events.
+func (m *Mux) HandleSMSOpt(h func(*SMSOpt, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.SMSOpt()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeSMSOptIn, TypeSMSOptOut)
+}
+
+// HandleWebClick registers a handler for WEB_CLICK events.
+func (m *Mux) HandleWebClick(h func(*WebClick, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.WebClick()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeWebClick)
+}
+
+// HandleCustom registers a handler for CUSTOM events.
+func (m *Mux) HandleCustom(h func(*Custom, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.Custom()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeCustom)
+}
+
+// HandleAttributeOperations registers a handler for ATTRIBUTE_OPERATION
+// events.
+func (m *Mux) HandleAttributeOperations(h func([]AttributeOperation, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.AttributeOperations()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeAttributeOperation)
+}
+
+// HandleSubscriptionListChange registers a handler for
+// SUBSCRIPTION_LIST_CHANGE events.
+func (m *Mux) HandleSubscriptionListChange(h func(*SubscriptionListChange, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.SubscriptionListChange()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeSubscriptionListChange)
+}
+
+// HandleCompliance registers a handler for COMPLIANCE events.
+func (m *Mux) HandleCompliance(h func(*Compliance, *Event)) {
+	m.handle(func(ev *Event) error {
+		b, err := ev.Compliance()
+		if err == nil {
+			h(b, ev)
+		}
+		return err
+	}, TypeCompliance)
+}
+
+// Dispatch an event to its handler. An error is returned if the event's body
+// fails to decode.
+func (m *Mux) Dispatch(ev *Event) error {
+	m.mu.RLock()
+	h := m.handlers[ev.Type]
+	m.mu.RUnlock()
+	if h == nil {
+		if m.Default != nil {
+			m.Default(ev)
+		}
+		return nil
+	}
+	if err := h(ev); err != nil {
+		return fmt.Errorf("error decoding %s event %s: %v", ev.Type, ev.ID, err)
+	}
+	return nil
+}
+
+// Serve dispatches every event from src until it ends, returning its error.
+// If an event's body fails to decode src is closed and the error returned.
+// Streams ending with io.EOF return nil.
+func (m *Mux) Serve(src Source) error {
+	for ev := range src.Events() {
+		if err := m.Dispatch(ev); err != nil {
+			src.Close()
+			return err
+		}
+	}
+	if err := src.Err(); err != io.EOF {
+		return err
+	}
+	return nil
+}
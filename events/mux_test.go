@@ -0,0 +1,103 @@
+package events_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+func TestMux(t *testing.T) {
+	t.Parallel()
+	const body = `{"id":"a","type":"OPEN","offset":"1","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{"session_id":"s1"}}
+{"id":"b","type":"RICH_READ","offset":"2","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{"push_id":"p1"}}
+{"id":"c","type":"UNINSTALL","offset":"3","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}
+{"id":"d","type":"FIRST_OPEN","offset":"4","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}
+{"id":"e","type":"SEND","offset":"5","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{"push_id":5}}
+{"id":"f","type":"OPEN","offset":"6","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}
+`
+	resp, err := events.NewResponse(&http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(body))})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	seen := []string{}
+	m := events.NewMux()
+	m.HandleOpen(func(o *events.Open, ev *events.Event) {
+		if o.SessionID != "s1" {
+			t.Errorf("Unexpected open body: %#v", o)
+		}
+		seen = append(seen, ev.ID)
+	})
+	m.HandleRich(func(p *events.RichEvent, ev *events.Event) {
+		if p.PushID != "p1" {
+			t.Errorf("Unexpected rich body: %#v", p)
+		}
+		seen = append(seen, ev.ID)
+	})
+	m.Handle(events.TypeUninstall, func(ev *events.Event) { seen = append(seen, ev.ID) })
+	m.HandleSend(func(*events.Send, *events.Event) { t.Errorf("Invalid send body should not be handled") })
+	m.Default = func(ev *events.Event) { seen = append(seen, "default:"+ev.ID) }
+
+	// Serve stops at the undecodable SEND body
+	if err := m.Serve(resp); err == nil {
+		t.Errorf("Expected decoding error")
+	}
+	if exp := "a b c default:d"; strings.Join(seen, " ") != exp {
+		t.Errorf("Expected %q but handled %q", exp, strings.Join(seen, " "))
+	}
+
+	// Streams ending normally return nil
+	resp, err = events.NewResponse(&http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(body[:strings.Index(body, `{"id":"e"`)]))})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := m.Serve(resp); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestMuxTypedHandlers(t *testing.T) {
+	t.Parallel()
+	const body = `{"id":"a","type":"EMAIL_CLICK","offset":"1","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{"link_url":"https://example.com"}}
+{"id":"b","type":"CUSTOM","offset":"2","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{"name":"purchase"}}
+{"id":"c","type":"ATTRIBUTE_OPERATION","offset":"3","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{"mutations":[{"action":"SET","key":"k","value":1}]}}
+{"id":"d","type":"EMAIL_BOUNCE","offset":"4","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{"bounce_reason":"full"}}
+`
+	resp, err := events.NewResponse(&http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(body))})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	seen := []string{}
+	m := events.NewMux()
+	m.HandleEmail(func(e *events.Email, ev *events.Event) {
+		if e.URL == "" && e.BounceReason == "" {
+			t.Errorf("Unexpected email body: %#v", e)
+		}
+		seen = append(seen, ev.ID)
+	})
+	m.HandleCustom(func(c *events.Custom, ev *events.Event) {
+		if c.Name != "purchase" {
+			t.Errorf("Unexpected custom body: %#v", c)
+		}
+		seen = append(seen, ev.ID)
+	})
+	m.HandleAttributeOperations(func(ops []events.AttributeOperation, ev *events.Event) {
+		if len(ops) != 1 || ops[0].Key != "k" {
+			t.Errorf("Unexpected attribute operations: %#v", ops)
+		}
+		seen = append(seen, ev.ID)
+	})
+	m.Default = func(ev *events.Event) { seen = append(seen, "default:"+ev.ID) }
+
+	if err := m.Serve(resp); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if exp := "a b c d"; strings.Join(seen, " ") != exp {
+		t.Errorf("Expected %q but handled %q", exp, strings.Join(seen, " "))
+	}
+}
@@ -0,0 +1,152 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Offset is a position in the event stream. Offsets may exceed 2^53 so they
+// are encoded as JSON strings to survive tools which decode JSON numbers as
+// float64. Use it when persisting or reporting offsets, such as in
+// checkpoints, manifests, and stats.
+type Offset uint64
+
+// ParseOffset parses a base 10 offset.
+func ParseOffset(s string) (Offset, error) {
+	o, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid offset %q", s)
+	}
+	return Offset(o), nil
+}
+
+// String returns the base 10 offset.
+func (o Offset) String() string { return strconv.FormatUint(uint64(o), 10) }
+
+// MarshalText implements encoding.TextMarshaler.
+func (o Offset) MarshalText() ([]byte, error) { return []byte(o.String()), nil }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (o *Offset) UnmarshalText(b []byte) error {
+	v, err := ParseOffset(string(b))
+	if err != nil {
+		return err
+	}
+	*o = v
+	return nil
+}
+
+// MarshalJSON encodes the offset as a JSON string.
+func (o Offset) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(o.String())), nil
+}
+
+// UnmarshalJSON decodes a JSON string or, for compatibility, a JSON number
+// without converting it to a float64.
+func (o *Offset) UnmarshalJSON(b []byte) error {
+	b = bytes.Trim(b, `"`)
+	return o.UnmarshalText(b)
+}
+
+// decodeOffset decodes a JSON offset which is either numeric, as a string or
+// number, or an opaque string such as those used by the Real-Time Data
+// Streaming API. Exactly one of the numeric and opaque offsets is returned
+// unless raw is empty or null.
+func decodeOffset(raw json.RawMessage) (*uint64, string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, "", nil
+	}
+	var o Offset
+	if err := o.UnmarshalJSON(raw); err == nil {
+		n := uint64(o)
+		return &n, "", nil
+	}
+	if raw[0] != '"' {
+		return nil, "", fmt.Errorf("invalid offset %s", raw)
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, "", err
+	}
+	return nil, s, nil
+}
+
+// eventFields has Event's fields without its methods.
+type eventFields Event
+
+// wireEvent decodes an Event, handing its offset to eventOffset. Decoding into
+// a wireEvent rather than calling Event.UnmarshalJSON avoids scanning each
+// event twice.
+type wireEvent struct {
+	*eventFields
+	Offset eventOffset `json:"offset"`
+}
+
+// wire returns a wireEvent which decodes into e.
+func (e *Event) wire() *wireEvent {
+	e.Offset, e.OpaqueOffset = 0, ""
+	return &wireEvent{eventFields: (*eventFields)(e), Offset: eventOffset{e}}
+}
+
+// eventOffset decodes a numeric or opaque offset into its Event.
+type eventOffset struct{ e *Event }
+
+func (o eventOffset) UnmarshalJSON(b []byte) error {
+	offset, opaque, err := decodeOffset(b)
+	if err != nil {
+		return err
+	}
+	o.e.OpaqueOffset = opaque
+	if offset != nil {
+		o.e.Offset = *offset
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes an event whose offset may be opaque.
+func (e *Event) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, e.wire())
+}
+
+// MarshalJSON encodes the event with its OpaqueOffset if it has one.
+func (e Event) MarshalJSON() ([]byte, error) {
+	if e.OpaqueOffset == "" {
+		return json.Marshal(eventFields(e))
+	}
+	return json.Marshal(struct {
+		eventFields
+		Offset string `json:"offset"`
+	}{eventFields(e), e.OpaqueOffset})
+}
+
+// UnmarshalJSON decodes a request whose resume offset may be opaque.
+func (r *Request) UnmarshalJSON(b []byte) error {
+	type request Request
+	aux := struct {
+		*request
+		Offset json.RawMessage `json:"resume_offset"`
+	}{request: (*request)(r)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	offset, opaque, err := decodeOffset(aux.Offset)
+	if err != nil {
+		return err
+	}
+	r.Offset, r.OpaqueOffset = offset, opaque
+	return nil
+}
+
+// MarshalJSON encodes the request with its OpaqueOffset if it has one.
+func (r Request) MarshalJSON() ([]byte, error) {
+	type request Request
+	if r.OpaqueOffset == "" {
+		return json.Marshal(request(r))
+	}
+	return json.Marshal(struct {
+		request
+		Offset string `json:"resume_offset"`
+	}{request(r), r.OpaqueOffset})
+}
@@ -0,0 +1,90 @@
+package events_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+func TestOffset(t *testing.T) {
+	t.Parallel()
+	const big = events.Offset(1<<64 - 1)
+	buf, err := json.Marshal(map[string]events.Offset{"offset": big})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(buf) != `{"offset":"18446744073709551615"}` {
+		t.Errorf("Unexpected JSON: %s", buf)
+	}
+
+	for _, in := range []string{`"18446744073709551615"`, `18446744073709551615`} {
+		var o events.Offset
+		if err := json.Unmarshal([]byte(in), &o); err != nil || o != big {
+			t.Errorf("Expected %s to decode to %d but found %d (%v)", in, big, o, err)
+		}
+	}
+	for _, in := range []string{`"-1"`, `1.5`, `"abc"`, `"18446744073709551616"`} {
+		var o events.Offset
+		if err := json.Unmarshal([]byte(in), &o); err == nil {
+			t.Errorf("Expected error decoding %s", in)
+		}
+	}
+	if o, err := events.ParseOffset("42"); err != nil || o.String() != "42" {
+		t.Errorf("Unexpected offset %s (%v)", o, err)
+	}
+}
+
+func TestOpaqueOffset(t *testing.T) {
+	t.Parallel()
+	for in, expected := range map[string]uint64{`"12"`: 12, `12`: 12} {
+		ev := &events.Event{}
+		if err := json.Unmarshal([]byte(`{"id":"a","offset":`+in+`}`), ev); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ev.Offset != expected || ev.OpaqueOffset != "" {
+			t.Errorf("Expected offset %d but found %d %q", expected, ev.Offset, ev.OpaqueOffset)
+		}
+	}
+
+	ev := &events.Event{}
+	if err := json.Unmarshal([]byte(`{"id":"a","offset":"AAAB-xyz"}`), ev); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ev.Offset != 0 || ev.OpaqueOffset != "AAAB-xyz" || ev.ID != "a" {
+		t.Errorf("Unexpected event: %+v", ev)
+	}
+	buf, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ev2 := &events.Event{}
+	if err := json.Unmarshal(buf, ev2); err != nil || ev2.OpaqueOffset != ev.OpaqueOffset {
+		t.Errorf("Expected opaque offset to round trip: %s (%v)", buf, err)
+	}
+	if err := json.Unmarshal([]byte(`{"offset":true}`), &events.Event{}); err == nil {
+		t.Errorf("Expected error decoding a boolean offset")
+	}
+
+	req := &events.Request{Start: events.StartOffset, OpaqueOffset: ev.OpaqueOffset}
+	if err := req.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if buf, err = json.Marshal(req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	req2 := &events.Request{}
+	if err := json.Unmarshal(buf, req2); err != nil || req2.OpaqueOffset != req.OpaqueOffset || req2.Offset != nil {
+		t.Errorf("Expected request to round trip: %s (%v)", buf, err)
+	}
+
+	offset := uint64(1)
+	for _, bad := range []*events.Request{
+		{Start: events.StartFirst, OpaqueOffset: "x"},
+		{Start: events.StartOffset, Offset: &offset, OpaqueOffset: "x"},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Expected error validating %+v", bad)
+		}
+	}
+}
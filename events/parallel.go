@@ -0,0 +1,118 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// DecodeWorkers splits the response body into lines and decodes them on n
+// goroutines. Events are still emitted in stream order. Useful when a single
+// decoding goroutine cannot keep up with a multi-MB/s stream on a multi-core
+// machine.
+//
+// The Quarantine passed to QuarantineEvents must be safe for concurrent use
+// when n > 1.
+func DecodeWorkers(n int) Option {
+	return func(r *Response) { r.workers = n }
+}
+
+// maxChunk is the most lines decoded as a single unit of work.
+const maxChunk = 64
+
+// chunk of lines decoded by a worker. The reader sends err, including io.EOF,
+// in a final chunk without lines.
+type chunk struct {
+	lines  [][]byte
+	events []*Event // nil where the event was quarantined
+	err    error
+	done   chan struct{}
+}
+
+// decodeParallel reads chunks of lines on one goroutine, decodes them on
+// r.workers goroutines, and emits them in order on the calling goroutine. Also
+// used with a single worker to decode line by line for DeadLetter.
+func (r *Response) decodeParallel(body io.Reader) {
+	quit := make(chan struct{})
+	defer close(quit)
+
+	// pending chunks in stream order; its capacity bounds the read-ahead
+	workers := r.workers
+	if workers < 1 {
+		workers = 1
+	}
+	pending := make(chan *chunk, workers*2)
+	jobs := make(chan *chunk, workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for c := range jobs {
+				c.events = make([]*Event, 0, len(c.lines))
+				for _, line := range c.lines {
+					ev, err := r.parse(line)
+					if err != nil {
+						c.err = err
+						break
+					}
+					c.events = append(c.events, ev)
+				}
+				close(c.done)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		br := bufio.NewReader(body)
+		c := &chunk{done: make(chan struct{})}
+		send := func() bool {
+			select {
+			case pending <- c:
+			case <-quit:
+				return false
+			}
+			if c.err == nil {
+				jobs <- c
+			} else {
+				close(c.done)
+			}
+			c = &chunk{done: make(chan struct{})}
+			return true
+		}
+		for {
+			line, err := br.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				c.lines = append(c.lines, line)
+			}
+			// Send the chunk when full or before blocking on the network so
+			// slow streams aren't delayed
+			if len(c.lines) > 0 && (len(c.lines) == maxChunk || br.Buffered() == 0 || err != nil) {
+				if !send() {
+					return
+				}
+			}
+			if err != nil {
+				c.err = err
+				send()
+				return
+			}
+		}
+	}()
+
+	for c := range pending {
+		<-c.done
+		for _, ev := range c.events {
+			if ev == nil {
+				// Event was quarantined
+				continue
+			}
+			if !r.emit(ev) {
+				return
+			}
+		}
+		if c.err != nil {
+			r.fail(c.err)
+			return
+		}
+	}
+}
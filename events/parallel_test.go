@@ -0,0 +1,57 @@
+package events_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+func TestDecodeWorkers(t *testing.T) {
+	t.Parallel()
+	const line = `{"id":"%d","type":"CLOSE","offset":"%d","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}` + "\n"
+	buf := &bytes.Buffer{}
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(buf, line, i, i)
+		if i == 500 {
+			buf.WriteString("{\"id\":\"bad\",\"offset\":true}\n\n")
+		}
+	}
+	qbuf := &bytes.Buffer{}
+	q := events.NewQuarantineWriter(qbuf)
+	hr := &http.Response{StatusCode: 200, Body: ioutil.NopCloser(buf)}
+	resp, err := events.NewResponse(hr, events.DecodeWorkers(4), events.QuarantineEvents(q, nil))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	next := uint64(0)
+	for ev := range resp.Events() {
+		if ev.Offset != next {
+			t.Fatalf("Expected offset %d but found %d", next, ev.Offset)
+		}
+		next++
+	}
+	if next != 1000 {
+		t.Errorf("Expected 1000 events but found %d", next)
+	}
+	if !bytes.Contains(qbuf.Bytes(), []byte(`bad`)) {
+		t.Errorf("Expected bad event to be quarantined: %s", qbuf.String())
+	}
+
+	// Without quarantine bad events end the stream
+	hr = &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(fmt.Sprintf(line, 1, 1) + "{nope}\n" + fmt.Sprintf(line, 2, 2)))}
+	resp, err = events.NewResponse(hr, events.DecodeWorkers(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	n := 0
+	for range resp.Events() {
+		n++
+	}
+	if n != 1 || resp.Err() == nil {
+		t.Errorf("Expected 1 event and an error but found %d and %v", n, resp.Err())
+	}
+}
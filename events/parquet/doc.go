@@ -0,0 +1,45 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package writes events to Apache Parquet files so archived streams can
+// be queried directly from Athena, Presto, Spark, and other engines which
+// read columnar data.
+//
+// Every file uses one envelope schema:
+//
+//	id         string
+//	type       string
+//	offset     int64
+//	occurred   timestamp (milliseconds)
+//	processed  timestamp (milliseconds)
+//	device     optional string of the device's JSON
+//	body       optional string of the body's JSON
+//
+// so files holding every type of event can share a table, and engines can
+// extract body fields with their JSON functions such as Presto's
+// json_extract_scalar. Write batches of events and Close the Writer to write
+// the file's footer:
+//
+//	w := parquet.NewWriter(f)
+//	for batch := range batches {
+//		if err := w.Write(batch); err != nil {
+//			return err
+//		}
+//	}
+//	if err := w.Close(); err != nil {
+//		return err
+//	}
+//
+// This package only depends on the standard library.
+package parquet
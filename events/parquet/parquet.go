@@ -0,0 +1,383 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+// Codec compresses the pages of a Parquet file.
+type Codec int32
+
+// Codecs supported by Writer.
+const (
+	Uncompressed Codec = 0
+	Gzip         Codec = 2
+)
+
+// DefaultRowGroupSize is the default number of events in each row group.
+const DefaultRowGroupSize = 100000
+
+// ErrClosed is returned when writing to a closed Writer.
+var ErrClosed = errors.New("writer closed")
+
+const magic = "PAR1"
+
+// Parquet physical types, converted types, and encodings.
+const (
+	typeInt64     = 2
+	typeByteArray = 6
+
+	convertedNone            = -1
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	encodingRLE   = 3
+)
+
+// column buffers the values of one column of the current row group.
+type column struct {
+	name      string
+	typ       int32
+	converted int32
+	optional  bool
+
+	values []byte // plain encoded non-null values
+	defs   []bool // whether each value is defined, for optional columns
+	n      int    // values including nulls
+}
+
+func newColumns() []*column {
+	return []*column{
+		{name: "id", typ: typeByteArray, converted: convertedUTF8},
+		{name: "type", typ: typeByteArray, converted: convertedUTF8},
+		{name: "offset", typ: typeInt64, converted: convertedNone},
+		{name: "occurred", typ: typeInt64, converted: convertedTimestampMillis},
+		{name: "processed", typ: typeInt64, converted: convertedTimestampMillis},
+		{name: "device", typ: typeByteArray, converted: convertedUTF8, optional: true},
+		{name: "body", typ: typeByteArray, converted: convertedUTF8, optional: true},
+	}
+}
+
+func (c *column) int64(v int64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(v))
+	c.values = append(c.values, buf[:]...)
+	c.n++
+}
+
+func (c *column) bytes(v []byte) {
+	if c.optional {
+		c.defs = append(c.defs, v != nil)
+	}
+	c.n++
+	if v == nil {
+		return
+	}
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(len(v)))
+	c.values = append(append(c.values, buf[:]...), v...)
+}
+
+func (c *column) reset() {
+	c.values = c.values[:0]
+	c.defs = c.defs[:0]
+	c.n = 0
+}
+
+// chunk is the metadata of a column chunk which has been written.
+type chunk struct {
+	offset       int64
+	uncompressed int64
+	compressed   int64
+	n            int
+}
+
+// rowGroup is the metadata of a row group which has been written.
+type rowGroup struct {
+	chunks []chunk
+	rows   int64
+	size   int64
+}
+
+// Writer writes events to a Parquet file. Events are buffered in memory until
+// RowGroupSize events have been written or Flush is called, then written as a
+// row group. Not safe for concurrent use.
+type Writer struct {
+	// Compression of pages. Defaults to Gzip; set before the first Write.
+	Compression Codec
+
+	// RowGroupSize is how many events are buffered before a row group is
+	// written. Defaults to DefaultRowGroupSize. Larger row groups compress
+	// better and are read more efficiently but use more memory.
+	RowGroupSize int
+
+	w       io.Writer
+	offset  int64
+	started bool
+	closed  bool
+	codec   Codec
+	cols    []*column
+	rows    int
+	groups  []rowGroup
+}
+
+// NewWriter creates a Writer which writes a Parquet file to w. Close must be
+// called to write the file's footer.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, Compression: Gzip, cols: newColumns()}
+}
+
+// Marshal returns a Parquet file of evs.
+func Marshal(evs []*events.Event) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+	if err := w.Write(evs); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (w *Writer) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	return err
+}
+
+// Write appends events to the file.
+func (w *Writer) Write(evs []*events.Event) error {
+	if w.closed {
+		return ErrClosed
+	}
+	size := w.RowGroupSize
+	if size <= 0 {
+		size = DefaultRowGroupSize
+	}
+	for _, ev := range evs {
+		var device []byte
+		if ev.Device != nil {
+			var err error
+			if device, err = json.Marshal(ev.Device); err != nil {
+				return err
+			}
+		}
+		var body []byte
+		if len(ev.Body) > 0 && string(ev.Body) != "null" {
+			body = ev.Body
+		}
+		w.cols[0].bytes([]byte(ev.ID))
+		w.cols[1].bytes([]byte(ev.Type))
+		w.cols[2].int64(int64(ev.Offset))
+		w.cols[3].int64(millis(ev.Occurred))
+		w.cols[4].int64(millis(ev.Processed))
+		w.cols[5].bytes(device)
+		w.cols[6].bytes(body)
+		w.rows++
+		if w.rows >= size {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func millis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// Flush writes buffered events as a row group. It's a no-op if no events are
+// buffered.
+func (w *Writer) Flush() error {
+	if w.closed {
+		return ErrClosed
+	}
+	if w.rows == 0 {
+		return nil
+	}
+	if !w.started {
+		w.codec = w.Compression
+		if err := w.write([]byte(magic)); err != nil {
+			return err
+		}
+		w.started = true
+	}
+	g := rowGroup{rows: int64(w.rows)}
+	for _, c := range w.cols {
+		ch, err := w.writeChunk(c)
+		if err != nil {
+			return err
+		}
+		g.chunks = append(g.chunks, ch)
+		g.size += ch.uncompressed
+		c.reset()
+	}
+	w.groups = append(w.groups, g)
+	w.rows = 0
+	return nil
+}
+
+// writeChunk writes a column chunk of a single data page.
+func (w *Writer) writeChunk(c *column) (chunk, error) {
+	var page []byte
+	if c.optional {
+		levels := definitionLevels(c.defs)
+		var buf [4]byte
+		binary.LittleEndian.PutUint32(buf[:], uint32(len(levels)))
+		page = append(append(page, buf[:]...), levels...)
+	}
+	page = append(page, c.values...)
+	data := page
+	if w.codec == Gzip {
+		buf := &bytes.Buffer{}
+		gz := gzip.NewWriter(buf)
+		gz.Write(page)
+		if err := gz.Close(); err != nil {
+			return chunk{}, err
+		}
+		data = buf.Bytes()
+	}
+
+	t := newThrift()
+	t.i32(1, 0) // DATA_PAGE
+	t.i32(2, int32(len(page)))
+	t.i32(3, int32(len(data)))
+	t.structField(5)
+	t.i32(1, int32(c.n))
+	t.i32(2, encodingPlain)
+	t.i32(3, encodingRLE)
+	t.i32(4, encodingRLE)
+	t.end()
+	t.end()
+
+	ch := chunk{
+		offset:       w.offset,
+		uncompressed: int64(len(t.b) + len(page)),
+		compressed:   int64(len(t.b) + len(data)),
+		n:            c.n,
+	}
+	if err := w.write(t.b); err != nil {
+		return chunk{}, err
+	}
+	return ch, w.write(data)
+}
+
+// definitionLevels encodes whether each value is defined with the RLE hybrid
+// encoding using only run length encoded runs.
+func definitionLevels(defs []bool) []byte {
+	var b []byte
+	var buf [binary.MaxVarintLen64]byte
+	for i := 0; i < len(defs); {
+		j := i + 1
+		for j < len(defs) && defs[j] == defs[i] {
+			j++
+		}
+		b = append(b, buf[:binary.PutUvarint(buf[:], uint64(j-i)<<1)]...)
+		if defs[i] {
+			b = append(b, 1)
+		} else {
+			b = append(b, 0)
+		}
+		i = j
+	}
+	return b
+}
+
+// Close writes any buffered events and the file's footer. It doesn't close
+// the underlying io.Writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	w.closed = true
+	if !w.started {
+		// Files always start with the magic number even without row groups
+		w.codec = w.Compression
+		if err := w.write([]byte(magic)); err != nil {
+			return err
+		}
+	}
+	meta := w.metadata()
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(len(meta)))
+	if err := w.write(append(append(meta, buf[:]...), magic...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// metadata encodes the FileMetaData of the file.
+func (w *Writer) metadata() []byte {
+	var rows int64
+	for _, g := range w.groups {
+		rows += g.rows
+	}
+	t := newThrift()
+	t.i32(1, 1) // version
+	t.list(2, thriftStruct, len(w.cols)+1)
+	t.begin()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(w.cols)))
+	t.end()
+	for _, c := range w.cols {
+		t.begin()
+		t.i32(1, c.typ)
+		if c.optional {
+			t.i32(3, 1)
+		} else {
+			t.i32(3, 0)
+		}
+		t.binary(4, c.name)
+		if c.converted != convertedNone {
+			t.i32(6, c.converted)
+		}
+		t.end()
+	}
+	t.i64(3, rows)
+	t.list(4, thriftStruct, len(w.groups))
+	for _, g := range w.groups {
+		t.begin()
+		t.list(1, thriftStruct, len(g.chunks))
+		for i, ch := range g.chunks {
+			c := w.cols[i]
+			t.begin()
+			t.i64(2, ch.offset)
+			t.structField(3)
+			t.i32(1, c.typ)
+			t.list(2, thriftI32, 2)
+			t.varint(encodingPlain)
+			t.varint(encodingRLE)
+			t.list(3, thriftBinary, 1)
+			t.str(c.name)
+			t.i32(4, int32(w.codec))
+			t.i64(5, int64(ch.n))
+			t.i64(6, ch.uncompressed)
+			t.i64(7, ch.compressed)
+			t.i64(9, ch.offset)
+			t.end()
+			t.end()
+		}
+		t.i64(2, g.size)
+		t.i64(3, g.rows)
+		t.end()
+	}
+	t.binary(6, "gobyairship")
+	t.end()
+	return t.b
+}
@@ -0,0 +1,253 @@
+package parquet_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/events/parquet"
+)
+
+// thrift decodes compact protocol structs into maps of field IDs to values:
+// int64, []byte, []interface{}, or map[int16]interface{}.
+type thrift struct{ b []byte }
+
+func (t *thrift) uvarint() uint64 {
+	v, n := binary.Uvarint(t.b)
+	if n <= 0 {
+		panic("invalid varint")
+	}
+	t.b = t.b[n:]
+	return v
+}
+
+func (t *thrift) value(typ byte) interface{} {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case 5, 6:
+		v, n := binary.Varint(t.b)
+		if n <= 0 {
+			panic("invalid varint")
+		}
+		t.b = t.b[n:]
+		return v
+	case 8:
+		n := t.uvarint()
+		v := t.b[:n]
+		t.b = t.b[n:]
+		return v
+	case 9:
+		h := t.b[0]
+		t.b = t.b[1:]
+		n := uint64(h >> 4)
+		if n == 15 {
+			n = t.uvarint()
+		}
+		l := make([]interface{}, n)
+		for i := range l {
+			l[i] = t.value(h & 0xf)
+		}
+		return l
+	case 12:
+		s := map[int16]interface{}{}
+		var last int16
+		for {
+			h := t.b[0]
+			t.b = t.b[1:]
+			if h == 0 {
+				return s
+			}
+			id := last + int16(h>>4)
+			if h>>4 == 0 {
+				v, n := binary.Varint(t.b)
+				t.b = t.b[n:]
+				id = int16(v)
+			}
+			s[id] = t.value(h & 0xf)
+			last = id
+		}
+	}
+	panic(fmt.Sprintf("unsupported type %d", typ))
+}
+
+// row is an event read back from a Parquet file.
+type row struct {
+	id, typ, device, body string
+	offset, occurred      int64
+	hasDevice, hasBody    bool
+}
+
+// read decodes a file written by a Writer.
+func read(t *testing.T, b []byte) (map[int16]interface{}, []row) {
+	if string(b[:4]) != "PAR1" || string(b[len(b)-4:]) != "PAR1" {
+		t.Fatalf("Expected magic numbers")
+	}
+	n := binary.LittleEndian.Uint32(b[len(b)-8:])
+	meta := (&thrift{b: b[len(b)-8-int(n) : len(b)-8]}).value(12).(map[int16]interface{})
+
+	var rows []row
+	for _, g := range meta[4].([]interface{}) {
+		g := g.(map[int16]interface{})
+		groupRows := make([]row, g[3].(int64))
+		for i, c := range g[1].([]interface{}) {
+			cm := c.(map[int16]interface{})[3].(map[int16]interface{})
+			th := &thrift{b: b[cm[9].(int64):]}
+			header := th.value(12).(map[int16]interface{})
+			data := th.b[:header[3].(int64)]
+			if cm[4].(int64) == int64(parquet.Gzip) {
+				gz, err := gzip.NewReader(bytes.NewReader(data))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if data, err = ioutil.ReadAll(gz); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			if int64(len(data)) != header[2].(int64) {
+				t.Fatalf("Expected %d uncompressed bytes but found %d", header[2], len(data))
+			}
+			defs := make([]bool, len(groupRows))
+			for j := range defs {
+				defs[j] = true
+			}
+			name := string(cm[3].([]interface{})[0].([]byte))
+			if name == "device" || name == "body" {
+				l := binary.LittleEndian.Uint32(data)
+				levels := &thrift{b: data[4 : 4+l]}
+				data = data[4+l:]
+				for j := 0; len(levels.b) > 0; {
+					run := int(levels.uvarint() >> 1)
+					v := levels.b[0] == 1
+					levels.b = levels.b[1:]
+					for ; run > 0; run-- {
+						defs[j] = v
+						j++
+					}
+				}
+			}
+			for j := range groupRows {
+				r := &groupRows[j]
+				if !defs[j] {
+					continue
+				}
+				var s string
+				var v int64
+				if i <= 1 || i >= 5 {
+					l := binary.LittleEndian.Uint32(data)
+					s = string(data[4 : 4+l])
+					data = data[4+l:]
+				} else {
+					v = int64(binary.LittleEndian.Uint64(data))
+					data = data[8:]
+				}
+				switch name {
+				case "id":
+					r.id = s
+				case "type":
+					r.typ = s
+				case "offset":
+					r.offset = v
+				case "occurred":
+					r.occurred = v
+				case "device":
+					r.device, r.hasDevice = s, true
+				case "body":
+					r.body, r.hasBody = s, true
+				}
+			}
+			if len(data) > 0 {
+				t.Fatalf("Expected %s values to be decoded but %d bytes remain", name, len(data))
+			}
+		}
+		rows = append(rows, groupRows...)
+	}
+	return meta, rows
+}
+
+func testEvents(n int) []*events.Event {
+	evs := make([]*events.Event, n)
+	for i := range evs {
+		evs[i] = &events.Event{
+			ID:       fmt.Sprintf("id-%d", i),
+			Type:     events.TypeOpen,
+			Offset:   uint64(i + 1),
+			Occurred: time.Date(2017, 4, 25, 9, 0, i, 0, time.UTC),
+		}
+		if i%3 != 0 {
+			evs[i].Body = json.RawMessage(fmt.Sprintf(`{"session_id":"%d"}`, i))
+		}
+		if i%2 == 0 {
+			evs[i].Device = &events.Device{IOS: "ios"}
+		}
+	}
+	return evs
+}
+
+func TestWriter(t *testing.T) {
+	for _, codec := range []parquet.Codec{parquet.Uncompressed, parquet.Gzip} {
+		evs := testEvents(25)
+		buf := &bytes.Buffer{}
+		w := parquet.NewWriter(buf)
+		w.Compression = codec
+		w.RowGroupSize = 10
+		if err := w.Write(evs[:5]); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := w.Write(evs[5:]); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := w.Write(evs); err != parquet.ErrClosed {
+			t.Errorf("Expected ErrClosed but found %v", err)
+		}
+
+		meta, rows := read(t, buf.Bytes())
+		if n := meta[3].(int64); n != 25 {
+			t.Errorf("Expected 25 rows but found %d", n)
+		}
+		if n := len(meta[4].([]interface{})); n != 3 {
+			t.Errorf("Expected 3 row groups but found %d", n)
+		}
+		if len(rows) != len(evs) {
+			t.Fatalf("Expected %d rows but read %d", len(evs), len(rows))
+		}
+		for i, ev := range evs {
+			r := rows[i]
+			if r.id != ev.ID || r.typ != string(ev.Type) || r.offset != int64(ev.Offset) {
+				t.Errorf("Expected %s %s at %d but read %+v", ev.Type, ev.ID, ev.Offset, r)
+			}
+			if ms := ev.Occurred.UnixNano() / 1e6; r.occurred != ms {
+				t.Errorf("Expected occurred %d but read %d", ms, r.occurred)
+			}
+			if r.hasBody != (ev.Body != nil) || r.body != string(ev.Body) {
+				t.Errorf("Expected body %s but read %q", ev.Body, r.body)
+			}
+			if r.hasDevice != (ev.Device != nil) || r.hasDevice && r.device != `{"ios_channel":"ios"}` {
+				t.Errorf("Expected device %v but read %q", ev.Device, r.device)
+			}
+		}
+	}
+}
+
+func TestMarshalEmpty(t *testing.T) {
+	b, err := parquet.Marshal(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	meta, rows := read(t, b)
+	if len(rows) != 0 || meta[3].(int64) != 0 {
+		t.Errorf("Expected no rows but found %d", len(rows))
+	}
+	if n := len(meta[2].([]interface{})); n != 8 {
+		t.Errorf("Expected 8 schema elements but found %d", n)
+	}
+}
@@ -0,0 +1,92 @@
+package parquet
+
+import "encoding/binary"
+
+// Thrift compact protocol types used by Parquet's metadata.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thrift encodes structs with Thrift's compact protocol, which Parquet uses
+// for page headers and file metadata. Fields must be written in increasing
+// order of their IDs.
+type thrift struct {
+	b    []byte
+	last []int16 // ID of the last field written in each open struct
+}
+
+func newThrift() *thrift { return &thrift{last: []int16{0}} }
+
+func (t *thrift) uvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	t.b = append(t.b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func (t *thrift) varint(v int64) {
+	var buf [binary.MaxVarintLen64]byte
+	t.b = append(t.b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+func (t *thrift) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.b = append(t.b, byte(delta)<<4|typ)
+	} else {
+		t.b = append(t.b, typ)
+		t.varint(int64(id))
+	}
+	*last = id
+}
+
+func (t *thrift) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thrift) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thrift) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.str(s)
+}
+
+// str writes a string list element.
+func (t *thrift) str(s string) {
+	t.uvarint(uint64(len(s)))
+	t.b = append(t.b, s...)
+}
+
+// list writes the header of a list of n elements of typ. Write elements with
+// str, varint, or begin and end.
+func (t *thrift) list(id int16, typ byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.b = append(t.b, byte(n)<<4|typ)
+		return
+	}
+	t.b = append(t.b, 0xf0|typ)
+	t.uvarint(uint64(n))
+}
+
+// structField writes the header of a struct field. Write its fields then call
+// end.
+func (t *thrift) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.begin()
+}
+
+// begin a struct list element.
+func (t *thrift) begin() { t.last = append(t.last, 0) }
+
+// end the current struct.
+func (t *thrift) end() {
+	t.b = append(t.b, 0)
+	t.last = t.last[:len(t.last)-1]
+}
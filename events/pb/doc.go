@@ -0,0 +1,43 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package converts events to and from the protocol buffer messages in
+// events.proto, the canonical binary schema shared by the gRPC relay and
+// binary archives.
+//
+// Marshal and Unmarshal convert single Event messages:
+//
+//	b, err := pb.Marshal(ev)
+//	if err != nil {
+//		return err
+//	}
+//
+// while Writer and Reader stream length delimited messages for archives:
+//
+//	w := pb.NewWriter(f)
+//	for ev := range s.Events() {
+//		if err := w.Write(ev); err != nil {
+//			return err
+//		}
+//	}
+//
+// Bodies of known types are typed messages; fields missing from events.proto
+// are dropped, so Unmarshal returns a Body with only the schema's fields,
+// re-encoded as JSON. Bodies of types added after the schema are kept as JSON
+// in json_body.
+//
+// Messages are encoded without generated code, so this package only depends
+// on the standard library. Generate code from events.proto to decode events in
+// other languages.
+package pb
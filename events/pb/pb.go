@@ -0,0 +1,502 @@
+package pb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+// kind of a body field: how it's represented in JSON and on the wire.
+type kind int
+
+const (
+	kString    kind = iota
+	kInt64          // JSON number
+	kDouble         // JSON number
+	kBool           // JSON boolean
+	kBytes          // base64 JSON string
+	kTimestamp      // RFC 3339 JSON string as a google.protobuf.Timestamp
+	kJSON           // any JSON value as a string
+	kMessage        // JSON object as a message
+	kMessages       // JSON array of objects as a repeated message
+	kTags           // JSON object of string arrays as map<string, StringList>
+	kJSONMap        // JSON object of any values as map<string, string>
+)
+
+// field of a message in events.proto.
+type field struct {
+	num  int
+	name string
+	kind kind
+	msg  *message
+}
+
+// message in events.proto which body JSON is converted to and from.
+type message struct {
+	name   string
+	fields []field
+}
+
+func (m *message) field(num int) *field {
+	for i := range m.fields {
+		if m.fields[i].num == num {
+			return &m.fields[i]
+		}
+	}
+	return nil
+}
+
+func str(num int, name string) field     { return field{num: num, name: name} }
+func int64f(num int, name string) field  { return field{num: num, name: name, kind: kInt64} }
+func double(num int, name string) field  { return field{num: num, name: name, kind: kDouble} }
+func boolean(num int, name string) field { return field{num: num, name: name, kind: kBool} }
+func msg(num int, name string, m *message) field {
+	return field{num: num, name: name, kind: kMessage, msg: m}
+}
+
+var (
+	pushMsg = &message{"Push", []field{str(1, "push_id"), str(2, "group_id")}}
+
+	attributeOperationMsg = &message{"AttributeOperation", []field{
+		str(1, "action"), str(2, "key"), {num: 3, name: "value", kind: kJSON}, {num: 4, name: "timestamp", kind: kTimestamp},
+	}}
+
+	resolutionFields = []field{
+		str(1, "push_id"), str(2, "group_id"), msg(3, "triggering_push", pushMsg), str(4, "session_id"),
+		{num: 5, name: "time_sent", kind: kTimestamp}, str(6, "type"), int64f(7, "duration"),
+		str(8, "button_id"), str(9, "button_group"), str(10, "button_description"),
+	}
+
+	richEventMsg = &message{"RichEvent", []field{
+		str(1, "push_id"), str(2, "group_id"), int64f(3, "variant_id"), str(4, "message_id"), str(5, "session_id"),
+	}}
+	emailMsg = &message{"Email", []field{
+		str(1, "push_id"), str(2, "group_id"), int64f(3, "variant_id"),
+		str(4, "message_type"), str(5, "link_url"), str(6, "bounce_reason"),
+	}}
+	smsOptMsg = &message{"SMSOpt", []field{str(1, "keyword"), str(2, "source")}}
+)
+
+// body is the oneof field of Event holding a type's body.
+type body struct {
+	num int
+	msg *message
+}
+
+// jsonBody is the oneof field of bodies of types without a message.
+const jsonBody = 15
+
+// bodies are the messages of each type's body.
+var bodies = map[events.Type]body{
+	events.TypePush: {16, &message{"PushBody", []field{
+		str(1, "push_id"), str(2, "group_id"), {num: 3, name: "payload", kind: kBytes},
+	}}},
+	events.TypeOpen: {17, &message{"Open", []field{
+		msg(1, "last_delivered", pushMsg), msg(2, "triggering_push", pushMsg), str(3, "session_id"),
+	}}},
+	events.TypeSend: {18, &message{"Send", []field{
+		str(1, "push_id"), str(2, "group_id"), int64f(3, "variant_id"),
+	}}},
+	events.TypeClose: {19, &message{"Close", []field{str(1, "session_id")}}},
+	events.TypeTagChange: {20, &message{"TagChange", []field{
+		{num: 1, name: "add", kind: kTags}, {num: 2, name: "remove", kind: kTags}, {num: 3, name: "current", kind: kTags},
+	}}},
+	events.TypeUninstall: {21, &message{"Uninstall", nil}},
+	events.TypeFirst:     {22, &message{"FirstOpen", []field{str(1, "session_id")}}},
+	events.TypeCustom: {23, &message{"Custom", []field{
+		str(1, "name"), double(2, "value"), {num: 3, name: "properties", kind: kJSONMap},
+		str(4, "interaction_id"), str(5, "interaction_type"), str(6, "session_id"),
+	}}},
+	events.TypeLocation: {24, &message{"Location", []field{
+		str(1, "latitude"), str(2, "longitude"), boolean(3, "foreground"), str(4, "session_id"),
+	}}},
+	events.TypeRichDelivery: {25, richEventMsg},
+	events.TypeRichRead:     {26, richEventMsg},
+	events.TypeRichDelete:   {27, richEventMsg},
+	events.TypeInAppMessageDisplay: {28, &message{"InAppMessageDisplay", []field{
+		str(1, "push_id"), str(2, "group_id"), msg(3, "triggering_push", pushMsg), str(4, "session_id"),
+	}}},
+	events.TypeInAppMessageResolution: {29, &message{"InAppMessageResolution", resolutionFields}},
+	events.TypeInAppMessageExpiration: {30, &message{"InAppMessageExpiration",
+		append(resolutionFields[:len(resolutionFields):len(resolutionFields)], msg(11, "replacing_push", pushMsg)),
+	}},
+	events.TypeScreenViewed: {31, &message{"ScreenViewed", []field{
+		str(1, "viewed_screen"), str(2, "previous_screen"), int64f(3, "duration"), str(4, "session_id"),
+	}}},
+	events.TypeRegion: {32, &message{"Region", []field{
+		str(1, "region_id"), str(2, "name"), str(3, "source"), str(4, "action"), str(5, "session_id"),
+	}}},
+	events.TypeControl: {33, &message{"Control", []field{
+		str(1, "push_id"), str(2, "group_id"), int64f(3, "variant_id"),
+	}}},
+	events.TypeSendAborted: {34, &message{"SendAborted", []field{
+		str(1, "push_id"), str(2, "group_id"), int64f(3, "variant_id"), str(4, "reason"),
+	}}},
+	events.TypeEmailDelivery:    {35, emailMsg},
+	events.TypeEmailOpen:        {36, emailMsg},
+	events.TypeEmailClick:       {37, emailMsg},
+	events.TypeEmailBounce:      {38, emailMsg},
+	events.TypeEmailUnsubscribe: {39, emailMsg},
+	events.TypeSMSDelivery: {40, &message{"SMSDelivery", []field{
+		str(1, "push_id"), str(2, "group_id"), str(3, "delivery_status"), str(4, "error_code"), boolean(5, "mms"),
+	}}},
+	events.TypeMobileOriginated: {41, &message{"MobileOriginated", []field{str(1, "keyword"), str(2, "inbound_message")}}},
+	events.TypeSMSOptIn:         {42, smsOptMsg},
+	events.TypeSMSOptOut:        {43, smsOptMsg},
+	events.TypeWebClick: {44, &message{"WebClick", []field{
+		str(1, "push_id"), str(2, "group_id"), str(3, "url"), str(4, "browser_name"), str(5, "session_id"),
+	}}},
+	events.TypeAttributeOperation: {45, &message{"AttributeOperations", []field{
+		{num: 1, name: "mutations", kind: kMessages, msg: attributeOperationMsg},
+	}}},
+	events.TypeSubscriptionListChange: {46, &message{"SubscriptionListChange", []field{
+		str(1, "list_id"), str(2, "action"), str(3, "scope"),
+	}}},
+	events.TypeCompliance: {47, &message{"Compliance", []field{
+		str(1, "action"), str(2, "request_id"), str(3, "reason"),
+	}}},
+}
+
+// bodyTypes maps body oneof field numbers to event types.
+var bodyTypes = map[int]events.Type{}
+
+func init() {
+	for t, b := range bodies {
+		bodyTypes[b.num] = t
+	}
+}
+
+// encode appends the fields of a JSON object as message m.
+func encode(b []byte, m *message, obj map[string]interface{}) ([]byte, error) {
+	for _, f := range m.fields {
+		v, ok := obj[f.name]
+		if !ok || v == nil {
+			continue
+		}
+		var err error
+		if b, err = encodeField(b, &f, v); err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", m.name, f.name, err)
+		}
+	}
+	return b, nil
+}
+
+func encodeField(b []byte, f *field, v interface{}) ([]byte, error) {
+	switch f.kind {
+	case kString:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string but found %T", v)
+		}
+		return appendBytes(b, f.num, []byte(s)), nil
+	case kInt64:
+		i, err := strconv.ParseInt(number(v), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return appendVarint(b, f.num, uint64(i)), nil
+	case kDouble:
+		d, err := strconv.ParseFloat(number(v), 64)
+		if err != nil {
+			return nil, err
+		}
+		return appendDouble(b, f.num, d), nil
+	case kBool:
+		t, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected boolean but found %T", v)
+		}
+		if t {
+			return appendVarint(b, f.num, 1), nil
+		}
+		return appendVarint(b, f.num, 0), nil
+	case kBytes:
+		s, _ := v.(string)
+		raw, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return appendBytes(b, f.num, raw), nil
+	case kTimestamp:
+		s, _ := v.(string)
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, err
+		}
+		return appendTimestamp(b, f.num, t), nil
+	case kJSON:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return appendBytes(b, f.num, raw), nil
+	case kMessage:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object but found %T", v)
+		}
+		sub, err := encode(nil, f.msg, obj)
+		if err != nil {
+			return nil, err
+		}
+		return appendBytes(b, f.num, sub), nil
+	case kMessages:
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array but found %T", v)
+		}
+		for _, el := range arr {
+			var err error
+			if b, err = encodeField(b, &field{num: f.num, kind: kMessage, msg: f.msg}, el); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case kTags, kJSONMap:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object but found %T", v)
+		}
+		for _, k := range sortedKeys(obj) {
+			var value []byte
+			if f.kind == kJSONMap {
+				raw, err := json.Marshal(obj[k])
+				if err != nil {
+					return nil, err
+				}
+				value = appendBytes(nil, 2, raw)
+			} else {
+				arr, _ := obj[k].([]interface{})
+				var list []byte
+				for _, s := range arr {
+					s, ok := s.(string)
+					if !ok {
+						return nil, fmt.Errorf("expected string but found %T", s)
+					}
+					list = appendBytes(list, 1, []byte(s))
+				}
+				value = appendBytes(nil, 2, list)
+			}
+			entry := append(appendBytes(nil, 1, []byte(k)), value...)
+			b = appendBytes(b, f.num, entry)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unknown kind %d", f.kind)
+}
+
+// number returns the text of a JSON number, which some event fields send as
+// strings.
+func number(v interface{}) string {
+	switch n := v.(type) {
+	case json.Number:
+		return n.String()
+	case string:
+		return n
+	}
+	return fmt.Sprint(v)
+}
+
+// decode a message m into a JSON object.
+func decode(data []byte, m *message) (map[string]interface{}, error) {
+	obj := map[string]interface{}{}
+	err := fields(data, func(num int, v uint64, b []byte) error {
+		f := m.field(num)
+		if f == nil {
+			// Skip fields from newer schemas
+			return nil
+		}
+		switch f.kind {
+		case kString:
+			obj[f.name] = string(b)
+		case kInt64:
+			obj[f.name] = json.Number(strconv.FormatInt(int64(v), 10))
+		case kDouble:
+			obj[f.name] = json.Number(strconv.FormatFloat(math.Float64frombits(v), 'g', -1, 64))
+		case kBool:
+			obj[f.name] = v != 0
+		case kBytes:
+			obj[f.name] = base64.StdEncoding.EncodeToString(b)
+		case kTimestamp:
+			t, err := timestamp(b)
+			if err != nil {
+				return err
+			}
+			obj[f.name] = t.Format(time.RFC3339Nano)
+		case kJSON:
+			obj[f.name] = json.RawMessage(append([]byte(nil), b...))
+		case kMessage, kMessages:
+			sub, err := decode(b, f.msg)
+			if err != nil {
+				return err
+			}
+			if f.kind == kMessage {
+				obj[f.name] = sub
+				return nil
+			}
+			arr, _ := obj[f.name].([]interface{})
+			obj[f.name] = append(arr, sub)
+		case kTags, kJSONMap:
+			m, _ := obj[f.name].(map[string]interface{})
+			if m == nil {
+				m = map[string]interface{}{}
+				obj[f.name] = m
+			}
+			var key string
+			var value interface{}
+			if f.kind == kTags {
+				value = []string{}
+			}
+			err := fields(b, func(num int, _ uint64, b []byte) error {
+				switch {
+				case num == 1:
+					key = string(b)
+				case num == 2 && f.kind == kJSONMap:
+					value = json.RawMessage(append([]byte(nil), b...))
+				case num == 2:
+					var list []string
+					err := fields(b, func(num int, _ uint64, b []byte) error {
+						if num == 1 {
+							list = append(list, string(b))
+						}
+						return nil
+					})
+					value = list
+					return err
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			m[key] = value
+		}
+		return nil
+	})
+	return obj, err
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Marshal returns the protocol buffer encoding of ev as an Event message.
+func Marshal(ev *events.Event) ([]byte, error) {
+	return Append(make([]byte, 0, len(ev.Body)+128), ev)
+}
+
+// Append appends the protocol buffer encoding of ev to b. Bodies of types
+// without a message, or which aren't JSON objects, are kept as JSON.
+func Append(b []byte, ev *events.Event) ([]byte, error) {
+	if ev.ID != "" {
+		b = appendBytes(b, 1, []byte(ev.ID))
+	}
+	if ev.Type != "" {
+		b = appendBytes(b, 2, []byte(ev.Type))
+	}
+	if ev.Offset != 0 {
+		b = appendVarint(b, 3, ev.Offset)
+	}
+	if ev.OpaqueOffset != "" {
+		b = appendBytes(b, 4, []byte(ev.OpaqueOffset))
+	}
+	b = appendTimestamp(b, 5, ev.Occurred)
+	b = appendTimestamp(b, 6, ev.Processed)
+	if d := ev.Device; d != nil {
+		b = appendBytes(b, 7, appendDevice(nil, d))
+	}
+
+	if len(ev.Body) == 0 || string(ev.Body) == "null" {
+		return b, nil
+	}
+	if bd, ok := bodies[ev.Type]; ok {
+		var obj map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(ev.Body))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err == nil && obj != nil {
+			sub, err := encode(nil, bd.msg, obj)
+			if err != nil {
+				return nil, fmt.Errorf("error encoding %s event %s: %v", ev.Type, ev.ID, err)
+			}
+			return appendBytes(b, bd.num, sub), nil
+		}
+	}
+	return appendBytes(b, jsonBody, ev.Body), nil
+}
+
+// deviceFields are the fields of Device messages in order.
+func deviceFields(d *events.Device) []*string {
+	return []*string{&d.Amazon, &d.Android, &d.IOS, &d.NamedUser, &d.Email, &d.EmailAddress, &d.SMS, &d.SMSSender, &d.MSISDN, &d.Web}
+}
+
+func appendDevice(b []byte, d *events.Device) []byte {
+	for i, s := range deviceFields(d) {
+		if *s != "" {
+			b = appendBytes(b, i+1, []byte(*s))
+		}
+	}
+	return b
+}
+
+// Unmarshal decodes an Event message into ev. Typed bodies are converted back
+// to JSON, so the Body may differ from the original in field order and
+// number formatting.
+func Unmarshal(data []byte, ev *events.Event) error {
+	*ev = events.Event{}
+	return fields(data, func(num int, v uint64, b []byte) error {
+		var err error
+		switch num {
+		case 1:
+			ev.ID = string(b)
+		case 2:
+			ev.Type = events.Type(b)
+		case 3:
+			ev.Offset = v
+		case 4:
+			ev.OpaqueOffset = string(b)
+		case 5:
+			ev.Occurred, err = timestamp(b)
+		case 6:
+			ev.Processed, err = timestamp(b)
+		case 7:
+			ev.Device = &events.Device{}
+			df := deviceFields(ev.Device)
+			err = fields(b, func(num int, _ uint64, b []byte) error {
+				if num >= 1 && num <= len(df) {
+					*df[num-1] = string(b)
+				}
+				return nil
+			})
+		case jsonBody:
+			ev.Body = append(json.RawMessage(nil), b...)
+		default:
+			t, ok := bodyTypes[num]
+			if !ok {
+				return nil
+			}
+			var obj map[string]interface{}
+			if obj, err = decode(b, bodies[t].msg); err != nil {
+				return err
+			}
+			if ev.Body, err = json.Marshal(obj); err != nil {
+				return err
+			}
+			if ev.Type == "" {
+				ev.Type = t
+			}
+		}
+		return err
+	})
+}
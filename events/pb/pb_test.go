@@ -0,0 +1,219 @@
+package pb_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/events/pb"
+)
+
+// fixture returns the events in a file in the events package's testdata.
+func fixture(t *testing.T, name string) []*events.Event {
+	f, err := os.Open(filepath.Join("..", "testdata", name))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer f.Close()
+	var evs []*events.Event
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		ev := &events.Event{}
+		if err := json.Unmarshal(s.Bytes(), ev); err != nil {
+			t.Fatalf("Unexpected error decoding %s: %v", name, err)
+		}
+		evs = append(evs, ev)
+	}
+	return evs
+}
+
+// subset returns true if every field of found is in expected with the same
+// value, as bodies only keep the fields in events.proto.
+func subset(expected, found interface{}) bool {
+	switch f := found.(type) {
+	case map[string]interface{}:
+		e, ok := expected.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, v := range f {
+			if !subset(e[k], v) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		e, ok := expected.([]interface{})
+		if !ok || len(e) != len(f) {
+			return false
+		}
+		for i := range f {
+			if !subset(e[i], f[i]) {
+				return false
+			}
+		}
+		return true
+	case string:
+		// Timestamps may be formatted with different precision
+		if ft, err := time.Parse(time.RFC3339Nano, f); err == nil {
+			if es, ok := expected.(string); ok {
+				et, err := time.Parse(time.RFC3339Nano, es)
+				return err == nil && et.Equal(ft)
+			}
+		}
+	}
+	return reflect.DeepEqual(expected, found)
+}
+
+func TestRoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "*.json"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var evs []*events.Event
+	for _, fn := range files {
+		evs = append(evs, fixture(t, filepath.Base(fn))...)
+	}
+	types := map[events.Type]bool{}
+	for _, ev := range evs {
+		types[ev.Type] = true
+		b, err := pb.Marshal(ev)
+		if err != nil {
+			t.Fatalf("Unexpected error encoding %s %s: %v", ev.Type, ev.ID, err)
+		}
+		decoded := &events.Event{}
+		if err := pb.Unmarshal(b, decoded); err != nil {
+			t.Fatalf("Unexpected error decoding %s %s: %v", ev.Type, ev.ID, err)
+		}
+		if decoded.ID != ev.ID || decoded.Type != ev.Type || decoded.Offset != ev.Offset || decoded.OpaqueOffset != ev.OpaqueOffset {
+			t.Errorf("Expected %s %s at %d but decoded %+v", ev.Type, ev.ID, ev.Offset, decoded)
+		}
+		if !decoded.Occurred.Equal(ev.Occurred) || !decoded.Processed.Equal(ev.Processed) {
+			t.Errorf("Expected %s %s to occur at %s but decoded %s", ev.Type, ev.ID, ev.Occurred, decoded.Occurred)
+		}
+		if !reflect.DeepEqual(decoded.Device, ev.Device) {
+			t.Errorf("Expected device %+v but decoded %+v", ev.Device, decoded.Device)
+		}
+
+		if len(ev.Body) == 0 {
+			if len(decoded.Body) != 0 {
+				t.Errorf("Expected %s %s to have no body but decoded %s", ev.Type, ev.ID, decoded.Body)
+			}
+			continue
+		}
+		var expected, found interface{}
+		json.Unmarshal(ev.Body, &expected)
+		if err := json.Unmarshal(decoded.Body, &found); err != nil {
+			t.Fatalf("Unexpected error decoding %s %s body %s: %v", ev.Type, ev.ID, decoded.Body, err)
+		}
+		if !subset(expected, found) {
+			t.Errorf("Expected %s %s body %s but decoded %s", ev.Type, ev.ID, ev.Body, decoded.Body)
+		}
+
+		// Encoding is deterministic so decoded events encode the same
+		again, err := pb.Marshal(decoded)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !bytes.Equal(b, again) {
+			t.Errorf("Expected %s %s to encode the same after decoding", ev.Type, ev.ID)
+		}
+	}
+	if len(types) < 30 {
+		t.Errorf("Expected fixtures to cover every type but found %d", len(types))
+	}
+}
+
+func TestBodies(t *testing.T) {
+	ev := &events.Event{
+		ID:   "custom",
+		Type: events.TypeCustom,
+		Body: json.RawMessage(`{"name":"purchase","value":49.99,"properties":{"sku":"SHOE-42","quantity":1},"extra":true}`),
+	}
+	b, err := pb.Marshal(ev)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	decoded := &events.Event{}
+	if err := pb.Unmarshal(b, decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{"name":"purchase","properties":{"quantity":1,"sku":"SHOE-42"},"value":49.99}`
+	if string(decoded.Body) != expected {
+		t.Errorf("Expected body %s but found %s", expected, decoded.Body)
+	}
+
+	// Types without a message and bodies which aren't objects are kept as is
+	for _, ev := range []*events.Event{
+		{ID: "new", Type: "NEW_TYPE", Body: json.RawMessage(`{"a":[1,2]}`)},
+		{ID: "array", Type: events.TypeSend, Body: json.RawMessage(`[1]`)},
+		{ID: "empty", Type: events.TypeUninstall},
+	} {
+		if b, err = pb.Marshal(ev); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := pb.Unmarshal(b, decoded); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(ev, decoded) {
+			t.Errorf("Expected %+v but decoded %+v", ev, decoded)
+		}
+	}
+
+	// Fields of the wrong type are errors rather than being dropped
+	ev = &events.Event{ID: "bad", Type: events.TypeSend, Body: json.RawMessage(`{"push_id":1}`)}
+	if _, err := pb.Marshal(ev); err == nil {
+		t.Errorf("Expected error encoding a numeric push_id")
+	}
+	if err := pb.Unmarshal(b[:len(b)-1], decoded); err == nil {
+		t.Errorf("Expected error decoding a truncated event")
+	}
+}
+
+func TestStream(t *testing.T) {
+	evs := fixture(t, "send.json")
+	buf := &bytes.Buffer{}
+	w := pb.NewWriter(buf)
+	for _, ev := range evs {
+		if err := w.Write(ev); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	r := pb.NewReader(bytes.NewReader(buf.Bytes()))
+	n := 0
+	for {
+		ev := &events.Event{}
+		err := r.Read(ev)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ev.ID != evs[n].ID {
+			t.Errorf("Expected event %s but read %s", evs[n].ID, ev.ID)
+		}
+		n++
+	}
+	if n != len(evs) {
+		t.Errorf("Expected %d events but read %d", len(evs), n)
+	}
+
+	r = pb.NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	var err error
+	for err == nil {
+		err = r.Read(&events.Event{})
+	}
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected unexpected EOF reading a truncated stream but found %v", err)
+	}
+}
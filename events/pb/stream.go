@@ -0,0 +1,64 @@
+package pb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+// Writer writes events as length delimited Event messages: each message is
+// preceded by its length as a varint, the framing used by Java's
+// writeDelimitedTo and most other protocol buffer libraries.
+type Writer struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewWriter creates a Writer which writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write an event.
+func (w *Writer) Write(ev *events.Event) error {
+	msg, err := Marshal(ev)
+	if err != nil {
+		return err
+	}
+	w.buf = append(appendUvarint(w.buf[:0], uint64(len(msg))), msg...)
+	_, err = w.w.Write(w.buf)
+	return err
+}
+
+// Reader reads length delimited Event messages written by a Writer.
+type Reader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+// NewReader creates a Reader which reads from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read the next event into ev. Returns io.EOF once every event has been read
+// and io.ErrUnexpectedEOF if the last message is truncated.
+func (r *Reader) Read(ev *events.Event) error {
+	l, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return err
+	}
+	if uint64(cap(r.buf)) < l {
+		r.buf = make([]byte, l)
+	}
+	r.buf = r.buf[:l]
+	if _, err := io.ReadFull(r.r, r.buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return Unmarshal(r.buf, ev)
+}
@@ -0,0 +1,124 @@
+package pb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Protocol buffer wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated message")
+
+func appendTag(b []byte, num int, wire uint64) []byte {
+	return appendUvarint(b, uint64(num)<<3|wire)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendVarint(b []byte, num int, v uint64) []byte {
+	return appendUvarint(appendTag(b, num, wireVarint), v)
+}
+
+func appendFixed64(b []byte, num int, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(appendTag(b, num, wireFixed64), buf[:]...)
+}
+
+func appendDouble(b []byte, num int, f float64) []byte {
+	return appendFixed64(b, num, math.Float64bits(f))
+}
+
+// appendBytes appends a length delimited field, even if v is empty.
+func appendBytes(b []byte, num int, v []byte) []byte {
+	b = appendUvarint(appendTag(b, num, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+// appendTimestamp appends t as a google.protobuf.Timestamp unless it's zero.
+func appendTimestamp(b []byte, num int, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	var ts []byte
+	if s := t.Unix(); s != 0 {
+		ts = appendVarint(ts, 1, uint64(s))
+	}
+	if n := t.Nanosecond(); n != 0 {
+		ts = appendVarint(ts, 2, uint64(n))
+	}
+	return appendBytes(b, num, ts)
+}
+
+func timestamp(data []byte) (time.Time, error) {
+	var s, n int64
+	err := fields(data, func(num int, v uint64, _ []byte) error {
+		switch num {
+		case 1:
+			s = int64(v)
+		case 2:
+			n = int64(int32(v))
+		}
+		return nil
+	})
+	return time.Unix(s, n).UTC(), err
+}
+
+// fields calls fn with the number and value of each field in a message:
+// varints and fixed width fields as v, length delimited fields as b.
+func fields(data []byte, fn func(num int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errTruncated
+		}
+		data = data[n:]
+		num := int(tag >> 3)
+		var v uint64
+		var b []byte
+		switch tag & 7 {
+		case wireVarint:
+			if v, n = binary.Uvarint(data); n <= 0 {
+				return errTruncated
+			}
+			data = data[n:]
+		case wireBytes:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return errTruncated
+			}
+			b = data[n : n+int(l)]
+			data = data[n+int(l):]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errTruncated
+			}
+			v = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errTruncated
+			}
+			v = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d", tag&7)
+		}
+		if err := fn(num, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
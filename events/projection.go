@@ -0,0 +1,256 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Projection selects the parts of events to keep. ID, Type, Offset, and
+// Occurred are always kept.
+type Projection struct {
+	// Processed keeps the Processed timestamp.
+	Processed bool
+
+	// Device lists the Device IDs to keep by their JSON names such as
+	// "ios_channel". If empty Device is dropped.
+	Device []string
+
+	// Body lists the top-level body fields to keep. If empty Body is dropped.
+	Body []string
+}
+
+// deviceFields returns the Device ID with each JSON name.
+var deviceFields = map[string]func(*Device) *string{
+	"amazon_channel":  func(d *Device) *string { return &d.Amazon },
+	"android_channel": func(d *Device) *string { return &d.Android },
+	"ios_channel":     func(d *Device) *string { return &d.IOS },
+	"named_user_id":   func(d *Device) *string { return &d.NamedUser },
+	"email_channel":   func(d *Device) *string { return &d.Email },
+	"email_address":   func(d *Device) *string { return &d.EmailAddress },
+	"sms_channel":     func(d *Device) *string { return &d.SMS },
+	"sms_sender":      func(d *Device) *string { return &d.SMSSender },
+	"msisdn":          func(d *Device) *string { return &d.MSISDN },
+	"web_channel":     func(d *Device) *string { return &d.Web },
+}
+
+// Project trims every event to the Projection as it's decoded, skipping the
+// dropped Device IDs and body fields rather than parsing them. This reduces
+// the work of decoding, the memory held by buffered events, and the size of
+// events passed downstream. An error is returned if Device names an unknown ID.
+//
+// Processed is still decoded for options which depend on it, such as
+// SkipOlderThan, and dropped afterwards. With ReportSchemaDrift events are
+// decoded in full so drift is detected before they are projected.
+func Project(p Projection) (Option, error) {
+	pr := &projection{processed: p.Processed}
+	for _, name := range p.Device {
+		field, ok := deviceFields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown device ID %q", name)
+		}
+		if pr.device == nil {
+			pr.device = map[string]func(*Device) *string{}
+		}
+		pr.device[`"`+name+`"`] = field
+	}
+	for _, name := range p.Body {
+		if pr.body == nil {
+			pr.body = map[string]bool{}
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		pr.body[string(key)] = true
+	}
+	return func(r *Response) { r.projection = pr }, nil
+}
+
+// projection is a validated Projection.
+type projection struct {
+	processed bool
+	device    map[string]func(*Device) *string
+	body      map[string]bool // quoted names of the kept fields
+}
+
+// projectedEvent decodes only the projected fields of an Event.
+type projectedEvent struct {
+	ID        *string         `json:"id"`
+	Type      *Type           `json:"type"`
+	Occurred  *time.Time      `json:"occurred"`
+	Processed *time.Time      `json:"processed"`
+	Offset    eventOffset     `json:"offset"`
+	Device    projectedDevice `json:"device"`
+	Body      projectedBody   `json:"body"`
+}
+
+// wire returns a projectedEvent which decodes into e.
+func (p *projection) wire(e *Event) *projectedEvent {
+	e.Offset, e.OpaqueOffset = 0, ""
+	if p.body == nil {
+		e.Body = nil
+	}
+	return &projectedEvent{
+		ID:        &e.ID,
+		Type:      &e.Type,
+		Occurred:  &e.Occurred,
+		Processed: &e.Processed,
+		Offset:    eventOffset{e},
+		Device:    projectedDevice{p, e},
+		Body:      projectedBody{p, e},
+	}
+}
+
+// project trims an event which was decoded in full.
+func (p *projection) project(e *Event) error {
+	if d := e.Device; d != nil {
+		kept := Device{}
+		for _, field := range p.device {
+			*field(&kept) = *field(d)
+		}
+		e.Device = nil
+		if kept != (Device{}) {
+			e.Device = &kept
+		}
+	}
+	if p.body == nil || e.Body == nil {
+		e.Body = nil
+		return nil
+	}
+	body, err := p.projectBody(nil, e.Body)
+	if err != nil {
+		return err
+	}
+	e.Body = body
+	return nil
+}
+
+// projectBody appends the kept fields of the JSON object b to dst.
+func (p *projection) projectBody(dst, b []byte) ([]byte, error) {
+	dst = append(dst, '{')
+	err := walkObject(b, func(key, value []byte) error {
+		if _, ok := p.body[string(key)]; ok {
+			if len(dst) > 1 {
+				dst = append(dst, ',')
+			}
+			dst = append(dst, key...)
+			dst = append(dst, ':')
+			dst = append(dst, value...)
+		}
+		return nil
+	})
+	return append(dst, '}'), err
+}
+
+// projectedDevice decodes the projected IDs of an Event's Device.
+type projectedDevice struct {
+	p *projection
+	e *Event
+}
+
+func (d projectedDevice) UnmarshalJSON(b []byte) error {
+	if d.p.device == nil || string(b) == "null" {
+		return nil
+	}
+	kept := Device{}
+	err := walkObject(b, func(key, value []byte) error {
+		if field, ok := d.p.device[string(key)]; ok {
+			return json.Unmarshal(value, field(&kept))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if kept != (Device{}) {
+		d.e.Device = &kept
+	}
+	return nil
+}
+
+// projectedBody decodes the projected fields of an Event's Body.
+type projectedBody struct {
+	p *projection
+	e *Event
+}
+
+func (pb projectedBody) UnmarshalJSON(b []byte) error {
+	if pb.p.body == nil || string(b) == "null" {
+		return nil
+	}
+	// Reuse the Body's memory of pooled events
+	body, err := pb.p.projectBody(pb.e.Body[:0], b)
+	if err != nil {
+		return err
+	}
+	pb.e.Body = body
+	return nil
+}
+
+// walkObject calls fn with the quoted key and raw value of each field of the
+// JSON object b. Values are skipped over rather than parsed, so b must be
+// valid JSON such as a value passed to UnmarshalJSON.
+func walkObject(b []byte, fn func(key, value []byte) error) error {
+	i := skipSpace(b, 0)
+	if i == len(b) || b[i] != '{' {
+		return fmt.Errorf("expected an object but found %.20q", b)
+	}
+	for i = skipSpace(b, i+1); i < len(b) && b[i] != '}'; {
+		end := skipValue(b, i)
+		key := b[i:end]
+		start := skipSpace(b, skipSpace(b, end)+1) // past the colon
+		end = skipValue(b, start)
+		if err := fn(key, b[start:end]); err != nil {
+			return err
+		}
+		i = skipSpace(b, end)
+		if i < len(b) && b[i] == ',' {
+			i = skipSpace(b, i+1)
+		}
+	}
+	return nil
+}
+
+// skipSpace returns the index of the first non-whitespace byte of b at or
+// after i.
+func skipSpace(b []byte, i int) int {
+	for i < len(b) && (b[i] == ' ' || b[i] == '\t' || b[i] == '\n' || b[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// skipValue returns the index just past the JSON value starting at b[i].
+func skipValue(b []byte, i int) int {
+	depth := 0
+	for ; i < len(b); i++ {
+		switch b[i] {
+		case '"':
+			for i++; i < len(b) && b[i] != '"'; i++ {
+				if b[i] == '\\' {
+					i++
+				}
+			}
+		case '{', '[':
+			depth++
+			continue
+		case '}', ']':
+			depth--
+			if depth < 0 {
+				return i
+			}
+		case ',', ' ', '\t', '\n', '\r', ':':
+			if depth == 0 {
+				return i
+			}
+			continue
+		default:
+			continue
+		}
+		if depth == 0 {
+			return i + 1
+		}
+	}
+	return i
+}
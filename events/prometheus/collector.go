@@ -0,0 +1,113 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/events"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace prefixes the names of all metrics.
+const Namespace = "gobyairship_events"
+
+// Collector is a prometheus.Collector for an event stream. Safe for
+// concurrent use.
+type Collector struct {
+	// Clock is used to compute lag. Defaults to gobyairship.RealClock.
+	Clock gobyairship.Clock
+
+	received     *prometheus.Desc
+	errors       *prometheus.Desc
+	decodeErrors *prometheus.Desc
+	reconnects   *prometheus.Desc
+	lag          *prometheus.Desc
+
+	mu       sync.Mutex
+	types    map[events.Type]uint64
+	errs     uint64
+	decoding uint64
+	lagged   time.Duration
+	observed bool
+	stream   *events.Stream
+}
+
+// NewCollector creates a Collector whose metrics have the given constant
+// labels, such as the name of the consumer. labels may be nil.
+func NewCollector(labels prometheus.Labels) *Collector {
+	desc := func(name, help string, variable ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", name), help, variable, labels)
+	}
+	return &Collector{
+		received:     desc("received_total", "Events received by type.", "type"),
+		errors:       desc("errors_total", "Errors which interrupted the stream."),
+		decodeErrors: desc("decode_errors_total", "Errors which interrupted the stream because an event failed to decode."),
+		reconnects:   desc("reconnects_total", "Reconnects of the stream."),
+		lag:          desc("lag_seconds", "Time between the last event being processed by Urban Airship and received."),
+		types:        map[events.Type]uint64{},
+	}
+}
+
+// Attach a Stream to report its reconnects.
+func (c *Collector) Attach(s *events.Stream) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stream = s
+}
+
+// Observe a received event.
+func (c *Collector) Observe(ev *events.Event) {
+	clock := c.Clock
+	if clock == nil {
+		clock = gobyairship.RealClock
+	}
+	lag := clock.Now().Sub(ev.Processed)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.types[ev.Type]++
+	c.lagged = lag
+	c.observed = true
+}
+
+// OnError counts an error which interrupted the stream. Suitable for use as a
+// StreamConfig's OnError.
+func (c *Collector) OnError(err error) {
+	if l, ok := err.(*gobyairship.LabeledError); ok {
+		err = l.Cause()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs++
+	switch err.(type) {
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		c.decoding++
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.received
+	ch <- c.errors
+	ch <- c.decodeErrors
+	ch <- c.reconnects
+	ch <- c.lag
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for t, n := range c.types {
+		ch <- prometheus.MustNewConstMetric(c.received, prometheus.CounterValue, float64(n), string(t))
+	}
+	ch <- prometheus.MustNewConstMetric(c.errors, prometheus.CounterValue, float64(c.errs))
+	ch <- prometheus.MustNewConstMetric(c.decodeErrors, prometheus.CounterValue, float64(c.decoding))
+	if c.stream != nil {
+		ch <- prometheus.MustNewConstMetric(c.reconnects, prometheus.CounterValue, float64(c.stream.Stats().Reconnects))
+	}
+	if c.observed {
+		ch <- prometheus.MustNewConstMetric(c.lag, prometheus.GaugeValue, c.lagged.Seconds())
+	}
+}
@@ -0,0 +1,112 @@
+package prometheus_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/events"
+	eventsprom "github.com/lytics/gobyairship/events/prometheus"
+	"github.com/lytics/gobyairship/uatest"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// collect the Collector's metrics by name and label values.
+func collect(t *testing.T, c prometheus.Collector) map[string]float64 {
+	ch := make(chan prometheus.Metric, 100)
+	c.Collect(ch)
+	close(ch)
+	values := map[string]float64{}
+	for m := range ch {
+		pb := &dto.Metric{}
+		if err := m.Write(pb); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		key := m.Desc().String()
+		for _, l := range pb.GetLabel() {
+			key += " " + l.GetName() + "=" + l.GetValue()
+		}
+		values[key] = pb.GetCounter().GetValue() + pb.GetGauge().GetValue()
+	}
+	return values
+}
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := eventsprom.NewCollector(prometheus.Labels{"consumer": "test"})
+	c.Clock = uatest.NewClock(now)
+
+	descs := make(chan *prometheus.Desc, 10)
+	c.Describe(descs)
+	if len(descs) != 5 {
+		t.Errorf("Expected 5 descriptions but found %d", len(descs))
+	}
+
+	c.Observe(&events.Event{Type: events.TypeOpen, Processed: now.Add(-time.Minute)})
+	c.Observe(&events.Event{Type: events.TypeOpen, Processed: now.Add(-3 * time.Second)})
+	c.Observe(&events.Event{Type: events.TypeClose, Processed: now.Add(-2 * time.Second)})
+	c.OnError(&gobyairship.LabeledError{Err: &json.SyntaxError{}})
+	c.OnError(errors.New("connection reset"))
+
+	values := collect(t, c)
+	expected := map[string]float64{
+		"gobyairship_events_received_total consumer=test type=OPEN":  2,
+		"gobyairship_events_received_total consumer=test type=CLOSE": 1,
+		"gobyairship_events_errors_total consumer=test":              2,
+		"gobyairship_events_decode_errors_total consumer=test":       1,
+		"gobyairship_events_lag_seconds consumer=test":               2,
+	}
+	if len(values) != len(expected) {
+		t.Errorf("Expected %d metrics but found %v", len(expected), values)
+	}
+	for k, v := range expected {
+		if values[k] != v {
+			t.Errorf("Expected %s to be %v but found %v", k, v, values[k])
+		}
+	}
+}
+
+func TestCollectorAttach(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	c := eventsprom.NewCollector(nil)
+	client := gobyairship.NewClient("", "")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, err := events.NewStream(ctx, &urlClient{c: client, url: ts.URL}, events.StreamConfig{
+		Start:   events.StartFirst,
+		Backoff: gobyairship.ConstantBackoff(time.Millisecond),
+		OnError: c.OnError,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	c.Attach(s)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for collect(t, c)["gobyairship_events_reconnects_total"] < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected reconnects to be reported: %v", collect(t, c))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// urlClient sends all requests to a fixed URL.
+type urlClient struct {
+	c   *gobyairship.Client
+	url string
+}
+
+func (c *urlClient) Post(_ string, body interface{}, extra http.Header) (*http.Response, error) {
+	return c.c.Post(c.url, body, extra)
+}
@@ -0,0 +1,35 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package provides a Prometheus collector for event streams from Urban
+// Airship's Event API.
+//
+// A Collector counts the events and errors it observes and reports the
+// reconnects of an attached Stream:
+//
+//	c := prometheus.NewCollector(nil)
+//	s, err := events.NewStream(ctx, client, events.StreamConfig{OnError: c.OnError})
+//	if err != nil {
+//		return err
+//	}
+//	c.Attach(s)
+//	registry.MustRegister(c)
+//	for ev := range s.Events() {
+//		c.Observe(ev)
+//		// process ev
+//	}
+//
+// Unlike the core packages this package depends on
+// github.com/prometheus/client_golang.
+package prometheus
@@ -0,0 +1,54 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Quarantine receives the raw JSON of events which failed to decode or
+// validate along with the reason they were rejected.
+type Quarantine interface {
+	// Quarantine the raw event. Returning an error ends the event stream.
+	Quarantine(raw []byte, reason error) error
+}
+
+// QuarantineEvents sends events which fail to decode to q instead of ending
+// the stream. If tol is non-nil events failing ValidateEvent are quarantined
+// as well.
+//
+// Malformed JSON which cannot be separated from the rest of the stream still
+// ends the stream.
+func QuarantineEvents(q Quarantine, tol *Tolerances) Option {
+	return func(r *Response) {
+		r.quarantine = q
+		r.tolerances = tol
+	}
+}
+
+// QuarantineRecord is written by QuarantineWriter for each quarantined event.
+type QuarantineRecord struct {
+	Time   time.Time `json:"time"`
+	Reason string    `json:"reason"`
+	Raw    string    `json:"raw"`
+}
+
+// QuarantineWriter writes quarantined events as newline delimited JSON
+// QuarantineRecords. Safe for concurrent use.
+type QuarantineWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewQuarantineWriter creates a QuarantineWriter which writes to w.
+func NewQuarantineWriter(w io.Writer) *QuarantineWriter {
+	return &QuarantineWriter{enc: json.NewEncoder(w)}
+}
+
+// Quarantine implements the Quarantine interface.
+func (q *QuarantineWriter) Quarantine(raw []byte, reason error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.enc.Encode(&QuarantineRecord{Time: time.Now(), Reason: reason.Error(), Raw: string(raw)})
+}
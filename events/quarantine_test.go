@@ -0,0 +1,55 @@
+package events_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+func TestQuarantine(t *testing.T) {
+	t.Parallel()
+	const body = `{"id":"a","type":"CLOSE","offset":"1","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}
+{"id":"b","type":"CLOSE","offset":true,"occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}
+{"id":"","type":"CLOSE","offset":"3","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}
+{"id":"d","type":"CLOSE","offset":"4","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}
+`
+	buf := &bytes.Buffer{}
+	q := events.NewQuarantineWriter(buf)
+	hr := &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(body))}
+	resp, err := events.NewResponse(hr, events.QuarantineEvents(q, &events.DefaultTolerances))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ids := ""
+	for ev := range resp.Events() {
+		ids += ev.ID
+	}
+	if resp.Err() != io.EOF {
+		t.Errorf("Unexpected error: %v", resp.Err())
+	}
+	if ids != "ad" {
+		t.Errorf("Expected events a and d but received %q", ids)
+	}
+
+	scanner := bufio.NewScanner(buf)
+	n := 0
+	for scanner.Scan() {
+		n++
+		rec := events.QuarantineRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("Error decoding quarantine record: %v", err)
+		}
+		if rec.Reason == "" || rec.Raw == "" || rec.Time.IsZero() {
+			t.Errorf("Incomplete quarantine record: %#v", rec)
+		}
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 quarantined events but found %d", n)
+	}
+}
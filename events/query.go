@@ -0,0 +1,115 @@
+package events
+
+import "time"
+
+// Query builds a Request. Criteria are combined into a single Filter so
+// events must match all of them. Use Build to get the validated Request:
+//
+//	req, err := events.NewQuery().
+//		Types(events.TypeOpen, events.TypeSend).
+//		Devices(events.DeviceIOS).
+//		Latency(5 * time.Minute).
+//		Partition(8, 2).
+//		Start(events.StartFirst).
+//		Build()
+type Query struct {
+	req    Request
+	filter Filter
+}
+
+// NewQuery creates a Query for all events starting at the end of the stream.
+func NewQuery() *Query {
+	return &Query{req: Request{Start: StartLast}}
+}
+
+// Start sets where the stream starts and clears any Offset.
+func (q *Query) Start(st Start) *Query {
+	q.req.Start = st
+	q.req.Offset = nil
+	return q
+}
+
+// Offset resumes the stream from an event's offset.
+func (q *Query) Offset(offset uint64) *Query {
+	q.req.Start = StartOffset
+	q.req.Offset = &offset
+	return q
+}
+
+// Types adds event types to match.
+func (q *Query) Types(types ...Type) *Query {
+	q.filter.Types = append(q.filter.Types, types...)
+	return q
+}
+
+// Devices adds device types to match.
+func (q *Query) Devices(types ...DeviceType) *Query {
+	q.filter.DeviceTypes = append(q.filter.DeviceTypes, types...)
+	return q
+}
+
+// Channels adds individual devices to match such as IOSChannel(id).
+func (q *Query) Channels(devices ...Device) *Query {
+	q.filter.Devices = append(q.filter.Devices, devices...)
+	return q
+}
+
+// Pushes adds push IDs to match.
+func (q *Query) Pushes(pushIDs ...string) *Query {
+	q.filter.Notification = append(q.filter.Notification, FilterPush(pushIDs...).Notification...)
+	return q
+}
+
+// Groups adds group IDs to match.
+func (q *Query) Groups(groupIDs ...string) *Query {
+	q.filter.Notification = append(q.filter.Notification, FilterGroup(groupIDs...).Notification...)
+	return q
+}
+
+// Latency limits events to those processed within d of occurring.
+func (q *Query) Latency(d time.Duration) *Query {
+	q.filter.Latency = FilterMaxLatency(d).Latency
+	return q
+}
+
+// Partition requests the selection partition of count partitions.
+func (q *Query) Partition(count, selection int) *Query {
+	q.req.Subset = SubsetPartition(count, selection)
+	return q
+}
+
+// Sample requests a random proportion of events between 0 and 1.
+func (q *Query) Sample(proportion float64) *Query {
+	q.req.Subset = SubsetSample(proportion)
+	return q
+}
+
+// Version sets the Event API version to request.
+func (q *Query) Version(v int) *Query {
+	q.req.Version = v
+	return q
+}
+
+// Build returns a new Request or an error if it would be invalid.
+func (q *Query) Build() (*Request, error) {
+	req := q.req
+	if q.filter.Types != nil || q.filter.DeviceTypes != nil || q.filter.Notification != nil ||
+		q.filter.Devices != nil || q.filter.Latency != 0 {
+		f := Filter{
+			Types:        append([]Type(nil), q.filter.Types...),
+			DeviceTypes:  append([]DeviceType(nil), q.filter.DeviceTypes...),
+			Notification: append([]Push(nil), q.filter.Notification...),
+			Devices:      append([]Device(nil), q.filter.Devices...),
+			Latency:      q.filter.Latency,
+		}
+		req.Filters = []*Filter{&f}
+	}
+	if q.req.Offset != nil {
+		offset := *q.req.Offset
+		req.Offset = &offset
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
@@ -0,0 +1,70 @@
+package events_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+	q := events.NewQuery().
+		Types(events.TypeOpen, events.TypeSend).
+		Devices(events.DeviceIOS).
+		Channels(events.NamedUser("u")).
+		Pushes("p").
+		Groups("g").
+		Latency(5*time.Minute).
+		Partition(8, 2).
+		Start(events.StartFirst)
+	req, err := q.Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	buf, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{"start":"EARLIEST","filters":[{"types":["OPEN","SEND"],"device_types":["ios"],` +
+		`"notification":[{"push_id":"p"},{"group_id":"g"}],"devices":[{"named_user_id":"u"}],"latency":300000}],` +
+		`"subset":{"type":"PARTITION","count":8,"selection":2}}`
+	if string(buf) != expected {
+		t.Errorf("Expected:\n%s\nFound:\n%s", expected, buf)
+	}
+
+	// Built Requests are independent of the Query
+	q.Types(events.TypeClose).Offset(10)
+	if len(req.Filters[0].Types) != 2 || req.Offset != nil {
+		t.Errorf("Modifying the Query modified a built Request: %+v", req)
+	}
+	req, err = q.Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if req.Start != events.StartOffset || req.Offset == nil || *req.Offset != 10 || len(req.Filters[0].Types) != 3 {
+		t.Errorf("Unexpected request: %+v", req)
+	}
+
+	// Queries without criteria have no filters
+	req, err = events.NewQuery().Sample(0.5).Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if req.Start != events.StartLast || len(req.Filters) != 0 || req.Subset == nil {
+		t.Errorf("Unexpected request: %+v", req)
+	}
+
+	invalid := map[string]*events.Query{
+		"unknown type":      events.NewQuery().Types("OPENED"),
+		"invalid partition": events.NewQuery().Partition(2, 2),
+		"invalid sample":    events.NewQuery().Sample(2),
+		"invalid version":   events.NewQuery().Version(99),
+	}
+	for name, q := range invalid {
+		if _, err := q.Build(); err == nil {
+			t.Errorf("Expected an error with %s", name)
+		}
+	}
+}
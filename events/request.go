@@ -1,9 +1,13 @@
 package events
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
+
+	"github.com/lytics/gobyairship"
 )
 
 const DefaultEventsURL = "https://connect.urbanairship.com/api/events/"
@@ -26,6 +30,42 @@ type Client interface {
 	Post(url string, body interface{}, extra http.Header) (*http.Response, error)
 }
 
+// ContextClient is a Client which can abort requests when a context is done,
+// such as *gobyairship.Client.
+type ContextClient interface {
+	Client
+	PostContext(ctx context.Context, url string, body interface{}, extra http.Header) (*http.Response, error)
+}
+
+// versioner is implemented by Clients with a configurable API version such as
+// *gobyairship.Client.
+type versioner interface {
+	APIVersion() int
+}
+
+// clocker is implemented by Clients with a Clock such as *gobyairship.Client.
+type clocker interface {
+	TimeSource() gobyairship.Clock
+}
+
+// clientClock returns the Clock of c or gobyairship.RealClock if it has none.
+func clientClock(c Client) gobyairship.Clock {
+	if cl, ok := c.(clocker); ok {
+		return cl.TimeSource()
+	}
+	return gobyairship.RealClock
+}
+
+// DefaultVersion of the Event API requested when neither the Request nor the
+// Client specify one.
+const DefaultVersion = 3
+
+// SupportedVersions of the Event API this package can decode.
+var SupportedVersions = []int{3}
+
+// MediaType of Event API responses.
+const MediaType = "application/vnd.urbanairship+x-ndjson"
+
 // Start indicates whether to start at the earliest or latest offset. See
 // Request for details.
 type Start string
@@ -47,9 +87,23 @@ const (
 	DeviceAndroid DeviceType = "android"
 	DeviceIOS     DeviceType = "ios"
 	DeviceUser    DeviceType = "named_user"
+	DeviceEmail   DeviceType = "email"
+	DeviceSMS     DeviceType = "sms"
+	DeviceWeb     DeviceType = "web"
 	deviceUnknown DeviceType = "unknown"
 )
 
+// knownDeviceTypes are the DeviceTypes Filters may specify.
+var knownDeviceTypes = map[DeviceType]bool{
+	DeviceAmazon:  true,
+	DeviceAndroid: true,
+	DeviceIOS:     true,
+	DeviceUser:    true,
+	DeviceEmail:   true,
+	DeviceSMS:     true,
+	DeviceWeb:     true,
+}
+
 type Filter struct {
 	Types        []Type       `json:"types,omitempty"`
 	DeviceTypes  []DeviceType `json:"device_types,omitempty"`
@@ -58,6 +112,74 @@ type Filter struct {
 	Latency      int64        `json:"latency,omitempty"`
 }
 
+// FilterPush creates a Filter for events associated with any of the given
+// push IDs.
+func FilterPush(pushIDs ...string) *Filter {
+	f := &Filter{Notification: make([]Push, len(pushIDs))}
+	for i, id := range pushIDs {
+		f.Notification[i].PushID = id
+	}
+	return f
+}
+
+// FilterGroup creates a Filter for events associated with any of the given
+// group IDs, such as those of automation rules or pushes to local time.
+func FilterGroup(groupIDs ...string) *Filter {
+	f := &Filter{Notification: make([]Push, len(groupIDs))}
+	for i, id := range groupIDs {
+		f.Notification[i].GroupID = id
+	}
+	return f
+}
+
+// FilterDevices creates a Filter for events from any of the given devices,
+// such as for debugging individual devices:
+//
+//	events.FilterDevices(events.IOSChannel(id), events.NamedUser(user))
+func FilterDevices(devices ...Device) *Filter {
+	return &Filter{Devices: devices}
+}
+
+// FilterMaxLatency creates a Filter for events processed within d of
+// occurring, such as to exclude latent CLOSE events. Filters are unioned, so
+// to combine a latency with other criteria set the other fields on the
+// returned Filter rather than passing separate Filters.
+func FilterMaxLatency(d time.Duration) *Filter {
+	return &Filter{Latency: int64(d / time.Millisecond)}
+}
+
+// Validate returns an error if the Filter specifies unknown types or device
+// types, a negative latency, or notifications or devices without IDs.
+func (f *Filter) Validate() error {
+	if f == nil {
+		return errors.New("nil filter")
+	}
+	for _, t := range f.Types {
+		if !knownTypes[t] {
+			return fmt.Errorf("unknown type %q", t)
+		}
+	}
+	for _, dt := range f.DeviceTypes {
+		if !knownDeviceTypes[dt] {
+			return fmt.Errorf("unknown device type %q", dt)
+		}
+	}
+	for i, p := range f.Notification {
+		if p.PushID == "" && p.GroupID == "" {
+			return fmt.Errorf("notification %d has no push_id or group_id", i)
+		}
+	}
+	for i, d := range f.Devices {
+		if err := d.Validate(); err != nil {
+			return fmt.Errorf("device %d: %v", i, err)
+		}
+	}
+	if f.Latency < 0 {
+		return fmt.Errorf("negative latency %d", f.Latency)
+	}
+	return nil
+}
+
 type SubsetType string
 
 const (
@@ -134,6 +256,10 @@ type Request struct {
 	// request ended.
 	Offset *uint64 `json:"resume_offset,omitempty"`
 
+	// OpaqueOffset is used instead of Offset to resume from an Event's
+	// OpaqueOffset. Start must be StartOffset.
+	OpaqueOffset string `json:"-"`
+
 	// Filters specifies the criteria an event must meet to be returned in the
 	// response. Filters are unioned.
 	Filters []*Filter `json:"filters,omitempty"`
@@ -141,6 +267,11 @@ type Request struct {
 	// Subset allows iterating over a subset of events based on either random
 	// sampling or deterministic partitioning. See Subset type for details.
 	Subset *Subset `json:"subset,omitempty"`
+
+	// Version of the Event API to request. If zero the Client's version is used
+	// if it has one, otherwise DefaultVersion. Must be one of
+	// SupportedVersions.
+	Version int `json:"-"`
 }
 
 // Validate returns nil if the request is valid or an error if there's an
@@ -149,36 +280,136 @@ func (r *Request) Validate() error {
 	if r.Start != StartOffset && r.Offset != nil {
 		return fmt.Errorf("only specify one of Start or Offset: start=%s offset=%d", r.Start, *r.Offset)
 	}
+	if r.Start != StartOffset && r.OpaqueOffset != "" {
+		return fmt.Errorf("only specify one of Start or OpaqueOffset: start=%s offset=%q", r.Start, r.OpaqueOffset)
+	}
+	if r.Offset != nil && r.OpaqueOffset != "" {
+		return errors.New("only specify one of Offset or OpaqueOffset")
+	}
 	if r.Start != StartOffset && r.Start != StartFirst && r.Start != StartLast {
 		return fmt.Errorf("start must be one of %q, %q, or %q", StartFirst, StartLast, StartOffset)
 	}
+	for i, f := range r.Filters {
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("invalid filter %d: %v", i, err)
+		}
+	}
 	if err := r.Subset.Validate(); err != nil {
 		return err
 	}
+	if r.Version != 0 && !supported(r.Version) {
+		return fmt.Errorf("unsupported version %d; must be one of %v", r.Version, SupportedVersions)
+	}
 	return nil
 }
 
+// nonNil returns filters without nil entries so Fetch callers may pass nil to
+// mean no filter.
+func nonNil(filters []*Filter) []*Filter {
+	var out []*Filter
+	for _, f := range filters {
+		if f != nil {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func supported(version int) bool {
+	for _, v := range SupportedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
 // Fetch events using a Client. Filters and subset may be nil to fetch all
 // events. If error is non-nil Response will stream events until Close is
 // called.
 func Fetch(c Client, st Start, offset uint64, su *Subset, filters ...*Filter) (*Response, error) {
-	req := &Request{Start: st, Subset: su, Filters: filters}
+	req := &Request{Start: st, Subset: su, Filters: nonNil(filters)}
 	if st == StartOffset {
 		req.Offset = &offset
 	}
+	return FetchRequest(c, req)
+}
+
+// FetchContext is like Fetch but the stream ends when ctx is done. The
+// Response's Events chan is closed and Err returns the context's error.
+//
+// If c is a ContextClient the HTTP request itself is aborted as well.
+func FetchContext(ctx context.Context, c Client, st Start, offset uint64, su *Subset, filters ...*Filter) (*Response, error) {
+	req := &Request{Start: st, Subset: su, Filters: nonNil(filters)}
+	if st == StartOffset {
+		req.Offset = &offset
+	}
+	return FetchRequestContext(ctx, c, req)
+}
+
+// FetchRequest fetches events using a Client and a manually created Request.
+// Options are passed on to the Response.
+func FetchRequest(c Client, req *Request, opts ...Option) (*Response, error) {
+	return FetchRequestContext(context.Background(), c, req, opts...)
+}
+
+// FetchRequestContext is like FetchRequest but the stream ends when ctx is
+// done. See FetchContext.
+func FetchRequestContext(ctx context.Context, c Client, req *Request, opts ...Option) (*Response, error) {
+	r, _, err := fetchRequest(ctx, c, req, opts...)
+	return r, err
+}
+
+// fetchRequest implements FetchRequestContext. If the request is rate limited
+// the duration of the response's Retry-After header is returned along with
+// LimitExceeded.
+func fetchRequest(ctx context.Context, c Client, req *Request, opts ...Option) (*Response, time.Duration, error) {
 	if err := req.Validate(); err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	version := req.Version
+	if version == 0 {
+		version = DefaultVersion
+		if v, ok := c.(versioner); ok {
+			version = v.APIVersion()
+		}
+		if !supported(version) {
+			return nil, 0, fmt.Errorf("client version %d unsupported; must be one of %v", version, SupportedVersions)
+		}
 	}
 
 	// Override Accept header with ndjson type
-	extra := http.Header{"Accept": []string{"application/vnd.urbanairship+x-ndjson;version=3;"}}
+	extra := http.Header{"Accept": []string{fmt.Sprintf("%s;version=%d;", MediaType, version)}}
 
 	// Valid request, post to API
-	resp, err := c.Post(evurl, req, extra)
+	var resp *http.Response
+	var err error
+	if cc, ok := c.(ContextClient); ok {
+		resp, err = cc.PostContext(ctx, evurl, req, extra)
+	} else {
+		resp, err = c.Post(evurl, req, extra)
+	}
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		resp.Body.Close()
+		return nil, 0, err
+	}
+	if resp.StatusCode == http.StatusPaymentRequired {
+		resp.Body.Close()
+		return nil, gobyairship.RetryAfter(resp.Header, clientClock(c).Now()), LimitExceeded
 	}
 
-	// Valid response, return events iterator
-	return NewResponse(resp)
+	// Valid response, return events iterator. The Client's Clock may be
+	// overridden by the options.
+	r, err := NewResponse(resp, append([]Option{WithClock(clientClock(c))}, opts...)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	if ctx.Done() != nil {
+		go r.watch(ctx)
+	}
+	return r, 0, nil
 }
@@ -1,13 +1,18 @@
 package events
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/lytics/gobyairship"
 )
 
 // LimitExceeded is returned when the number of simultaneous connections to
@@ -15,6 +20,56 @@ import (
 // Required status which is translated into this error.
 var LimitExceeded = errors.New("request was rate limited")
 
+// maxErrorBody is the most of an error response's body read into an APIError.
+const maxErrorBody = 64 * 1024
+
+// APIError is returned when Urban Airship responds to an events request with
+// a status other than 200 or 402, such as 400 for a malformed filter or 403
+// for credentials without access to the Event API.
+type APIError struct {
+	// StatusCode is the response's HTTP status.
+	StatusCode int `json:"-"`
+
+	// OperationID identifies the failed request for support requests. It's
+	// the UA-Operation-Id header or, if missing, the body's operation_id.
+	OperationID string `json:"operation_id"`
+
+	// Message and Code are the error and error_code from the body, if it was
+	// JSON, and Details any further details.
+	Message string          `json:"error"`
+	Code    int             `json:"error_code"`
+	Details json.RawMessage `json:"details,omitempty"`
+
+	// Body is the raw response body, truncated to 64KB.
+	Body []byte `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("unexpected non-200 response: %d", e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.OperationID != "" {
+		msg += " (operation " + e.OperationID + ")"
+	}
+	return msg
+}
+
+// newAPIError reads and closes the body of a failed response.
+func newAPIError(resp *http.Response) *APIError {
+	e := &APIError{StatusCode: resp.StatusCode}
+	if resp.Body != nil {
+		e.Body, _ = ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		resp.Body.Close()
+	}
+	// Errors decoding the body are ignored since it's kept raw
+	json.Unmarshal(e.Body, e)
+	if id := resp.Header.Get("UA-Operation-Id"); id != "" {
+		e.OperationID = id
+	}
+	return e
+}
+
 // Event is the envelope for a single even from Urban Airship's event stream.
 // Users should inspect the Event's Type and call the corresponding method to
 // receive a typed event body.
@@ -33,20 +88,57 @@ type Event struct {
 	// that the connection is severed.
 	Offset uint64 `json:"offset,string"`
 
+	// OpaqueOffset is set instead of Offset when the API uses non-numeric
+	// offsets, such as the Real-Time Data Streaming API. Resume from it by
+	// setting the Request's OpaqueOffset. Streams and Checkpointers only
+	// support numeric offsets.
+	OpaqueOffset string `json:"-"`
+
 	// Body is the raw event body. Use the Type specific methods to unmarshal the
 	// body.
 	Body   json.RawMessage `json:"body"`
 	Device *Device         `json:"device,omitempty"`
+
+	pooled bool
+	acker  *Stream // set by Streams using ManualAck
+}
+
+// Lag returns how long after occurring the event was processed by Urban
+// Airship. It's negative if the device's clock was ahead.
+func (e *Event) Lag() time.Duration { return e.Processed.Sub(e.Occurred) }
+
+var eventPool = sync.Pool{New: func() interface{} { return &Event{} }}
+
+// newEvent returns an empty Event from the pool if PoolEvents is set.
+func (r *Response) newEvent() *Event {
+	if !r.pool {
+		return &Event{}
+	}
+	ev := eventPool.Get().(*Event)
+	ev.pooled = true
+	return ev
+}
+
+// Release returns an Event received from a Response using PoolEvents to the
+// pool. The Event and its Body must not be used afterwards. Release is a no-op
+// for Events which were not pooled.
+func (e *Event) Release() {
+	if !e.pooled {
+		return
+	}
+	// Keep the Body's memory for the next event
+	*e = Event{Body: e.Body[:0]}
+	eventPool.Put(e)
 }
 
 type Push struct {
 	// PushID is the unique identifier for the push, included in responses to the
 	// push API.
-	PushID string `json:"push_id"`
+	PushID string `json:"push_id,omitempty"`
 
 	// GroupID is an optional identifier of the group this push is associated
 	// with; group IDs are created by both automation and push to local time.
-	GroupID string `json:"group_id"`
+	GroupID string `json:"group_id,omitempty"`
 }
 
 type PushBody struct {
@@ -258,93 +350,882 @@ func (e *Event) InAppMessageExpiration() (*InAppMessageExpiration, error) {
 	return &exp, nil
 }
 
-func (e *Event) RichEvent() (*Push, error) {
+// RichEvent bodies are emitted for Message Center messages: RICH_DELIVERY
+// when a message is delivered to a device's inbox, RICH_READ when it's read,
+// and RICH_DELETE when it's deleted.
+type RichEvent struct {
+	Push
+
+	// VariantID is only present if the push was part of an experiment.
+	VariantID *int `json:"variant_id,omitempty"`
+
+	// MessageID identifies the message in the device's inbox.
+	MessageID string `json:"message_id,omitempty"`
+
+	// SessionID is present on RICH_READ and RICH_DELETE events.
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// RichEvent returns a RichEvent struct for RICH_DELIVERY, RICH_READ, and
+// RICH_DELETE events. Other events will return the WrongType error.
+func (e *Event) RichEvent() (*RichEvent, error) {
 	if e.Type != TypeRichDelete && e.Type != TypeRichDelivery && e.Type != TypeRichRead {
 		return nil, WrongType
 	}
-	p := Push{}
-	if err := json.Unmarshal(e.Body, &p); err != nil {
+	r := RichEvent{}
+	if err := json.Unmarshal(e.Body, &r); err != nil {
 		return nil, err
 	}
-	return &p, nil
+	return &r, nil
+}
+
+// ScreenViewed events are emitted when a user leaves a screen of the
+// application.
+type ScreenViewed struct {
+	// Screen is the name of the screen which was viewed.
+	Screen string `json:"viewed_screen"`
+
+	// PreviousScreen is the name of the screen viewed before Screen, if any.
+	PreviousScreen string `json:"previous_screen,omitempty"`
+
+	// Duration is how long Screen was viewed in milliseconds.
+	Duration int64 `json:"duration"`
+
+	SessionID string `json:"session_id"`
+}
+
+// ScreenViewed returns a ScreenViewed struct for SCREEN_VIEWED events. Other
+// events will return the WrongType error.
+func (e *Event) ScreenViewed() (*ScreenViewed, error) {
+	if e.Type != TypeScreenViewed {
+		return nil, WrongType
+	}
+	sv := ScreenViewed{}
+	if err := json.Unmarshal(e.Body, &sv); err != nil {
+		return nil, err
+	}
+	return &sv, nil
+}
+
+// Region events are emitted when a device enters or exits a geographic region.
+type Region struct {
+	// RegionID is Urban Airship's identifier for the region.
+	RegionID string `json:"region_id"`
+
+	// Name of the region.
+	Name string `json:"name,omitempty"`
+
+	// Source identifies the provider which detected the region event.
+	Source string `json:"source"`
+
+	// Action is either "enter" or "exit".
+	Action string `json:"action"`
+
+	SessionID string `json:"session_id"`
+}
+
+// Region returns a Region struct for REGION events. Other events will return
+// the WrongType error.
+func (e *Event) Region() (*Region, error) {
+	if e.Type != TypeRegion {
+		return nil, WrongType
+	}
+	r := Region{}
+	if err := json.Unmarshal(e.Body, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Control events are emitted instead of Send events for devices held out of
+// a push as part of an experiment's control group.
+type Control struct {
+	Push
+
+	// VariantID is only present if the push was part of an experiment.
+	VariantID *int `json:"variant_id,omitempty"`
+}
+
+// Control returns a Control struct for CONTROL events. Other events will
+// return the WrongType error.
+func (e *Event) Control() (*Control, error) {
+	if e.Type != TypeControl {
+		return nil, WrongType
+	}
+	c := Control{}
+	if err := json.Unmarshal(e.Body, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// SendAborted events are emitted when a push to a device is aborted before
+// being sent, such as when a personalized template fails to render.
+type SendAborted struct {
+	Push
+
+	// VariantID is only present if the push was part of an experiment.
+	VariantID *int `json:"variant_id,omitempty"`
+
+	// Reason the send was aborted.
+	Reason string `json:"reason"`
+}
+
+// SendAborted returns a SendAborted struct for SEND_ABORTED events. Other
+// events will return the WrongType error.
+func (e *Event) SendAborted() (*SendAborted, error) {
+	if e.Type != TypeSendAborted {
+		return nil, WrongType
+	}
+	sa := SendAborted{}
+	if err := json.Unmarshal(e.Body, &sa); err != nil {
+		return nil, err
+	}
+	return &sa, nil
+}
+
+// Email events are emitted as emails are delivered to and interacted with by
+// their recipients. The Event's Device contains the Email channel.
+type Email struct {
+	Push
+
+	// VariantID is only present if the email was sent as part of an
+	// experiment.
+	VariantID *int `json:"variant_id,omitempty"`
+
+	// MessageType is either "commercial" or "transactional".
+	MessageType string `json:"message_type"`
+
+	// URL is the link clicked for EMAIL_CLICK events.
+	URL string `json:"link_url,omitempty"`
+
+	// BounceReason describes why an email couldn't be delivered for
+	// EMAIL_BOUNCE events.
+	BounceReason string `json:"bounce_reason,omitempty"`
+}
+
+// Email returns an Email struct for EMAIL_DELIVERY, EMAIL_OPEN, EMAIL_CLICK,
+// EMAIL_BOUNCE, and EMAIL_UNSUBSCRIBE events. Other events will return the
+// WrongType error.
+func (e *Event) Email() (*Email, error) {
+	switch e.Type {
+	case TypeEmailDelivery, TypeEmailOpen, TypeEmailClick, TypeEmailBounce, TypeEmailUnsubscribe:
+	default:
+		return nil, WrongType
+	}
+	em := Email{}
+	if err := json.Unmarshal(e.Body, &em); err != nil {
+		return nil, err
+	}
+	return &em, nil
+}
+
+// SMSDelivery events report whether an SMS or MMS message was delivered to a
+// phone.
+type SMSDelivery struct {
+	Push
+
+	// Status is "delivered" or "failed".
+	Status string `json:"delivery_status"`
+
+	// ErrorCode is the carrier's error code for failed messages.
+	ErrorCode string `json:"error_code,omitempty"`
+
+	// MMS is true for MMS messages.
+	MMS bool `json:"mms,omitempty"`
+}
+
+// SMSDelivery returns an SMSDelivery struct for SMS_DELIVERY_REPORT events.
+// Other events will return the WrongType error.
+func (e *Event) SMSDelivery() (*SMSDelivery, error) {
+	if e.Type != TypeSMSDelivery {
+		return nil, WrongType
+	}
+	d := SMSDelivery{}
+	if err := json.Unmarshal(e.Body, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// MobileOriginated events are emitted for messages sent from a phone to an
+// SMS sender.
+type MobileOriginated struct {
+	// Keyword matched in the message, if any.
+	Keyword string `json:"keyword,omitempty"`
+
+	// Message is the text of the message.
+	Message string `json:"inbound_message"`
+}
+
+// MobileOriginated returns a MobileOriginated struct for MOBILE_ORIGINATED
+// events. Other events will return the WrongType error.
+func (e *Event) MobileOriginated() (*MobileOriginated, error) {
+	if e.Type != TypeMobileOriginated {
+		return nil, WrongType
+	}
+	mo := MobileOriginated{}
+	if err := json.Unmarshal(e.Body, &mo); err != nil {
+		return nil, err
+	}
+	return &mo, nil
+}
+
+// SMSOpt events are emitted when a phone opts in to or out of messages from an
+// SMS sender.
+type SMSOpt struct {
+	// Keyword which triggered the change, such as STOP, if any.
+	Keyword string `json:"keyword,omitempty"`
+
+	// Source of the change such as "keyword" or "api".
+	Source string `json:"source"`
+}
+
+// SMSOpt returns an SMSOpt struct for SMS_OPT_IN and SMS_OPT_OUT events. Other
+// events will return the WrongType error.
+func (e *Event) SMSOpt() (*SMSOpt, error) {
+	if e.Type != TypeSMSOptIn && e.Type != TypeSMSOptOut {
+		return nil, WrongType
+	}
+	o := SMSOpt{}
+	if err := json.Unmarshal(e.Body, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// WebClick events are emitted when a web notification is clicked in a
+// browser. The Event's Device contains the Web channel.
+type WebClick struct {
+	Push
+
+	// URL opened by the click.
+	URL string `json:"url,omitempty"`
+
+	// Browser the notification was clicked in such as "chrome".
+	Browser string `json:"browser_name,omitempty"`
+
+	SessionID string `json:"session_id"`
+}
+
+// WebClick returns a WebClick struct for WEB_CLICK events. Other events will
+// return the WrongType error.
+func (e *Event) WebClick() (*WebClick, error) {
+	if e.Type != TypeWebClick {
+		return nil, WrongType
+	}
+	c := WebClick{}
+	if err := json.Unmarshal(e.Body, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Custom events are defined and emitted by applications.
+type Custom struct {
+	// Name of the event.
+	Name string `json:"name"`
+
+	// Value is an optional number associated with the event.
+	Value json.Number `json:"value,omitempty"`
+
+	// Properties are app-defined. Use DecodeProperties to unmarshal them into
+	// an app-specific struct.
+	Properties map[string]json.RawMessage `json:"properties,omitempty"`
+
+	// InteractionID and InteractionType identify what the user interacted
+	// with, such as a message center message, to cause the event.
+	InteractionID   string `json:"interaction_id,omitempty"`
+	InteractionType string `json:"interaction_type,omitempty"`
+
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// DecodeProperties unmarshals the event's Properties into dst such as a
+// struct with json tags matching the property names.
+func (c *Custom) DecodeProperties(dst interface{}) error {
+	buf, err := json.Marshal(c.Properties)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, dst)
+}
+
+// Custom returns a Custom struct for CUSTOM events. Other events will return
+// the WrongType error.
+func (e *Event) Custom() (*Custom, error) {
+	if e.Type != TypeCustom {
+		return nil, WrongType
+	}
+	c := Custom{}
+	if err := json.Unmarshal(e.Body, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Attribute operation actions.
+const (
+	AttributeSet    = "SET"
+	AttributeRemove = "REMOVE"
+)
+
+// AttributeOperation is a single attribute being set on or removed from a
+// channel or named user.
+type AttributeOperation struct {
+	// Action is AttributeSet or AttributeRemove.
+	Action string `json:"action"`
+
+	// Key is the name of the attribute.
+	Key string `json:"key"`
+
+	// Value is the JSON encoded value the attribute was set to. It's empty
+	// when the attribute was removed.
+	Value json.RawMessage `json:"value,omitempty"`
+
+	// Timestamp is when the operation was performed.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AttributeOperations returns the operations of ATTRIBUTE_OPERATION events in
+// the order they were performed. Other events will return the WrongType error.
+func (e *Event) AttributeOperations() ([]AttributeOperation, error) {
+	if e.Type != TypeAttributeOperation {
+		return nil, WrongType
+	}
+	body := struct {
+		Mutations []AttributeOperation `json:"mutations"`
+	}{}
+	if err := json.Unmarshal(e.Body, &body); err != nil {
+		return nil, err
+	}
+	return body.Mutations, nil
+}
+
+// Subscription list change actions.
+const (
+	SubscriptionSubscribe   = "subscribe"
+	SubscriptionUnsubscribe = "unsubscribe"
+)
+
+// SubscriptionListChange events are emitted when a channel or named user
+// subscribes to or unsubscribes from a subscription list, such as from a
+// preference center.
+type SubscriptionListChange struct {
+	// ListID identifies the subscription list.
+	ListID string `json:"list_id"`
+
+	// Action is SubscriptionSubscribe or SubscriptionUnsubscribe.
+	Action string `json:"action"`
+
+	// Scope is the channel the change applies to such as "app", "web",
+	// "email", or "sms".
+	Scope string `json:"scope,omitempty"`
+}
+
+// SubscriptionListChange returns a SubscriptionListChange struct for
+// SUBSCRIPTION_LIST_CHANGE events. Other events will return the WrongType
+// error.
+func (e *Event) SubscriptionListChange() (*SubscriptionListChange, error) {
+	if e.Type != TypeSubscriptionListChange {
+		return nil, WrongType
+	}
+	c := SubscriptionListChange{}
+	if err := json.Unmarshal(e.Body, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Compliance actions.
+const (
+	// ComplianceDelete means all data for the Event's Device was deleted.
+	ComplianceDelete = "DELETE"
+
+	// ComplianceAnonymize means the Event's Device was anonymized so it no
+	// longer identifies a user.
+	ComplianceAnonymize = "ANONYMIZE"
+)
+
+// Compliance events are emitted when Urban Airship deletes or anonymizes a
+// user's data, such as for a GDPR erasure request. Downstream systems should
+// purge the data they hold for the Event's Device.
+type Compliance struct {
+	// Action is ComplianceDelete or ComplianceAnonymize.
+	Action string `json:"action"`
+
+	// RequestID identifies the data privacy request, if any.
+	RequestID string `json:"request_id,omitempty"`
+
+	// Reason for the action, if given.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Compliance returns a Compliance struct for COMPLIANCE events. Other events
+// will return the WrongType error.
+func (e *Event) Compliance() (*Compliance, error) {
+	if e.Type != TypeCompliance {
+		return nil, WrongType
+	}
+	c := Compliance{}
+	if err := json.Unmarshal(e.Body, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// DecodeBody unmarshals the event's body into dst, such as an app-specific
+// struct for CUSTOM events. If types are given and the event's Type is not one
+// of them WrongType is returned.
+func (e *Event) DecodeBody(dst interface{}, types ...Type) error {
+	if len(types) > 0 {
+		ok := false
+		for _, t := range types {
+			if e.Type == t {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return WrongType
+		}
+	}
+	return json.Unmarshal(e.Body, dst)
 }
 
 // Response streams Events from a Fetch call.
 type Response struct {
-	// ID is the UA-Operation-Id header from Urban Airship's response.
+	// ID is the UA-Operation-Id header from Urban Airship's response. Include
+	// it in logs and support requests.
 	ID string
 
+	// ContentType is the Content-Type header from Urban Airship's response,
+	// including the API version.
+	ContentType string
+
 	out  chan *Event
 	body io.ReadCloser
 
-	mu     *sync.Mutex
-	closed chan struct{}
-	err    error
+	mu       *sync.Mutex
+	closed   chan struct{}
+	done     chan struct{} // closed when decoding stops
+	err      error
+	skew     Skew
+	skipped  uint64
+	counters *responseCounters
+
+	// options
+	clock      gobyairship.Clock
+	loc        *time.Location
+	quarantine Quarantine
+	tolerances *Tolerances
+	maxAge     time.Duration
+	maxIdle    time.Duration
+	tracer     *Tracer
+	header     http.Header
+	bufSize    int
+	readAhead  int
+	pool       bool
+	workers    int
+	deadLetter func(raw []byte, err error)
+	dead       uint64
+	projection *projection
+	drift      func(*Drift)
+	drifts     map[Drift]bool
+
+	// backpressure
+	dropOldest   bool
+	dropped      uint64
+	onSaturated  func()
+	saturated    bool
+	spillEnabled bool
+	spillDir     string
+	spillMax     int64
+	spillPolicy  SpillPolicy
+	spillCodec   SpillCodec
+	spill        *spill
+}
+
+// Option configures optional Response behavior. Options are passed to
+// NewResponse or FetchRequest.
+type Option func(*Response)
+
+// DefaultBufferSize is the number of decoded events a Response buffers ahead of
+// its consumer by default.
+const DefaultBufferSize = 10
+
+// BufferSize sets the number of decoded events buffered ahead of the consumer
+// of Events. Larger buffers smooth over bursty consumers of high-throughput
+// streams at the cost of memory. Zero disables buffering.
+func BufferSize(n int) Option {
+	return func(r *Response) {
+		if n >= 0 {
+			r.bufSize = n
+		}
+	}
+}
+
+// ReadAhead reads the response body in chunks of up to n bytes, reducing the
+// number of reads for high-throughput streams.
+func ReadAhead(n int) Option {
+	return func(r *Response) { r.readAhead = n }
+}
+
+// DeadLetter skips events which fail to decode, including malformed JSON,
+// passing their raw bytes and the error to fn instead of ending the stream.
+// Skipped events are counted by DeadLettered. The stream is decoded line by
+// line so decoding can resume after malformed lines.
+//
+// fn must be safe for concurrent use when combined with DecodeWorkers. If
+// QuarantineEvents is also used the Quarantine takes precedence.
+func DeadLetter(fn func(raw []byte, err error)) Option {
+	return func(r *Response) { r.deadLetter = fn }
+}
+
+// PoolEvents reuses Events, including the memory backing their Body, from a
+// pool instead of allocating each one, significantly reducing allocations
+// for high-throughput streams.
+//
+// Consumers must call Release on every Event once finished with it and must
+// not retain the Event or its Body afterwards. Copy any fields which must
+// outlive the Event.
+func PoolEvents() Option {
+	return func(r *Response) { r.pool = true }
+}
+
+// NormalizeTime converts the Occurred and Processed timestamps of every event
+// to the given location (such as time.UTC) as events are decoded.
+func NormalizeTime(loc *time.Location) Option {
+	return func(r *Response) { r.loc = loc }
+}
+
+// SkipOlderThan discards events processed more than age ago, letting consumers
+// which only care about fresh events skip quickly through a backlog. Discarded
+// events are counted by Skipped.
+func SkipOlderThan(age time.Duration) Option {
+	return func(r *Response) { r.maxAge = age }
+}
+
+// WithClock sets the Clock used for the Response's time-based behavior such as
+// SkipOlderThan, MaxIdle, Batches, and Stats. Defaults to the Client's Clock
+// when fetched or gobyairship.RealClock.
+func WithClock(c gobyairship.Clock) Option {
+	return func(r *Response) {
+		if c != nil {
+			r.clock = c
+		}
+	}
+}
+
+// Skew summarizes events whose Occurred timestamp is after their Processed
+// timestamp, which happens when devices have bad clocks.
+type Skew struct {
+	// Events is the number of events which occurred after they were processed.
+	Events uint64
+
+	// Max is the largest difference between Occurred and Processed seen.
+	Max time.Duration
 }
 
 // NewResponse creates an events iterator from an http.Response. Fetch is a
 // shortcut for creating a Response, but users can manually create a Response
-// from a custom HTTP request with this function.
-func NewResponse(resp *http.Response) (*Response, error) {
+// from a custom HTTP request with this function. Statuses other than 200 and
+// 402 are returned as an *APIError after the body is read and closed.
+func NewResponse(resp *http.Response, opts ...Option) (*Response, error) {
 	if resp.StatusCode == 402 {
 		return nil, LimitExceeded
 	}
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("unexpected non-200 response: %d", resp.StatusCode)
+		return nil, newAPIError(resp)
 	}
 	r := &Response{
-		ID:     resp.Header.Get("UA-Operation-Id"),
-		out:    make(chan *Event, 10), // provide some buffering
-		body:   resp.Body,
-		mu:     new(sync.Mutex),
-		closed: make(chan struct{}),
-	}
-	go func() {
-		// Always close Event chan to indicate to callers that response is done.
-		defer close(r.out)
-		dec := json.NewDecoder(r.body)
-		for {
-			var ev Event
-			if err := dec.Decode(&ev); err != nil {
-				select {
-				case <-r.closed:
-					//TODO Only ignore "closed" errors
-					return
-				default:
-					r.mu.Lock()
-					defer r.mu.Unlock()
-					r.err = err
-					return
-				}
-			}
-			select {
-			case r.out <- &ev:
-			case <-r.closed:
-				return
-			}
+		ID:          resp.Header.Get("UA-Operation-Id"),
+		ContentType: resp.Header.Get("Content-Type"),
+		bufSize:     DefaultBufferSize,
+		clock:       gobyairship.RealClock,
+		header:      resp.Header,
+		body:        resp.Body,
+		mu:          new(sync.Mutex),
+		closed:      make(chan struct{}),
+		done:        make(chan struct{}),
+		counters:    newResponseCounters(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.out = make(chan *Event, r.bufSize)
+	if r.spillEnabled {
+		s, err := newSpill(r)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
 		}
-	}()
+		r.spill = s
+	}
+	if r.maxIdle > 0 {
+		b := &idleReader{ReadCloser: r.body, reads: make(chan bool), quit: make(chan struct{})}
+		r.body = b
+		go r.watchIdle(b)
+	}
+	go r.decode()
 	return r, nil
 }
 
+// decode events from the response body until an error occurs or the Response
+// is closed.
+func (r *Response) decode() {
+	// Always close Event chan to indicate to callers that response is done.
+	defer close(r.out)
+	defer close(r.done)
+	if r.spill != nil {
+		defer r.spill.finish()
+	}
+	var body io.Reader = &countingReader{Reader: r.body, r: r}
+	if r.readAhead > 0 {
+		body = bufio.NewReaderSize(body, r.readAhead)
+	}
+	if r.workers > 1 || r.deadLetter != nil {
+		r.decodeParallel(body)
+		return
+	}
+	dec := json.NewDecoder(body)
+	for {
+		ev, err := r.next(dec)
+		if err != nil {
+			r.fail(err)
+			return
+		}
+		if ev == nil {
+			// Event was quarantined
+			continue
+		}
+		if !r.emit(ev) {
+			return
+		}
+	}
+}
+
+// emit applies options to a decoded event and sends it to the Events chan.
+// Returns false if the Response was closed.
+func (r *Response) emit(ev *Event) bool {
+	if r.maxAge > 0 && r.clock.Now().Sub(ev.Processed) > r.maxAge {
+		r.mu.Lock()
+		r.skipped++
+		r.mu.Unlock()
+		ev.Release()
+		return true
+	}
+	if r.loc != nil {
+		ev.Occurred = ev.Occurred.In(r.loc)
+		ev.Processed = ev.Processed.In(r.loc)
+	}
+	if ev.Occurred.After(ev.Processed) {
+		r.addSkew(ev.Occurred.Sub(ev.Processed))
+	}
+	if r.drift != nil {
+		r.checkSchema(ev)
+	}
+	if r.projection != nil {
+		if r.drift != nil {
+			// Drift was checked on the full event
+			if err := r.projection.project(ev); err != nil {
+				ev.Release()
+				r.fail(err)
+				return false
+			}
+		}
+		if !r.projection.processed {
+			ev.Processed = time.Time{}
+		}
+	}
+	r.received(ev)
+	return r.send(ev)
+}
+
+// next decodes the next event. If the event is quarantined nil is returned
+// for both the event and error.
+func (r *Response) next(dec *json.Decoder) (*Event, error) {
+	if r.quarantine == nil && r.tracer == nil {
+		ev := r.newEvent()
+		if err := dec.Decode(r.wire(ev)); err != nil {
+			ev.Release()
+			return nil, err
+		}
+		return ev, nil
+	}
+
+	// Decode the raw event first so it may be traced or quarantined
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	return r.parse(raw)
+}
+
+// wire returns what an event is decoded into, applying the Projection unless
+// schema drift is checked on the full event.
+func (r *Response) wire(ev *Event) interface{} {
+	if r.projection != nil && r.drift == nil {
+		return r.projection.wire(ev)
+	}
+	return ev.wire()
+}
+
+// parse a raw event, tracing, validating, and quarantining it as configured.
+// If the event is quarantined nil is returned for both the event and error.
+func (r *Response) parse(raw []byte) (*Event, error) {
+	if r.tracer != nil {
+		r.tracer.sample(raw, r.ID, r.header)
+	}
+	ev := r.newEvent()
+	if err := json.Unmarshal(raw, r.wire(ev)); err != nil {
+		ev.Release()
+		switch {
+		case r.quarantine != nil:
+			return nil, r.quarantine.Quarantine(raw, err)
+		case r.deadLetter != nil:
+			r.mu.Lock()
+			r.dead++
+			r.mu.Unlock()
+			r.deadLetter(raw, err)
+			return nil, nil
+		}
+		return nil, err
+	}
+	if r.quarantine != nil && r.tolerances != nil {
+		if err := ValidateEvent(ev, *r.tolerances); err != nil {
+			ev.Release()
+			return nil, r.quarantine.Quarantine(raw, err)
+		}
+	}
+	return ev, nil
+}
+
+// fail ends the stream with an error unless the Response was closed.
+func (r *Response) fail(err error) {
+	select {
+	case <-r.closed:
+		//TODO Only ignore "closed" errors
+		return
+	default:
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.err = err
+	}
+}
+
 // Events returns a chan that emits Events until closed. Events is safe for
 // concurrent calls and shares an underlying chan. This means events are not
 // duplicated between multiple receivers.
 func (r *Response) Events() <-chan *Event { return r.out }
 
+// ReadBatch blocks until at least one event is available and returns up to max
+// events without waiting for more. If ctx is done first its error is returned.
+// Once the stream ends ReadBatch returns the stream's error from Err, or io.EOF
+// if it ended without one.
+//
+// ReadBatch shares the chan returned by Events so mixing the two splits events
+// between callers.
+func (r *Response) ReadBatch(ctx context.Context, max int) ([]*Event, error) {
+	if max < 1 {
+		max = 1
+	}
+	var batch []*Event
+	select {
+	case ev, ok := <-r.out:
+		if !ok {
+			return nil, r.endErr()
+		}
+		batch = append(batch, ev)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	for len(batch) < max {
+		select {
+		case ev, ok := <-r.out:
+			if !ok {
+				return batch, nil
+			}
+			batch = append(batch, ev)
+		default:
+			return batch, nil
+		}
+	}
+	return batch, nil
+}
+
+// endErr returns the error which ended the stream or io.EOF.
+func (r *Response) endErr() error {
+	if err := r.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
 // Close the events stream. Safe to call concurrently.
-func (r *Response) Close() {
+func (r *Response) Close() { r.closeErr(nil) }
+
+// closeErr closes the events stream and sets its error if it's not already
+// closed.
+func (r *Response) closeErr(err error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	select {
 	case <-r.closed:
 		return
 	default:
+		if err != nil && r.err == nil {
+			r.err = err
+		}
 		close(r.closed)
 		r.body.Close()
 	}
 }
 
+// watch closes the Response when ctx is done.
+func (r *Response) watch(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		r.closeErr(ctx.Err())
+	case <-r.closed:
+	case <-r.done:
+	}
+}
+
+func (r *Response) addSkew(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skew.Events++
+	if d > r.skew.Max {
+		r.skew.Max = d
+	}
+}
+
+// Skew returns statistics about events which occurred after they were
+// processed. The counts only increase over the life of the Response. Safe for
+// concurrent access.
+func (r *Response) Skew() Skew {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.skew
+}
+
+// DeadLettered returns the number of events passed to the DeadLetter callback.
+// Safe for concurrent access.
+func (r *Response) DeadLettered() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dead
+}
+
+// Skipped returns the number of events discarded by SkipOlderThan. Safe for
+// concurrent access.
+func (r *Response) Skipped() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.skipped
+}
+
 // Err returns the error which caused the event stream to end or nil. May be
 // checked when the chan returned by Events() is closed. Safe for concurrent
 // access.
@@ -0,0 +1,272 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DriftKind describes how an event differs from its type's bundled schema.
+type DriftKind string
+
+const (
+	// DriftNewField is a body field the schema doesn't define.
+	DriftNewField DriftKind = "new field"
+
+	// DriftTypeChange is a body field, or the body itself, whose JSON type
+	// differs from the schema's.
+	DriftTypeChange DriftKind = "type change"
+
+	// DriftMissingField is a required body field which is absent.
+	DriftMissingField DriftKind = "missing field"
+
+	// DriftNewType is an event type without a bundled schema.
+	DriftNewType DriftKind = "new type"
+)
+
+// Drift is a difference between an event and the bundled JSON Schema of its
+// type, usually caused by a change to the Event API.
+type Drift struct {
+	// ID and Type of the event which drifted.
+	ID   string
+	Type Type
+
+	Kind DriftKind
+
+	// Path of the body field such as "triggering_push.push_id". Elements of
+	// arrays are "[]", so "mutations[].key". Empty for the body itself.
+	Path string
+
+	// Expected and Found JSON types of type changes, such as "string" and
+	// "number".
+	Expected string
+	Found    string
+}
+
+func (d *Drift) String() string {
+	s := fmt.Sprintf("%s %s", d.Type, d.Kind)
+	if d.Path != "" {
+		s += " " + d.Path
+	}
+	if d.Kind == DriftTypeChange {
+		s += fmt.Sprintf(": expected %s but found %s", d.Expected, d.Found)
+	}
+	return s + " (event " + d.ID + ")"
+}
+
+// schemaTypes are the one or more types of a JSON Schema's type keyword.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = schemaTypes{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(t))
+}
+
+// schema is the subset of JSON Schema used by bundledSchemas.
+type schema struct {
+	Type                 schemaTypes        `json:"type"`
+	Format               string             `json:"format"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	Required             []string           `json:"required"`
+
+	// closed is true if additionalProperties is false. Otherwise additional,
+	// if non-nil, is the schema of additional properties.
+	closed     bool
+	additional *schema
+}
+
+func (s *schema) UnmarshalJSON(b []byte) error {
+	type plain schema
+	if err := json.Unmarshal(b, (*plain)(s)); err != nil {
+		return err
+	}
+	switch ap := bytes.TrimSpace(s.AdditionalProperties); {
+	case string(ap) == "false":
+		s.closed = true
+	case len(ap) > 0 && ap[0] == '{':
+		s.additional = &schema{}
+		return json.Unmarshal(ap, s.additional)
+	}
+	return nil
+}
+
+var (
+	schemasOnce sync.Once
+	schemas     map[Type]*schema
+)
+
+func loadSchemas() map[Type]*schema {
+	schemasOnce.Do(func() {
+		if err := json.Unmarshal([]byte(bundledSchemas), &schemas); err != nil {
+			panic("events: invalid bundled schemas: " + err.Error())
+		}
+	})
+	return schemas
+}
+
+// Schema returns the bundled JSON Schema of a type's body, or nil if the type
+// has no schema.
+func Schema(t Type) json.RawMessage {
+	var raw map[Type]json.RawMessage
+	if err := json.Unmarshal([]byte(bundledSchemas), &raw); err != nil {
+		return nil
+	}
+	return raw[t]
+}
+
+// CheckSchema returns how an event's body differs from the bundled schema of
+// its type. Events of types without a schema have a single DriftNewType.
+// Events without a body aren't checked. Drifts are sorted by path.
+func CheckSchema(ev *Event) []*Drift {
+	s, ok := loadSchemas()[ev.Type]
+	if !ok {
+		return []*Drift{{ID: ev.ID, Type: ev.Type, Kind: DriftNewType}}
+	}
+	if len(ev.Body) == 0 || string(ev.Body) == "null" {
+		return nil
+	}
+	var body interface{}
+	dec := json.NewDecoder(bytes.NewReader(ev.Body))
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil {
+		return []*Drift{{ID: ev.ID, Type: ev.Type, Kind: DriftTypeChange, Expected: "object", Found: "invalid JSON"}}
+	}
+	var drifts []*Drift
+	seen := map[Drift]bool{}
+	s.check("", body, func(d Drift) {
+		// Arrays may repeat the same drift for each element
+		if seen[d] {
+			return
+		}
+		seen[d] = true
+		d.ID, d.Type = ev.ID, ev.Type
+		drifts = append(drifts, &d)
+	})
+	sort.Stable(driftsByPath(drifts))
+	return drifts
+}
+
+type driftsByPath []*Drift
+
+func (d driftsByPath) Len() int           { return len(d) }
+func (d driftsByPath) Less(i, j int) bool { return d[i].Path < d[j].Path }
+func (d driftsByPath) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// jsonType returns the JSON Schema type of a value decoded with UseNumber.
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
+
+func (s *schema) allows(typ string) bool {
+	if len(s.Type) == 0 {
+		return true
+	}
+	for _, t := range s.Type {
+		if t == typ || (t == "number" && typ == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// check v against s, calling report for each drift. Nulls are treated as
+// absent fields.
+func (s *schema) check(path string, v interface{}, report func(Drift)) {
+	if v == nil {
+		return
+	}
+	typ := jsonType(v)
+	if !s.allows(typ) {
+		report(Drift{Kind: DriftTypeChange, Path: path, Expected: strings.Join(s.Type, " or "), Found: typ})
+		return
+	}
+	switch v := v.(type) {
+	case string:
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				report(Drift{Kind: DriftTypeChange, Path: path, Expected: "date-time", Found: typ})
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for _, el := range v {
+				s.Items.check(path+"[]", el, report)
+			}
+		}
+	case map[string]interface{}:
+		for _, k := range s.Required {
+			if _, ok := v[k]; !ok {
+				report(Drift{Kind: DriftMissingField, Path: join(path, k)})
+			}
+		}
+		for k, el := range v {
+			switch ps, ok := s.Properties[k]; {
+			case ok:
+				ps.check(join(path, k), el, report)
+			case s.closed:
+				report(Drift{Kind: DriftNewField, Path: join(path, k), Found: jsonType(el)})
+			case s.additional != nil:
+				s.additional.check(join(path, k), el, report)
+			}
+		}
+	}
+}
+
+// ReportSchemaDrift checks every event against the bundled schema of its type
+// with CheckSchema, calling fn with each distinct drift the first time it's
+// seen on the Response. Drifts are distinct if their Type, Kind, Path, or
+// Found differ. Events are emitted whether or not they drift.
+//
+// Checking decodes every body, so this is best used on a sample of the stream
+// or while qualifying new Event API versions. fn is called from the
+// Response's decoding goroutine and must not block.
+func ReportSchemaDrift(fn func(*Drift)) Option {
+	return func(r *Response) {
+		r.drift = fn
+		r.drifts = map[Drift]bool{}
+	}
+}
+
+// checkSchema reports new drifts of an event.
+func (r *Response) checkSchema(ev *Event) {
+	for _, d := range CheckSchema(ev) {
+		key := Drift{Type: d.Type, Kind: d.Kind, Path: d.Path, Found: d.Found}
+		if r.drifts[key] {
+			continue
+		}
+		r.drifts[key] = true
+		r.drift(d)
+	}
+}
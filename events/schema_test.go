@@ -0,0 +1,131 @@
+package events_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+func TestSchemaFixtures(t *testing.T) {
+	t.Parallel()
+	// open.json predates the Event API renaming these fields to
+	// triggering_push and last_delivered
+	legacy := map[string]bool{"converting_push": true, "last_push_received": true}
+	for fname, typ := range filterTypes {
+		if typ == "" {
+			continue
+		}
+		fn := fmt.Sprintf("%s/%s.json", os.ExpandEnv(testDataPath), fname)
+		raw, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatalf("Error reading %q: %v", fn, err)
+		}
+		if events.Schema(typ) == nil {
+			t.Errorf("Expected a schema for %s", typ)
+		}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		for dec.More() {
+			ev := &events.Event{}
+			if err := dec.Decode(ev); err != nil {
+				t.Fatalf("Unexpected error decoding %s: %v", fname, err)
+			}
+			for _, d := range events.CheckSchema(ev) {
+				if d.Type == events.TypeOpen && d.Kind == events.DriftNewField && legacy[d.Path] {
+					continue
+				}
+				t.Errorf("Unexpected drift in %s: %s", fname, d)
+			}
+		}
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	t.Parallel()
+	ev := &events.Event{
+		ID:   "drift",
+		Type: events.TypeInAppMessageResolution,
+		Body: json.RawMessage(`{"push_id":"p","triggering_push":{"push_id":1,"new":true},"time_sent":"yesterday","duration":1.5,"button_id":null,"extra":[1]}`),
+	}
+	expected := []events.Drift{
+		{Kind: events.DriftNewField, Path: "extra", Found: "array"},
+		{Kind: events.DriftTypeChange, Path: "duration", Expected: "integer", Found: "number"},
+		{Kind: events.DriftNewField, Path: "triggering_push.new", Found: "boolean"},
+		{Kind: events.DriftTypeChange, Path: "triggering_push.push_id", Expected: "string", Found: "integer"},
+		{Kind: events.DriftTypeChange, Path: "time_sent", Expected: "date-time", Found: "string"},
+		{Kind: events.DriftMissingField, Path: "type"},
+	}
+	drifts := events.CheckSchema(ev)
+	found := map[events.Drift]bool{}
+	for _, d := range drifts {
+		if d.ID != ev.ID || d.Type != ev.Type {
+			t.Errorf("Expected drift of %s %s but found %s %s", ev.Type, ev.ID, d.Type, d.ID)
+		}
+		d.ID, d.Type = "", ""
+		found[*d] = true
+	}
+	for _, d := range expected {
+		if !found[d] {
+			t.Errorf("Expected drift %+v in %v", d, found)
+		}
+	}
+	if len(drifts) != len(expected) {
+		t.Errorf("Expected %d drifts but found %d", len(expected), len(drifts))
+	}
+
+	// Array elements drift under []
+	ev = &events.Event{ID: "attrs", Type: events.TypeAttributeOperation, Body: json.RawMessage(`{"mutations":[{"action":"SET","key":"a","x":1},{"action":"SET","key":"b","x":2}]}`)}
+	if drifts := events.CheckSchema(ev); len(drifts) != 1 || drifts[0].Path != "mutations[].x" {
+		t.Errorf("Expected one new field mutations[].x but found %v", drifts)
+	}
+
+	// Bodies which aren't objects and new types drift
+	ev = &events.Event{ID: "array", Type: events.TypeSend, Body: json.RawMessage(`[]`)}
+	if drifts := events.CheckSchema(ev); len(drifts) != 1 || drifts[0].Kind != events.DriftTypeChange || drifts[0].Path != "" {
+		t.Errorf("Expected the body to change type but found %v", drifts)
+	}
+	ev = &events.Event{ID: "new", Type: "NEW_TYPE", Body: json.RawMessage(`{}`)}
+	if drifts := events.CheckSchema(ev); len(drifts) != 1 || drifts[0].Kind != events.DriftNewType {
+		t.Errorf("Expected a new type but found %v", drifts)
+	}
+	if events.Schema("NEW_TYPE") != nil {
+		t.Errorf("Expected no schema for new types")
+	}
+}
+
+func TestReportSchemaDrift(t *testing.T) {
+	t.Parallel()
+	const line = `{"id":"%d","type":"CLOSE","offset":"%d","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{"session_id":"s","new":%d}}` + "\n"
+	buf := &bytes.Buffer{}
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(buf, line, i, i, i)
+	}
+	hr := &http.Response{StatusCode: 200, Body: ioutil.NopCloser(buf)}
+	var drifts []*events.Drift
+	resp, err := events.NewResponse(hr, events.ReportSchemaDrift(func(d *events.Drift) { drifts = append(drifts, d) }))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	n := 0
+	for range resp.Events() {
+		n++
+	}
+	if n != 3 {
+		t.Errorf("Expected drifting events to be emitted but received %d", n)
+	}
+
+	// The drift is only reported for the first event
+	if len(drifts) != 1 {
+		t.Fatalf("Expected 1 drift but found %d", len(drifts))
+	}
+	expected := &events.Drift{ID: "0", Type: events.TypeClose, Kind: events.DriftNewField, Path: "new", Found: "integer"}
+	if !reflect.DeepEqual(drifts[0], expected) {
+		t.Errorf("Expected %+v but found %+v", expected, drifts[0])
+	}
+}
@@ -0,0 +1,813 @@
+package events
+
+// bundledSchemas are the JSON Schemas of each type's body, keyed by type. The
+// schemas only use the type, format, properties, additionalProperties, items,
+// and required keywords.
+//
+// Keep these in sync with the body types in response.go: properties are the
+// fields documented by the Event API, required are the fields present on
+// every event of the type, and additionalProperties is false so new fields
+// are reported as drift.
+const bundledSchemas = `
+{
+	"PUSH_BODY": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"payload": {
+				"type": "string"
+			},
+			"trimmed": {
+				"type": "boolean"
+			},
+			"resource": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id",
+			"payload"
+		]
+	},
+	"OPEN": {
+		"type": "object",
+		"properties": {
+			"last_delivered": {
+				"type": "object",
+				"properties": {
+					"push_id": {
+						"type": "string"
+					},
+					"group_id": {
+						"type": "string"
+					}
+				},
+				"additionalProperties": false
+			},
+			"triggering_push": {
+				"type": "object",
+				"properties": {
+					"push_id": {
+						"type": "string"
+					},
+					"group_id": {
+						"type": "string"
+					}
+				},
+				"additionalProperties": false
+			},
+			"session_id": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false
+	},
+	"SEND": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"variant_id": {
+				"type": "integer"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id"
+		]
+	},
+	"CLOSE": {
+		"type": "object",
+		"properties": {
+			"session_id": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"session_id"
+		]
+	},
+	"TAG_CHANGE": {
+		"type": "object",
+		"properties": {
+			"add": {
+				"type": "object",
+				"additionalProperties": {
+					"type": "array",
+					"items": {
+						"type": "string"
+					}
+				}
+			},
+			"remove": {
+				"type": "object",
+				"additionalProperties": {
+					"type": "array",
+					"items": {
+						"type": "string"
+					}
+				}
+			},
+			"current": {
+				"type": "object",
+				"additionalProperties": {
+					"type": "array",
+					"items": {
+						"type": "string"
+					}
+				}
+			}
+		},
+		"additionalProperties": false
+	},
+	"UNINSTALL": {
+		"type": "object",
+		"properties": {},
+		"additionalProperties": false
+	},
+	"FIRST_OPEN": {
+		"type": "object",
+		"properties": {
+			"session_id": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false
+	},
+	"CUSTOM": {
+		"type": "object",
+		"properties": {
+			"name": {
+				"type": "string"
+			},
+			"value": {
+				"type": "number"
+			},
+			"properties": {
+				"type": "object"
+			},
+			"interaction_id": {
+				"type": "string"
+			},
+			"interaction_type": {
+				"type": "string"
+			},
+			"session_id": {
+				"type": "string"
+			},
+			"transaction": {
+				"type": "string"
+			},
+			"customer_id": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"name"
+		]
+	},
+	"LOCATION": {
+		"type": "object",
+		"properties": {
+			"latitude": {
+				"type": [
+					"string",
+					"number"
+				]
+			},
+			"longitude": {
+				"type": [
+					"string",
+					"number"
+				]
+			},
+			"foreground": {
+				"type": "boolean"
+			},
+			"session_id": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"latitude",
+			"longitude"
+		]
+	},
+	"RICH_DELIVERY": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"variant_id": {
+				"type": "integer"
+			},
+			"message_id": {
+				"type": "string"
+			},
+			"session_id": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id"
+		]
+	},
+	"RICH_READ": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"variant_id": {
+				"type": "integer"
+			},
+			"message_id": {
+				"type": "string"
+			},
+			"session_id": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id"
+		]
+	},
+	"RICH_DELETE": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"variant_id": {
+				"type": "integer"
+			},
+			"message_id": {
+				"type": "string"
+			},
+			"session_id": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id"
+		]
+	},
+	"IN_APP_MESSAGE_DISPLAY": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"triggering_push": {
+				"type": "object",
+				"properties": {
+					"push_id": {
+						"type": "string"
+					},
+					"group_id": {
+						"type": "string"
+					}
+				},
+				"additionalProperties": false
+			},
+			"session_id": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id"
+		]
+	},
+	"IN_APP_MESSAGE_RESOLUTION": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"triggering_push": {
+				"type": "object",
+				"properties": {
+					"push_id": {
+						"type": "string"
+					},
+					"group_id": {
+						"type": "string"
+					}
+				},
+				"additionalProperties": false
+			},
+			"session_id": {
+				"type": "string"
+			},
+			"time_sent": {
+				"type": "string",
+				"format": "date-time"
+			},
+			"type": {
+				"type": "string"
+			},
+			"duration": {
+				"type": "integer"
+			},
+			"button_id": {
+				"type": "string"
+			},
+			"button_group": {
+				"type": "string"
+			},
+			"button_description": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id",
+			"type"
+		]
+	},
+	"IN_APP_MESSAGE_EXPIRATION": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"triggering_push": {
+				"type": "object",
+				"properties": {
+					"push_id": {
+						"type": "string"
+					},
+					"group_id": {
+						"type": "string"
+					}
+				},
+				"additionalProperties": false
+			},
+			"session_id": {
+				"type": "string"
+			},
+			"time_sent": {
+				"type": "string",
+				"format": "date-time"
+			},
+			"type": {
+				"type": "string"
+			},
+			"duration": {
+				"type": "integer"
+			},
+			"button_id": {
+				"type": "string"
+			},
+			"button_group": {
+				"type": "string"
+			},
+			"button_description": {
+				"type": "string"
+			},
+			"replacing_push": {
+				"type": "object",
+				"properties": {
+					"push_id": {
+						"type": "string"
+					},
+					"group_id": {
+						"type": "string"
+					}
+				},
+				"additionalProperties": false
+			},
+			"time_expired": {
+				"type": "string",
+				"format": "date-time"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id",
+			"type"
+		]
+	},
+	"SCREEN_VIEWED": {
+		"type": "object",
+		"properties": {
+			"viewed_screen": {
+				"type": "string"
+			},
+			"previous_screen": {
+				"type": "string"
+			},
+			"duration": {
+				"type": "integer"
+			},
+			"session_id": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"viewed_screen"
+		]
+	},
+	"REGION": {
+		"type": "object",
+		"properties": {
+			"region_id": {
+				"type": "string"
+			},
+			"name": {
+				"type": "string"
+			},
+			"source": {
+				"type": "string"
+			},
+			"action": {
+				"type": "string"
+			},
+			"session_id": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"region_id",
+			"action"
+		]
+	},
+	"CONTROL": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"variant_id": {
+				"type": "integer"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id"
+		]
+	},
+	"SEND_ABORTED": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"variant_id": {
+				"type": "integer"
+			},
+			"reason": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id",
+			"reason"
+		]
+	},
+	"EMAIL_DELIVERY": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"variant_id": {
+				"type": "integer"
+			},
+			"message_type": {
+				"type": "string"
+			},
+			"link_url": {
+				"type": "string"
+			},
+			"bounce_reason": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id",
+			"message_type"
+		]
+	},
+	"EMAIL_OPEN": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"variant_id": {
+				"type": "integer"
+			},
+			"message_type": {
+				"type": "string"
+			},
+			"link_url": {
+				"type": "string"
+			},
+			"bounce_reason": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id",
+			"message_type"
+		]
+	},
+	"EMAIL_CLICK": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"variant_id": {
+				"type": "integer"
+			},
+			"message_type": {
+				"type": "string"
+			},
+			"link_url": {
+				"type": "string"
+			},
+			"bounce_reason": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id",
+			"message_type"
+		]
+	},
+	"EMAIL_BOUNCE": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"variant_id": {
+				"type": "integer"
+			},
+			"message_type": {
+				"type": "string"
+			},
+			"link_url": {
+				"type": "string"
+			},
+			"bounce_reason": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id",
+			"message_type"
+		]
+	},
+	"EMAIL_UNSUBSCRIBE": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"variant_id": {
+				"type": "integer"
+			},
+			"message_type": {
+				"type": "string"
+			},
+			"link_url": {
+				"type": "string"
+			},
+			"bounce_reason": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"push_id",
+			"message_type"
+		]
+	},
+	"SMS_DELIVERY_REPORT": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"delivery_status": {
+				"type": "string"
+			},
+			"error_code": {
+				"type": "string"
+			},
+			"mms": {
+				"type": "boolean"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"delivery_status"
+		]
+	},
+	"MOBILE_ORIGINATED": {
+		"type": "object",
+		"properties": {
+			"keyword": {
+				"type": "string"
+			},
+			"inbound_message": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"inbound_message"
+		]
+	},
+	"SMS_OPT_IN": {
+		"type": "object",
+		"properties": {
+			"keyword": {
+				"type": "string"
+			},
+			"source": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"source"
+		]
+	},
+	"SMS_OPT_OUT": {
+		"type": "object",
+		"properties": {
+			"keyword": {
+				"type": "string"
+			},
+			"source": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"source"
+		]
+	},
+	"WEB_CLICK": {
+		"type": "object",
+		"properties": {
+			"push_id": {
+				"type": "string"
+			},
+			"group_id": {
+				"type": "string"
+			},
+			"url": {
+				"type": "string"
+			},
+			"browser_name": {
+				"type": "string"
+			},
+			"session_id": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false
+	},
+	"ATTRIBUTE_OPERATION": {
+		"type": "object",
+		"properties": {
+			"mutations": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"action": {
+							"type": "string"
+						},
+						"key": {
+							"type": "string"
+						},
+						"value": {},
+						"timestamp": {
+							"type": "string",
+							"format": "date-time"
+						}
+					},
+					"additionalProperties": false,
+					"required": [
+						"action",
+						"key"
+					]
+				}
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"mutations"
+		]
+	},
+	"SUBSCRIPTION_LIST_CHANGE": {
+		"type": "object",
+		"properties": {
+			"list_id": {
+				"type": "string"
+			},
+			"action": {
+				"type": "string"
+			},
+			"scope": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"list_id",
+			"action"
+		]
+	},
+	"COMPLIANCE": {
+		"type": "object",
+		"properties": {
+			"action": {
+				"type": "string"
+			},
+			"request_id": {
+				"type": "string"
+			},
+			"reason": {
+				"type": "string"
+			}
+		},
+		"additionalProperties": false,
+		"required": [
+			"action"
+		]
+	}
+}`
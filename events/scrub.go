@@ -0,0 +1,176 @@
+package events
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Scrub is how a Scrubber treats an identifier.
+type Scrub int
+
+const (
+	// ScrubKeep keeps the identifier as is.
+	ScrubKeep Scrub = iota
+
+	// ScrubHash hashes the identifier with HMAC-SHA256 so events can still be
+	// grouped by it without storing it.
+	ScrubHash
+
+	// ScrubRedact removes the identifier entirely.
+	ScrubRedact
+)
+
+// Scrubber hashes or redacts identifiers in events so privacy-constrained
+// pipelines can consume the stream without storing them. The zero Scrubber
+// keeps everything.
+type Scrubber struct {
+	// Key of the HMAC used to hash identifiers. The same key always hashes an
+	// identifier to the same value, so keep it secret and stable. Without a
+	// Key identifiers to hash are redacted instead.
+	Key []byte
+
+	// Channels are the Device's channel IDs such as its iOS channel.
+	Channels Scrub
+
+	// NamedUsers are the Device's named user ID.
+	NamedUsers Scrub
+
+	// Addresses are the Device's email address, phone number (MSISDN), and
+	// SMS sender.
+	Addresses Scrub
+
+	// RedactLocation removes the coordinates of LOCATION events. Otherwise,
+	// if LocationDecimals is positive, they're rounded to that many decimal
+	// places; one is roughly 11km.
+	RedactLocation   bool
+	LocationDecimals int
+
+	// Fields are further body fields to scrub by their dotted path such as
+	// "properties.email". Only string fields are hashed; others are redacted.
+	Fields map[string]Scrub
+}
+
+// hash returns the hex HMAC-SHA256 of an identifier.
+func (s *Scrubber) hash(id string) string {
+	mac := hmac.New(sha256.New, s.Key)
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// scrub returns id treated as specified by how.
+func (s *Scrubber) scrub(id string, how Scrub) string {
+	switch {
+	case id == "" || how == ScrubKeep:
+		return id
+	case how == ScrubHash && len(s.Key) > 0:
+		return s.hash(id)
+	}
+	return ""
+}
+
+// Scrub returns a scrubbed copy of ev, leaving ev unchanged. The copy may be
+// acknowledged like the original but isn't pooled. An error is returned if
+// the body must be scrubbed but isn't valid JSON.
+func (s *Scrubber) Scrub(ev *Event) (*Event, error) {
+	cp := *ev
+	cp.pooled = false
+	if d := ev.Device; d != nil {
+		cp.Device = &Device{
+			Amazon:       s.scrub(d.Amazon, s.Channels),
+			Android:      s.scrub(d.Android, s.Channels),
+			IOS:          s.scrub(d.IOS, s.Channels),
+			NamedUser:    s.scrub(d.NamedUser, s.NamedUsers),
+			Email:        s.scrub(d.Email, s.Channels),
+			EmailAddress: s.scrub(d.EmailAddress, s.Addresses),
+			SMS:          s.scrub(d.SMS, s.Channels),
+			SMSSender:    s.scrub(d.SMSSender, s.Addresses),
+			MSISDN:       s.scrub(d.MSISDN, s.Addresses),
+			Web:          s.scrub(d.Web, s.Channels),
+		}
+		if *cp.Device == (Device{}) {
+			cp.Device = nil
+		}
+	}
+
+	location := ev.Type == TypeLocation && (s.RedactLocation || s.LocationDecimals > 0)
+	if len(ev.Body) == 0 || (!location && len(s.Fields) == 0) {
+		return &cp, nil
+	}
+	var body map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(ev.Body))
+	dec.UseNumber()
+	if err := dec.Decode(&body); err != nil {
+		return nil, err
+	}
+	if body == nil {
+		return &cp, nil
+	}
+	if location {
+		for _, k := range []string{"latitude", "longitude"} {
+			if s.RedactLocation {
+				delete(body, k)
+			} else if v, ok := body[k]; ok {
+				body[k] = s.round(v)
+			}
+		}
+	}
+	for path, how := range s.Fields {
+		if how != ScrubKeep {
+			s.scrubField(body, strings.Split(path, "."), how)
+		}
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	cp.Body = b
+	return &cp, nil
+}
+
+// round a coordinate, which the Event API sends as a string, to
+// LocationDecimals places. Coordinates which aren't numbers are redacted.
+func (s *Scrubber) round(v interface{}) interface{} {
+	var text string
+	switch v := v.(type) {
+	case string:
+		text = v
+	case json.Number:
+		text = v.String()
+	default:
+		return nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil
+	}
+	scale := math.Pow(10, float64(s.LocationDecimals))
+	rounded := strconv.FormatFloat(math.Floor(f*scale+0.5)/scale, 'f', s.LocationDecimals, 64)
+	if _, ok := v.(string); ok {
+		return rounded
+	}
+	return json.Number(rounded)
+}
+
+func (s *Scrubber) scrubField(obj map[string]interface{}, path []string, how Scrub) {
+	v, ok := obj[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) > 1 {
+		if child, ok := v.(map[string]interface{}); ok {
+			s.scrubField(child, path[1:], how)
+		}
+		return
+	}
+	if str, ok := v.(string); ok && how == ScrubHash && len(s.Key) > 0 {
+		obj[path[0]] = s.hash(str)
+		return
+	}
+	delete(obj, path[0])
+}
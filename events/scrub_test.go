@@ -0,0 +1,74 @@
+package events_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+func TestScrub(t *testing.T) {
+	t.Parallel()
+	key := []byte("secret")
+	hash := func(id string) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(id))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	s := &events.Scrubber{
+		Key:              key,
+		Channels:         events.ScrubHash,
+		NamedUsers:       events.ScrubRedact,
+		LocationDecimals: 2,
+		Fields:           map[string]events.Scrub{"session_id": events.ScrubHash, "extra.n": events.ScrubHash, "missing.x": events.ScrubRedact},
+	}
+	ev := &events.Event{
+		ID:     "loc",
+		Type:   events.TypeLocation,
+		Device: &events.Device{IOS: "ios", NamedUser: "kim", MSISDN: "15035551234"},
+		Body:   json.RawMessage(`{"latitude":"45.5231","longitude":-122.6765,"foreground":true,"session_id":"s","extra":{"n":1}}`),
+	}
+	orig := string(ev.Body)
+	scrubbed, err := s.Scrub(ev)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(ev.Body) != orig || ev.Device.IOS != "ios" {
+		t.Errorf("Expected the original event to be unchanged")
+	}
+	d := scrubbed.Device
+	if d.IOS != hash("ios") || d.NamedUser != "" || d.MSISDN != "15035551234" {
+		t.Errorf("Unexpected scrubbed device: %+v", d)
+	}
+	expected := `{"extra":{},"foreground":true,"latitude":"45.52","longitude":-122.68,"session_id":"` + hash("s") + `"}`
+	if string(scrubbed.Body) != expected {
+		t.Errorf("Expected body:\n%s\nFound:\n%s", expected, scrubbed.Body)
+	}
+
+	// Without a key hashed identifiers are redacted, and empty devices dropped
+	s = &events.Scrubber{Channels: events.ScrubHash, RedactLocation: true}
+	ev.Device = &events.Device{Android: "android"}
+	if scrubbed, err = s.Scrub(ev); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if scrubbed.Device != nil {
+		t.Errorf("Expected no device but found %+v", scrubbed.Device)
+	}
+	expected = `{"extra":{"n":1},"foreground":true,"session_id":"s"}`
+	if string(scrubbed.Body) != expected {
+		t.Errorf("Expected body:\n%s\nFound:\n%s", expected, scrubbed.Body)
+	}
+
+	// Bodies are only decoded when they must be scrubbed
+	ev = &events.Event{ID: "bad", Type: events.TypeSend, Body: json.RawMessage(`{`)}
+	if _, err := s.Scrub(ev); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	s.Fields = map[string]events.Scrub{"push_id": events.ScrubRedact}
+	if _, err := s.Scrub(ev); err == nil {
+		t.Errorf("Expected error scrubbing an invalid body")
+	}
+}
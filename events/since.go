@@ -0,0 +1,121 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// probeTimeout is how long a probe waits for an event before assuming its
+// offset is past the end of the stream.
+const probeTimeout = 5 * time.Second
+
+// maxProbes limits how many times the search for an upper bound doubles its
+// step. By then the step spans far more offsets than any stream holds, so a
+// server still returning events processed before since is misbehaving.
+const maxProbes = 40
+
+// FetchSince is like Fetch but starts the stream near the first event
+// processed at or after since, such as to replay the last 6 hours of events.
+//
+// The Event API only starts streams at offsets, so FetchSince searches for the
+// offset by briefly fetching events from candidate offsets. This takes a number
+// of requests logarithmic in the size of the stream. Processed times are only
+// approximately ordered by offset so a few events processed just before since
+// may be included. An error is returned if the search can't find an upper
+// bound for since.
+func FetchSince(c Client, since time.Time, su *Subset, filters ...*Filter) (*Response, error) {
+	return FetchSinceContext(context.Background(), c, since, su, filters...)
+}
+
+// FetchSinceContext is FetchSince with a context which cancels both the search
+// and the returned Response.
+func FetchSinceContext(ctx context.Context, c Client, since time.Time, su *Subset, filters ...*Filter) (*Response, error) {
+	offset, ok, err := searchSince(ctx, c, since)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return FetchContext(ctx, c, StartFirst, 0, su, filters...)
+	}
+	return FetchContext(ctx, c, StartOffset, offset, su, filters...)
+}
+
+// searchSince returns the offset to start from to receive events processed at
+// or after since. The bool is false if the stream should start from its
+// beginning.
+func searchSince(ctx context.Context, c Client, since time.Time) (uint64, bool, error) {
+	lo, processed, ok, err := probe(ctx, c, nil)
+	if err != nil || !ok || !processed.Before(since) {
+		return 0, false, err
+	}
+
+	// Find an upper bound by probing exponentially further offsets. Every
+	// event up to lo was processed before since and the first event at or
+	// after hi was not. The first probe past the end of the stream is an
+	// upper bound.
+	var hi uint64
+	for i, step := 0, uint64(1024); ; i, step = i+1, step*2 {
+		next := lo + step
+		if i == maxProbes || next < lo {
+			return 0, false, fmt.Errorf("no event processed at or after %s within %d probes", since, i)
+		}
+		offset, processed, ok, err := probe(ctx, c, &next)
+		if err != nil {
+			return 0, false, err
+		}
+		if !ok || !processed.Before(since) {
+			hi = next
+			break
+		}
+		lo = offset
+	}
+
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		offset, processed, ok, err := probe(ctx, c, &mid)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok && processed.Before(since) {
+			lo = offset
+		} else {
+			hi = mid
+		}
+	}
+	return lo + 1, true, nil
+}
+
+// probe returns the offset and processed time of the first event at or after
+// offset, or the first event in the stream if offset is nil. The bool is false
+// if there is no such event.
+func probe(ctx context.Context, c Client, offset *uint64) (uint64, time.Time, bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req := &Request{Start: StartFirst}
+	if offset != nil {
+		req.Start = StartOffset
+		req.Offset = offset
+	}
+	resp, _, err := fetchRequest(ctx, c, req, BufferSize(0))
+	if err != nil {
+		return 0, time.Time{}, false, err
+	}
+	defer resp.Close()
+
+	select {
+	case ev, ok := <-resp.Events():
+		if !ok {
+			if err := resp.Err(); err != io.EOF {
+				return 0, time.Time{}, false, err
+			}
+			return 0, time.Time{}, false, nil
+		}
+		return ev.Offset, ev.Processed, true, nil
+	case <-clientClock(c).After(probeTimeout):
+		return 0, time.Time{}, false, nil
+	case <-ctx.Done():
+		return 0, time.Time{}, false, ctx.Err()
+	}
+}
@@ -0,0 +1,78 @@
+package events_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/uatest"
+)
+
+func TestFetchSince(t *testing.T) {
+	t.Parallel()
+	srv := uatest.NewServer()
+	defer srv.Close()
+
+	// Sparse offsets with one event processed per minute
+	start := time.Date(2017, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3000; i++ {
+		p := start.Add(time.Duration(i) * time.Minute)
+		ev := &events.Event{ID: "e", Type: events.TypeClose, Offset: uint64(100 + i*3), Occurred: p, Processed: p, Body: []byte(`{}`)}
+		if err := srv.Add(ev); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	c := &urlClient{c: gobyairship.NewClient("", ""), url: srv.EventsURL()}
+
+	tests := []struct {
+		since  time.Time
+		offset uint64 // of the first event or 0 for none
+	}{
+		{start.Add(-time.Hour), 100},
+		{start, 100},
+		{start.Add(90 * time.Second), 106},
+		{start.Add(2000 * time.Minute), 6100},
+		{start.Add(2999 * time.Minute), 9097},
+		{start.Add(3000 * time.Minute), 0},
+	}
+	for _, test := range tests {
+		resp, err := events.FetchSince(c, test.since, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ev := <-resp.Events()
+		switch {
+		case test.offset == 0 && ev != nil:
+			t.Errorf("Since %s expected no events but found offset %d", test.since, ev.Offset)
+		case test.offset != 0 && ev == nil:
+			t.Errorf("Since %s expected offset %d but found no events: %v", test.since, test.offset, resp.Err())
+		case test.offset != 0 && ev.Offset != test.offset:
+			t.Errorf("Since %s expected offset %d but found %d", test.since, test.offset, ev.Offset)
+		}
+		resp.Close()
+	}
+}
+
+func TestFetchSinceBounded(t *testing.T) {
+	t.Parallel()
+	// A misbehaving server returning an old event for every offset
+	const line = `{"id":"a","type":"CLOSE","offset":"1","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}` + "\n"
+	var probes int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		w.Write([]byte(line))
+	}))
+	defer ts.Close()
+
+	c := &urlClient{c: gobyairship.NewClient("", ""), url: ts.URL}
+	if _, err := events.FetchSince(c, time.Date(2017, 5, 1, 0, 0, 0, 0, time.UTC), nil); err == nil {
+		t.Errorf("Expected an error with an unbounded stream")
+	}
+	if n := atomic.LoadInt32(&probes); n > 64 {
+		t.Errorf("Expected a bounded number of probes but found %d", n)
+	}
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"sync"
+)
+
+// SpillCodec compresses the events written to the spill file of SpillToDisk,
+// trading CPU for disk when buffering a large backlog. Wrap a codec such as
+// snappy or zstd to use it, or use the pure-Go NewFlateCodec.
+//
+// SpillCodecs must be safe for concurrent use.
+type SpillCodec interface {
+	// Encode appends the compressed form of src to dst.
+	Encode(dst, src []byte) ([]byte, error)
+
+	// Decode appends the decompressed form of src to dst.
+	Decode(dst, src []byte) ([]byte, error)
+}
+
+// SpillCompression compresses each event in the spill file of SpillToDisk
+// with codec. Events are stored uncompressed by default.
+//
+// Every spilled event is checksummed with CRC-32C whether or not it's
+// compressed, and a Response ends with an error if an event read back from the
+// file doesn't match.
+func SpillCompression(codec SpillCodec) Option {
+	return func(r *Response) { r.spillCodec = codec }
+}
+
+// flateCodec is a SpillCodec using compress/flate.
+type flateCodec struct {
+	level   int
+	writers sync.Pool
+	readers sync.Pool
+}
+
+// NewFlateCodec returns a SpillCodec using DEFLATE from the standard library
+// at a compression level from compress/flate. It's pure Go so it's available
+// on every platform. An error is returned if level is invalid.
+func NewFlateCodec(level int) (SpillCodec, error) {
+	if _, err := flate.NewWriter(nil, level); err != nil {
+		return nil, err
+	}
+	return &flateCodec{level: level}, nil
+}
+
+func (c *flateCodec) Encode(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	w, _ := c.writers.Get().(*flate.Writer)
+	if w == nil {
+		// The level was validated by NewFlateCodec
+		w, _ = flate.NewWriter(buf, c.level)
+	} else {
+		w.Reset(buf)
+	}
+	defer c.writers.Put(w)
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *flateCodec) Decode(dst, src []byte) ([]byte, error) {
+	br := bytes.NewReader(src)
+	r, _ := c.readers.Get().(io.ReadCloser)
+	if r == nil {
+		r = flate.NewReader(br)
+	} else if err := r.(flate.Resetter).Reset(br, nil); err != nil {
+		return nil, err
+	}
+	defer c.readers.Put(r)
+	buf := bytes.NewBuffer(dst)
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrStreamStalled is the Response error when no data arrives within the
+// duration passed to MaxIdle.
+var ErrStreamStalled = errors.New("event stream stalled")
+
+// MaxIdle closes the Response with ErrStreamStalled if the body is waited on
+// for longer than d without receiving any data, including keep-alive bytes.
+// Time spent waiting on slow consumers of Events does not count as idle.
+func MaxIdle(d time.Duration) Option {
+	return func(r *Response) { r.maxIdle = d }
+}
+
+// idleReader reports when Reads start and finish so the stall watchdog only
+// counts time spent blocked on the network.
+type idleReader struct {
+	io.ReadCloser
+	reads chan bool // true when a Read starts, false when it returns
+	quit  chan struct{}
+}
+
+func (b *idleReader) Read(p []byte) (int, error) {
+	b.report(true)
+	n, err := b.ReadCloser.Read(p)
+	b.report(false)
+	return n, err
+}
+
+func (b *idleReader) report(reading bool) {
+	select {
+	case b.reads <- reading:
+	case <-b.quit:
+	}
+}
+
+// watchIdle closes the Response with ErrStreamStalled if a Read blocks for
+// longer than maxIdle. Only one timer is pending at a time: when it fires
+// during a Read which hasn't yet been blocked for maxIdle it's rearmed for the
+// remainder.
+func (r *Response) watchIdle(b *idleReader) {
+	defer close(b.quit)
+	// The first Read is imminent
+	reading, started := true, r.clock.Now()
+	expired := r.clock.After(r.maxIdle)
+	for {
+		select {
+		case reading = <-b.reads:
+			if !reading {
+				continue
+			}
+			started = r.clock.Now()
+			if expired == nil {
+				expired = r.clock.After(r.maxIdle)
+			}
+		case now := <-expired:
+			expired = nil
+			if !reading {
+				continue
+			}
+			if idle := now.Sub(started); idle < r.maxIdle {
+				expired = r.clock.After(r.maxIdle - idle)
+				continue
+			}
+			r.closeErr(ErrStreamStalled)
+			return
+		case <-r.closed:
+			return
+		case <-r.done:
+			return
+		}
+	}
+}
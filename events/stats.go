@@ -0,0 +1,112 @@
+package events
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// ResponseStats is a snapshot of a Response's activity.
+type ResponseStats struct {
+	// Events is the number of events received.
+	Events uint64 `json:"events"`
+
+	// Bytes is the number of bytes read from the response body.
+	Bytes uint64 `json:"bytes"`
+
+	// Types is the number of events received by Type.
+	Types map[Type]uint64 `json:"types"`
+
+	// Offset of the last event received or nil if none have been received.
+	Offset *Offset `json:"offset,omitempty"`
+
+	// LastEvent is when the last event was received and SinceLastEvent how long
+	// ago that was. Both are zero if no events have been received.
+	LastEvent      time.Time     `json:"last_event"`
+	SinceLastEvent time.Duration `json:"since_last_event"`
+}
+
+// responseCounters are updated atomically as events are received and bytes
+// read so counting doesn't contend with Stats. They're allocated separately
+// from the Response so the 64-bit fields are aligned on 32-bit platforms.
+type responseCounters struct {
+	events    uint64
+	bytes     uint64
+	offset    uint64
+	lastEvent int64  // UnixNano
+	hasOffset uint32 // set once offset is
+
+	// types counts known Types. The map is never modified after it's
+	// created; Types which aren't known are counted in other, guarded by the
+	// Response's mu.
+	types map[Type]*uint64
+	other map[Type]uint64
+}
+
+func newResponseCounters() *responseCounters {
+	c := &responseCounters{types: make(map[Type]*uint64, len(knownTypes)), other: map[Type]uint64{}}
+	for t := range knownTypes {
+		c.types[t] = new(uint64)
+	}
+	return c
+}
+
+// Stats returns a snapshot of the Response's counters. Safe for concurrent
+// access.
+func (r *Response) Stats() ResponseStats {
+	c := r.counters
+	s := ResponseStats{
+		Events: atomic.LoadUint64(&c.events),
+		Bytes:  atomic.LoadUint64(&c.bytes),
+		Types:  map[Type]uint64{},
+	}
+	for t, n := range c.types {
+		if n := atomic.LoadUint64(n); n > 0 {
+			s.Types[t] = n
+		}
+	}
+	r.mu.Lock()
+	for t, n := range c.other {
+		s.Types[t] = n
+	}
+	r.mu.Unlock()
+	if atomic.LoadUint32(&c.hasOffset) == 1 {
+		offset := Offset(atomic.LoadUint64(&c.offset))
+		s.Offset = &offset
+	}
+	if last := atomic.LoadInt64(&c.lastEvent); last != 0 {
+		s.LastEvent = time.Unix(0, last)
+		s.SinceLastEvent = r.clock.Now().Sub(s.LastEvent)
+	}
+	return s
+}
+
+// received counts an event in the Response's stats.
+func (r *Response) received(ev *Event) {
+	c := r.counters
+	atomic.AddUint64(&c.events, 1)
+	if n, ok := c.types[ev.Type]; ok {
+		atomic.AddUint64(n, 1)
+	} else {
+		r.mu.Lock()
+		c.other[ev.Type]++
+		r.mu.Unlock()
+	}
+	atomic.StoreUint64(&c.offset, ev.Offset)
+	atomic.StoreUint32(&c.hasOffset, 1)
+	atomic.StoreInt64(&c.lastEvent, r.clock.Now().UnixNano())
+}
+
+// countingReader counts the bytes read into its Response's stats.
+type countingReader struct {
+	io.Reader
+	r *Response
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	if n > 0 {
+		atomic.AddUint64(&c.r.counters.bytes, uint64(n))
+	}
+	return n, err
+}
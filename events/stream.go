@@ -0,0 +1,415 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lytics/gobyairship"
+)
+
+// StreamConfig configures a Stream. Only one of Start and Offset may be set.
+type StreamConfig struct {
+	// Start of the stream when Offset is nil.
+	Start Start
+
+	// Offset to resume the stream from.
+	Offset *uint64
+
+	// Filters and Subset are passed on to each Request.
+	Filters []*Filter
+	Subset  *Subset
+
+	// Options are passed on to each Response.
+	Options []Option
+
+	// Backoff determines how long to wait between consecutive reconnects.
+	// Defaults to a gobyairship.ExponentialBackoff from MinBackoff to
+	// MaxBackoff, which default to 1 second and 1 minute respectively.
+	Backoff    gobyairship.Backoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// LimitBackoff is how long to wait before reconnecting after the API
+	// responds with LimitExceeded. The response's Retry-After header takes
+	// precedence. If zero the normal backoff is used.
+	LimitBackoff time.Duration
+
+	// OnError, if non-nil, is called with each error which causes the Stream to
+	// reconnect or prevents a checkpoint from being saved. Errors are
+	// *gobyairship.LabeledErrors carrying the Stream's Labels.
+	OnError func(error)
+
+	// OnConnect, OnReconnect, OnCheckpoint, and OnShutdown, if non-nil, are
+	// called at points in the Stream's life such as to write audit logs or
+	// warm caches. Each hook is called serially and delays the Stream until it
+	// returns.
+	//
+	// OnConnect is called each time a Response is opened with the offset it
+	// resumes after, or false if it starts from Start. OnReconnect is called
+	// before waiting to reconnect with the attempt number and how long the
+	// Stream will wait. OnCheckpoint is called with each offset saved to the
+	// Checkpointer. OnShutdown is called once the Stream ends, after its final
+	// checkpoint, with the error from Err.
+	OnConnect    func(offset uint64, resumed bool)
+	OnReconnect  func(attempt int, wait time.Duration)
+	OnCheckpoint func(offset uint64)
+	OnShutdown   func(err error)
+
+	// Clock is used for reconnect backoff, checkpoint intervals, and stats,
+	// and passed on to each Response with WithClock. Defaults to the Client's
+	// Clock if it has one, such as *gobyairship.Client, or
+	// gobyairship.RealClock.
+	Clock gobyairship.Clock
+
+	// Labels, such as a pipeline name, are added to the Stream's Labels.
+	Labels gobyairship.Labels
+
+	// Checkpointer, if non-nil, is loaded when the Stream starts and the
+	// Committed offset is saved to it every CheckpointInterval (default 5
+	// seconds) and when the Stream ends. A loaded offset takes precedence over
+	// Start and Offset.
+	Checkpointer       Checkpointer
+	CheckpointInterval time.Duration
+
+	// CheckpointEvery, if positive, also saves a checkpoint once that many
+	// events have been delivered, or acknowledged with ManualAck, since the
+	// last save.
+	CheckpointEvery int
+
+	// ManualAck checkpoints only events which have been acknowledged with
+	// Event.Ack, along with every event before them, instead of every
+	// delivered event. Ack events once they have been durably processed for
+	// at-least-once delivery across crashes: after a restart the Stream
+	// resumes after the last checkpointed event, so unacknowledged events are
+	// delivered again.
+	ManualAck bool
+
+	// Transforms are applied in order to each event before it's delivered,
+	// for example to attach an app key or to drop events from test devices.
+	// They're called serially from the Stream's goroutine. Dropped events
+	// aren't delivered but are checkpointed like delivered ones. Transforms
+	// which drop or replace pooled events should Release them.
+	Transforms []Transform
+}
+
+// Stream is a long-lived event stream which transparently reconnects when the
+// underlying Response ends, whether due to EOF, a network error, or a 402 rate
+// limit. It tracks the offset of the last delivered event and resumes from it
+// so events are not duplicated across reconnects.
+type Stream struct {
+	c   Client
+	cfg StreamConfig
+	out chan *Event
+
+	cancel context.CancelFunc
+	labels gobyairship.Labels
+
+	mu     sync.Mutex
+	offset *uint64
+	err    error
+	stats  StreamStats
+
+	saveMu sync.Mutex
+	saved  *uint64
+
+	// ManualAck state guarded by mu
+	acks      []pendingAck
+	committed *uint64
+	unsaved   int
+	commit    chan struct{} // signals the checkpointer that CheckpointEvery was reached
+}
+
+// StreamStats are counters describing a Stream's activity.
+type StreamStats struct {
+	// Delivered is the number of events delivered over the life of the Stream.
+	Delivered uint64 `json:"delivered"`
+
+	// Reconnects is the number of times the Stream has reconnected.
+	Reconnects uint64 `json:"reconnects"`
+
+	// LastEvent is when the last event was delivered.
+	LastEvent time.Time `json:"last_event"`
+
+	// Acked is the number of events acknowledged with ManualAck.
+	Acked uint64 `json:"acked"`
+
+	// Dropped is the number of events dropped by Transforms.
+	Dropped uint64 `json:"dropped"`
+}
+
+// NewStream starts a Stream which runs until ctx is done or Close is called.
+// An error is returned if the config would create an invalid Request or the
+// Checkpointer fails to load.
+func NewStream(ctx context.Context, c Client, cfg StreamConfig) (*Stream, error) {
+	if cfg.Checkpointer != nil {
+		offset, ok, err := cfg.Checkpointer.Load()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			cfg.Start = StartOffset
+			cfg.Offset = &offset
+		}
+	}
+	req := &Request{Start: cfg.Start, Offset: cfg.Offset, Filters: cfg.Filters, Subset: cfg.Subset}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Minute
+	}
+	if cfg.Backoff == nil {
+		cfg.Backoff = gobyairship.ExponentialBackoff{Min: cfg.MinBackoff, Max: cfg.MaxBackoff}
+	}
+	if cfg.CheckpointInterval <= 0 {
+		cfg.CheckpointInterval = 5 * time.Second
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clientClock(c)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Stream{c: c, cfg: cfg, out: make(chan *Event), cancel: cancel, labels: streamLabels(c, cfg), commit: make(chan struct{}, 1)}
+	if cfg.Offset != nil {
+		offset, committed := *cfg.Offset, *cfg.Offset
+		s.offset = &offset
+		s.committed = &committed
+	}
+	go s.run(ctx)
+	if cfg.Checkpointer != nil {
+		go s.checkpoint(ctx)
+	}
+	return s, nil
+}
+
+// labeler is implemented by Clients with Labels such as *gobyairship.Client.
+type labeler interface {
+	Labels() gobyairship.Labels
+}
+
+// streamLabels combines the Client's labels, the partition, and the config's
+// labels.
+func streamLabels(c Client, cfg StreamConfig) gobyairship.Labels {
+	labels := gobyairship.Labels{}
+	if l, ok := c.(labeler); ok {
+		labels = l.Labels()
+	}
+	if su := cfg.Subset; su != nil && su.Type == SubsetTypePartition {
+		labels = labels.Merge(gobyairship.Labels{gobyairship.LabelPartition: fmt.Sprintf("%d/%d", *su.Selection, *su.Count)})
+	}
+	return labels.Merge(cfg.Labels)
+}
+
+// onError passes err to OnError labeled with the Stream's labels.
+func (s *Stream) onError(err error) {
+	if s.cfg.OnError != nil {
+		s.cfg.OnError(&gobyairship.LabeledError{Labels: s.labels, Err: err})
+	}
+}
+
+// checkpoint periodically saves the offset of the last delivered event until
+// ctx is done.
+func (s *Stream) checkpoint(ctx context.Context) {
+	tick := s.cfg.Clock.After(s.cfg.CheckpointInterval)
+	for {
+		select {
+		case <-tick:
+			s.save()
+			tick = s.cfg.Clock.After(s.cfg.CheckpointInterval)
+		case <-s.commit:
+			s.save()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// save the committed offset to the Checkpointer if it has changed since the
+// last save.
+func (s *Stream) save() {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	offset, ok := s.Committed()
+	if !ok || (s.saved != nil && *s.saved == offset) {
+		return
+	}
+	if err := s.cfg.Checkpointer.Save(offset); err != nil {
+		s.onError(err)
+		return
+	}
+	s.saved = &offset
+	if s.cfg.OnCheckpoint != nil {
+		s.cfg.OnCheckpoint(offset)
+	}
+}
+
+// run fetches and forwards events, reconnecting with backoff, until ctx is
+// done.
+func (s *Stream) run(ctx context.Context) {
+	defer func() {
+		if s.cfg.Checkpointer != nil {
+			s.save()
+		}
+		if s.cfg.OnShutdown != nil {
+			s.cfg.OnShutdown(s.Err())
+		}
+		close(s.out)
+	}()
+	attempt, last := 0, time.Duration(0)
+	for {
+		delivered, retry, err := s.fetch(ctx)
+		if ctx.Err() != nil {
+			s.mu.Lock()
+			s.err = ctx.Err()
+			s.mu.Unlock()
+			return
+		}
+		if delivered {
+			attempt, last = 0, 0
+		}
+		if err != nil {
+			s.onError(err)
+		}
+		attempt++
+		wait := s.cfg.Backoff.Next(attempt, last)
+		last = wait
+		if err == LimitExceeded {
+			switch {
+			case retry > 0:
+				wait = retry
+			case s.cfg.LimitBackoff > 0:
+				wait = s.cfg.LimitBackoff
+			}
+		}
+		if s.cfg.OnReconnect != nil {
+			s.cfg.OnReconnect(attempt, wait)
+		}
+		select {
+		case <-s.cfg.Clock.After(wait):
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.err = ctx.Err()
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Lock()
+		s.stats.Reconnects++
+		s.mu.Unlock()
+	}
+}
+
+// fetch a single Response and forward its events until it ends. Returns
+// whether any events were delivered, how long the API asked to wait before
+// retrying, and the error which ended the Response.
+func (s *Stream) fetch(ctx context.Context) (bool, time.Duration, error) {
+	req := &Request{Start: s.cfg.Start, Filters: s.cfg.Filters, Subset: s.cfg.Subset}
+	last, resumed := s.Offset()
+	if resumed {
+		req.Start = StartOffset
+		req.Offset = &last
+	}
+	resp, retry, err := fetchRequest(ctx, s.c, req, append([]Option{WithClock(s.cfg.Clock)}, s.cfg.Options...)...)
+	if err != nil {
+		return false, retry, err
+	}
+	defer resp.Close()
+	if s.cfg.OnConnect != nil {
+		s.cfg.OnConnect(last, resumed)
+	}
+
+	delivered := false
+	for ev := range resp.Events() {
+		// Resuming from an offset includes the event at that offset
+		if resumed && ev.Offset <= last {
+			continue
+		}
+		// Track the event before sending it in case it's acknowledged and
+		// released immediately
+		offset := ev.Offset
+		if s.cfg.ManualAck {
+			s.track(offset)
+		}
+		var ok bool
+		if ev, ok = s.transform(ev); !ok {
+			s.dropped(offset)
+			continue
+		}
+		if s.cfg.ManualAck {
+			ev.acker = s
+		}
+		select {
+		case s.out <- ev:
+		case <-ctx.Done():
+			return delivered, 0, ctx.Err()
+		}
+		delivered = true
+		s.delivered(offset)
+	}
+	return delivered, 0, resp.Err()
+}
+
+// Events returns a chan that emits Events until the Stream is closed or its
+// context is done.
+func (s *Stream) Events() <-chan *Event { return s.out }
+
+// Offset returns the offset of the last delivered event, or the configured
+// Offset if no events have been delivered. The second return value is false if
+// there is no offset yet. Safe for concurrent access.
+func (s *Stream) Offset() (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.offset == nil {
+		return 0, false
+	}
+	return *s.offset, true
+}
+
+// Committed returns the offset which would be checkpointed: the offset of the
+// last delivered event, or with ManualAck the last event which has been
+// acknowledged along with every event before it. The second return value is
+// false if there is no offset yet. Safe for concurrent access.
+func (s *Stream) Committed() (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offset := s.offset
+	if s.cfg.ManualAck {
+		offset = s.committed
+	}
+	if offset == nil {
+		return 0, false
+	}
+	return *offset, true
+}
+
+// Stats returns a snapshot of the Stream's counters. Safe for concurrent
+// access.
+func (s *Stream) Stats() StreamStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Labels returns the Stream's labels: those of its Client if it has any, its
+// partition, and the configured Labels.
+func (s *Stream) Labels() gobyairship.Labels { return s.labels.Merge() }
+
+// Filters returns the Filters the Stream requests.
+func (s *Stream) Filters() []*Filter { return s.cfg.Filters }
+
+// Subset returns the Subset the Stream requests or nil.
+func (s *Stream) Subset() *Subset { return s.cfg.Subset }
+
+// Close the Stream. Safe to call concurrently.
+func (s *Stream) Close() { s.cancel() }
+
+// Err returns the context error which ended the Stream or nil. May be checked
+// once the chan returned by Events() is closed.
+func (s *Stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
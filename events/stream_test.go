@@ -0,0 +1,329 @@
+package events_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/uatest"
+)
+
+func streamEvent(offset uint64) *events.Event {
+	now := time.Now().UTC()
+	return &events.Event{ID: "ev", Type: events.TypeClose, Offset: offset, Occurred: now, Processed: now, Body: []byte("{}")}
+}
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+	srv := uatest.NewServer()
+	defer srv.Close()
+	srv.Add(streamEvent(1), streamEvent(2), streamEvent(3))
+
+	var errs []error
+	c := &urlClient{c: gobyairship.NewClient("", ""), url: srv.EventsURL()}
+	s, err := events.NewStream(context.Background(), c, events.StreamConfig{
+		Start:      events.StartFirst,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+		OnError:    func(err error) { errs = append(errs, err) },
+		Labels:     gobyairship.Labels{"env": "test"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	next := func() *events.Event {
+		select {
+		case ev := <-s.Events():
+			return ev
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timed out waiting for event")
+			return nil
+		}
+	}
+	for i := uint64(1); i <= 3; i++ {
+		if ev := next(); ev.Offset != i {
+			t.Fatalf("Expected offset %d but found %d", i, ev.Offset)
+		}
+	}
+
+	// The server ends each stream after its last event; the Stream should
+	// reconnect through rate limits and resume after offset 3
+	srv.RateLimit(2)
+	srv.Add(streamEvent(4), streamEvent(5))
+	for i := uint64(4); i <= 5; i++ {
+		if ev := next(); ev.Offset != i {
+			t.Fatalf("Expected offset %d but found %d", i, ev.Offset)
+		}
+	}
+
+	s.Close()
+	for range s.Events() {
+	}
+	if offset, ok := s.Offset(); !ok || offset != 5 {
+		t.Errorf("Expected offset 5 but found %d (%t)", offset, ok)
+	}
+	if st := s.Stats(); st.Delivered != 5 || st.Reconnects < 3 || st.LastEvent.IsZero() {
+		t.Errorf("Unexpected stats: %#v", st)
+	}
+	if err := s.Err(); err != context.Canceled {
+		t.Errorf("Expected context.Canceled but found %v", err)
+	}
+	limited := false
+	for _, err := range errs {
+		lerr, ok := err.(*gobyairship.LabeledError)
+		if !ok {
+			t.Errorf("Expected labeled error but found %T", err)
+			continue
+		}
+		if lerr.Labels["env"] != "test" {
+			t.Errorf("Unexpected labels: %v", lerr.Labels)
+		}
+		if lerr.Err == events.LimitExceeded {
+			limited = true
+		}
+	}
+	if !limited {
+		t.Errorf("Expected OnError to receive LimitExceeded but received: %v", errs)
+	}
+}
+
+func TestStreamLabels(t *testing.T) {
+	t.Parallel()
+	c := gobyairship.NewClient("appkey123", "")
+	c.StaticLabels = gobyairship.Labels{"env": "prod"}
+	s, err := events.NewStream(context.Background(), c, events.StreamConfig{
+		Start:  events.StartLast,
+		Subset: events.SubsetPartition(4, 1),
+		Labels: gobyairship.Labels{"pipeline": "opens"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.Close()
+	if l := s.Labels().String(); l != "app_key=appk... env=prod partition=1/4 pipeline=opens" {
+		t.Errorf("Unexpected labels: %s", l)
+	}
+}
+
+func TestStreamInvalid(t *testing.T) {
+	t.Parallel()
+	offset := uint64(1)
+	_, err := events.NewStream(context.Background(), nil, events.StreamConfig{Start: events.StartFirst, Offset: &offset})
+	if err == nil {
+		t.Errorf("Expected error for invalid config")
+	}
+}
+
+func TestStreamLimitBackoff(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		retryAfter   time.Duration
+		limitBackoff time.Duration
+		min          time.Duration
+	}{
+		{limitBackoff: 200 * time.Millisecond, min: 200 * time.Millisecond},
+		{retryAfter: time.Second, limitBackoff: time.Millisecond, min: time.Second},
+	}
+	for _, test := range tests {
+		srv := uatest.NewServer()
+		srv.RetryAfter = test.retryAfter
+		srv.RateLimit(1)
+		srv.Add(streamEvent(1))
+
+		c := &urlClient{c: gobyairship.NewClient("", ""), url: srv.EventsURL()}
+		start := time.Now()
+		s, err := events.NewStream(context.Background(), c, events.StreamConfig{
+			Start:        events.StartFirst,
+			MinBackoff:   time.Millisecond,
+			LimitBackoff: test.limitBackoff,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		select {
+		case <-s.Events():
+			if d := time.Since(start); d < test.min {
+				t.Errorf("Expected to wait at least %s after 402 but waited %s", test.min, d)
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("Timed out waiting for event")
+		}
+		s.Close()
+		srv.Close()
+	}
+}
+
+func TestStreamBackoff(t *testing.T) {
+	t.Parallel()
+	srv := uatest.NewServer()
+	defer srv.Close()
+	srv.RateLimit(3)
+	srv.Add(streamEvent(1))
+
+	attempts := make(chan int, 10)
+	c := &urlClient{c: gobyairship.NewClient("", ""), url: srv.EventsURL()}
+	s, err := events.NewStream(context.Background(), c, events.StreamConfig{
+		Start: events.StartFirst,
+		Backoff: gobyairship.BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+			attempts <- attempt
+			return time.Millisecond
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	<-s.Events()
+	s.Close()
+	for i := 1; i <= 3; i++ {
+		if a := <-attempts; a != i {
+			t.Errorf("Expected attempt %d but found %d", i, a)
+		}
+	}
+}
+
+func TestStreamTransforms(t *testing.T) {
+	t.Parallel()
+	srv := uatest.NewServer()
+	defer srv.Close()
+	srv.Add(streamEvent(1), streamEvent(2), streamEvent(3), streamEvent(4))
+
+	c := &urlClient{c: gobyairship.NewClient("", ""), url: srv.EventsURL()}
+	s, err := events.NewStream(context.Background(), c, events.StreamConfig{
+		Start:      events.StartFirst,
+		MinBackoff: time.Millisecond,
+		ManualAck:  true,
+		Transforms: []events.Transform{
+			func(ev *events.Event) (*events.Event, bool) {
+				return ev, ev.Offset%2 == 1
+			},
+			func(ev *events.Event) (*events.Event, bool) {
+				cp := *ev
+				cp.ID = "rewritten"
+				return &cp, true
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var evs []*events.Event
+	for len(evs) < 2 {
+		select {
+		case ev := <-s.Events():
+			evs = append(evs, ev)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timed out waiting for events")
+		}
+	}
+	for i, ev := range evs {
+		if expected := uint64(2*i + 1); ev.Offset != expected || ev.ID != "rewritten" {
+			t.Errorf("Expected rewritten event %d but found %s at %d", expected, ev.ID, ev.Offset)
+		}
+	}
+
+	// Dropped events are committed once the events before them are
+	// acknowledged
+	waitCommitted := func(expected uint64) {
+		deadline := time.Now().Add(3 * time.Second)
+		for offset, _ := s.Committed(); offset != expected; offset, _ = s.Committed() {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected committed offset %d but found %d", expected, offset)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if offset, ok := s.Committed(); ok {
+		t.Errorf("Expected no committed offset but found %d", offset)
+	}
+	// Acknowledging an event more than once, even concurrently, is a no-op
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			evs[0].Ack()
+		}()
+	}
+	wg.Wait()
+	waitCommitted(2)
+	evs[1].Ack()
+	waitCommitted(4)
+
+	s.Close()
+	for range s.Events() {
+	}
+	if st := s.Stats(); st.Delivered != 2 || st.Dropped != 2 || st.Acked != 2 {
+		t.Errorf("Unexpected stats: %#v", st)
+	}
+}
+
+func TestStreamHooks(t *testing.T) {
+	t.Parallel()
+	srv := uatest.NewServer()
+	defer srv.Close()
+	srv.Add(streamEvent(1), streamEvent(2))
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, fmt.Sprintf(format, args...))
+	}
+	cp := &events.MemoryCheckpointer{}
+	c := &urlClient{c: gobyairship.NewClient("", ""), url: srv.EventsURL()}
+	s, err := events.NewStream(context.Background(), c, events.StreamConfig{
+		Start:              events.StartFirst,
+		Backoff:            gobyairship.ExponentialBackoff{Min: time.Millisecond, Max: time.Millisecond},
+		Checkpointer:       cp,
+		CheckpointInterval: time.Hour,
+		OnConnect:          func(offset uint64, resumed bool) { record("connect %d %t", offset, resumed) },
+		OnReconnect:        func(attempt int, wait time.Duration) { record("reconnect %d", attempt) },
+		OnCheckpoint:       func(offset uint64) { record("checkpoint %d", offset) },
+		OnShutdown:         func(err error) { record("shutdown %v", err) },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-s.Events():
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timed out waiting for event")
+		}
+	}
+	// Wait for the Stream to reconnect after the server ends the stream
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		mu.Lock()
+		n := len(calls)
+		mu.Unlock()
+		if n >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for reconnect: %v", calls)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.Close()
+	for range s.Events() {
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{"connect 0 false", "reconnect 1", "connect 2 true"}
+	for i, call := range expected {
+		if calls[i] != call {
+			t.Fatalf("Expected %v to start with %v", calls, expected)
+		}
+	}
+	if n := len(calls); calls[n-2] != "checkpoint 2" || calls[n-1] != "shutdown context canceled" {
+		t.Errorf("Expected a final checkpoint and shutdown but found %v", calls)
+	}
+}
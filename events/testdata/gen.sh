@@ -33,5 +33,45 @@ echo First Open
 echo '{"filters":[{"types":["FIRST_OPEN"]}]}' | $cmd | head -n 50 > first_open.json
 echo Location
 echo '{"filters":[{"types":["LOCATION"]}]}' | $cmd | head -n 50 > location.json
-echo Skipping Custom events
-#echo '{"filters":[{"types":["CUSTOM"]}]}' | $cmd | head -n 50 > custom.json
+echo Screen Viewed
+echo '{"filters":[{"types":["SCREEN_VIEWED"]}]}' | $cmd | head -n 50 > screen_viewed.json
+echo Region
+echo '{"filters":[{"types":["REGION"]}]}' | $cmd | head -n 50 > region.json
+echo Control
+echo '{"filters":[{"types":["CONTROL"]}]}' | $cmd | head -n 50 > control.json
+echo Send Aborted
+echo '{"filters":[{"types":["SEND_ABORTED"]}]}' | $cmd | head -n 50 > send_aborted.json
+echo Email Delivery
+echo '{"filters":[{"types":["EMAIL_DELIVERY"]}]}' | $cmd | head -n 50 > email_delivery.json
+echo Email Open
+echo '{"filters":[{"types":["EMAIL_OPEN"]}]}' | $cmd | head -n 50 > email_open.json
+echo Email Click
+echo '{"filters":[{"types":["EMAIL_CLICK"]}]}' | $cmd | head -n 50 > email_click.json
+echo Email Bounce
+echo '{"filters":[{"types":["EMAIL_BOUNCE"]}]}' | $cmd | head -n 50 > email_bounce.json
+echo Email Unsubscribe
+echo '{"filters":[{"types":["EMAIL_UNSUBSCRIBE"]}]}' | $cmd | head -n 50 > email_unsubscribe.json
+echo SMS Delivery Report
+echo '{"filters":[{"types":["SMS_DELIVERY_REPORT"]}]}' | $cmd | head -n 50 > sms_delivery_report.json
+echo Mobile Originated
+echo '{"filters":[{"types":["MOBILE_ORIGINATED"]}]}' | $cmd | head -n 50 > mobile_originated.json
+echo SMS Opt In
+echo '{"filters":[{"types":["SMS_OPT_IN"]}]}' | $cmd | head -n 50 > sms_opt_in.json
+echo SMS Opt Out
+echo '{"filters":[{"types":["SMS_OPT_OUT"]}]}' | $cmd | head -n 50 > sms_opt_out.json
+echo Web Click
+echo '{"filters":[{"types":["WEB_CLICK"]}]}' | $cmd | head -n 50 > web_click.json
+echo Custom
+echo '{"filters":[{"types":["CUSTOM"]}]}' | $cmd | head -n 50 > custom.json
+echo Attribute Operation
+echo '{"filters":[{"types":["ATTRIBUTE_OPERATION"]}]}' | $cmd | head -n 50 > attribute_operation.json
+echo Subscription List Change
+echo '{"filters":[{"types":["SUBSCRIPTION_LIST_CHANGE"]}]}' | $cmd | head -n 50 > subscription_list_change.json
+echo Compliance
+echo '{"filters":[{"types":["COMPLIANCE"]}]}' | $cmd | head -n 50 > compliance.json
+echo Rich Delivery
+echo '{"filters":[{"types":["RICH_DELIVERY"]}]}' | $cmd | head -n 50 > rich_delivery.json
+echo Rich Read
+echo '{"filters":[{"types":["RICH_READ"]}]}' | $cmd | head -n 50 > rich_read.json
+echo Rich Delete
+echo '{"filters":[{"types":["RICH_DELETE"]}]}' | $cmd | head -n 50 > rich_delete.json
@@ -0,0 +1,92 @@
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TraceRecord is a raw event sampled by a Tracer.
+type TraceRecord struct {
+	// Received is when the event was decoded from the stream.
+	Received time.Time `json:"received"`
+
+	// ResponseID is the ID of the Response the event was received on.
+	ResponseID string `json:"response_id"`
+
+	// Header of the HTTP response the event was received on.
+	Header http.Header `json:"header"`
+
+	// Raw is the event exactly as it was received.
+	Raw json.RawMessage `json:"raw"`
+}
+
+// Tracer retains a sample of complete raw events in a ring buffer for
+// debugging decoding and mapping issues in production. Tracers may be shared
+// between Responses and are safe for concurrent use. Tracer is an
+// http.Handler which serves its records as JSON.
+type Tracer struct {
+	every int
+
+	mu      sync.Mutex
+	seen    uint64
+	records []TraceRecord
+	next    int
+	full    bool
+}
+
+// NewTracer creates a Tracer which samples 1 in every events and retains the
+// last size samples.
+func NewTracer(every, size int) *Tracer {
+	if every < 1 {
+		every = 1
+	}
+	if size < 1 {
+		size = 1
+	}
+	return &Tracer{every: every, records: make([]TraceRecord, size)}
+}
+
+// Trace samples the raw events of a Response into t.
+func Trace(t *Tracer) Option {
+	return func(r *Response) { r.tracer = t }
+}
+
+// sample the raw event if it's the Tracer's turn.
+func (t *Tracer) sample(raw []byte, id string, header http.Header) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seen++
+	if (t.seen-1)%uint64(t.every) != 0 {
+		return
+	}
+	t.records[t.next] = TraceRecord{
+		Received:   time.Now(),
+		ResponseID: id,
+		Header:     header,
+		Raw:        append(json.RawMessage(nil), raw...),
+	}
+	if t.next++; t.next == len(t.records) {
+		t.next = 0
+		t.full = true
+	}
+}
+
+// Records returns the retained samples from oldest to newest.
+func (t *Tracer) Records() []TraceRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.full {
+		return append([]TraceRecord(nil), t.records[:t.next]...)
+	}
+	out := make([]TraceRecord, 0, len(t.records))
+	out = append(out, t.records[t.next:]...)
+	return append(out, t.records[:t.next]...)
+}
+
+// ServeHTTP writes the retained samples as a JSON array.
+func (t *Tracer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t.Records())
+}
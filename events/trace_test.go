@@ -0,0 +1,61 @@
+package events_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+	const line = `{"id":"%d","type":"CLOSE","offset":"%d","occurred":"2015-05-27T11:32:07.729Z","processed":"2015-05-27T11:32:07.729Z","body":{}}` + "\n"
+	buf := &bytes.Buffer{}
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(buf, line, i, i)
+	}
+	hr := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Ua-Operation-Id": []string{"op"}},
+		Body:       ioutil.NopCloser(buf),
+	}
+	tr := events.NewTracer(3, 2)
+	resp, err := events.NewResponse(hr, events.Trace(tr))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	n := 0
+	for range resp.Events() {
+		n++
+	}
+	if n != 10 {
+		t.Errorf("Expected tracing not to affect events but received %d", n)
+	}
+
+	// Events 0, 3, 6, 9 are sampled and the last 2 retained
+	recs := tr.Records()
+	if len(recs) != 2 {
+		t.Fatalf("Expected 2 records but found %d", len(recs))
+	}
+	for i, id := range []string{"6", "9"} {
+		ev := &events.Event{}
+		if err := json.Unmarshal(recs[i].Raw, ev); err != nil || ev.ID != id {
+			t.Errorf("Expected record %d to be event %s but found %s (%v)", i, id, recs[i].Raw, err)
+		}
+		if recs[i].ResponseID != "op" || recs[i].Received.IsZero() {
+			t.Errorf("Unexpected record: %#v", recs[i])
+		}
+	}
+
+	w := httptest.NewRecorder()
+	tr.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	served := []events.TraceRecord{}
+	if err := json.Unmarshal(w.Body.Bytes(), &served); err != nil || len(served) != 2 {
+		t.Errorf("Unexpected handler response: %s (%v)", w.Body.String(), err)
+	}
+}
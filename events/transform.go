@@ -0,0 +1,34 @@
+package events
+
+// Transform enriches, rewrites, or drops an event before a Stream delivers it.
+// It returns the event to deliver, which may be ev itself or a replacement,
+// and false to drop the event. Transforms must not change an event's Offset.
+type Transform func(ev *Event) (*Event, bool)
+
+// transform applies the configured Transforms in order, stopping at the first
+// which drops the event.
+func (s *Stream) transform(ev *Event) (*Event, bool) {
+	for _, t := range s.cfg.Transforms {
+		out, ok := t(ev)
+		if !ok || out == nil {
+			return nil, false
+		}
+		ev = out
+	}
+	return ev, true
+}
+
+// dropped records that the event at offset was dropped by a Transform. It's
+// committed as if it was delivered, and acknowledged with ManualAck, so
+// checkpoints pass it.
+func (s *Stream) dropped(offset uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset = &offset
+	s.stats.Dropped++
+	if s.cfg.ManualAck {
+		s.acked(offset)
+	} else {
+		s.committedEvents(1)
+	}
+}
@@ -26,11 +26,115 @@ const (
 	TypeInAppMessageDisplay    Type = "IN_APP_MESSAGE_DISPLAY"
 	TypeInAppMessageResolution Type = "IN_APP_MESSAGE_RESOLUTION"
 	TypeInAppMessageExpiration Type = "IN_APP_MESSAGE_EXPIRATION"
+	TypeScreenViewed           Type = "SCREEN_VIEWED"
+	TypeRegion                 Type = "REGION"
+	TypeControl                Type = "CONTROL"
+	TypeSendAborted            Type = "SEND_ABORTED"
+	TypeEmailDelivery          Type = "EMAIL_DELIVERY"
+	TypeEmailOpen              Type = "EMAIL_OPEN"
+	TypeEmailClick             Type = "EMAIL_CLICK"
+	TypeEmailBounce            Type = "EMAIL_BOUNCE"
+	TypeEmailUnsubscribe       Type = "EMAIL_UNSUBSCRIBE"
+	TypeSMSDelivery            Type = "SMS_DELIVERY_REPORT"
+	TypeMobileOriginated       Type = "MOBILE_ORIGINATED"
+	TypeSMSOptIn               Type = "SMS_OPT_IN"
+	TypeSMSOptOut              Type = "SMS_OPT_OUT"
+	TypeWebClick               Type = "WEB_CLICK"
+	TypeAttributeOperation     Type = "ATTRIBUTE_OPERATION"
+	TypeSubscriptionListChange Type = "SUBSCRIPTION_LIST_CHANGE"
+	TypeCompliance             Type = "COMPLIANCE"
 )
 
+// knownTypes are the Types Filters may specify.
+var knownTypes = map[Type]bool{
+	TypePush:                   true,
+	TypeOpen:                   true,
+	TypeSend:                   true,
+	TypeClose:                  true,
+	TypeTagChange:              true,
+	TypeUninstall:              true,
+	TypeFirst:                  true,
+	TypeCustom:                 true,
+	TypeLocation:               true,
+	TypeRichDelivery:           true,
+	TypeRichRead:               true,
+	TypeRichDelete:             true,
+	TypeInAppMessageDisplay:    true,
+	TypeInAppMessageResolution: true,
+	TypeInAppMessageExpiration: true,
+	TypeScreenViewed:           true,
+	TypeRegion:                 true,
+	TypeControl:                true,
+	TypeSendAborted:            true,
+	TypeEmailDelivery:          true,
+	TypeEmailOpen:              true,
+	TypeEmailClick:             true,
+	TypeEmailBounce:            true,
+	TypeEmailUnsubscribe:       true,
+	TypeSMSDelivery:            true,
+	TypeMobileOriginated:       true,
+	TypeSMSOptIn:               true,
+	TypeSMSOptOut:              true,
+	TypeWebClick:               true,
+	TypeAttributeOperation:     true,
+	TypeSubscriptionListChange: true,
+	TypeCompliance:             true,
+}
+
 type Device struct {
 	Amazon    string `json:"amazon_channel,omitempty"`
 	Android   string `json:"android_channel,omitempty"`
 	IOS       string `json:"ios_channel,omitempty"`
 	NamedUser string `json:"named_user_id,omitempty"`
+
+	// Email channel and address of the recipient of email events.
+	Email        string `json:"email_channel,omitempty"`
+	EmailAddress string `json:"email_address,omitempty"`
+
+	// SMS channel, sender, and phone number (MSISDN) of SMS events.
+	SMS       string `json:"sms_channel,omitempty"`
+	SMSSender string `json:"sms_sender,omitempty"`
+	MSISDN    string `json:"msisdn,omitempty"`
+
+	// Web channel of browsers registered for web notifications.
+	Web string `json:"web_channel,omitempty"`
+}
+
+// AmazonChannel creates a Device for an Amazon channel ID.
+func AmazonChannel(id string) Device { return Device{Amazon: id} }
+
+// AndroidChannel creates a Device for an Android channel ID.
+func AndroidChannel(id string) Device { return Device{Android: id} }
+
+// IOSChannel creates a Device for an iOS channel ID.
+func IOSChannel(id string) Device { return Device{IOS: id} }
+
+// NamedUser creates a Device for a named user ID.
+func NamedUser(id string) Device { return Device{NamedUser: id} }
+
+// EmailChannel creates a Device for an email channel ID.
+func EmailChannel(id string) Device { return Device{Email: id} }
+
+// SMSChannel creates a Device for an SMS channel ID.
+func SMSChannel(id string) Device { return Device{SMS: id} }
+
+// WebChannel creates a Device for a web channel ID.
+func WebChannel(id string) Device { return Device{Web: id} }
+
+// Validate returns an error unless exactly one channel or named user ID is
+// set, as required to identify a device in a Filter.
+func (d Device) Validate() error {
+	n := 0
+	for _, id := range []string{d.Amazon, d.Android, d.IOS, d.NamedUser, d.Email, d.SMS, d.Web} {
+		if id != "" {
+			n++
+		}
+	}
+	switch {
+	case n == 0:
+		return errors.New("device has no channel or named user ID")
+	case n > 1:
+		return errors.New("device has more than one channel or named user ID")
+	}
+	return nil
 }
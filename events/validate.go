@@ -0,0 +1,78 @@
+package events
+
+import (
+	"fmt"
+	"time"
+)
+
+// Tolerances configure how strictly ValidateEvent treats timestamps.
+type Tolerances struct {
+	// Future is how far in the future Occurred and Processed timestamps may be
+	// before the event is considered invalid.
+	Future time.Duration
+
+	// Skew is how far Occurred may be after Processed before the event is
+	// considered invalid. Devices with bad clocks routinely report events
+	// occurring after Urban Airship processed them.
+	Skew time.Duration
+
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// DefaultTolerances allow a minute of clock skew.
+var DefaultTolerances = Tolerances{Future: time.Minute, Skew: time.Minute}
+
+// InvalidEventError is returned by ValidateEvent for suspicious events.
+type InvalidEventError struct {
+	// ID of the invalid event. May be empty.
+	ID string
+
+	// Reason the event is invalid.
+	Reason string
+}
+
+func (e *InvalidEventError) Error() string {
+	return fmt.Sprintf("invalid event %q: %s", e.ID, e.Reason)
+}
+
+// ValidateEvent returns an *InvalidEventError if the event is missing its ID,
+// type, or timestamps, has timestamps beyond the tolerances, or has an empty
+// device. Otherwise nil is returned.
+//
+// Body is not decoded; use the per-Type methods to check bodies.
+func ValidateEvent(ev *Event, tol Tolerances) error {
+	invalid := func(format string, args ...interface{}) error {
+		return &InvalidEventError{ID: ev.ID, Reason: fmt.Sprintf(format, args...)}
+	}
+	now := time.Now
+	if tol.Now != nil {
+		now = tol.Now
+	}
+	if ev.ID == "" {
+		return invalid("missing id")
+	}
+	if ev.Type == "" {
+		return invalid("missing type")
+	}
+	if ev.Occurred.IsZero() {
+		return invalid("missing occurred timestamp")
+	}
+	if ev.Processed.IsZero() {
+		return invalid("missing processed timestamp")
+	}
+	limit := now().Add(tol.Future)
+	if ev.Occurred.After(limit) {
+		return invalid("occurred in the future: %s", ev.Occurred)
+	}
+	if ev.Processed.After(limit) {
+		return invalid("processed in the future: %s", ev.Processed)
+	}
+	if skew := ev.Occurred.Sub(ev.Processed); skew > tol.Skew {
+		return invalid("occurred %s after processed", skew)
+	}
+	if d := ev.Device; d != nil && len(d.Amazon)+len(d.Android)+len(d.IOS)+len(d.NamedUser)+len(d.Email)+len(d.SMS)+len(d.Web) == 0 {
+		return invalid("device specified but no IDs")
+	}
+	return nil
+}
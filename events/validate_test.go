@@ -0,0 +1,54 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+func TestValidateEvent(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2015, 5, 27, 12, 0, 0, 0, time.UTC)
+	tol := events.Tolerances{Future: time.Minute, Skew: 5 * time.Second, Now: func() time.Time { return now }}
+	valid := func() *events.Event {
+		return &events.Event{
+			ID:        "a",
+			Type:      events.TypeOpen,
+			Occurred:  now.Add(-time.Hour),
+			Processed: now.Add(-time.Hour),
+			Device:    &events.Device{IOS: "ios"},
+		}
+	}
+
+	if err := events.ValidateEvent(valid(), tol); err != nil {
+		t.Fatalf("Unexpected error validating event: %v", err)
+	}
+
+	tests := map[string]func(*events.Event){
+		"missing id":         func(ev *events.Event) { ev.ID = "" },
+		"missing type":       func(ev *events.Event) { ev.Type = "" },
+		"missing occurred":   func(ev *events.Event) { ev.Occurred = time.Time{} },
+		"missing processed":  func(ev *events.Event) { ev.Processed = time.Time{} },
+		"future occurred":    func(ev *events.Event) { ev.Occurred = now.Add(2 * time.Minute) },
+		"future processed":   func(ev *events.Event) { ev.Processed = now.Add(2 * time.Minute) },
+		"occurred>processed": func(ev *events.Event) { ev.Occurred = ev.Processed.Add(10 * time.Second) },
+		"empty device":       func(ev *events.Event) { ev.Device = &events.Device{} },
+	}
+	for name, mutate := range tests {
+		ev := valid()
+		mutate(ev)
+		err := events.ValidateEvent(ev, tol)
+		if _, ok := err.(*events.InvalidEventError); !ok {
+			t.Errorf("%s: expected an InvalidEventError but found %v", name, err)
+		}
+	}
+
+	// Within tolerances
+	ev := valid()
+	ev.Occurred = ev.Processed.Add(5 * time.Second)
+	ev.Processed = now.Add(30 * time.Second)
+	if err := events.ValidateEvent(ev, tol); err != nil {
+		t.Errorf("Unexpected error for event within tolerances: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package runs A/B tests of pushes with Urban Airship's Experiments API.
+//
+// An Experiment sends one of its Variants to each device in its audience,
+// holding back a Control fraction of the audience which receives nothing.
+// Variants reuse push's content types, and their audience and device types
+// are the experiment's:
+//
+//	api := experiments.New(client)
+//	resp, err := api.Create(&experiments.Experiment{
+//		Name:     "Welcome copy",
+//		Audience: audience.Tag("new"),
+//		Control:  0.1,
+//		Variants: []*experiments.Variant{
+//			{Name: "short", Push: &experiments.VariantPush{Notification: &push.Notification{Alert: "Welcome!"}}},
+//			{Name: "long", Push: &experiments.VariantPush{Notification: &push.Notification{Alert: "Welcome, here's what's new"}}},
+//		},
+//	})
+//
+// List and ListScheduled page through experiments like schedules.List, Get
+// looks one up by its ID, and Delete removes an experiment which hasn't been
+// sent yet.
+package experiments
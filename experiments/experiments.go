@@ -0,0 +1,264 @@
+package experiments
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/lytics/gobyairship/push"
+	"github.com/lytics/gobyairship/schedules"
+)
+
+// DefaultURL is the Experiments API endpoint.
+const DefaultURL = "https://go.urbanairship.com/api/experiments"
+
+// MaxVariants is the most variants an experiment may have.
+const MaxVariants = 26
+
+// Client used to manage experiments. Usually *gobyairship.Client.
+type Client interface {
+	Get(url string, extra http.Header) (*http.Response, error)
+	Post(url string, body interface{}, extra http.Header) (*http.Response, error)
+	Delete(url string, extra http.Header) (*http.Response, error)
+}
+
+// VariantPush is the content of a variant. It's a push.Push without an
+// audience or device types, which are the experiment's.
+type VariantPush struct {
+	// Notification, InApp, and Message are the content of the push. At least
+	// one is required.
+	Notification *push.Notification `json:"notification,omitempty"`
+	InApp        *push.InApp        `json:"in_app,omitempty"`
+	Message      *push.Message      `json:"message,omitempty"`
+
+	Options *push.Options `json:"options,omitempty"`
+}
+
+// Variant is one version of an experiment's push.
+type Variant struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// Weight of the variant relative to the others: a variant with weight 2
+	// is sent to twice as many devices as one with weight 1. Zero is a
+	// weight of 1.
+	Weight int `json:"weight,omitempty"`
+
+	// Schedule sends the variant at a later time instead of immediately.
+	Schedule *schedules.When `json:"schedule,omitempty"`
+
+	Push *VariantPush `json:"push"`
+}
+
+// Experiment sends one of its variants to each device in its audience.
+type Experiment struct {
+	// ID and PushID identify the experiment and the push which sent it. Both
+	// are set by the API.
+	ID     string `json:"id,omitempty"`
+	PushID string `json:"push_id,omitempty"`
+
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// Control is the fraction of the audience, from 0 to 1, which receives
+	// no variant so their behavior can be compared.
+	Control float64 `json:"control,omitempty"`
+
+	// Audience and DeviceTypes are as a push.Push's and shared by every
+	// variant.
+	Audience    interface{}      `json:"audience"`
+	DeviceTypes push.DeviceTypes `json:"device_types"`
+
+	Variants []*Variant `json:"variants"`
+}
+
+// Validate returns nil if the experiment is valid or an error if there's an
+// issue which the Experiments API would reject.
+func (e *Experiment) Validate() error {
+	if e.Control < 0 || e.Control > 1 {
+		return fmt.Errorf("control must be from 0 to 1: %v", e.Control)
+	}
+	switch {
+	case len(e.Variants) == 0:
+		return errors.New("no variants")
+	case len(e.Variants) > MaxVariants:
+		return fmt.Errorf("at most %d variants are allowed: %d", MaxVariants, len(e.Variants))
+	}
+	for i, v := range e.Variants {
+		if err := e.validateVariant(v); err != nil {
+			return fmt.Errorf("invalid variant %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// validateVariant validates v's push as a push.Push to the experiment's
+// audience.
+func (e *Experiment) validateVariant(v *Variant) error {
+	switch {
+	case v == nil:
+		return errors.New("nil variant")
+	case v.Weight < 0:
+		return fmt.Errorf("negative weight: %d", v.Weight)
+	case v.Push == nil:
+		return errors.New("missing push")
+	}
+	if v.Schedule != nil {
+		if err := v.Schedule.Validate(); err != nil {
+			return err
+		}
+	}
+	p := &push.Push{
+		Audience:     e.Audience,
+		Notification: v.Push.Notification,
+		InApp:        v.Push.InApp,
+		Message:      v.Push.Message,
+		Options:      v.Push.Options,
+		DeviceTypes:  e.DeviceTypes,
+	}
+	return p.Validate()
+}
+
+// Response to a created experiment.
+type Response struct {
+	OK          bool   `json:"ok"`
+	OperationID string `json:"operation_id"`
+
+	ExperimentID string `json:"experiment_id"`
+	PushID       string `json:"push_id"`
+}
+
+// API manages the experiments of an app.
+type API struct {
+	c   Client
+	url string
+}
+
+// New creates an API using DefaultURL.
+func New(c Client) *API {
+	return NewURL(c, DefaultURL)
+}
+
+// NewURL creates an API using the Experiments API at url.
+func NewURL(c Client, url string) *API {
+	return &API{c: c, url: strings.TrimSuffix(url, "/")}
+}
+
+// experimentURL returns the URL of the experiment with id under base.
+func experimentURL(base, id string) (string, error) {
+	if id == "" || strings.Contains(id, "/") {
+		return "", fmt.Errorf("invalid experiment ID %q", id)
+	}
+	return base + "/" + id, nil
+}
+
+// Create an experiment, sending it immediately unless its variants are
+// scheduled. Invalid experiments return Validate's error without being sent.
+func (a *API) Create(e *Experiment) (*Response, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	// The IDs are read-only
+	cp := *e
+	cp.ID, cp.PushID = "", ""
+	resp, err := a.c.Post(a.url, &cp, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := &Response{}
+	if err := push.DecodeResult(resp, out, &out.OperationID); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Get the experiment with id.
+func (a *API) Get(id string) (*Experiment, error) {
+	url, err := experimentURL(a.url, id)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.c.Get(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	e := &Experiment{}
+	if err := push.DecodeResponse(resp, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Delete the scheduled experiment with id so it's never sent. Experiments
+// which have been sent can't be deleted.
+func (a *API) Delete(id string) error {
+	url, err := experimentURL(a.url+"/scheduled", id)
+	if err != nil {
+		return err
+	}
+	resp, err := a.c.Delete(url, nil)
+	if err != nil {
+		return err
+	}
+	return push.DecodeResponse(resp, nil)
+}
+
+// Pager pages through the experiments of an app.
+type Pager struct {
+	pages *push.Pages
+}
+
+// List returns a Pager starting at the first page of experiments, newest
+// first.
+func (a *API) List() *Pager {
+	return a.ListURL(a.url)
+}
+
+// ListScheduled returns a Pager starting at the first page of experiments
+// which haven't been sent yet.
+func (a *API) ListScheduled() *Pager {
+	return a.ListURL(a.url + "/scheduled")
+}
+
+// ListURL returns a Pager starting at url, such as a next page URL saved from
+// a previous listing.
+func (a *API) ListURL(url string) *Pager {
+	return &Pager{pages: push.NewPages(a.c, url)}
+}
+
+// NextURL returns the URL of the next page or an empty string if there are
+// no more pages.
+func (p *Pager) NextURL() string { return p.pages.NextURL() }
+
+// Next returns the next page of experiments. io.EOF is returned once every
+// page has been read.
+func (p *Pager) Next() ([]*Experiment, error) {
+	pg := struct {
+		Experiments []*Experiment `json:"experiments"`
+	}{}
+	if err := p.pages.Next(&pg); err != nil {
+		return nil, err
+	}
+	return pg.Experiments, nil
+}
+
+// Each calls fn for every remaining experiment, stopping at the first error.
+// A nil error is returned once every page has been read.
+func (p *Pager) Each(fn func(*Experiment) error) error {
+	for {
+		experiments, err := p.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, e := range experiments {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+}
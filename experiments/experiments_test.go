@@ -0,0 +1,207 @@
+package experiments_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/audience"
+	"github.com/lytics/gobyairship/experiments"
+	"github.com/lytics/gobyairship/push"
+	"github.com/lytics/gobyairship/schedules"
+)
+
+// server is a fake Experiments API storing experiments in memory. Experiments
+// with a scheduled variant are scheduled.
+type server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	experiments map[string]map[string]interface{}
+	ids         []string
+}
+
+func newServer(t *testing.T) *server {
+	s := &server{experiments: map[string]map[string]interface{}{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		path := strings.TrimPrefix(r.URL.Path, "/api/experiments")
+		switch {
+		case r.Method == "POST" && path == "":
+			var e map[string]interface{}
+			body, _ := ioutil.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &e); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if _, ok := e["id"]; ok {
+				t.Errorf("Expected an experiment without an ID but found %s", body)
+			}
+			id := fmt.Sprintf("e%d", len(s.ids)+1)
+			e["id"], e["push_id"] = id, "p"+id
+			s.experiments[id] = e
+			s.ids = append(s.ids, id)
+			w.Header().Set("UA-Operation-Id", "op")
+			w.WriteHeader(201)
+			fmt.Fprintf(w, `{"ok":true,"experiment_id":%q,"push_id":"p%s"}`, id, id)
+		case r.Method == "GET" && (path == "" || path == "/scheduled"):
+			var list []map[string]interface{}
+			for _, id := range s.ids {
+				if e := s.experiments[id]; e != nil && (path == "" || scheduled(e)) {
+					list = append(list, e)
+				}
+			}
+			// One experiment per page
+			start := 0
+			fmt.Sscan(r.URL.Query().Get("start"), &start)
+			page := map[string]interface{}{"ok": true, "total_count": len(list)}
+			if start < len(list) {
+				page["experiments"] = list[start : start+1]
+			}
+			if start+1 < len(list) {
+				page["next_page"] = fmt.Sprintf("%s%s?start=%d", s.URL, r.URL.Path, start+1)
+			}
+			json.NewEncoder(w).Encode(page)
+		case r.Method == "GET" && s.experiments[strings.TrimPrefix(path, "/")] != nil:
+			json.NewEncoder(w).Encode(s.experiments[strings.TrimPrefix(path, "/")])
+		case r.Method == "DELETE" && strings.HasPrefix(path, "/scheduled/"):
+			id := strings.TrimPrefix(path, "/scheduled/")
+			if e := s.experiments[id]; e == nil || !scheduled(e) {
+				w.WriteHeader(404)
+				w.Write([]byte(`{"ok":false,"error":"Could not find scheduled experiment","error_code":40401}`))
+				return
+			}
+			delete(s.experiments, id)
+			w.WriteHeader(204)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	return s
+}
+
+func scheduled(e map[string]interface{}) bool {
+	for _, v := range e["variants"].([]interface{}) {
+		if v.(map[string]interface{})["schedule"] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func variants(alerts ...string) []*experiments.Variant {
+	var out []*experiments.Variant
+	for _, a := range alerts {
+		out = append(out, &experiments.Variant{Name: a, Push: &experiments.VariantPush{Notification: &push.Notification{Alert: a}}})
+	}
+	return out
+}
+
+func TestExperiments(t *testing.T) {
+	t.Parallel()
+	srv := newServer(t)
+	defer srv.Close()
+	api := experiments.NewURL(gobyairship.NewClient("key", "token"), srv.URL+"/api/experiments")
+
+	resp, err := api.Create(&experiments.Experiment{
+		Name:     "now",
+		Audience: audience.Tag("new"),
+		Control:  0.1,
+		Variants: variants("Hi", "Hello"),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !resp.OK || resp.OperationID != "op" || resp.ExperimentID != "e1" || resp.PushID != "pe1" {
+		t.Fatalf("Unexpected response: %#v", resp)
+	}
+	later := variants("Later")
+	later[0].Weight = 2
+	later[0].Schedule = &schedules.When{ScheduledTime: time.Now().Add(time.Hour)}
+	if _, err := api.Create(&experiments.Experiment{Name: "later", Audience: audience.All, Variants: later}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	e, err := api.Get("e1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if e.ID != "e1" || e.PushID != "pe1" || e.Name != "now" || e.Control != 0.1 || len(e.Variants) != 2 {
+		t.Errorf("Unexpected experiment: %+v", e)
+	}
+	if v := e.Variants[1]; v.Push == nil || v.Push.Notification == nil || v.Push.Notification.Alert != "Hello" {
+		t.Errorf("Unexpected variant: %+v", v)
+	}
+
+	names := func(p *experiments.Pager) string {
+		var out []string
+		if err := p.Each(func(e *experiments.Experiment) error {
+			out = append(out, e.Name)
+			return nil
+		}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return strings.Join(out, ",")
+	}
+	if found := names(api.List()); found != "now,later" {
+		t.Errorf("Expected experiments now and later but found %s", found)
+	}
+	if found := names(api.ListScheduled()); found != "later" {
+		t.Errorf("Expected scheduled experiment later but found %s", found)
+	}
+
+	err = api.Delete("e1")
+	if apiErr, ok := err.(*push.APIError); !ok || apiErr.StatusCode != 404 {
+		t.Errorf("Expected a 404 *push.APIError deleting a sent experiment but found %v", err)
+	}
+	if err := api.Delete("e2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if found := names(api.ListScheduled()); found != "" {
+		t.Errorf("Expected no scheduled experiments but found %s", found)
+	}
+}
+
+func TestExperimentValidate(t *testing.T) {
+	t.Parallel()
+	many := variants(strings.Split(strings.Repeat("x", experiments.MaxVariants+1), "")...)
+	negative := variants("Hi")
+	negative[0].Weight = -1
+	badSchedule := variants("Hi")
+	badSchedule[0].Schedule = &schedules.When{}
+	invalid := map[string]*experiments.Experiment{
+		"missing audience":  {Variants: variants("Hi")},
+		"invalid audience":  {Audience: audience.Tag(""), Variants: variants("Hi")},
+		"no variants":       {Audience: audience.All},
+		"too many variants": {Audience: audience.All, Variants: many},
+		"nil variant":       {Audience: audience.All, Variants: []*experiments.Variant{nil}},
+		"missing push":      {Audience: audience.All, Variants: []*experiments.Variant{{Name: "empty"}}},
+		"empty push":        {Audience: audience.All, Variants: []*experiments.Variant{{Push: &experiments.VariantPush{}}}},
+		"negative control":  {Audience: audience.All, Control: -0.1, Variants: variants("Hi")},
+		"control over 1":    {Audience: audience.All, Control: 1.5, Variants: variants("Hi")},
+		"negative weight":   {Audience: audience.All, Variants: negative},
+		"invalid schedule":  {Audience: audience.All, Variants: badSchedule},
+		"unknown device":    {Audience: audience.All, DeviceTypes: push.DeviceTypes{"fax"}, Variants: variants("Hi")},
+	}
+	for name, e := range invalid {
+		if err := e.Validate(); err == nil {
+			t.Errorf("Expected an error with %s", name)
+		}
+	}
+
+	// Invalid experiments aren't sent
+	api := experiments.NewURL(gobyairship.NewClient("key", "token"), "http://127.0.0.1:0/api/experiments")
+	if _, err := api.Create(invalid["no variants"]); err == nil {
+		t.Errorf("Expected an error creating an invalid experiment")
+	}
+	if _, err := api.Get("../push"); err == nil {
+		t.Errorf("Expected an error getting an invalid experiment ID")
+	}
+}
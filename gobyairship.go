@@ -1,15 +1,57 @@
 package gobyairship
 
 import (
-	"bytes"
+	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"sync"
 )
 
 var ErrTooManyRedirects = errors.New("too many redirects")
 
+// ErrBodyNotReplayable is returned when a redirected request's body cannot be
+// resent. See PostReader.
+var ErrBodyNotReplayable = errors.New("request body cannot be resent")
+
+// DefaultVersion is the Urban Airship API version requested when a Client's
+// Version is not set.
+const DefaultVersion = 3
+
+// MediaTypeJSON is the media type of most Urban Airship API responses.
+const MediaTypeJSON = "application/vnd.urbanairship+json"
+
+// IdempotencyHeader carries a key identifying a mutating request so retries of
+// the same request can be correlated. The key is advisory only: Urban Airship
+// doesn't document the header or deduplicate requests carrying it, but it's
+// recorded by proxies and request logs. Retried requests may still be applied
+// more than once.
+const IdempotencyHeader = "X-UA-Idempotency-Key"
+
+// NewIdempotencyKey returns a random key suitable for the IdempotencyHeader.
+// Callers retrying requests themselves should generate one key and pass it
+// as an extra header on every attempt.
+func NewIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Accept returns an Accept header value requesting the given media type and
+// API version. Pass it as an extra header to override the Client's version for
+// a single request.
+func Accept(mediaType string, version int) string {
+	return fmt.Sprintf("%s;version=%d;", mediaType, version)
+}
+
 // Client is an Urban Airship API client. It handles authentication and
 // provides helpers for making requests against the API.
 type Client struct {
@@ -17,6 +59,39 @@ type Client struct {
 	// http.DefaultClient.
 	HTTPClient *http.Client
 
+	// Version of the API to request in the Accept header of every request.
+	// Defaults to DefaultVersion. Requests may override it by passing their own
+	// Accept header.
+	Version int
+
+	// Idempotent causes POSTs without an IdempotencyHeader to be sent with a
+	// new idempotency key. The key is reused when following redirects. See
+	// IdempotencyHeader for why it doesn't prevent duplicate requests.
+	Idempotent bool
+
+	// Limiter, if non-nil, caps the number of concurrent in-flight requests.
+	Limiter *Limiter
+
+	// OnResponse, if non-nil, is called with the URL and Meta of every
+	// response. Useful for logging operation IDs.
+	OnResponse func(url string, m *Meta)
+
+	// Clock used for time-based behavior such as measuring Limiter waits.
+	// Defaults to RealClock.
+	Clock Clock
+
+	// PingURL is requested by Ping. Defaults to DefaultPingURL.
+	PingURL string
+
+	// Timeouts determines the timeout of each request. Defaults to
+	// DefaultTimeoutPolicy. If nil requests never time out.
+	Timeouts *TimeoutPolicy
+
+	// StaticLabels, such as an environment or pipeline name, are added to the
+	// Labels of every response's Meta and propagated to consumers using the
+	// Client.
+	StaticLabels Labels
+
 	app_key      string
 	access_token string
 }
@@ -26,17 +101,114 @@ type Client struct {
 func NewClient(app_key, access_token string) *Client {
 	return &Client{
 		HTTPClient:   http.DefaultClient,
+		Timeouts:     DefaultTimeoutPolicy(),
 		app_key:      app_key,
 		access_token: access_token,
 	}
 }
 
+// APIVersion returns the API version requested by the Client.
+func (c *Client) APIVersion() int {
+	if c.Version == 0 {
+		return DefaultVersion
+	}
+	return c.Version
+}
+
+// TimeSource returns the Client's Clock or RealClock if it's nil. Helpers
+// built on the Client, such as event Streams, default to it.
+func (c *Client) TimeSource() Clock {
+	if c.Clock == nil {
+		return RealClock
+	}
+	return c.Clock
+}
+
 // Post a request to the Urban Airship API with the Client's credentials. If
 // body is non-nil it is marshaled to JSON and the appropriate headers are set.
 //
-// Extra headers an be added and will override any default values.
+// Extra headers an be added and will override any default values. Extra
+// headers are sent with every redirected request as well.
 func (c *Client) Post(url string, body interface{}, extra http.Header) (*http.Response, error) {
-	// Marshal body if it is non-nil
+	return c.PostContext(context.Background(), url, body, extra)
+}
+
+// PostContext is like Post but aborts the request, including reading the
+// response body, when ctx is done. Use WithTimeout to override the Client's
+// TimeoutPolicy for a single request.
+func (c *Client) PostContext(ctx context.Context, url string, body interface{}, extra http.Header) (*http.Response, error) {
+	return c.sendJSON(ctx, "POST", url, body, extra)
+}
+
+// PostReader posts the contents of body with the given content type. The body
+// is streamed instead of buffered in memory, making it suitable for bulk
+// uploads.
+//
+// If the API redirects the request body must be resent, which is only possible
+// if body is an io.Seeker; otherwise ErrBodyNotReplayable is returned. Bodies
+// whose length cannot be determined (via a Len method or by seeking) are sent
+// chunked, which some APIs reject.
+func (c *Client) PostReader(url string, body io.Reader, contentType string) (*http.Response, error) {
+	return c.PostReaderContext(context.Background(), url, body, contentType)
+}
+
+// PostReaderContext is like PostReader but aborts the request, including
+// reading the response body, when ctx is done.
+func (c *Client) PostReaderContext(ctx context.Context, url string, body io.Reader, contentType string) (*http.Response, error) {
+	return c.send(ctx, "POST", url, readerBody(body), contentType, nil)
+}
+
+// PostReaderFunc is like PostReader but calls body to create a new reader for
+// each attempt so bodies which cannot seek may be resent when redirected.
+func (c *Client) PostReaderFunc(url string, body func() (io.Reader, error), contentType string) (*http.Response, error) {
+	return c.PostReaderFuncContext(context.Background(), url, body, contentType)
+}
+
+// PostReaderFuncContext is like PostReaderFunc but aborts the request,
+// including reading the response body, when ctx is done.
+func (c *Client) PostReaderFuncContext(ctx context.Context, url string, body func() (io.Reader, error), contentType string) (*http.Response, error) {
+	return c.send(ctx, "POST", url, funcBody(body), contentType, nil)
+}
+
+// Get a resource from the Urban Airship API with the Client's credentials.
+// Extra headers override default values.
+func (c *Client) Get(url string, extra http.Header) (*http.Response, error) {
+	return c.GetContext(context.Background(), url, extra)
+}
+
+// GetContext is like Get but aborts the request, including reading the
+// response body, when ctx is done.
+func (c *Client) GetContext(ctx context.Context, url string, extra http.Header) (*http.Response, error) {
+	return c.send(ctx, "GET", url, nil, "", extra)
+}
+
+// Put a resource to the Urban Airship API with the Client's credentials. If
+// body is non-nil it is marshaled to JSON like Post's. Extra headers override
+// default values.
+func (c *Client) Put(url string, body interface{}, extra http.Header) (*http.Response, error) {
+	return c.PutContext(context.Background(), url, body, extra)
+}
+
+// PutContext is like Put but aborts the request, including reading the
+// response body, when ctx is done.
+func (c *Client) PutContext(ctx context.Context, url string, body interface{}, extra http.Header) (*http.Response, error) {
+	return c.sendJSON(ctx, "PUT", url, body, extra)
+}
+
+// Delete a resource from the Urban Airship API with the Client's credentials.
+// Extra headers override default values.
+func (c *Client) Delete(url string, extra http.Header) (*http.Response, error) {
+	return c.DeleteContext(context.Background(), url, extra)
+}
+
+// DeleteContext is like Delete but aborts the request, including reading the
+// response body, when ctx is done.
+func (c *Client) DeleteContext(ctx context.Context, url string, extra http.Header) (*http.Response, error) {
+	return c.send(ctx, "DELETE", url, nil, "", extra)
+}
+
+// sendJSON sends body, if it's non-nil, marshaled to JSON.
+func (c *Client) sendJSON(ctx context.Context, method, url string, body interface{}, extra http.Header) (*http.Response, error) {
 	var buf []byte
 	if body != nil {
 		var err error
@@ -45,24 +217,71 @@ func (c *Client) Post(url string, body interface{}, extra http.Header) (*http.Re
 			return nil, err
 		}
 	}
+	return c.send(ctx, method, url, bytesBody(buf), "application/json", extra)
+}
+
+// send a request applying the Client's Limiter and TimeoutPolicy.
+func (c *Client) send(ctx context.Context, method, url string, body bodyFunc, contentType string, extra http.Header) (*http.Response, error) {
+	// Funcs to call once the request completes or its body is closed
+	var done []func()
+	finish := func() {
+		for _, f := range done {
+			f()
+		}
+	}
+
+	// The timeout also bounds waiting for a slot in the Limiter
+	if d := c.Timeouts.timeout(ctx, url); d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		done = append(done, cancel)
+	}
+	if c.Limiter != nil {
+		if err := c.Limiter.acquire(ctx, c.TimeSource()); err != nil {
+			finish()
+			return nil, err
+		}
+		done = append(done, c.Limiter.release)
+	}
 
-	req, err := c.newRequest("POST", url, buf)
+	resp, err := c.do(ctx, method, url, body, contentType, extra)
 	if err != nil {
+		finish()
 		return nil, err
 	}
-	if extra != nil {
+	if len(done) > 0 {
+		resp.Body = &hookBody{ReadCloser: resp.Body, hook: finish}
+	}
+	return resp, nil
+}
+
+func (c *Client) do(ctx context.Context, method, url string, body bodyFunc, contentType string, extra http.Header) (*http.Response, error) {
+	if c.Idempotent && method == "POST" {
+		// Copy extra headers to avoid mutating the caller's
+		h := http.Header{}
 		for k, v := range extra {
-			ck := http.CanonicalHeaderKey(k)
-			req.Header[ck] = v
+			h[http.CanonicalHeaderKey(k)] = v
+		}
+		if h.Get(IdempotencyHeader) == "" {
+			h.Set(IdempotencyHeader, NewIdempotencyKey())
 		}
+		extra = h
 	}
 
-	resp, err := c.HTTPClient.Do(req)
+	hc := c.httpClient()
+	req, err := c.newRequest(method, url, body, contentType)
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
+	setHeaders(req, extra)
 
-	// The Urban Airship API may respond with a 307 + Set-Cookie on POSTs which
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The Urban Airship API may respond with a 307 + Set-Cookie on requests which
 	// is non-standard and therefore handled by this wrapper method instead of by
 	// Go's http.Client. Give up after 10 redirects.
 	try := 0
@@ -72,7 +291,7 @@ func (c *Client) Post(url string, body interface{}, extra http.Header) (*http.Re
 		ioutil.ReadAll(resp.Body)
 		resp.Body.Close()
 
-		// POST to specified location (if one specified)
+		// Resend to specified location (if one specified)
 		loc, err := resp.Location()
 		if err != nil && err != http.ErrNoLocation {
 			return nil, err
@@ -82,16 +301,18 @@ func (c *Client) Post(url string, body interface{}, extra http.Header) (*http.Re
 			url = loc.String()
 		}
 
-		req, err := c.newRequest("POST", url, buf)
+		req, err := c.newRequest(method, url, body, contentType)
 		if err != nil {
 			return nil, err
 		}
+		req = req.WithContext(ctx)
+		setHeaders(req, extra)
 
 		// Set the cookie token if it's sent
 		if cookie := resp.Header.Get("Set-Cookie"); cookie != "" {
 			req.Header.Add("Cookie", cookie)
 		}
-		resp, err = c.HTTPClient.Do(req)
+		resp, err = hc.Do(req)
 		if err != nil {
 			return nil, err
 		}
@@ -102,24 +323,80 @@ func (c *Client) Post(url string, body interface{}, extra http.Header) (*http.Re
 		resp.Body.Close()
 		return nil, ErrTooManyRedirects
 	}
+	if c.OnResponse != nil {
+		m := parseMeta(resp, c.TimeSource().Now())
+		m.Labels = c.Labels()
+		c.OnResponse(url, m)
+	}
 	return resp, nil
 }
 
-// newRequest adds auth and accept headers to an Urban Airship API
-// request. If buf is non-nil it is assumed to be JSON.
-func (c *Client) newRequest(method, url string, buf []byte) (*http.Request, error) {
+// httpClient returns a copy of HTTPClient which returns 307 responses instead
+// of following them so do can resend the body and cookie.
+func (c *Client) httpClient() *http.Client {
+	hc := *c.HTTPClient
+	check := hc.CheckRedirect
+	hc.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.Response != nil && req.Response.StatusCode == http.StatusTemporaryRedirect {
+			return http.ErrUseLastResponse
+		}
+		if check != nil {
+			return check(req, via)
+		}
+		if len(via) >= 10 {
+			return ErrTooManyRedirects
+		}
+		return nil
+	}
+	return &hc
+}
+
+// hookBody calls hook once when the body is closed.
+type hookBody struct {
+	io.ReadCloser
+	once sync.Once
+	hook func()
+}
+
+func (b *hookBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.hook)
+	return err
+}
+
+// setHeaders on a request, overriding existing values.
+func setHeaders(req *http.Request, extra http.Header) {
+	for k, v := range extra {
+		ck := http.CanonicalHeaderKey(k)
+		req.Header[ck] = v
+	}
+}
+
+// newRequest adds auth and accept headers to an Urban Airship API request. If
+// body is non-nil it's called for the request's body.
+func (c *Client) newRequest(method, url string, body bodyFunc, contentType string) (*http.Request, error) {
 	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("X-UA-Appkey", c.app_key)
 	req.Header.Set("Authorization", "Bearer "+c.access_token)
-	if len(buf) > 0 {
-		req.Body = ioutil.NopCloser(bytes.NewReader(buf))
-		req.Header.Set("Content-Type", "application/json")
-
-		// Urban Airship APIs do not support chunked requests; set the Content-Length
-		req.ContentLength = int64(len(buf))
+	req.Header.Set("Accept", Accept(MediaTypeJSON, c.APIVersion()))
+	if body == nil {
+		return req, nil
 	}
+	r, n, err := body()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return req, nil
+	}
+	req.Body = ioutil.NopCloser(r)
+	req.Header.Set("Content-Type", contentType)
+
+	// Urban Airship APIs do not support chunked requests; set the Content-Length
+	// when it's known
+	req.ContentLength = n
 	return req, nil
 }
@@ -0,0 +1,76 @@
+package gobyairship
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Label keys set by this library.
+const (
+	LabelAppKey    = "app_key"
+	LabelPartition = "partition"
+)
+
+// Labels identify the source of metrics, logs, and errors, such as the masked
+// app key, event stream partition, or static labels like an environment or
+// pipeline name.
+type Labels map[string]string
+
+// Merge returns a new Labels containing l's labels overridden by others'.
+func (l Labels) Merge(others ...Labels) Labels {
+	out := Labels{}
+	for k, v := range l {
+		out[k] = v
+	}
+	for _, o := range others {
+		for k, v := range o {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// String formats labels as sorted key=value pairs suitable for logs.
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + l[k]
+	}
+	return strings.Join(pairs, " ")
+}
+
+// MaskAppKey returns the first 4 characters of an app key followed by "..." so
+// apps can be distinguished in logs without exposing the full key.
+func MaskAppKey(key string) string {
+	if len(key) <= 4 {
+		return key
+	}
+	return key[:4] + "..."
+}
+
+// LabeledError annotates an error with the Labels of where it occurred.
+type LabeledError struct {
+	Labels Labels
+	Err    error
+}
+
+func (e *LabeledError) Error() string {
+	if len(e.Labels) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v [%s]", e.Err, e.Labels)
+}
+
+// Cause returns the underlying error.
+func (e *LabeledError) Cause() error { return e.Err }
+
+// Labels returns the Client's StaticLabels along with its masked app key.
+func (c *Client) Labels() Labels {
+	return c.StaticLabels.Merge(Labels{LabelAppKey: MaskAppKey(c.app_key)})
+}
@@ -0,0 +1,32 @@
+package gobyairship_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/lytics/gobyairship"
+)
+
+func TestLabels(t *testing.T) {
+	t.Parallel()
+	c := NewClient("0123456789abcdef", "secret")
+	if l := c.Labels(); len(l) != 1 || l[LabelAppKey] != "0123..." {
+		t.Errorf("Unexpected labels: %v", l)
+	}
+	c.StaticLabels = Labels{"env": "prod", LabelAppKey: "overridden"}
+	l := c.Labels()
+	if s := l.String(); s != "app_key=0123... env=prod" {
+		t.Errorf("Unexpected labels: %s", s)
+	}
+	if merged := l.Merge(Labels{"env": "dev"}); merged["env"] != "dev" || l["env"] != "prod" {
+		t.Errorf("Merge should override without mutating: %v %v", merged, l)
+	}
+	if k := MaskAppKey("abc"); k != "abc" {
+		t.Errorf("Short keys should not be masked: %q", k)
+	}
+
+	err := &LabeledError{Labels: l, Err: errors.New("boom")}
+	if s := err.Error(); s != "boom [app_key=0123... env=prod]" {
+		t.Errorf("Unexpected error string: %s", s)
+	}
+}
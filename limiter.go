@@ -0,0 +1,68 @@
+package gobyairship
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrLimited is returned when all of a Limiter's slots are in use and the
+// Limiter does not queue requests.
+var ErrLimited = errors.New("too many concurrent requests")
+
+// Limiter caps the number of concurrent in-flight requests made by a Client.
+// Urban Airship limits simultaneous connections to some APIs and responds with
+// a 402 when the limit is exceeded.
+//
+// Queued requests stop waiting with their context's error when it's done,
+// including when the Client's Timeouts expire.
+//
+// A request is in-flight until its response body is closed, so streaming
+// responses such as events hold their slot for the life of the stream.
+//
+// Limiters must be created with NewLimiter.
+type Limiter struct {
+	// Queue causes requests to wait for a free slot instead of failing with
+	// ErrLimited.
+	Queue bool
+
+	// OnWait, if non-nil, is called with how long each request waited for a
+	// slot.
+	OnWait func(time.Duration)
+
+	sem chan struct{}
+}
+
+// NewLimiter creates a Limiter allowing n concurrent requests.
+func NewLimiter(n int) *Limiter {
+	return &Limiter{sem: make(chan struct{}, n)}
+}
+
+// InFlight returns the number of slots in use.
+func (l *Limiter) InFlight() int { return len(l.sem) }
+
+func (l *Limiter) acquire(ctx context.Context, clock Clock) error {
+	select {
+	case l.sem <- struct{}{}:
+		if l.OnWait != nil {
+			l.OnWait(0)
+		}
+		return nil
+	default:
+	}
+	if !l.Queue {
+		return ErrLimited
+	}
+	start := clock.Now()
+	select {
+	case l.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if l.OnWait != nil {
+		l.OnWait(clock.Now().Sub(start))
+	}
+	return nil
+}
+
+func (l *Limiter) release() { <-l.sem }
@@ -0,0 +1,81 @@
+package gobyairship
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Meta is metadata Urban Airship includes in API responses. Include the
+// OperationID when contacting Urban Airship support about a request.
+type Meta struct {
+	// StatusCode of the response.
+	StatusCode int
+
+	// OperationID is the UA-Operation-Id header which uniquely identifies the
+	// request.
+	OperationID string
+
+	// ContentType of the response body.
+	ContentType string
+
+	RateLimit RateLimit
+
+	// Labels of the Client which made the request. Only set for Meta passed to
+	// Client.OnResponse.
+	Labels Labels
+}
+
+// RateLimit headers from a response. Fields are zero when the corresponding
+// header was not sent.
+type RateLimit struct {
+	// Limit is the number of requests allowed in the current window.
+	Limit int
+
+	// Remaining is the number of requests left in the current window.
+	Remaining int
+
+	// Reset is when the current window ends.
+	Reset time.Time
+
+	// RetryAfter is how long to wait before retrying a rate limited request.
+	RetryAfter time.Duration
+}
+
+// ParseMeta extracts Meta from an API response's headers.
+func ParseMeta(resp *http.Response) *Meta {
+	return parseMeta(resp, time.Now())
+}
+
+func parseMeta(resp *http.Response, now time.Time) *Meta {
+	h := resp.Header
+	m := &Meta{
+		StatusCode:  resp.StatusCode,
+		OperationID: h.Get("UA-Operation-Id"),
+		ContentType: h.Get("Content-Type"),
+	}
+	m.RateLimit.Limit, _ = strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	m.RateLimit.Remaining, _ = strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		m.RateLimit.Reset = time.Unix(reset, 0)
+	}
+	m.RateLimit.RetryAfter = RetryAfter(h, now)
+	return m
+}
+
+// RetryAfter parses the Retry-After header which may be either a number of
+// seconds or an HTTP date. Zero is returned if the header is missing or
+// invalid.
+func RetryAfter(h http.Header, now time.Time) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
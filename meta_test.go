@@ -0,0 +1,76 @@
+package gobyairship_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/lytics/gobyairship"
+)
+
+func TestMeta(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("UA-Operation-Id", "op-1")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "5")
+		w.Header().Set("X-RateLimit-Reset", "1432726327")
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(429)
+	}))
+	defer ts.Close()
+
+	var meta *Meta
+	c := NewClient("appkey123", "")
+	c.StaticLabels = Labels{"env": "test"}
+	c.OnResponse = func(url string, m *Meta) { meta = m }
+	resp, err := c.Post(ts.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if meta == nil {
+		t.Fatal("OnResponse not called")
+	}
+	expected := Meta{
+		StatusCode:  429,
+		OperationID: "op-1",
+		ContentType: "application/json",
+		RateLimit: RateLimit{
+			Limit:      100,
+			Remaining:  5,
+			Reset:      time.Unix(1432726327, 0),
+			RetryAfter: 30 * time.Second,
+		},
+		Labels: Labels{"env": "test", LabelAppKey: "appk..."},
+	}
+	if !reflect.DeepEqual(*meta, expected) {
+		t.Errorf("Expected %#v but found %#v", expected, *meta)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2015, 5, 27, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"junk":                          0,
+		"120":                           2 * time.Minute,
+		"Wed, 27 May 2015 12:01:00 GMT": time.Minute,
+		"Wed, 27 May 2015 11:00:00 GMT": 0,
+	}
+	for v, expected := range tests {
+		h := http.Header{}
+		if v != "" {
+			h.Set("Retry-After", v)
+		}
+		if d := RetryAfter(h, now); d != expected {
+			t.Errorf("Retry-After %q: expected %s but found %s", v, expected, d)
+		}
+	}
+}
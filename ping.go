@@ -0,0 +1,74 @@
+package gobyairship
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// DefaultPingURL is a lightweight authenticated endpoint requested by Ping.
+const DefaultPingURL = "https://go.urbanairship.com/api/channels?limit=1"
+
+// ErrUnauthorized is returned by Ping when the Client's credentials are
+// rejected.
+var ErrUnauthorized = errors.New("credentials rejected")
+
+// PingResult describes the outcome of a Ping.
+type PingResult struct {
+	// Reachable is true if the API responded.
+	Reachable bool
+
+	// AuthOK is true if the API accepted the Client's credentials.
+	AuthOK bool
+
+	// StatusCode of the response if Reachable.
+	StatusCode int
+
+	// OperationID of the response if Reachable.
+	OperationID string
+
+	// Latency until response headers were received.
+	Latency time.Duration
+}
+
+// Ping performs a lightweight authenticated request against PingURL (or
+// DefaultPingURL) to verify the API is reachable and the Client's credentials
+// are valid. Long-running consumers should Ping at startup. The request is
+// sent like any other, applying the Client's Limiter, Timeouts, and
+// OnResponse.
+//
+// A non-nil PingResult is always returned. The error is non-nil if the API
+// was unreachable, rejected the credentials (ErrUnauthorized), or returned any
+// other non-2xx status.
+func (c *Client) Ping(ctx context.Context) (*PingResult, error) {
+	url := c.PingURL
+	if url == "" {
+		url = DefaultPingURL
+	}
+	result := &PingResult{}
+	start := c.TimeSource().Now()
+	resp, err := c.send(ctx, "GET", url, nil, "", nil)
+	if err != nil {
+		return result, err
+	}
+	result.Latency = c.TimeSource().Now().Sub(start)
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	result.Reachable = true
+	result.StatusCode = resp.StatusCode
+	result.OperationID = resp.Header.Get("UA-Operation-Id")
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return result, ErrUnauthorized
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		result.AuthOK = true
+		return result, fmt.Errorf("unexpected ping response: %d", resp.StatusCode)
+	}
+	result.AuthOK = true
+	return result, nil
+}
@@ -0,0 +1,63 @@
+package gobyairship_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/lytics/gobyairship"
+)
+
+func TestPing(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.WriteHeader(405)
+			return
+		}
+		w.Header().Set("UA-Operation-Id", "op")
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(`{"ok":true,"channels":[]}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient("key", "token")
+	c.PingURL = ts.URL
+	responses := 0
+	c.OnResponse = func(string, *Meta) { responses++ }
+	res, err := c.Ping(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !res.Reachable || !res.AuthOK || res.StatusCode != 200 || res.OperationID != "op" {
+		t.Errorf("Unexpected result: %#v", res)
+	}
+	if responses != 1 {
+		t.Errorf("Expected OnResponse to be called once but found %d", responses)
+	}
+
+	c = NewClient("key", "wrong")
+	c.PingURL = ts.URL
+	res, err = c.Ping(context.Background())
+	if err != ErrUnauthorized {
+		t.Errorf("Expected ErrUnauthorized but received: %v", err)
+	}
+	if !res.Reachable || res.AuthOK {
+		t.Errorf("Unexpected result: %#v", res)
+	}
+
+	// Unreachable
+	ts.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err = c.Ping(ctx)
+	if err == nil || res.Reachable {
+		t.Errorf("Expected error pinging closed server: %v %#v", err, res)
+	}
+}
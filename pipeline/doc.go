@@ -0,0 +1,23 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package provides a runtime for long-running event pipelines built
+// from named components such as event sources, transforms, and sinks.
+//
+// Components are run by a Supervisor which restarts them when they fail
+// according to its Strategy. Supervisors are Components themselves so they
+// may be nested into a supervision tree, with failures escalating upward
+// until a Supervisor handles them. Status reports the state of every
+// component in the tree.
+package pipeline
@@ -0,0 +1,278 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lytics/gobyairship"
+)
+
+// Component is a long-running part of a pipeline. Run should block until ctx
+// is done, returning nil, or until the component fails, returning an error.
+type Component interface {
+	Run(ctx context.Context) error
+}
+
+// ComponentFunc adapts a function to the Component interface.
+type ComponentFunc func(ctx context.Context) error
+
+// Run calls f(ctx).
+func (f ComponentFunc) Run(ctx context.Context) error { return f(ctx) }
+
+// Strategy determines how a Supervisor handles failed components.
+type Strategy int
+
+const (
+	// OneForOne restarts only the failed component. If it fails more than
+	// MaxRestarts times within the Supervisor's Window the failure is
+	// escalated.
+	OneForOne Strategy = iota
+
+	// Escalate stops all of the Supervisor's components and returns the
+	// failure from Run, passing it to the parent Supervisor if there is one.
+	Escalate
+)
+
+// State of a component.
+type State string
+
+const (
+	StateRunning    State = "running"
+	StateRestarting State = "restarting"
+	StateStopped    State = "stopped"
+	StateFailed     State = "failed"
+)
+
+// Status of a component. Children is set for nested Supervisors.
+type Status struct {
+	Name      string    `json:"name"`
+	State     State     `json:"state"`
+	Since     time.Time `json:"since"`
+	Restarts  int       `json:"restarts"`
+	LastError string    `json:"last_error,omitempty"`
+	Children  []Status  `json:"children,omitempty"`
+}
+
+// Supervisor runs named components and restarts them according to its
+// Strategy. Configure exported fields and Add components before calling Run.
+type Supervisor struct {
+	Name     string
+	Strategy Strategy
+
+	// MaxRestarts is how many times a component may be restarted within
+	// Window before the failure is escalated. Zero values default to 5 in a
+	// 1 minute Window.
+	MaxRestarts int
+	Window      time.Duration
+
+	// RestartDelay is how long to wait before restarting a failed component.
+	RestartDelay time.Duration
+
+	// Backoff, if non-nil, determines the wait before restarting a failed
+	// component instead of RestartDelay. Attempts count restarts within
+	// Window.
+	Backoff gobyairship.Backoff
+
+	// Clock defaults to gobyairship.RealClock.
+	Clock gobyairship.Clock
+
+	mu       sync.Mutex
+	children []*child
+}
+
+type child struct {
+	name     string
+	c        Component
+	state    State
+	since    time.Time
+	restarts []time.Time // recent restarts within Window
+	total    int
+	lastErr  error
+}
+
+// Default restart limits of a Supervisor.
+const (
+	DefaultMaxRestarts = 5
+	DefaultWindow      = time.Minute
+)
+
+// NewSupervisor creates a Supervisor with the default restart limits.
+func NewSupervisor(name string, strategy Strategy) *Supervisor {
+	return &Supervisor{Name: name, Strategy: strategy}
+}
+
+// Add a named component. Must be called before Run.
+func (s *Supervisor) Add(name string, c Component) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.children = append(s.children, &child{name: name, c: c, state: StateStopped})
+}
+
+func (s *Supervisor) clock() gobyairship.Clock {
+	if s.Clock == nil {
+		return gobyairship.RealClock
+	}
+	return s.Clock
+}
+
+// ComponentError is returned by Supervisor.Run when a component's failure is
+// escalated.
+type ComponentError struct {
+	Supervisor string
+	Component  string
+	Err        error
+}
+
+func (e *ComponentError) Error() string {
+	return fmt.Sprintf("%s/%s: %v", e.Supervisor, e.Component, e.Err)
+}
+
+// Run all components until ctx is done, returning nil, or until a failure is
+// escalated, returning a *ComponentError after stopping all components.
+// Components which return nil before ctx is done are not restarted.
+func (s *Supervisor) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.mu.Lock()
+	children := append([]*child(nil), s.children...)
+	s.mu.Unlock()
+
+	escalated := make(chan error, len(children))
+	wg := sync.WaitGroup{}
+	for _, ch := range children {
+		wg.Add(1)
+		go func(ch *child) {
+			defer wg.Done()
+			if err := s.supervise(ctx, ch); err != nil {
+				escalated <- err
+			}
+		}(ch)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case err := <-escalated:
+		cancel()
+		<-done
+		return err
+	case <-done:
+		select {
+		case err := <-escalated:
+			return err
+		default:
+		}
+		return nil
+	}
+}
+
+// supervise runs a single component, restarting it as needed. Returns an
+// error if the failure should be escalated.
+func (s *Supervisor) supervise(ctx context.Context, ch *child) error {
+	var last time.Duration
+	for {
+		s.setState(ch, StateRunning, nil)
+		err := ch.c.Run(ctx)
+		if ctx.Err() != nil || err == nil {
+			s.setState(ch, StateStopped, err)
+			return nil
+		}
+		if s.Strategy == Escalate || !s.restart(ch) {
+			s.setState(ch, StateFailed, err)
+			return &ComponentError{Supervisor: s.Name, Component: ch.name, Err: err}
+		}
+		s.setState(ch, StateRestarting, err)
+		delay := s.RestartDelay
+		if s.Backoff != nil {
+			delay = s.Backoff.Next(s.attempts(ch), last)
+			last = delay
+		}
+		if delay > 0 {
+			select {
+			case <-s.clock().After(delay):
+			case <-ctx.Done():
+				s.setState(ch, StateStopped, err)
+				return nil
+			}
+		}
+	}
+}
+
+// restart records a restart and returns false if the component has exceeded
+// MaxRestarts within Window.
+func (s *Supervisor) restart(ch *child) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	max, window := s.MaxRestarts, s.Window
+	if max <= 0 {
+		max = DefaultMaxRestarts
+	}
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	now := s.clock().Now()
+	recent := ch.restarts[:0]
+	for _, t := range ch.restarts {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	ch.restarts = recent
+	if len(ch.restarts) >= max {
+		return false
+	}
+	ch.restarts = append(ch.restarts, now)
+	ch.total++
+	return true
+}
+
+// attempts returns the number of recent restarts of a component.
+func (s *Supervisor) attempts(ch *child) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(ch.restarts)
+}
+
+func (s *Supervisor) setState(ch *child, state State, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch.state = state
+	ch.since = s.clock().Now()
+	if err != nil {
+		ch.lastErr = err
+	}
+}
+
+// Status returns the status of every component, including the components of
+// nested Supervisors. Safe to call concurrently with Run.
+func (s *Supervisor) Status() []Status {
+	s.mu.Lock()
+	children := append([]*child(nil), s.children...)
+	statuses := make([]Status, len(children))
+	for i, ch := range children {
+		statuses[i] = Status{
+			Name:     ch.name,
+			State:    ch.state,
+			Since:    ch.since,
+			Restarts: ch.total,
+		}
+		if ch.lastErr != nil {
+			statuses[i].LastError = ch.lastErr.Error()
+		}
+	}
+	s.mu.Unlock()
+
+	for i, ch := range children {
+		if sub, ok := ch.c.(*Supervisor); ok {
+			statuses[i].Children = sub.Status()
+		}
+	}
+	return statuses
+}
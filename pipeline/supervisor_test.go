@@ -0,0 +1,129 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/pipeline"
+)
+
+var errBoom = errors.New("boom")
+
+// failing returns a component which fails n times before running until its
+// context is done.
+func failing(n int32, runs *int32) pipeline.Component {
+	return pipeline.ComponentFunc(func(ctx context.Context) error {
+		if atomic.AddInt32(runs, 1) <= n {
+			return errBoom
+		}
+		<-ctx.Done()
+		return nil
+	})
+}
+
+func TestOneForOne(t *testing.T) {
+	t.Parallel()
+	var flaky, steady int32
+	s := pipeline.NewSupervisor("root", pipeline.OneForOne)
+	s.Add("flaky", failing(3, &flaky))
+	s.Add("steady", failing(0, &steady))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for atomic.LoadInt32(&flaky) < 4 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	st := s.Status()
+	if st[0].Name != "flaky" || st[0].State != pipeline.StateRunning || st[0].Restarts != 3 || st[0].LastError != "boom" {
+		t.Errorf("Unexpected flaky status: %#v", st[0])
+	}
+	if st[1].State != pipeline.StateRunning || st[1].Restarts != 0 || atomic.LoadInt32(&steady) != 1 {
+		t.Errorf("Expected steady component to run once: %#v", st[1])
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	for _, st := range s.Status() {
+		if st.State != pipeline.StateStopped {
+			t.Errorf("Expected %s to be stopped but found %s", st.Name, st.State)
+		}
+	}
+}
+
+func TestEscalate(t *testing.T) {
+	t.Parallel()
+	var flaky, steady, other int32
+
+	// Child supervisor escalates immediately; root restarts it a limited
+	// number of times
+	child := pipeline.NewSupervisor("child", pipeline.Escalate)
+	child.Add("flaky", failing(100, &flaky))
+	child.Add("steady", failing(0, &steady))
+
+	root := pipeline.NewSupervisor("root", pipeline.OneForOne)
+	root.MaxRestarts = 2
+	root.Add("child", child)
+	root.Add("other", failing(0, &other))
+
+	err := root.Run(context.Background())
+	cerr, ok := err.(*pipeline.ComponentError)
+	if !ok || cerr.Supervisor != "root" || cerr.Component != "child" {
+		t.Fatalf("Expected child failure to escalate from root but received: %v", err)
+	}
+	if inner, ok := cerr.Err.(*pipeline.ComponentError); !ok || inner.Component != "flaky" || inner.Err != errBoom {
+		t.Errorf("Unexpected inner error: %v", cerr.Err)
+	}
+	if n := atomic.LoadInt32(&flaky); n != 3 {
+		t.Errorf("Expected flaky to run 3 times but ran %d", n)
+	}
+
+	st := root.Status()
+	if st[0].State != pipeline.StateFailed || len(st[0].Children) != 2 || st[0].Children[0].State != pipeline.StateFailed {
+		t.Errorf("Unexpected status: %#v", st)
+	}
+	if st[1].State != pipeline.StateStopped {
+		t.Errorf("Expected other component to be stopped: %#v", st[1])
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+	var runs int32
+	var attempts []int
+	s := pipeline.NewSupervisor("root", pipeline.OneForOne)
+	s.MaxRestarts = 3
+	s.Backoff = gobyairship.BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+		attempts = append(attempts, attempt)
+		return time.Millisecond
+	})
+	s.Add("flaky", failing(100, &runs))
+	if err := s.Run(context.Background()); err == nil {
+		t.Fatalf("Expected failure to escalate")
+	}
+	if len(attempts) != 3 || attempts[0] != 1 || attempts[2] != 3 {
+		t.Errorf("Unexpected backoff attempts: %v", attempts)
+	}
+}
+
+func TestZeroSupervisor(t *testing.T) {
+	t.Parallel()
+	var runs int32
+	s := &pipeline.Supervisor{Name: "root"}
+	s.Add("flaky", failing(100, &runs))
+	if err := s.Run(context.Background()); err == nil {
+		t.Fatalf("Expected failure to escalate")
+	}
+	if n := atomic.LoadInt32(&runs); n != pipeline.DefaultMaxRestarts+1 {
+		t.Errorf("Expected flaky to run %d times but ran %d", pipeline.DefaultMaxRestarts+1, n)
+	}
+}
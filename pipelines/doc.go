@@ -0,0 +1,37 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package manages automation with Urban Airship's Automation API, whose
+// rules are called pipelines. It's unrelated to package pipeline, which runs
+// this library's own components.
+//
+// A Pipeline sends its Outcome's push when a device matches its trigger, such
+// as when a tag is added, the app is first opened, or a custom event occurs.
+// The push is sent to the device which triggered it unless its audience says
+// otherwise:
+//
+//	api := pipelines.New(client)
+//	resp, err := api.Create(&pipelines.Pipeline{
+//		Name:             "Welcome",
+//		Enabled:          true,
+//		ImmediateTrigger: pipelines.FirstOpen,
+//		Outcome: &pipelines.Outcome{
+//			Delay: 3600,
+//			Push:  &push.Push{Notification: &push.Notification{Alert: "Welcome!"}},
+//		},
+//	})
+//
+// Get, Update, and Delete manage a single pipeline by its ID, and Activate
+// and Deactivate enable or disable it.
+package pipelines
@@ -0,0 +1,281 @@
+package pipelines
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lytics/gobyairship/push"
+)
+
+// DefaultURL is the Automation API endpoint.
+const DefaultURL = "https://go.urbanairship.com/api/pipelines"
+
+// Triggered is the audience of an outcome's push sent to the device which
+// triggered the pipeline. It's the default audience of outcomes.
+const Triggered = "triggered"
+
+// Client used to manage pipelines. Usually *gobyairship.Client.
+type Client interface {
+	Get(url string, extra http.Header) (*http.Response, error)
+	Post(url string, body interface{}, extra http.Header) (*http.Response, error)
+	Put(url string, body interface{}, extra http.Header) (*http.Response, error)
+	Delete(url string, extra http.Header) (*http.Response, error)
+}
+
+// Outcome is what a pipeline does when it's triggered.
+type Outcome struct {
+	// Delay is the number of seconds after the trigger the push is sent.
+	Delay int `json:"delay,omitempty"`
+
+	// Push is sent when the pipeline is triggered. A nil Audience is
+	// Triggered.
+	Push *push.Push `json:"push"`
+}
+
+// push returns the outcome's push with its default audience.
+func (o *Outcome) push() *push.Push {
+	if o.Push == nil || o.Push.Audience != nil {
+		return o.Push
+	}
+	cp := *o.Push
+	cp.Audience = Triggered
+	return &cp
+}
+
+// MarshalJSON sets the default audience of the push.
+func (o *Outcome) MarshalJSON() ([]byte, error) {
+	type plain Outcome
+	out := plain(*o)
+	out.Push = o.push()
+	return json.Marshal(&out)
+}
+
+// Pipeline is an automation rule which sends a push when it's triggered.
+type Pipeline struct {
+	// URL of the pipeline, set by the API.
+	URL string `json:"url,omitempty"`
+
+	Name string `json:"name,omitempty"`
+
+	// Enabled pipelines are triggered. Disabled pipelines are kept but
+	// ignored until they're activated.
+	Enabled bool `json:"enabled"`
+
+	// ImmediateTrigger starts the pipeline. It's usually a Trigger but is
+	// marshaled as is, so it may also be a raw trigger such as
+	// map[string]interface{}{"first_seen": true}. Pipelines from the API
+	// have raw triggers decoded from JSON.
+	ImmediateTrigger interface{} `json:"immediate_trigger"`
+
+	Outcome *Outcome `json:"outcome"`
+
+	// Status of the pipeline such as "live", set by the API.
+	Status string `json:"status,omitempty"`
+}
+
+// ID of the pipeline, the last element of its URL.
+func (p *Pipeline) ID() string {
+	u := strings.TrimSuffix(p.URL, "/")
+	return u[strings.LastIndex(u, "/")+1:]
+}
+
+// Validate returns nil if the pipeline is valid or an error if there's an
+// issue which the Automation API would reject.
+func (p *Pipeline) Validate() error {
+	if p.ImmediateTrigger == nil {
+		return errors.New("missing immediate trigger")
+	}
+	if t, ok := p.ImmediateTrigger.(Trigger); ok {
+		if err := t.Validate(); err != nil {
+			return fmt.Errorf("invalid trigger: %v", err)
+		}
+	}
+	if p.Outcome == nil || p.Outcome.Push == nil {
+		return errors.New("missing outcome push")
+	}
+	if p.Outcome.Delay < 0 {
+		return fmt.Errorf("negative outcome delay: %d", p.Outcome.Delay)
+	}
+	if err := p.Outcome.push().Validate(); err != nil {
+		return fmt.Errorf("invalid outcome push: %v", err)
+	}
+	return nil
+}
+
+// Response to a created or updated pipeline.
+type Response struct {
+	OK          bool   `json:"ok"`
+	OperationID string `json:"operation_id"`
+
+	// PipelineURLs identify each created pipeline in request order.
+	PipelineURLs []string `json:"pipeline_urls"`
+}
+
+// API manages the pipelines of an app.
+type API struct {
+	c   Client
+	url string
+}
+
+// New creates an API using DefaultURL.
+func New(c Client) *API {
+	return NewURL(c, DefaultURL)
+}
+
+// NewURL creates an API using the Automation API at url.
+func NewURL(c Client, url string) *API {
+	return &API{c: c, url: strings.TrimSuffix(url, "/")}
+}
+
+// pipelineURL returns the URL of the pipeline with id.
+func (a *API) pipelineURL(id string) (string, error) {
+	if id == "" || strings.Contains(id, "/") {
+		return "", fmt.Errorf("invalid pipeline ID %q", id)
+	}
+	return a.url + "/" + id, nil
+}
+
+// respond decodes a Response filling in its OperationID from the response's
+// headers if the body has none.
+func respond(resp *http.Response, err error) (*Response, error) {
+	if err != nil {
+		return nil, err
+	}
+	out := &Response{}
+	if err := push.DecodeResult(resp, out, &out.OperationID); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// writable returns a copy of p without its read-only fields.
+func writable(p *Pipeline) *Pipeline {
+	cp := *p
+	cp.URL, cp.Status = "", ""
+	return &cp
+}
+
+// Create pipelines. Invalid pipelines return an error without being sent.
+func (a *API) Create(pipelines ...*Pipeline) (*Response, error) {
+	if len(pipelines) == 0 {
+		return nil, errors.New("no pipelines to create")
+	}
+	body := make([]*Pipeline, len(pipelines))
+	for i, p := range pipelines {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid pipeline %d: %v", i, err)
+		}
+		body[i] = writable(p)
+	}
+	if len(body) == 1 {
+		return respond(a.c.Post(a.url, body[0], nil))
+	}
+	return respond(a.c.Post(a.url, body, nil))
+}
+
+// Get the pipeline with id.
+func (a *API) Get(id string) (*Pipeline, error) {
+	url, err := a.pipelineURL(id)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.c.Get(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := struct {
+		Pipeline *Pipeline `json:"pipeline"`
+	}{}
+	if err := push.DecodeResponse(resp, &out); err != nil {
+		return nil, err
+	}
+	if out.Pipeline == nil {
+		return nil, fmt.Errorf("no pipeline in response for %s", id)
+	}
+	return out.Pipeline, nil
+}
+
+// List every pipeline of the app.
+func (a *API) List() ([]*Pipeline, error) {
+	resp, err := a.c.Get(a.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := struct {
+		Pipelines []*Pipeline `json:"pipelines"`
+	}{}
+	if err := push.DecodeResponse(resp, &out); err != nil {
+		return nil, err
+	}
+	return out.Pipelines, nil
+}
+
+// Update replaces the pipeline with id. Invalid pipelines return an error
+// without being sent.
+func (a *API) Update(id string, p *Pipeline) (*Response, error) {
+	url, err := a.pipelineURL(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return respond(a.c.Put(url, writable(p), nil))
+}
+
+// Delete the pipeline with id.
+func (a *API) Delete(id string) error {
+	url, err := a.pipelineURL(id)
+	if err != nil {
+		return err
+	}
+	resp, err := a.c.Delete(url, nil)
+	if err != nil {
+		return err
+	}
+	return push.DecodeResponse(resp, nil)
+}
+
+// Activate enables the pipeline with id so it's triggered.
+func (a *API) Activate(id string) (*Response, error) {
+	return a.setEnabled(id, true)
+}
+
+// Deactivate disables the pipeline with id so it's no longer triggered
+// without deleting it.
+func (a *API) Deactivate(id string) (*Response, error) {
+	return a.setEnabled(id, false)
+}
+
+// setEnabled updates the pipeline with id, which the API only allows as a
+// whole, with enabled set. The pipeline is sent back as the raw JSON it was
+// stored as so fields Pipeline doesn't model, such as its constraints and
+// cancellation trigger, are kept.
+func (a *API) setEnabled(id string, enabled bool) (*Response, error) {
+	url, err := a.pipelineURL(id)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.c.Get(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := struct {
+		Pipeline map[string]json.RawMessage `json:"pipeline"`
+	}{}
+	if err := push.DecodeResponse(resp, &out); err != nil {
+		return nil, err
+	}
+	if out.Pipeline == nil {
+		return nil, fmt.Errorf("no pipeline in response for %s", id)
+	}
+	p := out.Pipeline
+	p["enabled"], _ = json.Marshal(enabled)
+	// The URL and status are read-only
+	delete(p, "url")
+	delete(p, "status")
+	return respond(a.c.Put(url, p, nil))
+}
@@ -0,0 +1,226 @@
+package pipelines_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/audience"
+	"github.com/lytics/gobyairship/pipelines"
+	"github.com/lytics/gobyairship/push"
+)
+
+// server is a fake Automation API storing pipelines in memory.
+type server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	pipelines map[string]map[string]interface{}
+	ids       []string
+}
+
+func newServer(t *testing.T) *server {
+	s := &server{pipelines: map[string]map[string]interface{}{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/pipelines"), "/")
+		body, _ := ioutil.ReadAll(r.Body)
+		var p map[string]interface{}
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &p); err != nil {
+				t.Errorf("Expected a single pipeline but found %s", body)
+			}
+			if _, ok := p["url"]; ok {
+				t.Errorf("Expected a pipeline without a URL but found %s", body)
+			}
+		}
+		switch {
+		case r.Method == "POST" && id == "":
+			id = fmt.Sprintf("a%d", len(s.ids)+1)
+			p["url"], p["status"] = s.URL+"/api/pipelines/"+id, "live"
+			s.pipelines[id] = p
+			s.ids = append(s.ids, id)
+			w.Header().Set("UA-Operation-Id", "op")
+			w.WriteHeader(201)
+			fmt.Fprintf(w, `{"ok":true,"pipeline_urls":[%q]}`, p["url"])
+		case r.Method == "GET" && id == "":
+			var list []map[string]interface{}
+			for _, id := range s.ids {
+				if s.pipelines[id] != nil {
+					list = append(list, s.pipelines[id])
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "pipelines": list})
+		case s.pipelines[id] == nil:
+			w.WriteHeader(404)
+			w.Write([]byte(`{"ok":false,"error":"Could not find pipeline","error_code":40401}`))
+		case r.Method == "GET":
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "pipeline": s.pipelines[id]})
+		case r.Method == "PUT":
+			p["url"] = s.URL + "/api/pipelines/" + id
+			s.pipelines[id] = p
+			fmt.Fprintf(w, `{"ok":true,"operation_id":"op2","pipeline_urls":[%q]}`, p["url"])
+		case r.Method == "DELETE":
+			delete(s.pipelines, id)
+			w.WriteHeader(204)
+		default:
+			w.WriteHeader(405)
+		}
+	}))
+	return s
+}
+
+func outcome(alert string) *pipelines.Outcome {
+	return &pipelines.Outcome{Push: &push.Push{Notification: &push.Notification{Alert: alert}}}
+}
+
+func TestPipelines(t *testing.T) {
+	t.Parallel()
+	srv := newServer(t)
+	defer srv.Close()
+	api := pipelines.NewURL(gobyairship.NewClient("key", "token"), srv.URL+"/api/pipelines/")
+
+	welcome := outcome("Welcome")
+	welcome.Delay = 60
+	resp, err := api.Create(&pipelines.Pipeline{Name: "welcome", Enabled: true, ImmediateTrigger: pipelines.FirstOpen, Outcome: welcome})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !resp.OK || resp.OperationID != "op" || len(resp.PipelineURLs) != 1 {
+		t.Fatalf("Unexpected response: %#v", resp)
+	}
+	if _, err := api.Create(&pipelines.Pipeline{Name: "thanks", Enabled: true, ImmediateTrigger: pipelines.CustomEvent("purchase"), Outcome: outcome("Thanks")}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	p, err := api.Get("a1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p.ID() != "a1" || p.Name != "welcome" || !p.Enabled || p.Status != "live" || p.ImmediateTrigger != "first_open" {
+		t.Errorf("Unexpected pipeline: %+v", p)
+	}
+	if o := p.Outcome; o == nil || o.Delay != 60 || o.Push == nil || o.Push.Audience != pipelines.Triggered {
+		t.Errorf("Unexpected outcome: %+v", o)
+	}
+
+	p.Name = "renamed"
+	if resp, err = api.Update(p.ID(), p); err != nil || resp.OperationID != "op2" {
+		t.Fatalf("Unexpected update response %#v: %v", resp, err)
+	}
+	// Fields Pipeline doesn't model survive toggling
+	srv.mu.Lock()
+	srv.pipelines["a2"]["cancellation_trigger"] = map[string]interface{}{"tag_added": map[string]interface{}{"tag": "bought"}}
+	srv.pipelines["a2"]["outcome"].(map[string]interface{})["push"].(map[string]interface{})["campaigns"] = map[string]interface{}{"categories": []interface{}{"sales"}}
+	srv.mu.Unlock()
+	if _, err := api.Deactivate("a2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	srv.mu.Lock()
+	stored, _ := json.Marshal(srv.pipelines["a2"])
+	srv.mu.Unlock()
+	for _, field := range []string{`"cancellation_trigger":{"tag_added":{"tag":"bought"}}`, `"campaigns":{"categories":["sales"]}`, `"enabled":false`} {
+		if !strings.Contains(string(stored), field) {
+			t.Errorf("Expected %s after deactivating but found %s", field, stored)
+		}
+	}
+	list, err := api.List()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var found []string
+	for _, p := range list {
+		found = append(found, fmt.Sprintf("%s:%t", p.Name, p.Enabled))
+	}
+	if strings.Join(found, ",") != "renamed:true,thanks:false" {
+		t.Errorf("Expected pipelines renamed and disabled thanks but found %v", found)
+	}
+	if _, err := api.Activate("a2"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p, err := api.Get("a2"); err != nil || !p.Enabled {
+		t.Errorf("Expected an enabled pipeline but found %+v: %v", p, err)
+	}
+
+	if err := api.Delete("a1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = api.Activate("a1")
+	if apiErr, ok := err.(*push.APIError); !ok || apiErr.StatusCode != 404 {
+		t.Errorf("Expected a 404 *push.APIError but found %v", err)
+	}
+}
+
+func TestTriggers(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		trigger  pipelines.Trigger
+		expected string
+	}{
+		{pipelines.FirstOpen, `"first_open"`},
+		{pipelines.TagAdded("vip"), `{"tag_added":{"tag":"vip"}}`},
+		{pipelines.TagGroupRemoved("loyalty", "gold"), `{"tag_removed":{"group":"loyalty","tag":"gold"}}`},
+		{pipelines.CustomEvent("purchase"), `{"custom_event":{"key":"name","value":{"equals":"purchase"}}}`},
+	}
+	for _, tc := range tests {
+		if err := tc.trigger.Validate(); err != nil {
+			t.Errorf("Unexpected error validating %s: %v", tc.expected, err)
+		}
+		buf, err := json.Marshal(tc.trigger)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(buf) != tc.expected {
+			t.Errorf("Expected %s but found %s", tc.expected, buf)
+		}
+	}
+
+	// Outcomes default to the triggering device but keep other audiences
+	buf, _ := json.Marshal(outcome("Hi"))
+	if !strings.Contains(string(buf), `"audience":"triggered"`) {
+		t.Errorf("Expected the triggered audience but found %s", buf)
+	}
+	o := outcome("Hi")
+	o.Push.Audience = audience.Tag("vip")
+	buf, _ = json.Marshal(o)
+	if !strings.Contains(string(buf), `"audience":{"tag":"vip"}`) {
+		t.Errorf("Expected the vip audience but found %s", buf)
+	}
+}
+
+func TestPipelineValidate(t *testing.T) {
+	t.Parallel()
+	delayed := outcome("Hi")
+	delayed.Delay = -1
+	invalid := map[string]*pipelines.Pipeline{
+		"missing trigger":    {Outcome: outcome("Hi")},
+		"empty tag":          {ImmediateTrigger: pipelines.TagAdded(""), Outcome: outcome("Hi")},
+		"empty custom event": {ImmediateTrigger: pipelines.CustomEvent(""), Outcome: outcome("Hi")},
+		"missing outcome":    {ImmediateTrigger: pipelines.FirstOpen},
+		"missing push":       {ImmediateTrigger: pipelines.FirstOpen, Outcome: &pipelines.Outcome{}},
+		"empty notification": {ImmediateTrigger: pipelines.FirstOpen, Outcome: outcome("")},
+		"negative delay":     {ImmediateTrigger: pipelines.FirstOpen, Outcome: delayed},
+		"invalid audience":   {ImmediateTrigger: pipelines.FirstOpen, Outcome: &pipelines.Outcome{Push: &push.Push{Audience: audience.Tag(""), Notification: &push.Notification{Alert: "Hi"}}}},
+	}
+	for name, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("Expected an error with %s", name)
+		}
+	}
+
+	// Invalid pipelines aren't sent
+	api := pipelines.NewURL(gobyairship.NewClient("key", "token"), "http://127.0.0.1:0/api/pipelines")
+	if _, err := api.Create(invalid["missing trigger"]); err == nil {
+		t.Errorf("Expected an error creating an invalid pipeline")
+	}
+	if _, err := api.Get("../push"); err == nil {
+		t.Errorf("Expected an error getting an invalid pipeline ID")
+	}
+}
@@ -0,0 +1,89 @@
+package pipelines
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Trigger starts a pipeline for the device which matches it.
+type Trigger interface {
+	json.Marshaler
+
+	// Validate returns nil if the trigger is valid or an error if there's an
+	// issue which the API would reject.
+	Validate() error
+}
+
+// FirstOpen triggers when a device opens the app for the first time.
+var FirstOpen Trigger = named("first_open")
+
+// named is a trigger without parameters, marshaled as its name.
+type named string
+
+func (n named) MarshalJSON() ([]byte, error) { return json.Marshal(string(n)) }
+func (n named) Validate() error              { return nil }
+
+// tagTrigger triggers when a tag is added to or removed from a device.
+type tagTrigger struct {
+	key   string
+	tag   string
+	group string
+}
+
+func (t *tagTrigger) MarshalJSON() ([]byte, error) {
+	m := map[string]string{"tag": t.tag}
+	if t.group != "" {
+		m["group"] = t.group
+	}
+	return json.Marshal(map[string]interface{}{t.key: m})
+}
+
+func (t *tagTrigger) Validate() error {
+	if t.tag == "" {
+		return fmt.Errorf("%s trigger without a tag", t.key)
+	}
+	return nil
+}
+
+// TagAdded triggers when a tag in the device tag group is added to a device.
+func TagAdded(tag string) Trigger { return &tagTrigger{key: "tag_added", tag: tag} }
+
+// TagGroupAdded triggers when a tag in a tag group is added to a device.
+func TagGroupAdded(group, tag string) Trigger {
+	return &tagTrigger{key: "tag_added", tag: tag, group: group}
+}
+
+// TagRemoved triggers when a tag in the device tag group is removed from a
+// device.
+func TagRemoved(tag string) Trigger { return &tagTrigger{key: "tag_removed", tag: tag} }
+
+// TagGroupRemoved triggers when a tag in a tag group is removed from a device.
+func TagGroupRemoved(group, tag string) Trigger {
+	return &tagTrigger{key: "tag_removed", tag: tag, group: group}
+}
+
+// customEvent triggers when a device emits a custom event.
+type customEvent struct {
+	name string
+}
+
+func (e *customEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"custom_event": map[string]interface{}{
+			"key":   "name",
+			"value": map[string]string{"equals": e.name},
+		},
+	})
+}
+
+func (e *customEvent) Validate() error {
+	if e.name == "" {
+		return errors.New("custom event trigger without a name")
+	}
+	return nil
+}
+
+// CustomEvent triggers when a device emits a custom event with name, such as
+// the Name of an events.Custom body.
+func CustomEvent(name string) Trigger { return &customEvent{name: name} }
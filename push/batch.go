@@ -0,0 +1,120 @@
+package push
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Limits of a single request to the Push API. SendBatch splits larger
+// batches into several requests.
+const (
+	MaxBatchPushes = 100
+	MaxBatchBytes  = 1 << 20
+)
+
+// BatchResult is the outcome of one push in a batch.
+type BatchResult struct {
+	// PushID identifies the push if it was sent.
+	PushID string
+
+	// Result of the request which sent the push, shared by every push in
+	// the request. Set for partially failed requests from the
+	// *APIError's Result.
+	Result *SendResult
+
+	// Err is why the push wasn't sent: its Validate error, or the error of
+	// the request which included it. Pushes identified by a partially
+	// failed request's Result were sent and have no Err.
+	Err error
+}
+
+// BatchError is returned by SendBatch if any push wasn't sent.
+type BatchError struct {
+	// Results of every push in the batch, including those which were sent.
+	Results []BatchResult
+}
+
+func (e *BatchError) Error() string {
+	failed := 0
+	var first error
+	for _, r := range e.Results {
+		if r.Err != nil {
+			if first == nil {
+				first = r.Err
+			}
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d of %d pushes failed: %v", failed, len(e.Results), first)
+}
+
+// SendBatch sends pushes to DefaultURL in as few requests as possible.
+func SendBatch(c Client, pushes []*Push) ([]BatchResult, error) {
+	return SendBatchURL(c, DefaultURL, pushes)
+}
+
+// SendBatchURL sends pushes to the Push API at url in as few requests as
+// possible, each with at most MaxBatchPushes pushes and MaxBatchBytes of
+// JSON. Results are returned in the order of pushes. Invalid pushes aren't
+// sent and a failed request fails every push in it, so a *BatchError is
+// returned along with the results if any push wasn't sent.
+func SendBatchURL(c Client, url string, pushes []*Push) ([]BatchResult, error) {
+	results := make([]BatchResult, len(pushes))
+	var (
+		chunk   []json.RawMessage
+		indexes []int
+		size    int
+	)
+	flush := func() {
+		if len(chunk) == 0 {
+			return
+		}
+		sendChunk(c, url, chunk, indexes, results)
+		chunk, indexes, size = nil, nil, 0
+	}
+	for i, p := range pushes {
+		if err := p.Validate(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		b, err := json.Marshal(p)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		// Each push adds a comma, or the brackets for the first
+		if len(chunk) == MaxBatchPushes || len(chunk) > 0 && size+len(b)+1 > MaxBatchBytes {
+			flush()
+		}
+		chunk = append(chunk, b)
+		indexes = append(indexes, i)
+		size += len(b) + 1
+	}
+	flush()
+
+	for _, r := range results {
+		if r.Err != nil {
+			return results, &BatchError{Results: results}
+		}
+	}
+	return results, nil
+}
+
+// sendChunk sends the pushes of one request and records their results at
+// indexes.
+func sendChunk(c Client, url string, chunk []json.RawMessage, indexes []int, results []BatchResult) {
+	out, err := Post(c, url, chunk, nil)
+	if apiErr, ok := err.(*APIError); ok && apiErr.Result != nil {
+		out = apiErr.Result
+	}
+	for n, i := range indexes {
+		results[i].Result = out
+		// Push IDs are in request order when there's one per push
+		if out != nil && len(out.PushIDs) == len(indexes) {
+			results[i].PushID = out.PushIDs[n]
+		}
+		if err != nil && results[i].PushID == "" {
+			results[i].Err = err
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package push_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/push"
+)
+
+func TestSendBatch(t *testing.T) {
+	t.Parallel()
+	var (
+		mu    sync.Mutex
+		sizes []int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var pushes []push.Push
+		if err := json.NewDecoder(r.Body).Decode(&pushes); err != nil {
+			t.Errorf("Expected an array of pushes: %v", err)
+		}
+		mu.Lock()
+		sizes = append(sizes, len(pushes))
+		mu.Unlock()
+		var ids []string
+		for _, p := range pushes {
+			if p.Notification.Alert == "fail" {
+				w.WriteHeader(400)
+				w.Write([]byte(`{"ok":false,"error":"Rejected","error_code":40001}`))
+				return
+			}
+			ids = append(ids, "id-"+p.Notification.Alert)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "operation_id": fmt.Sprintf("op%d", len(sizes)), "push_ids": ids})
+	}))
+	defer srv.Close()
+
+	var pushes []*push.Push
+	for i := 0; i < 205; i++ {
+		pushes = append(pushes, &push.Push{Audience: push.All, Notification: &push.Notification{Alert: fmt.Sprint(i)}})
+	}
+	// An invalid push isn't sent and a rejected one fails its whole request
+	pushes[3] = &push.Push{Audience: push.All}
+	pushes[204].Notification.Alert = "fail"
+
+	results, err := push.SendBatchURL(gobyairship.NewClient("key", "token"), srv.URL, pushes)
+	if _, ok := err.(*push.BatchError); !ok {
+		t.Fatalf("Expected *push.BatchError but found %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "5 of 205 pushes failed") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if fmt.Sprint(sizes) != "[100 100 4]" {
+		t.Errorf("Expected requests of 100, 100, and 4 pushes but found %v", sizes)
+	}
+	for i, r := range results {
+		switch {
+		case i == 3:
+			if r.Err == nil || r.PushID != "" {
+				t.Errorf("Expected invalid push %d to fail but found %+v", i, r)
+			}
+		case i > 200:
+			if _, ok := r.Err.(*push.APIError); !ok || r.PushID != "" {
+				t.Errorf("Expected push %d to be rejected but found %+v", i, r)
+			}
+		default:
+			if r.Err != nil || r.PushID != fmt.Sprintf("id-%d", i) || r.Result == nil || r.Result.OperationID == "" {
+				t.Errorf("Unexpected result %d: %+v", i, r)
+			}
+		}
+	}
+
+	// Batches are split when they'd exceed the size limit
+	sizes = nil
+	big := strings.Repeat("x", push.MaxBatchBytes/3)
+	pushes = pushes[:0]
+	for i := 0; i < 4; i++ {
+		pushes = append(pushes, &push.Push{Audience: push.All, Notification: &push.Notification{Alert: big}})
+	}
+	if _, err := push.SendBatchURL(gobyairship.NewClient("key", "token"), srv.URL, pushes); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fmt.Sprint(sizes) != "[2 2]" {
+		t.Errorf("Expected requests of 2 pushes but found %v", sizes)
+	}
+}
+
+func TestSendBatchPartialFailure(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"ok":false,"error":"Partially sent","push_ids":["a"]}`))
+	}))
+	defer srv.Close()
+	c := gobyairship.NewClient("key", "token")
+
+	// Push IDs can't be matched to pushes unless there's one per push
+	pushes := []*push.Push{
+		{Audience: push.All, Notification: &push.Notification{Alert: "1"}},
+		{Audience: push.All, Notification: &push.Notification{Alert: "2"}},
+	}
+	results, err := push.SendBatchURL(c, srv.URL, pushes)
+	if _, ok := err.(*push.BatchError); !ok {
+		t.Fatalf("Expected *push.BatchError but found %v", err)
+	}
+	for i, r := range results {
+		if _, ok := r.Err.(*push.APIError); !ok || r.PushID != "" || r.Result == nil || len(r.Result.PushIDs) != 1 {
+			t.Errorf("Unexpected result %d: %+v", i, r)
+		}
+	}
+
+	results, err = push.SendBatchURL(c, srv.URL, pushes[:1])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if r := results[0]; r.PushID != "a" || r.Result == nil {
+		t.Errorf("Expected push ID a but found %+v", r)
+	}
+}
@@ -0,0 +1,224 @@
+package push
+
+import "time"
+
+// Builder builds a Push. The Push API has no title or extras common to every
+// platform, so Title and Extra are set on the override of each of the push's
+// device types which supports them: iOS, Android, Amazon, and Web. They take
+// precedence over the same fields of overrides passed to IOS, Android,
+// Amazon, and Web. Use Build to get the validated Push:
+//
+//	p, err := push.NewBuilder().
+//		Audience(audience.Tag("sports")).
+//		Alert("Kickoff in 5 minutes").
+//		Title("Game day").
+//		Extra("match_id", "42").
+//		DeviceTypes(push.DeviceIOS, push.DeviceAndroid).
+//		Expiry(time.Hour).
+//		Build()
+type Builder struct {
+	audience    interface{}
+	alert       string
+	title       string
+	extra       map[string]string
+	inApp       *InApp
+	message     *Message
+	ios         *IOS
+	android     *Android
+	amazon      *Amazon
+	web         *Web
+	expiry      time.Duration
+	personalize bool
+	deviceTypes DeviceTypes
+}
+
+// NewBuilder creates a Builder for a push to every device type.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Audience sets the devices the push is sent to such as audience.All.
+func (b *Builder) Audience(selector interface{}) *Builder {
+	b.audience = selector
+	return b
+}
+
+// Alert sets the notification's text.
+func (b *Builder) Alert(alert string) *Builder {
+	b.alert = alert
+	return b
+}
+
+// Title sets the notification's title.
+func (b *Builder) Title(title string) *Builder {
+	b.title = title
+	return b
+}
+
+// Extra adds a key and value delivered to the app with the notification.
+func (b *Builder) Extra(key, value string) *Builder {
+	if b.extra == nil {
+		b.extra = map[string]string{}
+	}
+	b.extra[key] = value
+	return b
+}
+
+// IOS sets the notification's iOS override.
+func (b *Builder) IOS(o *IOS) *Builder {
+	b.ios = o
+	return b
+}
+
+// Android sets the notification's Android override.
+func (b *Builder) Android(o *Android) *Builder {
+	b.android = o
+	return b
+}
+
+// Amazon sets the notification's Amazon override.
+func (b *Builder) Amazon(o *Amazon) *Builder {
+	b.amazon = o
+	return b
+}
+
+// Web sets the notification's web override.
+func (b *Builder) Web(o *Web) *Builder {
+	b.web = o
+	return b
+}
+
+// InApp adds a banner displayed in the app the next time it's opened.
+func (b *Builder) InApp(alert string) *Builder {
+	b.inApp = &InApp{Alert: alert, DisplayType: DisplayBanner}
+	return b
+}
+
+// Message adds a Message Center message with an HTML body.
+func (b *Builder) Message(title, body string) *Builder {
+	b.message = &Message{Title: title, Body: body, ContentType: ContentHTML}
+	return b
+}
+
+// Expiry sets how long the push may be delivered for, rounded down to the
+// second.
+func (b *Builder) Expiry(d time.Duration) *Builder {
+	b.expiry = d
+	return b
+}
+
+// Personalize renders the push's text as a template for each device, such as
+// "Hi {{first_name}}!".
+func (b *Builder) Personalize() *Builder {
+	b.personalize = true
+	return b
+}
+
+// DeviceTypes adds device types to send to. Pushes without device types are
+// sent to every type.
+func (b *Builder) DeviceTypes(types ...DeviceType) *Builder {
+	b.deviceTypes = append(b.deviceTypes, types...)
+	return b
+}
+
+// targets returns true if the push is sent to device type t.
+func (b *Builder) targets(t DeviceType) bool {
+	if len(b.deviceTypes) == 0 {
+		return true
+	}
+	for _, dt := range b.deviceTypes {
+		if dt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// extras returns base's extras with the Builder's added. The result is a
+// copy so built Pushes are independent of the Builder.
+func (b *Builder) extras(base map[string]string) map[string]string {
+	if len(base) == 0 && len(b.extra) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(base)+len(b.extra))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range b.extra {
+		out[k] = v
+	}
+	return out
+}
+
+// titled returns the Builder's title if it has one, otherwise title.
+func (b *Builder) titled(title string) string {
+	if b.title != "" {
+		return b.title
+	}
+	return title
+}
+
+// Build returns a new Push or an error if it would be invalid.
+func (b *Builder) Build() (*Push, error) {
+	p := &Push{
+		Audience:    b.audience,
+		DeviceTypes: append(DeviceTypes(nil), b.deviceTypes...),
+	}
+	n := &Notification{Alert: b.alert}
+	shared := b.title != "" || len(b.extra) > 0
+	if b.ios != nil || shared && b.targets(DeviceIOS) {
+		o := IOS{}
+		if b.ios != nil {
+			o = *b.ios
+		}
+		o.Title, o.Extra = b.titled(o.Title), b.extras(o.Extra)
+		n.IOS = &o
+	}
+	if b.android != nil || shared && b.targets(DeviceAndroid) {
+		o := Android{}
+		if b.android != nil {
+			o = *b.android
+		}
+		o.Title, o.Extra = b.titled(o.Title), b.extras(o.Extra)
+		n.Android = &o
+	}
+	if b.amazon != nil || shared && b.targets(DeviceAmazon) {
+		o := Amazon{}
+		if b.amazon != nil {
+			o = *b.amazon
+		}
+		o.Title, o.Extra = b.titled(o.Title), b.extras(o.Extra)
+		n.Amazon = &o
+	}
+	if b.web != nil || shared && b.targets(DeviceWeb) {
+		o := Web{}
+		if b.web != nil {
+			o = *b.web
+			o.Buttons = append([]WebButton(nil), o.Buttons...)
+			if o.Icon != nil {
+				icon := *o.Icon
+				o.Icon = &icon
+			}
+		}
+		o.Title, o.Extra = b.titled(o.Title), b.extras(o.Extra)
+		n.Web = &o
+	}
+	if *n != (Notification{}) {
+		p.Notification = n
+	}
+	if b.inApp != nil {
+		inApp := *b.inApp
+		p.InApp = &inApp
+	}
+	if b.message != nil {
+		message := *b.message
+		p.Message = &message
+	}
+	if b.expiry > 0 || b.personalize {
+		p.Options = &Options{Expiry: int(b.expiry / time.Second), Personalization: b.personalize}
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
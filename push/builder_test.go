@@ -0,0 +1,96 @@
+package push_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/push"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Parallel()
+	b := push.NewBuilder().
+		Audience(map[string]interface{}{"tag": "sports"}).
+		Alert("Kickoff in 5 minutes").
+		Title("Game day").
+		Extra("match_id", "42").
+		InApp("Kickoff soon").
+		DeviceTypes(push.DeviceIOS, push.DeviceWeb).
+		Expiry(90 * time.Minute)
+	p, err := b.Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	buf, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{"audience":{"tag":"sports"},"notification":{"alert":"Kickoff in 5 minutes",` +
+		`"ios":{"title":"Game day","extra":{"match_id":"42"}},"web":{"title":"Game day","extra":{"match_id":"42"}}},` +
+		`"in_app":{"alert":"Kickoff soon","display_type":"banner"},"options":{"expiry":5400},"device_types":["ios","web"]}`
+	if string(buf) != expected {
+		t.Errorf("Expected:\n%s\nFound:\n%s", expected, buf)
+	}
+
+	// Built Pushes are independent of the Builder
+	b.Extra("match_id", "43").DeviceTypes(push.DeviceAndroid)
+	if p.Notification.IOS.Extra["match_id"] != "42" || len(p.DeviceTypes) != 2 {
+		t.Errorf("Modifying the Builder modified a built Push: %+v", p)
+	}
+
+	// Titles alone are set on every platform's override
+	p, err = push.NewBuilder().Audience(push.All).Title("Hi").Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	n := p.Notification
+	if n.Alert != "" || n.IOS == nil || n.Android == nil || n.Amazon == nil || n.Web == nil || n.Android.Title != "Hi" {
+		t.Errorf("Unexpected notification: %+v", n)
+	}
+
+	// Overrides keep their fields and the shared title and extras are added
+	p, err = push.NewBuilder().
+		Audience(push.All).
+		Alert("Hi").
+		Title("Hello").
+		Extra("a", "1").
+		IOS(&push.IOS{Badge: push.BadgeAuto, Extra: map[string]string{"b": "2"}}).
+		DeviceTypes(push.DeviceIOS).
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ios := p.Notification.IOS
+	if ios.Badge != push.BadgeAuto || ios.Title != "Hello" || len(ios.Extra) != 2 || p.Notification.Android != nil {
+		t.Errorf("Unexpected notification: %+v", p.Notification)
+	}
+
+	// Personalized templates are validated
+	p, err = push.NewBuilder().Audience(push.All).Alert(`Hi {{$def first_name "there"}}`).Personalize().Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p.Options == nil || !p.Options.Personalization {
+		t.Errorf("Expected personalization but found %+v", p.Options)
+	}
+
+	// Extras are delivered to the app without an alert
+	if _, err := push.NewBuilder().Audience(push.All).Extra("k", "v").Build(); err != nil {
+		t.Errorf("Unexpected error with only extras: %v", err)
+	}
+
+	invalid := map[string]*push.Builder{
+		"missing audience":    push.NewBuilder().Alert("Hi"),
+		"empty notification":  push.NewBuilder().Audience(push.All),
+		"empty in-app":        push.NewBuilder().Audience(push.All).InApp(""),
+		"unknown device type": push.NewBuilder().Audience(push.All).Alert("Hi").DeviceTypes("blackberry"),
+		"invalid template":    push.NewBuilder().Audience(push.All).Alert("Hi {{#if vip}}").Personalize(),
+		"invalid override":    push.NewBuilder().Audience(push.All).Android(&push.Android{Alert: "Hi", Priority: 5}),
+	}
+	for name, b := range invalid {
+		if _, err := b.Build(); err == nil {
+			t.Errorf("Expected an error with %s", name)
+		}
+	}
+}
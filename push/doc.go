@@ -0,0 +1,46 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package sends notifications with Urban Airship's Push API.
+//
+// Send posts a Push to the API and returns the IDs of the pushes it sent:
+//
+//	resp, err := push.Send(client, &push.Push{
+//		Audience:     push.All,
+//		Notification: &push.Notification{Alert: "Hello"},
+//	})
+//
+// SendBatch sends many pushes in as few requests as the API's limits allow and
+// reports the result of each.
+//
+// Builder builds and validates Pushes fluently. Send validates pushes too, so
+// a missing audience or empty notification fails before reaching the API.
+//
+// Notification content may use Urban Airship's handlebars-style
+// personalization, such as "Hi {{$def first_name "there"}}!", when
+// Options.Personalization is set. ValidateTemplate checks the syntax
+// client-side, as does Validate for personalized pushes, so unclosed tags and
+// blocks fail before sending. Helpers aren't checked, so any the API supports
+// may be used.
+//
+// A Push's InApp is displayed as a banner the next time the app is opened, with
+// optional actions and buttons whose IDs are reported by in-app events.
+//
+// A Push's Message is delivered to Message Center inboxes, and DeleteMessage
+// removes a message from every inbox.
+//
+// SendTemplate sends a push whose content is a template stored in Urban
+// Airship, selected with a TemplateSelector along with the substitutions for
+// its variables.
+package push
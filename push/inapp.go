@@ -0,0 +1,218 @@
+package push
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DisplayBanner is the display type of in-app messages shown as a banner.
+const DisplayBanner = "banner"
+
+// Positions of in-app banners.
+const (
+	PositionTop    = "top"
+	PositionBottom = "bottom"
+)
+
+// Types of OpenActions.
+const (
+	OpenURL         = "url"
+	OpenDeepLink    = "deep_link"
+	OpenLandingPage = "landing_page"
+)
+
+// OpenAction opens Content, a URL, deep link, or landing page URL according to
+// Type.
+type OpenAction struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+}
+
+// InAppActions are run when an in-app message, or one of its buttons, is
+// tapped.
+type InAppActions struct {
+	AddTags    []string    `json:"add_tag,omitempty"`
+	RemoveTags []string    `json:"remove_tag,omitempty"`
+	Open       *OpenAction `json:"open,omitempty"`
+
+	// Share opens the platform's share sheet with the text.
+	Share string `json:"share,omitempty"`
+
+	// AppDefined are passed to the app's own action handlers.
+	AppDefined map[string]interface{} `json:"app_defined,omitempty"`
+}
+
+func (a *InAppActions) validate() error {
+	for _, tags := range [][]string{a.AddTags, a.RemoveTags} {
+		for _, t := range tags {
+			if t == "" {
+				return errors.New("empty tag")
+			}
+		}
+	}
+	if o := a.Open; o != nil {
+		if o.Type != OpenURL && o.Type != OpenDeepLink && o.Type != OpenLandingPage {
+			return fmt.Errorf("unknown open action type %q", o.Type)
+		}
+		if o.Content == "" {
+			return fmt.Errorf("%s open action without content", o.Type)
+		}
+	}
+	return nil
+}
+
+// InAppDisplay is how an in-app banner looks.
+type InAppDisplay struct {
+	// Position of the banner, PositionTop or PositionBottom. Defaults to
+	// PositionBottom.
+	Position string `json:"position,omitempty"`
+
+	// Duration is the number of seconds the banner is displayed before it's
+	// dismissed. Zero uses the SDK's default.
+	Duration int `json:"duration,omitempty"`
+
+	// PrimaryColor and SecondaryColor are the banner's background and text
+	// colors as "#RRGGBB".
+	PrimaryColor   string `json:"primary_color,omitempty"`
+	SecondaryColor string `json:"secondary_color,omitempty"`
+}
+
+// validColor returns true if c is empty or "#RRGGBB".
+func validColor(c string) bool {
+	if c == "" {
+		return true
+	}
+	return len(c) == 7 && c[0] == '#' && strings.Trim(c[1:], "0123456789abcdefABCDEF") == ""
+}
+
+func (d *InAppDisplay) validate() error {
+	switch {
+	case d.Position != "" && d.Position != PositionTop && d.Position != PositionBottom:
+		return fmt.Errorf("unknown position %q", d.Position)
+	case d.Duration < 0:
+		return fmt.Errorf("negative duration: %d", d.Duration)
+	case !validColor(d.PrimaryColor):
+		return fmt.Errorf("invalid primary color %q: use #RRGGBB", d.PrimaryColor)
+	case !validColor(d.SecondaryColor):
+		return fmt.Errorf("invalid secondary color %q: use #RRGGBB", d.SecondaryColor)
+	}
+	return nil
+}
+
+// InAppInteractive adds buttons to an in-app message.
+type InAppInteractive struct {
+	// Type is the button category, such as "ua_yes_no_foreground" or one
+	// registered by the app.
+	Type string `json:"type"`
+
+	// ButtonActions are run when the button with each ID is tapped. The IDs
+	// are reported by events.InAppMessageResolution's ButtonID.
+	ButtonActions map[string]*InAppActions `json:"button_actions,omitempty"`
+}
+
+// InApp is a message displayed inside the app the next time it's opened.
+// Devices report its display, resolution, and expiration as events decoded by
+// events.InAppMessageDisplay and its siblings.
+type InApp struct {
+	Alert string `json:"alert"`
+
+	// DisplayType is how the message is displayed. Defaults to
+	// DisplayBanner, the only type the API supports.
+	DisplayType string `json:"display_type"`
+
+	// Expiry is when the message is no longer displayed. It's sent to the API
+	// in UTC with second precision. Zero uses the API's default of 30 days.
+	Expiry time.Time `json:"-"`
+
+	Display     *InAppDisplay     `json:"display,omitempty"`
+	Actions     *InAppActions     `json:"actions,omitempty"`
+	Interactive *InAppInteractive `json:"interactive,omitempty"`
+
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// MarshalJSON sets the default DisplayType and encodes Expiry in the API's
+// layout.
+func (m *InApp) MarshalJSON() ([]byte, error) {
+	type plain InApp
+	cp := plain(*m)
+	if cp.DisplayType == "" {
+		cp.DisplayType = DisplayBanner
+	}
+	out := struct {
+		*plain
+		Expiry string `json:"expiry,omitempty"`
+	}{plain: &cp}
+	if !m.Expiry.IsZero() {
+		out.Expiry = m.Expiry.UTC().Format(timeLayout)
+	}
+	return json.Marshal(&out)
+}
+
+// UnmarshalJSON decodes Expiry in the API's layout.
+func (m *InApp) UnmarshalJSON(b []byte) error {
+	type plain InApp
+	*m = InApp{}
+	in := struct {
+		*plain
+		Expiry string `json:"expiry"`
+	}{plain: (*plain)(m)}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	if in.Expiry != "" {
+		t, err := time.Parse(timeLayout, in.Expiry)
+		if err != nil {
+			return err
+		}
+		m.Expiry = t
+	}
+	return nil
+}
+
+func (m *InApp) validate() error {
+	if m.Alert == "" {
+		return errors.New("empty alert")
+	}
+	if m.DisplayType != "" && m.DisplayType != DisplayBanner {
+		return fmt.Errorf("unsupported display type %q", m.DisplayType)
+	}
+	if m.Display != nil {
+		if err := m.Display.validate(); err != nil {
+			return err
+		}
+	}
+	if m.Actions != nil {
+		if err := m.Actions.validate(); err != nil {
+			return fmt.Errorf("invalid actions: %v", err)
+		}
+	}
+	if i := m.Interactive; i != nil {
+		if i.Type == "" {
+			return errors.New("interactive without a type")
+		}
+		ids := make([]string, 0, len(i.ButtonActions))
+		for id := range i.ButtonActions {
+			ids = append(ids, id)
+		}
+		// Report the same error for the same message
+		sort.Strings(ids)
+		for _, id := range ids {
+			if id == "" {
+				return errors.New("button actions without a button ID")
+			}
+			a := i.ButtonActions[id]
+			if a == nil {
+				continue
+			}
+			if err := a.validate(); err != nil {
+				return fmt.Errorf("invalid actions for button %q: %v", id, err)
+			}
+		}
+	}
+	return nil
+}
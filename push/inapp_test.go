@@ -0,0 +1,87 @@
+package push_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/push"
+)
+
+func TestInApp(t *testing.T) {
+	t.Parallel()
+	m := &push.InApp{
+		Alert:  "Kickoff soon",
+		Expiry: time.Date(2030, 1, 2, 7, 4, 5, 0, time.FixedZone("PST", -8*3600)),
+		Display: &push.InAppDisplay{
+			Position:     push.PositionTop,
+			Duration:     10,
+			PrimaryColor: "#FF0000",
+		},
+		Actions: &push.InAppActions{
+			AddTags: []string{"engaged"},
+			Open:    &push.OpenAction{Type: push.OpenURL, Content: "https://example.com/match"},
+		},
+		Interactive: &push.InAppInteractive{
+			Type:          "ua_yes_no_foreground",
+			ButtonActions: map[string]*push.InAppActions{"yes": {AddTags: []string{"watching"}}},
+		},
+	}
+	p := &push.Push{Audience: push.All, InApp: m}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	buf, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{"alert":"Kickoff soon","display_type":"banner",` +
+		`"display":{"position":"top","duration":10,"primary_color":"#FF0000"},` +
+		`"actions":{"add_tag":["engaged"],"open":{"type":"url","content":"https://example.com/match"}},` +
+		`"interactive":{"type":"ua_yes_no_foreground","button_actions":{"yes":{"add_tag":["watching"]}}},` +
+		`"expiry":"2030-01-02T15:04:05"}`
+	if string(buf) != expected {
+		t.Errorf("Expected:\n%s\nFound:\n%s", expected, buf)
+	}
+
+	var decoded push.InApp
+	if err := json.Unmarshal(buf, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !decoded.Expiry.Equal(m.Expiry) {
+		t.Errorf("Expected expiry %s but found %s", m.Expiry, decoded.Expiry)
+	}
+	decoded.Expiry = m.Expiry
+	m.DisplayType = push.DisplayBanner
+	if !reflect.DeepEqual(&decoded, m) {
+		t.Errorf("Expected %+v after decoding but found %+v", m, decoded)
+	}
+}
+
+func TestInAppValidate(t *testing.T) {
+	t.Parallel()
+	invalid := map[string]*push.InApp{
+		"empty alert":          {},
+		"unknown display type": {Alert: "Hi", DisplayType: "modal"},
+		"unknown position":     {Alert: "Hi", Display: &push.InAppDisplay{Position: "middle"}},
+		"negative duration":    {Alert: "Hi", Display: &push.InAppDisplay{Duration: -1}},
+		"invalid color":        {Alert: "Hi", Display: &push.InAppDisplay{SecondaryColor: "red"}},
+		"empty tag":            {Alert: "Hi", Actions: &push.InAppActions{RemoveTags: []string{""}}},
+		"unknown open type":    {Alert: "Hi", Actions: &push.InAppActions{Open: &push.OpenAction{Type: "app", Content: "x"}}},
+		"open without content": {Alert: "Hi", Actions: &push.InAppActions{Open: &push.OpenAction{Type: push.OpenDeepLink}}},
+		"interactive no type":  {Alert: "Hi", Interactive: &push.InAppInteractive{}},
+		"empty button ID": {Alert: "Hi", Interactive: &push.InAppInteractive{
+			Type: "ua_yes_no_foreground", ButtonActions: map[string]*push.InAppActions{"": {}},
+		}},
+		"invalid button action": {Alert: "Hi", Interactive: &push.InAppInteractive{
+			Type: "ua_yes_no_foreground", ButtonActions: map[string]*push.InAppActions{"no": {AddTags: []string{""}}},
+		}},
+	}
+	for name, m := range invalid {
+		p := &push.Push{Audience: push.All, InApp: m}
+		if err := p.Validate(); err == nil {
+			t.Errorf("Expected an error with %s", name)
+		}
+	}
+}
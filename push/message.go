@@ -0,0 +1,144 @@
+package push
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultMessagesURL is the Message Center API endpoint for messages.
+const DefaultMessagesURL = "https://go.urbanairship.com/api/user/messages"
+
+// timeLayout of message and in-app message expiry times, which are in UTC.
+const timeLayout = "2006-01-02T15:04:05"
+
+// Content types of Message Center message bodies.
+const (
+	ContentHTML  = "text/html"
+	ContentPlain = "text/plain"
+)
+
+// MessageIcons are images shown with a message in the inbox.
+type MessageIcons struct {
+	// ListIcon is the URL of the icon shown in the inbox's message list.
+	ListIcon string `json:"list_icon,omitempty"`
+}
+
+// Message is a rich message delivered to the Message Center inbox of each
+// device in a push's audience. Devices report its delivery, reading, and
+// deletion as events decoded by events.RichEvent, identified by the message
+// IDs in the push's SendResult.
+type Message struct {
+	Title string
+	Body  string
+
+	// ContentType of Body, ContentHTML or ContentPlain. Defaults to
+	// ContentHTML.
+	ContentType string
+
+	Extra map[string]string
+
+	// Expiry is when the message is removed from inboxes. It's sent to the
+	// API in UTC with second precision. Zero never expires.
+	Expiry time.Time
+
+	Icons *MessageIcons
+}
+
+type message struct {
+	Title       string            `json:"title"`
+	Body        string            `json:"body"`
+	ContentType string            `json:"content_type,omitempty"`
+	Extra       map[string]string `json:"extra,omitempty"`
+	Expiry      string            `json:"expiry,omitempty"`
+	Icons       *MessageIcons     `json:"icons,omitempty"`
+}
+
+// MarshalJSON encodes Expiry in the API's layout.
+func (m *Message) MarshalJSON() ([]byte, error) {
+	out := message{
+		Title:       m.Title,
+		Body:        m.Body,
+		ContentType: m.ContentType,
+		Extra:       m.Extra,
+		Icons:       m.Icons,
+	}
+	if !m.Expiry.IsZero() {
+		out.Expiry = m.Expiry.UTC().Format(timeLayout)
+	}
+	return json.Marshal(&out)
+}
+
+// UnmarshalJSON decodes Expiry in the API's layout.
+func (m *Message) UnmarshalJSON(b []byte) error {
+	in := message{}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	*m = Message{
+		Title:       in.Title,
+		Body:        in.Body,
+		ContentType: in.ContentType,
+		Extra:       in.Extra,
+		Icons:       in.Icons,
+	}
+	if in.Expiry != "" {
+		t, err := time.Parse(timeLayout, in.Expiry)
+		if err != nil {
+			return err
+		}
+		m.Expiry = t
+	}
+	return nil
+}
+
+func (m *Message) validate() error {
+	switch {
+	case m.Title == "":
+		return errors.New("message without a title")
+	case m.Body == "":
+		return errors.New("message without a body")
+	case m.ContentType != "" && m.ContentType != ContentHTML && m.ContentType != ContentPlain:
+		return fmt.Errorf("unsupported message content type %q", m.ContentType)
+	}
+	return nil
+}
+
+// MessageClient deletes Message Center messages. Usually *gobyairship.Client.
+type MessageClient interface {
+	Delete(url string, extra http.Header) (*http.Response, error)
+}
+
+// DeleteMessage removes the message with id from every inbox using
+// DefaultMessagesURL.
+//
+// Listing a user's inbox is only available with the user's own credentials,
+// which the Message Center SDK uses, so it isn't supported here. Use the
+// message IDs from SendResult or events.RichEvent instead.
+func DeleteMessage(c MessageClient, id string) error {
+	return DeleteMessageURL(c, DefaultMessagesURL, id)
+}
+
+// DeleteMessageURL removes the message with id from every inbox using the
+// Message Center API at url. An *APIError is returned if the API rejects the
+// request, such as 404 if there's no such message.
+func DeleteMessageURL(c MessageClient, url, id string) error {
+	if id == "" || strings.Contains(id, "/") {
+		return fmt.Errorf("invalid message ID %q", id)
+	}
+	resp, err := c.Delete(strings.TrimSuffix(url, "/")+"/"+id, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return NewAPIError(resp)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,95 @@
+package push_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/push"
+)
+
+func TestMessage(t *testing.T) {
+	t.Parallel()
+	p, err := push.NewBuilder().
+		Audience(push.All).
+		Alert("You have a new message").
+		Message("Sale", "50% off today").
+		Build()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	p.Message.Extra = map[string]string{"sale": "spring"}
+	p.Message.Expiry = time.Date(2030, 1, 2, 7, 4, 5, 0, time.FixedZone("PST", -8*3600))
+	p.Message.Icons = &push.MessageIcons{ListIcon: "https://example.com/icon.png"}
+	buf, err := json.Marshal(p.Message)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{"title":"Sale","body":"50% off today","content_type":"text/html",` +
+		`"extra":{"sale":"spring"},"expiry":"2030-01-02T15:04:05","icons":{"list_icon":"https://example.com/icon.png"}}`
+	if string(buf) != expected {
+		t.Errorf("Expected:\n%s\nFound:\n%s", expected, buf)
+	}
+	var m push.Message
+	if err := json.Unmarshal(buf, &m); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !m.Expiry.Equal(p.Message.Expiry) || m.Icons == nil || m.Title != "Sale" {
+		t.Errorf("Unexpected message after decoding: %+v", m)
+	}
+
+	// Messages alone are valid content
+	p = &push.Push{Audience: push.All, Message: &push.Message{Title: "Hi", Body: "Hello", ContentType: push.ContentPlain}}
+	if err := p.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	invalid := map[string]*push.Message{
+		"missing title":        {Body: "Hello"},
+		"missing body":         {Title: "Hi"},
+		"unknown content type": {Title: "Hi", Body: "Hello", ContentType: "image/png"},
+		"invalid template":     {Title: "Hi {{name", Body: "Hello"},
+	}
+	for name, m := range invalid {
+		p := &push.Push{Audience: push.All, Message: m, Options: &push.Options{Personalization: true}}
+		if err := p.Validate(); err == nil {
+			t.Errorf("Expected an error with %s", name)
+		}
+	}
+}
+
+func TestDeleteMessage(t *testing.T) {
+	t.Parallel()
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" {
+			t.Errorf("Expected DELETE but found %s", r.Method)
+		}
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path != "/api/user/messages/m1" {
+			w.WriteHeader(404)
+			w.Write([]byte(`{"ok":false,"error":"Could not find message","error_code":40401}`))
+			return
+		}
+		w.WriteHeader(204)
+	}))
+	defer srv.Close()
+	c := gobyairship.NewClient("key", "token")
+
+	if err := push.DeleteMessageURL(c, srv.URL+"/api/user/messages/", "m1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	err := push.DeleteMessageURL(c, srv.URL+"/api/user/messages", "m2")
+	if apiErr, ok := err.(*push.APIError); !ok || apiErr.StatusCode != 404 {
+		t.Errorf("Expected a 404 *push.APIError but found %v", err)
+	}
+	if err := push.DeleteMessageURL(c, srv.URL+"/api/user/messages", "../m1"); err == nil {
+		t.Errorf("Expected an error deleting an invalid message ID")
+	}
+	if len(paths) != 2 {
+		t.Errorf("Expected 2 requests but found %v", paths)
+	}
+}
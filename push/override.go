@@ -0,0 +1,276 @@
+package push
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Badge sets an iOS app's badge. Use BadgeValue, BadgeIncrement, or
+// BadgeAuto.
+type Badge string
+
+// BadgeAuto increments the badge by one for each push. Devices reset the
+// count through the SDK.
+const BadgeAuto Badge = "auto"
+
+// BadgeValue sets the badge to n.
+func BadgeValue(n int) Badge { return Badge(strconv.Itoa(n)) }
+
+// BadgeIncrement changes the badge by n, which may be negative.
+func BadgeIncrement(n int) Badge {
+	if n < 0 {
+		return Badge(strconv.Itoa(n))
+	}
+	return Badge("+" + strconv.Itoa(n))
+}
+
+// MarshalJSON encodes values as numbers and increments as strings.
+func (b Badge) MarshalJSON() ([]byte, error) {
+	if b != "" && b[0] != '+' && b[0] != '-' {
+		if n, err := strconv.Atoi(string(b)); err == nil {
+			return json.Marshal(n)
+		}
+	}
+	return json.Marshal(string(b))
+}
+
+// UnmarshalJSON decodes numbers as values.
+func (b *Badge) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		*b = BadgeValue(n)
+		return nil
+	}
+	return json.Unmarshal(data, (*string)(b))
+}
+
+func (b Badge) validate() error {
+	if b == "" || b == BadgeAuto {
+		return nil
+	}
+	s := string(b)
+	if s[0] == '+' {
+		s = s[1:]
+	}
+	if _, err := strconv.Atoi(s); err != nil {
+		return fmt.Errorf("invalid badge %q", string(b))
+	}
+	return nil
+}
+
+// InterruptionLevel is how an iOS notification may interrupt the user.
+type InterruptionLevel string
+
+const (
+	InterruptionPassive       InterruptionLevel = "passive"
+	InterruptionActive        InterruptionLevel = "active"
+	InterruptionTimeSensitive InterruptionLevel = "time-sensitive"
+	InterruptionCritical      InterruptionLevel = "critical"
+)
+
+// IOS overrides a notification on iOS devices.
+type IOS struct {
+	Alert    string `json:"alert,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Badge    Badge  `json:"badge,omitempty"`
+	Sound    string `json:"sound,omitempty"`
+
+	// ContentAvailable wakes the app in the background. Pushes with only
+	// ContentAvailable are silent.
+	ContentAvailable bool `json:"content-available,omitempty"`
+
+	// MutableContent lets a notification service extension modify the
+	// notification before it's displayed.
+	MutableContent bool `json:"mutable-content,omitempty"`
+
+	// Category selects the notification's actions registered by the app.
+	Category string `json:"category,omitempty"`
+
+	// ThreadID groups notifications.
+	ThreadID string `json:"thread_id,omitempty"`
+
+	InterruptionLevel InterruptionLevel `json:"interruption_level,omitempty"`
+
+	// RelevanceScore between 0 and 1 ranks the notification in its group's
+	// summary.
+	RelevanceScore float64 `json:"relevance_score,omitempty"`
+
+	// Priority is APNs' priority: 10 to deliver immediately or 5 to conserve
+	// power.
+	Priority int `json:"priority,omitempty"`
+
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// empty returns true if the override has no content: nothing to display,
+// play, or pass to the app.
+func (o *IOS) empty() bool {
+	return o.Alert == "" && o.Title == "" && o.Subtitle == "" && o.Badge == "" && o.Sound == "" &&
+		!o.ContentAvailable && o.Category == "" && len(o.Extra) == 0
+}
+
+func (o *IOS) validate() error {
+	if err := o.Badge.validate(); err != nil {
+		return err
+	}
+	switch o.InterruptionLevel {
+	case "", InterruptionPassive, InterruptionActive, InterruptionTimeSensitive, InterruptionCritical:
+	default:
+		return fmt.Errorf("unknown interruption level %q", o.InterruptionLevel)
+	}
+	if o.RelevanceScore < 0 || o.RelevanceScore > 1 {
+		return fmt.Errorf("relevance score must be between 0 and 1: %v", o.RelevanceScore)
+	}
+	if o.Priority != 0 && o.Priority != 5 && o.Priority != 10 {
+		return fmt.Errorf("priority must be 5 or 10: %d", o.Priority)
+	}
+	return nil
+}
+
+// DeliveryPriority is how urgently FCM delivers an Android notification.
+type DeliveryPriority string
+
+const (
+	DeliveryHigh   DeliveryPriority = "high"
+	DeliveryNormal DeliveryPriority = "normal"
+)
+
+// Android overrides a notification on Android devices.
+type Android struct {
+	Alert   string `json:"alert,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Sound   string `json:"sound,omitempty"`
+
+	// NotificationChannel is the ID of the app's notification channel the
+	// notification is posted to.
+	NotificationChannel string `json:"notification_channel,omitempty"`
+
+	// Icon is the name of a drawable resource in the app and IconColor a
+	// "#RRGGBB" color.
+	Icon      string `json:"icon,omitempty"`
+	IconColor string `json:"icon_color,omitempty"`
+
+	// Priority of the notification from -2 (minimum) to 2 (maximum).
+	Priority int `json:"priority,omitempty"`
+
+	DeliveryPriority DeliveryPriority `json:"delivery_priority,omitempty"`
+
+	// CollapseKey replaces undelivered notifications with the same key.
+	CollapseKey string `json:"collapse_key,omitempty"`
+
+	// TimeToLive is how many seconds FCM keeps an undelivered notification.
+	TimeToLive int `json:"time_to_live,omitempty"`
+
+	Category string `json:"category,omitempty"`
+
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+func (o *Android) empty() bool {
+	return o.Alert == "" && o.Title == "" && o.Summary == "" && o.Sound == "" && o.Category == "" && len(o.Extra) == 0
+}
+
+func (o *Android) validate() error {
+	if o.Priority < -2 || o.Priority > 2 {
+		return fmt.Errorf("priority must be between -2 and 2: %d", o.Priority)
+	}
+	switch o.DeliveryPriority {
+	case "", DeliveryHigh, DeliveryNormal:
+	default:
+		return fmt.Errorf("unknown delivery priority %q", o.DeliveryPriority)
+	}
+	if o.TimeToLive < 0 {
+		return fmt.Errorf("negative time to live: %d", o.TimeToLive)
+	}
+	return nil
+}
+
+// Amazon overrides a notification on Amazon devices.
+type Amazon struct {
+	Alert   string `json:"alert,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Sound   string `json:"sound,omitempty"`
+
+	NotificationChannel string `json:"notification_channel,omitempty"`
+	Icon                string `json:"icon,omitempty"`
+	IconColor           string `json:"icon_color,omitempty"`
+
+	// ConsolidationKey replaces undelivered notifications with the same key.
+	ConsolidationKey string `json:"consolidation_key,omitempty"`
+
+	// ExpiresAfter is how many seconds ADM keeps an undelivered notification.
+	ExpiresAfter int `json:"expires_after,omitempty"`
+
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+func (o *Amazon) empty() bool {
+	return o.Alert == "" && o.Title == "" && o.Summary == "" && o.Sound == "" && len(o.Extra) == 0
+}
+
+func (o *Amazon) validate() error {
+	if o.ExpiresAfter < 0 {
+		return fmt.Errorf("negative expiry: %d", o.ExpiresAfter)
+	}
+	return nil
+}
+
+// WebIcon is the image shown with a web notification.
+type WebIcon struct {
+	URL string `json:"url"`
+}
+
+// WebButton is an action button shown with a web notification.
+type WebButton struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// maxWebButtons is the most buttons browsers display.
+const maxWebButtons = 2
+
+// Web overrides a notification in web browsers.
+type Web struct {
+	Alert string   `json:"alert,omitempty"`
+	Title string   `json:"title,omitempty"`
+	Icon  *WebIcon `json:"icon,omitempty"`
+
+	// RequireInteraction keeps the notification displayed until the user
+	// dismisses or clicks it.
+	RequireInteraction bool `json:"require_interaction,omitempty"`
+
+	// Buttons, at most two.
+	Buttons []WebButton `json:"buttons,omitempty"`
+
+	// TimeToLive is how many seconds an undelivered notification is kept.
+	TimeToLive int `json:"time_to_live,omitempty"`
+
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+func (o *Web) empty() bool {
+	return o.Alert == "" && o.Title == "" && o.Icon == nil && len(o.Extra) == 0
+}
+
+func (o *Web) validate() error {
+	if o.Icon != nil && o.Icon.URL == "" {
+		return errors.New("web icon without a URL")
+	}
+	if len(o.Buttons) > maxWebButtons {
+		return fmt.Errorf("at most %d web buttons are supported: %d", maxWebButtons, len(o.Buttons))
+	}
+	for i, b := range o.Buttons {
+		if b.ID == "" || b.Label == "" {
+			return fmt.Errorf("web button %d needs an ID and label", i)
+		}
+	}
+	if o.TimeToLive < 0 {
+		return fmt.Errorf("negative time to live: %d", o.TimeToLive)
+	}
+	return nil
+}
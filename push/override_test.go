@@ -0,0 +1,110 @@
+package push_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lytics/gobyairship/push"
+)
+
+func TestOverrides(t *testing.T) {
+	t.Parallel()
+	n := &push.Notification{
+		Alert: "Hello",
+		IOS: &push.IOS{
+			Badge:             push.BadgeIncrement(1),
+			Sound:             "chime.caf",
+			InterruptionLevel: push.InterruptionTimeSensitive,
+			MutableContent:    true,
+		},
+		Android: &push.Android{
+			NotificationChannel: "news",
+			Icon:                "ic_news",
+			DeliveryPriority:    push.DeliveryHigh,
+		},
+		Amazon: &push.Amazon{ConsolidationKey: "news"},
+		Web: &push.Web{
+			Icon:               &push.WebIcon{URL: "https://example.com/icon.png"},
+			RequireInteraction: true,
+			Buttons:            []push.WebButton{{ID: "read", Label: "Read"}},
+		},
+	}
+	p := &push.Push{Audience: push.All, Notification: n}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	buf, err := json.Marshal(n)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `{"alert":"Hello",` +
+		`"ios":{"badge":"+1","sound":"chime.caf","mutable-content":true,"interruption_level":"time-sensitive"},` +
+		`"android":{"notification_channel":"news","icon":"ic_news","delivery_priority":"high"},` +
+		`"amazon":{"consolidation_key":"news"},` +
+		`"web":{"icon":{"url":"https://example.com/icon.png"},"require_interaction":true,"buttons":[{"id":"read","label":"Read"}]}}`
+	if string(buf) != expected {
+		t.Errorf("Expected:\n%s\nFound:\n%s", expected, buf)
+	}
+
+	// Silent iOS pushes have no alert, and overrides with only data or a
+	// sound have content too
+	content := map[string]*push.Notification{
+		"silent push":   {IOS: &push.IOS{ContentAvailable: true}},
+		"ios extra":     {IOS: &push.IOS{Extra: map[string]string{"k": "v"}}},
+		"ios sound":     {IOS: &push.IOS{Sound: "chime.caf"}},
+		"ios category":  {IOS: &push.IOS{Category: "reply"}},
+		"android extra": {Android: &push.Android{Extra: map[string]string{"k": "v"}}},
+		"android sound": {Android: &push.Android{Sound: "chime"}},
+		"amazon extra":  {Amazon: &push.Amazon{Extra: map[string]string{"k": "v"}}},
+		"web extra":     {Web: &push.Web{Extra: map[string]string{"k": "v"}}},
+	}
+	for name, n := range content {
+		p := &push.Push{Audience: push.All, Notification: n}
+		if err := p.Validate(); err != nil {
+			t.Errorf("Unexpected error with %s: %v", name, err)
+		}
+	}
+	p = &push.Push{Audience: push.All, Notification: &push.Notification{IOS: &push.IOS{ThreadID: "t"}}}
+	if err := p.Validate(); err == nil {
+		t.Errorf("Expected an error with an override without content")
+	}
+
+	invalid := map[string]*push.Notification{
+		"invalid badge":              {IOS: &push.IOS{Alert: "Hi", Badge: "lots"}},
+		"unknown interruption level": {IOS: &push.IOS{Alert: "Hi", InterruptionLevel: "urgent"}},
+		"invalid relevance score":    {IOS: &push.IOS{Alert: "Hi", RelevanceScore: 2}},
+		"invalid android priority":   {Android: &push.Android{Alert: "Hi", Priority: 3}},
+		"unknown delivery priority":  {Android: &push.Android{Alert: "Hi", DeliveryPriority: "urgent"}},
+		"negative amazon expiry":     {Amazon: &push.Amazon{Alert: "Hi", ExpiresAfter: -1}},
+		"too many web buttons":       {Web: &push.Web{Alert: "Hi", Buttons: make([]push.WebButton, 3)}},
+		"web button without a label": {Web: &push.Web{Alert: "Hi", Buttons: []push.WebButton{{ID: "a"}}}},
+	}
+	for name, n := range invalid {
+		p := &push.Push{Audience: push.All, Notification: n}
+		if err := p.Validate(); err == nil {
+			t.Errorf("Expected an error with %s", name)
+		}
+	}
+}
+
+func TestBadge(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		badge push.Badge
+		json  string
+	}{
+		{push.BadgeValue(5), `5`},
+		{push.BadgeIncrement(2), `"+2"`},
+		{push.BadgeIncrement(-1), `"-1"`},
+		{push.BadgeAuto, `"auto"`},
+	} {
+		b, err := json.Marshal(tc.badge)
+		if err != nil || string(b) != tc.json {
+			t.Errorf("Expected %s but found %s (%v)", tc.json, b, err)
+		}
+		var badge push.Badge
+		if err := json.Unmarshal(b, &badge); err != nil || badge != tc.badge {
+			t.Errorf("Expected %q but found %q (%v)", tc.badge, badge, err)
+		}
+	}
+}
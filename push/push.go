@@ -0,0 +1,329 @@
+package push
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/audience"
+)
+
+// DefaultURL is the Push API endpoint which sends pushes.
+const DefaultURL = "https://go.urbanairship.com/api/push"
+
+// maxErrorBody is the most of an error response's body read into an APIError.
+const maxErrorBody = 64 * 1024
+
+// Client used to send pushes. Usually *gobyairship.Client.
+type Client interface {
+	Post(url string, body interface{}, extra http.Header) (*http.Response, error)
+}
+
+// All is the audience and device types of a push sent to every device.
+const All = "all"
+
+// DeviceType is a platform a push may be sent to.
+type DeviceType string
+
+// Device types supported by the Push API.
+const (
+	DeviceIOS     DeviceType = "ios"
+	DeviceAndroid DeviceType = "android"
+	DeviceAmazon  DeviceType = "amazon"
+	DeviceWeb     DeviceType = "web"
+	DeviceEmail   DeviceType = "email"
+	DeviceSMS     DeviceType = "sms"
+)
+
+// DeviceTypes a push is sent to. Empty DeviceTypes are every type.
+type DeviceTypes []DeviceType
+
+// MarshalJSON encodes empty DeviceTypes as "all".
+func (d DeviceTypes) MarshalJSON() ([]byte, error) {
+	if len(d) == 0 {
+		return json.Marshal(All)
+	}
+	return json.Marshal([]DeviceType(d))
+}
+
+// UnmarshalJSON decodes "all" as empty DeviceTypes.
+func (d *DeviceTypes) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		if s != All {
+			return fmt.Errorf("invalid device_types: %q", s)
+		}
+		*d = nil
+		return nil
+	}
+	return json.Unmarshal(b, (*[]DeviceType)(d))
+}
+
+// Notification is the content of a push.
+type Notification struct {
+	// Alert is the text displayed on every platform without an override.
+	Alert string `json:"alert,omitempty"`
+
+	// Platform overrides replace or add to the notification on their device
+	// type.
+	IOS     *IOS     `json:"ios,omitempty"`
+	Android *Android `json:"android,omitempty"`
+	Amazon  *Amazon  `json:"amazon,omitempty"`
+	Web     *Web     `json:"web,omitempty"`
+}
+
+// empty returns true if the notification displays nothing on any platform.
+func (n *Notification) empty() bool {
+	return n.Alert == "" &&
+		(n.IOS == nil || n.IOS.empty()) &&
+		(n.Android == nil || n.Android.empty()) &&
+		(n.Amazon == nil || n.Amazon.empty()) &&
+		(n.Web == nil || n.Web.empty())
+}
+
+// validate the notification's platform overrides.
+func (n *Notification) validate() error {
+	if n.empty() {
+		return errors.New("empty notification: set an alert or title")
+	}
+	if n.IOS != nil {
+		if err := n.IOS.validate(); err != nil {
+			return fmt.Errorf("invalid ios override: %v", err)
+		}
+	}
+	if n.Android != nil {
+		if err := n.Android.validate(); err != nil {
+			return fmt.Errorf("invalid android override: %v", err)
+		}
+	}
+	if n.Amazon != nil {
+		if err := n.Amazon.validate(); err != nil {
+			return fmt.Errorf("invalid amazon override: %v", err)
+		}
+	}
+	if n.Web != nil {
+		if err := n.Web.validate(); err != nil {
+			return fmt.Errorf("invalid web override: %v", err)
+		}
+	}
+	return nil
+}
+
+// Options of a push's delivery.
+type Options struct {
+	// Expiry is the number of seconds after which the push is no longer
+	// delivered to devices which haven't received it. Zero uses the
+	// platform's default.
+	Expiry int `json:"expiry,omitempty"`
+
+	// Personalization renders the push's text as a template for each device
+	// with merge fields such as {{first_name}} from its named user's
+	// attributes. Validate checks the templates' syntax.
+	Personalization bool `json:"personalization,omitempty"`
+}
+
+// Push is a request to the Push API.
+type Push struct {
+	// Audience selects the devices the push is sent to. It's usually an
+	// audience.Selector but is marshaled as is, so it may also be All or a
+	// raw selector such as map[string]interface{}{"tag": "sports"}.
+	Audience interface{} `json:"audience"`
+
+	// Notification, InApp, and Message are the content of the push. At least
+	// one is required.
+	Notification *Notification `json:"notification,omitempty"`
+	InApp        *InApp        `json:"in_app,omitempty"`
+	Message      *Message      `json:"message,omitempty"`
+
+	Options *Options `json:"options,omitempty"`
+
+	DeviceTypes DeviceTypes `json:"device_types"`
+}
+
+// deviceTypes the Push API accepts.
+var deviceTypes = map[DeviceType]bool{
+	DeviceIOS: true, DeviceAndroid: true, DeviceAmazon: true,
+	DeviceWeb: true, DeviceEmail: true, DeviceSMS: true,
+}
+
+// Validate returns nil if the push is valid or an error if there's an issue
+// which the Push API would reject.
+func (p *Push) Validate() error {
+	if p.Audience == nil {
+		return errors.New("missing audience")
+	}
+	if sel, ok := p.Audience.(audience.Selector); ok {
+		if err := sel.Validate(); err != nil {
+			return fmt.Errorf("invalid audience: %v", err)
+		}
+	}
+	if p.Notification == nil && p.InApp == nil && p.Message == nil {
+		return errors.New("missing notification, in-app message, or Message Center message")
+	}
+	if p.Notification != nil {
+		if err := p.Notification.validate(); err != nil {
+			return err
+		}
+	}
+	if p.InApp != nil {
+		if err := p.InApp.validate(); err != nil {
+			return fmt.Errorf("invalid in-app message: %v", err)
+		}
+	}
+	if p.Message != nil {
+		if err := p.Message.validate(); err != nil {
+			return err
+		}
+	}
+	if p.Options != nil && p.Options.Expiry < 0 {
+		return fmt.Errorf("negative expiry: %d", p.Options.Expiry)
+	}
+	if p.Options != nil && p.Options.Personalization {
+		if err := p.validateTemplates(); err != nil {
+			return err
+		}
+	}
+	for _, t := range p.DeviceTypes {
+		if !deviceTypes[t] {
+			return fmt.Errorf("unknown device type %q", t)
+		}
+	}
+	return nil
+}
+
+// SendResult describes the pushes sent by a request.
+type SendResult struct {
+	OK bool `json:"ok"`
+
+	// OperationID identifies the request for support requests. It's the
+	// body's operation_id or, if missing, the UA-Operation-Id header.
+	OperationID string `json:"operation_id"`
+
+	// PushIDs identify each push which was sent, one per push in the request.
+	PushIDs []string `json:"push_ids"`
+
+	// MessageIDs identify Message Center messages and ContentURLs are where
+	// their content is hosted, for pushes which included messages.
+	MessageIDs  []string `json:"message_ids,omitempty"`
+	ContentURLs []string `json:"content_urls,omitempty"`
+
+	// Meta of the response such as its rate limit.
+	Meta *gobyairship.Meta `json:"-"`
+}
+
+// sent returns true if the result identifies anything which was sent.
+func (r *SendResult) sent() bool {
+	return len(r.PushIDs) > 0 || len(r.MessageIDs) > 0 || len(r.ContentURLs) > 0
+}
+
+// APIError is returned when Urban Airship responds to a push, or a request to
+// a related API such as schedules, with a non-2xx status, such as 400 for a
+// malformed push or 401 for invalid credentials.
+type APIError struct {
+	// StatusCode is the response's HTTP status.
+	StatusCode int `json:"-"`
+
+	// OperationID identifies the failed request for support requests. It's
+	// the UA-Operation-Id header or, if missing, the body's operation_id.
+	OperationID string `json:"operation_id"`
+
+	// Message and Code are the error and error_code from the body, if it was
+	// JSON, and Details any further details.
+	Message string          `json:"error"`
+	Code    int             `json:"error_code"`
+	Details json.RawMessage `json:"details,omitempty"`
+
+	// Body is the raw response body, truncated to 64KB.
+	Body []byte `json:"-"`
+
+	// Result is set if the request partially failed: the body identified
+	// pushes or messages which were sent despite the error.
+	Result *SendResult `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("unexpected non-2xx response: %d", e.StatusCode)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.OperationID != "" {
+		msg += " (operation " + e.OperationID + ")"
+	}
+	if e.Result != nil {
+		msg += fmt.Sprintf(" after sending %d pushes", len(e.Result.PushIDs))
+	}
+	return msg
+}
+
+// NewAPIError reads the body of a failed response from the Push API or an API
+// sharing its errors, such as schedules. The body isn't closed.
+func NewAPIError(resp *http.Response) *APIError {
+	e := &APIError{StatusCode: resp.StatusCode}
+	e.Body, _ = ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	// Errors decoding the body are ignored since it's kept raw
+	json.Unmarshal(e.Body, e)
+	if id := resp.Header.Get("UA-Operation-Id"); id != "" {
+		e.OperationID = id
+	}
+	result := &SendResult{}
+	if json.Unmarshal(e.Body, result) == nil && result.sent() {
+		result.Meta = gobyairship.ParseMeta(resp)
+		result.OperationID = e.OperationID
+		e.Result = result
+	}
+	return e
+}
+
+// Send a push to DefaultURL.
+func Send(c Client, p *Push) (*SendResult, error) {
+	return SendURL(c, DefaultURL, p)
+}
+
+// SendURL sends a push to the Push API at url. Invalid pushes return
+// Validate's error without being sent, and an *APIError is returned if the
+// push is rejected. Check the APIError's Result for pushes sent despite the
+// error.
+func SendURL(c Client, url string, p *Push) (*SendResult, error) {
+	return SendURLHeader(c, url, p, nil)
+}
+
+// SendHeader is like Send but adds extra headers to the request, such as a
+// gobyairship.IdempotencyHeader to reuse when retrying.
+func SendHeader(c Client, p *Push, extra http.Header) (*SendResult, error) {
+	return SendURLHeader(c, DefaultURL, p, extra)
+}
+
+// SendURLHeader is like SendURL but adds extra headers to the request.
+func SendURLHeader(c Client, url string, p *Push, extra http.Header) (*SendResult, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return Post(c, url, p, extra)
+}
+
+// Post body to an API at url which responds like the Push API, such as Create
+// and Send, and decode its SendResult. An *APIError is returned if the request
+// is rejected.
+func Post(c Client, url string, body interface{}, extra http.Header) (*SendResult, error) {
+	resp, err := c.Post(url, body, extra)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, NewAPIError(resp)
+	}
+	out := &SendResult{}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, err
+	}
+	out.Meta = gobyairship.ParseMeta(resp)
+	if out.OperationID == "" {
+		out.OperationID = out.Meta.OperationID
+	}
+	return out, nil
+}
@@ -0,0 +1,176 @@
+package push_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/audience"
+	"github.com/lytics/gobyairship/push"
+)
+
+func TestSend(t *testing.T) {
+	t.Parallel()
+	var body []byte
+	var key string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Unexpected request: %s %v", r.Method, r.Header)
+		}
+		body, _ = ioutil.ReadAll(r.Body)
+		key = r.Header.Get(gobyairship.IdempotencyHeader)
+		w.Header().Set("UA-Operation-Id", "op")
+		w.Header().Set("X-RateLimit-Remaining", "9")
+		w.WriteHeader(202)
+		w.Write([]byte(`{"ok":true,"push_ids":["p1"],"message_ids":["m1"],"content_urls":["https://dl.example.com/m1"]}`))
+	}))
+	defer srv.Close()
+
+	resp, err := push.SendURL(gobyairship.NewClient("key", "token"), srv.URL, &push.Push{
+		Audience:     push.All,
+		Notification: &push.Notification{Alert: "Hello"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !resp.OK || resp.OperationID != "op" || len(resp.PushIDs) != 1 || resp.PushIDs[0] != "p1" {
+		t.Errorf("Unexpected response: %#v", resp)
+	}
+	if len(resp.MessageIDs) != 1 || len(resp.ContentURLs) != 1 || resp.Meta == nil || resp.Meta.RateLimit.Remaining != 9 {
+		t.Errorf("Unexpected response: %#v", resp)
+	}
+	const expected = `{"audience":"all","notification":{"alert":"Hello"},"device_types":"all"}`
+	if string(body) != expected {
+		t.Errorf("Expected request %s but found %s", expected, body)
+	}
+
+	// Callers may pass their own headers such as an idempotency key
+	extra := http.Header{gobyairship.IdempotencyHeader: []string{"k1"}}
+	if _, err := push.SendURLHeader(gobyairship.NewClient("key", "token"), srv.URL, &push.Push{
+		Audience:     push.All,
+		Notification: &push.Notification{Alert: "Hello"},
+	}, extra); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if key != "k1" {
+		t.Errorf("Expected idempotency key k1 but found %q", key)
+	}
+}
+
+func TestSendError(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		w.Write([]byte(`{"ok":false,"error":"Could not parse request body.","error_code":40001,"operation_id":"op"}`))
+	}))
+	defer srv.Close()
+
+	_, err := push.SendURL(gobyairship.NewClient("key", "token"), srv.URL, &push.Push{
+		Audience:     map[string]interface{}{"tag": "sports"},
+		Notification: &push.Notification{Alert: "Hello"},
+		DeviceTypes:  push.DeviceTypes{push.DeviceIOS},
+	})
+	apiErr, ok := err.(*push.APIError)
+	if !ok {
+		t.Fatalf("Expected *push.APIError but found %T: %v", err, err)
+	}
+	if apiErr.StatusCode != 400 || apiErr.Code != 40001 || apiErr.OperationID != "op" || apiErr.Result != nil {
+		t.Errorf("Unexpected error: %#v", apiErr)
+	}
+}
+
+func TestSendPartialFailure(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("UA-Operation-Id", "op")
+		w.WriteHeader(500)
+		w.Write([]byte(`{"ok":false,"error":"Message Center content failed","push_ids":["p1"]}`))
+	}))
+	defer srv.Close()
+
+	_, err := push.SendURL(gobyairship.NewClient("key", "token"), srv.URL, &push.Push{
+		Audience:     push.All,
+		Notification: &push.Notification{Alert: "Hello"},
+	})
+	apiErr, ok := err.(*push.APIError)
+	if !ok {
+		t.Fatalf("Expected *push.APIError but found %T: %v", err, err)
+	}
+	r := apiErr.Result
+	if r == nil || len(r.PushIDs) != 1 || r.PushIDs[0] != "p1" || r.OperationID != "op" || r.Meta == nil {
+		t.Errorf("Expected the partial result on the error but found %#v", r)
+	}
+}
+
+func TestSendAudience(t *testing.T) {
+	t.Parallel()
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(`{"ok":true,"push_ids":["p1"]}`))
+	}))
+	defer srv.Close()
+
+	c := gobyairship.NewClient("key", "token")
+	p := &push.Push{
+		Audience:     audience.And(audience.Tag("vip"), audience.Not(audience.NamedUser("u1"))),
+		Notification: &push.Notification{Alert: "Hello"},
+	}
+	if _, err := push.SendURL(c, srv.URL, p); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	const expected = `{"audience":{"and":[{"tag":"vip"},{"not":{"named_user":"u1"}}]},"notification":{"alert":"Hello"},"device_types":"all"}`
+	if string(body) != expected {
+		t.Errorf("Expected request %s but found %s", expected, body)
+	}
+
+	// Invalid selectors aren't sent
+	p.Audience = audience.Or()
+	if _, err := push.SendURL(c, srv.URL, p); err == nil {
+		t.Errorf("Expected an error sending to an invalid audience")
+	}
+}
+
+func TestSendInvalid(t *testing.T) {
+	t.Parallel()
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer srv.Close()
+
+	_, err := push.SendURL(gobyairship.NewClient("key", "token"), srv.URL, &push.Push{Audience: push.All})
+	if err == nil {
+		t.Errorf("Expected an error sending a push without a notification")
+	}
+	if requests != 0 {
+		t.Errorf("Expected invalid push not to be sent but found %d requests", requests)
+	}
+}
+
+func TestDeviceTypes(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		json  string
+		types push.DeviceTypes
+	}{
+		{`"all"`, nil},
+		{`["ios","android"]`, push.DeviceTypes{push.DeviceIOS, push.DeviceAndroid}},
+	} {
+		b, err := json.Marshal(tc.types)
+		if err != nil || string(b) != tc.json {
+			t.Errorf("Expected %s but found %s (%v)", tc.json, b, err)
+		}
+		var types push.DeviceTypes
+		if err := json.Unmarshal([]byte(tc.json), &types); err != nil || len(types) != len(tc.types) {
+			t.Errorf("Expected %v but found %v (%v)", tc.types, types, err)
+		}
+	}
+	var types push.DeviceTypes
+	if err := json.Unmarshal([]byte(`"ios"`), &types); err == nil {
+		t.Errorf("Expected an error decoding a single device type")
+	}
+}
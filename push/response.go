@@ -0,0 +1,85 @@
+package push
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// DecodeResponse decodes a successful response from the Push API or an API
+// sharing its conventions, such as schedules, into v. An *APIError is
+// returned if the request failed. If v is nil the body is discarded. The body
+// is closed.
+func DecodeResponse(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return NewAPIError(resp)
+	}
+	if v == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// DecodeResult is like DecodeResponse but also sets operationID, usually a
+// field of v, from the UA-Operation-Id header if the body didn't include one.
+func DecodeResult(resp *http.Response, v interface{}, operationID *string) error {
+	if err := DecodeResponse(resp, v); err != nil {
+		return err
+	}
+	if *operationID == "" {
+		*operationID = resp.Header.Get("UA-Operation-Id")
+	}
+	return nil
+}
+
+// Getter gets API resources. Usually *gobyairship.Client.
+type Getter interface {
+	Get(url string, extra http.Header) (*http.Response, error)
+}
+
+// Pages reads the pages of a listing from an API sharing the Push API's
+// conventions, such as schedules, following each page's next_page URL. Typed
+// pagers wrap it to decode their items.
+type Pages struct {
+	c    Getter
+	next string
+}
+
+// NewPages creates Pages starting at url.
+func NewPages(c Getter, url string) *Pages {
+	return &Pages{c: c, next: url}
+}
+
+// NextURL returns the URL of the next page or an empty string if there are
+// no more pages.
+func (p *Pages) NextURL() string { return p.next }
+
+// Next decodes the next page into v. io.EOF is returned once every page has
+// been read.
+func (p *Pages) Next(v interface{}) error {
+	if p.next == "" {
+		return io.EOF
+	}
+	resp, err := p.c.Get(p.next, nil)
+	if err != nil {
+		return err
+	}
+	var body json.RawMessage
+	if err := DecodeResponse(resp, &body); err != nil {
+		return err
+	}
+	pg := struct {
+		NextPage string `json:"next_page"`
+	}{}
+	if err := json.Unmarshal(body, &pg); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return err
+	}
+	p.next = pg.NextPage
+	return nil
+}
@@ -0,0 +1,82 @@
+package push_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/push"
+)
+
+func TestDecodeResult(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("UA-Operation-Id", "op")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(404)
+			w.Write([]byte(`{"ok":false,"error":"Not found","error_code":40401}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+	c := gobyairship.NewClient("key", "token")
+
+	resp, err := c.Get(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := struct {
+		OK          bool   `json:"ok"`
+		OperationID string `json:"operation_id"`
+	}{}
+	if err := push.DecodeResult(resp, &out, &out.OperationID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !out.OK || out.OperationID != "op" {
+		t.Errorf("Unexpected result: %+v", out)
+	}
+
+	resp, err = c.Get(srv.URL+"/fail", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err, ok := push.DecodeResponse(resp, nil).(*push.APIError); !ok || err.Code != 40401 || err.OperationID != "op" {
+		t.Errorf("Expected an *APIError but found %v", err)
+	}
+}
+
+func TestPages(t *testing.T) {
+	t.Parallel()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "" {
+			fmt.Fprintf(w, `{"ok":true,"items":[1,2],"next_page":"%s?page=2"}`, srv.URL)
+			return
+		}
+		w.Write([]byte(`{"ok":true,"items":[3]}`))
+	}))
+	defer srv.Close()
+
+	pages := push.NewPages(gobyairship.NewClient("key", "token"), srv.URL)
+	var items []int
+	for {
+		pg := struct {
+			Items []int `json:"items"`
+		}{}
+		err := pages.Next(&pg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		items = append(items, pg.Items...)
+	}
+	if fmt.Sprint(items) != "[1 2 3]" || pages.NextURL() != "" {
+		t.Errorf("Expected items [1 2 3] but found %v", items)
+	}
+}
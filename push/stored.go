@@ -0,0 +1,71 @@
+package push
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lytics/gobyairship/audience"
+)
+
+// DefaultTemplateURL is the Push API endpoint which sends pushes from stored
+// templates.
+const DefaultTemplateURL = "https://go.urbanairship.com/api/templates/push"
+
+// TemplateSelector selects a template stored in Urban Airship and the values
+// substituted for its variables, such as {"FIRST_NAME": "Ada"} for
+// {{FIRST_NAME}}.
+type TemplateSelector struct {
+	TemplateID    string            `json:"template_id"`
+	Substitutions map[string]string `json:"substitutions,omitempty"`
+}
+
+// TemplatePush is a push whose content is a stored template.
+type TemplatePush struct {
+	// Audience and DeviceTypes are as a Push's.
+	Audience    interface{} `json:"audience"`
+	DeviceTypes DeviceTypes `json:"device_types"`
+
+	Template *TemplateSelector `json:"merge_data"`
+}
+
+// Validate returns nil if the push is valid or an error if there's an issue
+// which the Push API would reject.
+func (p *TemplatePush) Validate() error {
+	if p.Audience == nil {
+		return errors.New("missing audience")
+	}
+	if sel, ok := p.Audience.(audience.Selector); ok {
+		if err := sel.Validate(); err != nil {
+			return fmt.Errorf("invalid audience: %v", err)
+		}
+	}
+	if p.Template == nil || p.Template.TemplateID == "" {
+		return errors.New("missing template ID")
+	}
+	for k := range p.Template.Substitutions {
+		if k == "" {
+			return errors.New("empty substitution variable")
+		}
+	}
+	for _, t := range p.DeviceTypes {
+		if !deviceTypes[t] {
+			return fmt.Errorf("unknown device type %q", t)
+		}
+	}
+	return nil
+}
+
+// SendTemplate sends a push from a stored template to DefaultTemplateURL.
+func SendTemplate(c Client, p *TemplatePush) (*SendResult, error) {
+	return SendTemplateURL(c, DefaultTemplateURL, p)
+}
+
+// SendTemplateURL sends a push from a stored template to the API at url.
+// Invalid pushes return Validate's error without being sent, and an
+// *APIError is returned if the push is rejected.
+func SendTemplateURL(c Client, url string, p *TemplatePush) (*SendResult, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return Post(c, url, p, nil)
+}
@@ -0,0 +1,58 @@
+package push_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/audience"
+	"github.com/lytics/gobyairship/push"
+)
+
+func TestSendTemplate(t *testing.T) {
+	t.Parallel()
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Header().Set("UA-Operation-Id", "op")
+		w.WriteHeader(202)
+		w.Write([]byte(`{"ok":true,"push_ids":["p1"]}`))
+	}))
+	defer srv.Close()
+
+	c := gobyairship.NewClient("key", "token")
+	p := &push.TemplatePush{
+		Audience:    audience.NamedUser("u1"),
+		DeviceTypes: push.DeviceTypes{push.DeviceIOS},
+		Template: &push.TemplateSelector{
+			TemplateID:    "tmpl",
+			Substitutions: map[string]string{"FIRST_NAME": "Ada"},
+		},
+	}
+	resp, err := push.SendTemplateURL(c, srv.URL, p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.OperationID != "op" || len(resp.PushIDs) != 1 {
+		t.Errorf("Unexpected response: %#v", resp)
+	}
+	const expected = `{"audience":{"named_user":"u1"},"device_types":["ios"],` +
+		`"merge_data":{"template_id":"tmpl","substitutions":{"FIRST_NAME":"Ada"}}}`
+	if string(body) != expected {
+		t.Errorf("Expected request %s but found %s", expected, body)
+	}
+
+	invalid := map[string]*push.TemplatePush{
+		"missing audience":    {Template: &push.TemplateSelector{TemplateID: "tmpl"}},
+		"missing template":    {Audience: audience.All},
+		"missing template ID": {Audience: audience.All, Template: &push.TemplateSelector{}},
+		"invalid audience":    {Audience: audience.Tag(""), Template: &push.TemplateSelector{TemplateID: "tmpl"}},
+	}
+	for name, p := range invalid {
+		if _, err := push.SendTemplateURL(c, srv.URL, p); err == nil {
+			t.Errorf("Expected an error with %s", name)
+		}
+	}
+}
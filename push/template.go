@@ -0,0 +1,172 @@
+package push
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TemplateError describes a syntax error in a personalization template.
+type TemplateError struct {
+	// Pos is the byte offset of the error in the template.
+	Pos int
+	Msg string
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("template error at %d: %s", e.Pos, e.Msg)
+}
+
+// ValidateTemplate returns a *TemplateError if s is not a syntactically valid
+// personalization template. Only the syntax is checked: tags must be closed,
+// blocks such as {{#if name}}..{{else}}..{{/if}} balanced, and quotes and
+// subexpressions within tags closed. Helpers aren't checked since Urban
+// Airship adds new ones, so {{$upper name}} and {{#eq a b}}..{{/eq}} are
+// valid.
+func ValidateTemplate(s string) error {
+	// blocks are the names of the open blocks and where they started
+	var blocks []string
+	var starts []int
+	pos := 0
+	for {
+		i := strings.Index(s[pos:], "{{")
+		if i < 0 {
+			break
+		}
+		start := pos + i
+		tag, end, err := readTag(s, start)
+		if err != nil {
+			return err
+		}
+		pos = end
+		switch {
+		case strings.HasPrefix(tag, "!"):
+			// comment
+		case tag == "":
+			return &TemplateError{Pos: start, Msg: "empty tag"}
+		case tag == "else" || strings.HasPrefix(tag, "else "):
+			if len(blocks) == 0 {
+				return &TemplateError{Pos: start, Msg: "{{else}} outside a block"}
+			}
+		case strings.HasPrefix(tag, "#"):
+			fields := strings.Fields(tag[1:])
+			if len(fields) == 0 {
+				return &TemplateError{Pos: start, Msg: "block without a helper"}
+			}
+			if err := checkExpr(start, tag); err != nil {
+				return err
+			}
+			blocks, starts = append(blocks, fields[0]), append(starts, start)
+		case strings.HasPrefix(tag, "/"):
+			name := strings.TrimSpace(tag[1:])
+			if len(blocks) == 0 {
+				return &TemplateError{Pos: start, Msg: fmt.Sprintf("unexpected {{/%s}}", name)}
+			}
+			if open := blocks[len(blocks)-1]; open != name {
+				return &TemplateError{Pos: start, Msg: fmt.Sprintf("{{#%s}} closed by {{/%s}}", open, name)}
+			}
+			blocks, starts = blocks[:len(blocks)-1], starts[:len(starts)-1]
+		default:
+			if err := checkExpr(start, tag); err != nil {
+				return err
+			}
+		}
+	}
+	if len(blocks) > 0 {
+		return &TemplateError{Pos: starts[len(starts)-1], Msg: fmt.Sprintf("unclosed {{#%s}}", blocks[len(blocks)-1])}
+	}
+	return nil
+}
+
+// readTag reads the tag starting at start, returning its trimmed contents
+// and the position after it. Comments may contain "}}" when written as
+// {{!-- --}}.
+func readTag(s string, start int) (string, int, error) {
+	open, close := "{{", "}}"
+	switch {
+	case strings.HasPrefix(s[start:], "{{!--"):
+		open, close = "{{!--", "--}}"
+	case strings.HasPrefix(s[start:], "{{{"):
+		open, close = "{{{", "}}}"
+	}
+	end := strings.Index(s[start+len(open):], close)
+	if end < 0 {
+		return "", 0, &TemplateError{Pos: start, Msg: "unclosed tag"}
+	}
+	tag := strings.TrimSpace(s[start+len(open) : start+len(open)+end])
+	if open == "{{!--" {
+		tag = "!" + tag
+	}
+	if open == "{{{" && (tag == "" || strings.ContainsAny(tag[:1], "#/!") || tag == "else") {
+		return "", 0, &TemplateError{Pos: start, Msg: "only expressions may use {{{ }}}"}
+	}
+	return tag, start + len(open) + end + len(close), nil
+}
+
+// checkExpr returns an error if an expression's quotes or subexpressions
+// aren't closed.
+func checkExpr(start int, tag string) error {
+	depth := 0
+	var quote rune
+	for _, r := range tag {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			if depth--; depth < 0 {
+				return &TemplateError{Pos: start, Msg: "unopened )"}
+			}
+		}
+	}
+	switch {
+	case quote != 0:
+		return &TemplateError{Pos: start, Msg: "unclosed quote"}
+	case depth > 0:
+		return &TemplateError{Pos: start, Msg: "unclosed subexpression"}
+	}
+	return nil
+}
+
+// validateTemplates of a personalized push's text.
+func (p *Push) validateTemplates() error {
+	texts := map[string]string{}
+	if n := p.Notification; n != nil {
+		texts["alert"] = n.Alert
+		if o := n.IOS; o != nil {
+			texts["ios alert"], texts["ios title"], texts["ios subtitle"] = o.Alert, o.Title, o.Subtitle
+		}
+		if o := n.Android; o != nil {
+			texts["android alert"], texts["android title"], texts["android summary"] = o.Alert, o.Title, o.Summary
+		}
+		if o := n.Amazon; o != nil {
+			texts["amazon alert"], texts["amazon title"], texts["amazon summary"] = o.Alert, o.Title, o.Summary
+		}
+		if o := n.Web; o != nil {
+			texts["web alert"], texts["web title"] = o.Alert, o.Title
+		}
+	}
+	if p.InApp != nil {
+		texts["in-app alert"] = p.InApp.Alert
+	}
+	if p.Message != nil {
+		texts["message title"], texts["message body"] = p.Message.Title, p.Message.Body
+	}
+	names := make([]string, 0, len(texts))
+	for name := range texts {
+		names = append(names, name)
+	}
+	// Report the same error for the same push
+	sort.Strings(names)
+	for _, name := range names {
+		if err := ValidateTemplate(texts[name]); err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package push_test
+
+import (
+	"testing"
+
+	"github.com/lytics/gobyairship/push"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	t.Parallel()
+	valid := []string{
+		"Hello!",
+		"Hi {{first_name}}!",
+		"Hi {{{ first_name }}}!",
+		`Hi {{$def first_name "friend"}}!`,
+		"{{address.city}}",
+		"{{#if vip}}VIP{{else}}regular{{/if}}",
+		"{{#unless vip}}join{{/unless}}",
+		"{{#each items}}{{@index}}:{{this}} {{/each}}",
+		"a{{! comment }}b",
+		"a{{!-- a comment with }} inside --}}b",
+		// Helpers aren't checked so any the API supports may be used
+		"{{$upper first_name}}",
+		`{{$format_date signup "short"}}`,
+		"{{#eq tier 'gold'}}Gold{{else if (eq tier 'silver')}}Silver{{/eq}}",
+		"{{#with address}}{{city}}{{/with}}",
+		"{{$capitalize ($lower name)}}",
+	}
+	for _, src := range valid {
+		if err := push.ValidateTemplate(src); err != nil {
+			t.Errorf("Unexpected error validating %q: %v", src, err)
+		}
+	}
+
+	invalid := []string{
+		"Hi {{first_name",
+		"{{}}",
+		"{{#if vip}}unclosed",
+		"{{#if vip}}x{{/unless}}",
+		"{{/if}}",
+		"{{else}}",
+		"{{#}}x{{/}}",
+		`{{$def name "unclosed}}`,
+		"{{$upper (lower name}}",
+		"{{$upper lower name)}}",
+		"{{{#if a}}}",
+		"{{!-- unclosed comment }}",
+	}
+	for _, src := range invalid {
+		err := push.ValidateTemplate(src)
+		if _, ok := err.(*push.TemplateError); !ok {
+			t.Errorf("Expected TemplateError for %q but found %v", src, err)
+		}
+	}
+}
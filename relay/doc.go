@@ -0,0 +1,42 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package shares one Event API connection among many consumers.
+// Urban Airship limits how many connections an app may open, so rather than
+// each consumer running its own Stream a Hub reads from one Stream and fans
+// its events out to Subscriptions with their own filters and offsets.
+//
+//	s, err := events.NewStream(ctx, client, events.StreamConfig{Start: events.StartLast})
+//	if err != nil {
+//		// handle error
+//	}
+//	hub := relay.NewHub(s)
+//	defer hub.Close()
+//
+//	sub, err := hub.Subscribe([]*events.Filter{{Types: []events.Type{events.TypeOpen}}}, nil)
+//	if err != nil {
+//		// handle error
+//	}
+//	for ev := range sub.Events() {
+//		// process ev
+//	}
+//
+// The Hub retains recent events so a consumer which disconnects may resume
+// from the last offset it processed. Set the Hub's Archive, such as a
+// sinks.ArchiveReader of the objects a sinks.Archive wrote, to also serve
+// consumers resuming from older offsets. Subscriptions are Sources, so they work
+// with Mux, Batch, and the other helpers in the events package. See the
+// relay/grpc package for serving Subscriptions to consumers in other
+// processes and languages.
+package relay
@@ -0,0 +1,34 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package serves relay.Hub Subscriptions over gRPC so consumers in other
+// processes and languages can share one Event API connection. The service is
+// defined in relay.proto; generate clients from it with protoc.
+//
+//	hub := relay.NewHub(stream)
+//	defer hub.Close()
+//
+//	lis, err := net.Listen("tcp", ":9090")
+//	if err != nil {
+//		// handle error
+//	}
+//	log.Fatal(grpc.NewServer(hub).Serve(lis))
+//
+// Messages are encoded by Codec rather than generated code, so the Server
+// only serves the Relay service. Go clients may decode events with Codec:
+//
+//	stream, err := conn.NewStream(ctx, &grpc.ServiceDesc.Streams[0], grpc.SubscribeMethod, gogrpc.ForceCodec(grpc.Codec{}))
+//
+// Unlike the core packages this package depends on google.golang.org/grpc.
+package grpc
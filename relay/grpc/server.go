@@ -0,0 +1,87 @@
+package grpc
+
+import (
+	"io"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/lytics/gobyairship/relay"
+)
+
+// SubscribeMethod is the full name of the Subscribe RPC.
+const SubscribeMethod = "/gobyairship.relay.Relay/Subscribe"
+
+// ServiceDesc describes the Relay service in relay.proto.
+var ServiceDesc = gogrpc.ServiceDesc{
+	ServiceName: "gobyairship.relay.Relay",
+	HandlerType: (*interface{})(nil),
+	Streams: []gogrpc.StreamDesc{{
+		StreamName:    "Subscribe",
+		Handler:       subscribeHandler,
+		ServerStreams: true,
+	}},
+	Metadata: "relay.proto",
+}
+
+// NewServer creates a gRPC server serving Subscriptions to hub. The server
+// uses Codec for every message so it can't serve other services.
+func NewServer(hub *relay.Hub, opts ...gogrpc.ServerOption) *gogrpc.Server {
+	s := gogrpc.NewServer(append(opts, gogrpc.ForceServerCodec(Codec{}))...)
+	s.RegisterService(&ServiceDesc, hub)
+	return s
+}
+
+func subscribeHandler(srv interface{}, stream gogrpc.ServerStream) error {
+	return Subscribe(srv.(*relay.Hub), stream)
+}
+
+// Subscribe serves one Subscribe call on stream, sending events from hub until
+// the client cancels or the Subscription ends.
+func Subscribe(hub *relay.Hub, stream gogrpc.ServerStream) error {
+	req := &SubscribeRequest{}
+	if err := stream.RecvMsg(req); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+	}
+	for _, f := range req.Filters {
+		if err := f.Validate(); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid filter: %v", err)
+		}
+	}
+	sub, err := hub.Subscribe(req.Filters, req.After)
+	if err != nil {
+		return statusError(err)
+	}
+	defer sub.Close()
+
+	done := stream.Context().Done()
+	for {
+		select {
+		case ev, ok := <-sub.Events():
+			if !ok {
+				return statusError(sub.Err())
+			}
+			if err := stream.SendMsg(ev); err != nil {
+				return err
+			}
+		case <-done:
+			return stream.Context().Err()
+		}
+	}
+}
+
+// statusError converts relay errors to gRPC statuses.
+func statusError(err error) error {
+	switch err {
+	case nil, io.EOF:
+		return nil
+	case relay.ErrOffsetUnavailable:
+		return status.Error(codes.OutOfRange, err.Error())
+	case relay.ErrSlowSubscriber:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case relay.ErrClosed:
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Unavailable, err.Error())
+}
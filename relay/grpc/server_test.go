@@ -0,0 +1,153 @@
+package grpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/relay"
+	"github.com/lytics/gobyairship/relay/grpc"
+)
+
+func TestCodec(t *testing.T) {
+	ev := &events.Event{
+		ID:        "c0ffee",
+		Type:      events.TypeOpen,
+		Offset:    1<<64 - 1,
+		Occurred:  time.Date(2017, 4, 25, 9, 0, 0, 123, time.UTC),
+		Processed: time.Date(1960, 1, 1, 0, 0, 1, 0, time.UTC),
+		Body:      json.RawMessage(`{"session_id":"s"}`),
+		Device:    &events.Device{IOS: "ios"},
+	}
+	c := grpc.Codec{}
+	b, err := c.Marshal(ev)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	decoded := &events.Event{}
+	if err := c.Unmarshal(b, decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(ev, decoded) {
+		t.Errorf("Expected %+v but decoded %+v", ev, decoded)
+	}
+	if err := c.Unmarshal(b[:len(b)-1], decoded); err == nil {
+		t.Errorf("Expected error decoding a truncated event")
+	}
+
+	offset := uint64(0)
+	req := &grpc.SubscribeRequest{Filters: []*events.Filter{events.FilterPush("p")}, After: &offset}
+	if b, err = c.Marshal(req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	decodedReq := &grpc.SubscribeRequest{}
+	if err := c.Unmarshal(b, decodedReq); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(req, decodedReq) {
+		t.Errorf("Expected %+v but decoded %+v", req, decodedReq)
+	}
+}
+
+// serverStream is a grpc.ServerStream which receives req and sends events to
+// a chan.
+type serverStream struct {
+	ctx  context.Context
+	req  *grpc.SubscribeRequest
+	sent chan *events.Event
+}
+
+func (s *serverStream) SetHeader(metadata.MD) error  { return nil }
+func (s *serverStream) SendHeader(metadata.MD) error { return nil }
+func (s *serverStream) SetTrailer(metadata.MD)       {}
+func (s *serverStream) Context() context.Context     { return s.ctx }
+
+// SendMsg and RecvMsg round trip messages through the Codec.
+func (s *serverStream) SendMsg(m interface{}) error {
+	b, err := grpc.Codec{}.Marshal(m)
+	if err != nil {
+		return err
+	}
+	ev := &events.Event{}
+	if err := (grpc.Codec{}).Unmarshal(b, ev); err != nil {
+		return err
+	}
+	s.sent <- ev
+	return nil
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	b, err := grpc.Codec{}.Marshal(s.req)
+	if err != nil {
+		return err
+	}
+	return grpc.Codec{}.Unmarshal(b, m)
+}
+
+// source is an events.Source fed by tests.
+type source chan *events.Event
+
+func (s source) Events() <-chan *events.Event { return s }
+func (s source) Close()                       {}
+func (s source) Err() error                   { return io.EOF }
+
+func TestSubscribe(t *testing.T) {
+	src := make(source)
+	hub := relay.NewHub(src)
+	hub.Retain = 1
+	defer hub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &serverStream{
+		ctx:  ctx,
+		req:  &grpc.SubscribeRequest{Filters: []*events.Filter{{Types: []events.Type{events.TypeSend}}}},
+		sent: make(chan *events.Event, 10),
+	}
+	done := make(chan error)
+	go func() { done <- grpc.Subscribe(hub, stream) }()
+	for hub.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	src <- &events.Event{ID: "open", Type: events.TypeOpen, Offset: 1}
+	src <- &events.Event{ID: "send", Type: events.TypeSend, Offset: 2}
+	select {
+	case ev := <-stream.sent:
+		if ev.ID != "send" || ev.Offset != 2 {
+			t.Errorf("Expected send event at offset 2 but received %+v", ev)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timed out waiting for event")
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Expected context.Canceled but found %v", err)
+	}
+
+	// Invalid requests and offsets are status errors
+	stream.ctx = context.Background()
+	stream.req = &grpc.SubscribeRequest{Filters: []*events.Filter{{Latency: -1}}}
+	if err := grpc.Subscribe(hub, stream); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument but found %v", err)
+	}
+	offset := uint64(0)
+	stream.req = &grpc.SubscribeRequest{After: &offset}
+	if err := grpc.Subscribe(hub, stream); status.Code(err) != codes.OutOfRange {
+		t.Errorf("Expected OutOfRange but found %v", err)
+	}
+
+	// The stream ends cleanly with the Hub
+	close(src)
+	stream.req = &grpc.SubscribeRequest{}
+	if err := grpc.Subscribe(hub, stream); err != nil && status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected nil or Unavailable but found %v", err)
+	}
+}
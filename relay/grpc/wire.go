@@ -0,0 +1,172 @@
+package grpc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/events/pb"
+)
+
+// SubscribeRequest is the request message of the Subscribe RPC.
+type SubscribeRequest struct {
+	// Filters are unioned. No filters matches all events.
+	Filters []*events.Filter
+
+	// After, if non-nil, resumes after an offset.
+	After *uint64
+}
+
+// Protocol buffer wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated message")
+
+// Codec encodes and decodes the messages in relay.proto: *SubscribeRequest,
+// and *events.Event as the gobyairship.events.Event message of package pb. It implements google.golang.org/grpc/encoding.Codec.
+type Codec struct{}
+
+// Name implements encoding.Codec.
+func (Codec) Name() string { return "proto" }
+
+// Marshal implements encoding.Codec.
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case *SubscribeRequest:
+		return marshalRequest(m)
+	case *events.Event:
+		return pb.Marshal(m)
+	}
+	return nil, fmt.Errorf("cannot marshal %T", v)
+}
+
+// Unmarshal implements encoding.Codec.
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	switch m := v.(type) {
+	case *SubscribeRequest:
+		return unmarshalRequest(data, m)
+	case *events.Event:
+		return pb.Unmarshal(data, m)
+	}
+	return fmt.Errorf("cannot unmarshal %T", v)
+}
+
+func marshalRequest(r *SubscribeRequest) ([]byte, error) {
+	var b []byte
+	if len(r.Filters) > 0 {
+		filters, err := json.Marshal(r.Filters)
+		if err != nil {
+			return nil, err
+		}
+		b = appendBytes(b, 1, filters)
+	}
+	if r.After != nil {
+		b = appendVarint(b, 2, 1)
+		b = appendVarint(b, 3, *r.After)
+	}
+	return b, nil
+}
+
+func unmarshalRequest(data []byte, r *SubscribeRequest) error {
+	*r = SubscribeRequest{}
+	var resume bool
+	var offset uint64
+	err := fields(data, func(num int, v uint64, b []byte) error {
+		switch num {
+		case 1:
+			if len(b) > 0 {
+				return json.Unmarshal(b, &r.Filters)
+			}
+		case 2:
+			resume = v != 0
+		case 3:
+			offset = v
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if resume {
+		r.After = &offset
+	}
+	return nil
+}
+
+func appendTag(b []byte, num int, wire uint64) []byte {
+	return appendUvarint(b, uint64(num)<<3|wire)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendVarint(b []byte, num int, v uint64) []byte {
+	return appendUvarint(appendTag(b, num, wireVarint), v)
+}
+
+// appendBytes appends a length delimited field unless v is empty, which
+// proto3 treats as the default.
+func appendBytes(b []byte, num int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = appendUvarint(appendTag(b, num, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+// fields calls fn with the number and value of each field in a message:
+// varints as v, length delimited fields as b. Fixed width fields, which
+// SubscribeRequest doesn't use, are skipped.
+func fields(data []byte, fn func(num int, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errTruncated
+		}
+		data = data[n:]
+		num := int(tag >> 3)
+		var v uint64
+		var b []byte
+		switch tag & 7 {
+		case wireVarint:
+			if v, n = binary.Uvarint(data); n <= 0 {
+				return errTruncated
+			}
+			data = data[n:]
+		case wireBytes:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return errTruncated
+			}
+			b = data[n : n+int(l)]
+			data = data[n+int(l):]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errTruncated
+			}
+			data = data[8:]
+			continue
+		case wireFixed32:
+			if len(data) < 4 {
+				return errTruncated
+			}
+			data = data[4:]
+			continue
+		default:
+			return fmt.Errorf("unsupported wire type %d", tag&7)
+		}
+		if err := fn(num, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
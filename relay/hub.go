@@ -0,0 +1,379 @@
+package relay
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+// Default Hub limits.
+const (
+	DefaultRetain = 10000
+	DefaultBuffer = 1000
+)
+
+var (
+	// ErrOffsetUnavailable is returned by Subscribe when the events after the
+	// requested offset are no longer retained.
+	ErrOffsetUnavailable = errors.New("offset is no longer retained")
+
+	// ErrSlowSubscriber ends Subscriptions which fall more than the Hub's
+	// Buffer events behind. The consumer may resubscribe from the last
+	// offset it processed.
+	ErrSlowSubscriber = errors.New("subscriber fell too far behind")
+
+	// ErrClosed is returned by Subscribe once the Hub has ended.
+	ErrClosed = errors.New("hub closed")
+)
+
+// Archive replays events a Hub no longer retains, such as those written by a
+// sinks.Archive and read back by a sinks.ArchiveReader.
+type Archive interface {
+	// Replay calls fn with each archived event after offset in offset order,
+	// stopping at and returning the first error from fn.
+	Replay(after uint64, fn func(*events.Event) error) error
+}
+
+// Errors which stop an Archive's Replay once it reaches retained events or the
+// Subscription is closed.
+var (
+	errReplayed     = errors.New("replayed up to retained events")
+	errUnsubscribed = errors.New("subscription closed")
+)
+
+// Hub reads events from one Source, such as a Stream, and fans them out to
+// any number of Subscriptions.
+//
+// Events are shared by every Subscription so they must not be modified or
+// released. Don't use PoolEvents or ManualAck with a Hub's Source.
+type Hub struct {
+	// Retain is how many recent events are kept for Subscriptions resuming
+	// from an offset. Defaults to DefaultRetain.
+	Retain int
+
+	// Buffer is how many events a Subscription may fall behind before it's
+	// ended with ErrSlowSubscriber. Defaults to DefaultBuffer.
+	Buffer int
+
+	// Archive, if non-nil, serves Subscriptions resuming from offsets older
+	// than the retained events. It must hold every event the Hub discards,
+	// such as an archive of the same Stream written by a sinks.Archive.
+	Archive Archive
+
+	src  events.Source
+	once sync.Once
+
+	mu       sync.Mutex
+	recent   []*events.Event
+	floor    uint64 // offset of the newest discarded event
+	trimmed  bool   // true once recent events have been discarded
+	subs     map[*Subscription]bool
+	ended    bool
+	err      error
+	finished chan struct{}
+}
+
+// NewHub creates a Hub reading from src. Set Retain and Buffer before calling
+// Subscribe. The Hub starts reading when the first Subscription is created.
+func NewHub(src events.Source) *Hub {
+	return &Hub{
+		src:      src,
+		subs:     map[*Subscription]bool{},
+		finished: make(chan struct{}),
+	}
+}
+
+// Subscribe creates a Subscription for events matching any of filters as the
+// Event API would apply them. No filters matches all events.
+//
+// If after is nil the Subscription starts with the next event the Hub reads.
+// Otherwise it starts with the first event after that offset. Events which
+// have been discarded are replayed from the Hub's Archive before the retained
+// and then live events. Without an Archive ErrOffsetUnavailable is returned
+// if events after the offset have been discarded, and the Subscription ends
+// with ErrOffsetUnavailable if the Archive doesn't reach the retained events.
+// Subscriptions resuming from before the Hub started receive every retained
+// event.
+func (h *Hub) Subscribe(filters []*events.Filter, after *uint64) (*Subscription, error) {
+	for _, f := range filters {
+		if err := f.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	h.once.Do(func() { go h.run() })
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.ended {
+		return nil, ErrClosed
+	}
+	replay := after != nil && h.trimmed && *after < h.floor
+	if replay && h.Archive == nil {
+		return nil, ErrOffsetUnavailable
+	}
+	buffer := h.Buffer
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	s := &Subscription{
+		h:       h,
+		filters: filters,
+		max:     buffer,
+		out:     make(chan *events.Event),
+		notify:  make(chan struct{}, 1),
+		closed:  make(chan struct{}),
+	}
+	if replay {
+		go s.replay(*after)
+		return s, nil
+	}
+	h.attach(s, after)
+	go s.pump()
+	return s, nil
+}
+
+// attach queues the retained events after offset, if any, and then publishes
+// live events to s. If the Hub has ended s ends once the retained events are
+// sent. h.mu must be held.
+func (h *Hub) attach(s *Subscription, after *uint64) {
+	if after != nil {
+		for _, ev := range h.recent {
+			if ev.Offset > *after && events.MatchFilters(s.filters, ev) {
+				s.queue = append(s.queue, ev)
+			}
+		}
+	}
+	if h.ended {
+		s.end(h.err)
+		return
+	}
+	h.subs[s] = true
+}
+
+// run publishes events from the Source until it ends.
+func (h *Hub) run() {
+	retain := h.Retain
+	if retain <= 0 {
+		retain = DefaultRetain
+	}
+	for ev := range h.src.Events() {
+		h.mu.Lock()
+		h.recent = append(h.recent, ev)
+		if len(h.recent) > retain {
+			h.floor = h.recent[0].Offset
+			h.trimmed = true
+			h.recent[0] = nil
+			h.recent = h.recent[1:]
+		}
+		for s := range h.subs {
+			if !s.push(ev) {
+				delete(h.subs, s)
+			}
+		}
+		h.mu.Unlock()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ended = true
+	h.err = h.src.Err()
+	for s := range h.subs {
+		s.end(h.err)
+		delete(h.subs, s)
+	}
+	close(h.finished)
+}
+
+// Subscribers returns the number of active Subscriptions. Safe for concurrent
+// access.
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// Close the Hub's Source, ending every Subscription with its error, and wait
+// for the Hub to finish.
+func (h *Hub) Close() {
+	h.src.Close()
+	h.once.Do(func() { go h.run() })
+	<-h.finished
+}
+
+// Err returns the error which ended the Hub's Source or nil.
+func (h *Hub) Err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+func (h *Hub) remove(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, s)
+}
+
+// Subscription receives events from a Hub. It implements events.Source.
+type Subscription struct {
+	h       *Hub
+	filters []*events.Filter
+	max     int
+	out     chan *events.Event
+	notify  chan struct{}
+	closed  chan struct{}
+	once    sync.Once
+
+	mu    sync.Mutex
+	queue []*events.Event
+	ended bool
+	err   error
+}
+
+// push queues ev if it matches the Subscription's filters. Returns false if
+// the Subscription has ended. h.mu must be held.
+func (s *Subscription) push(ev *events.Event) bool {
+	if !events.MatchFilters(s.filters, ev) {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return false
+	}
+	if len(s.queue) >= s.max {
+		s.ended = true
+		s.err = ErrSlowSubscriber
+		s.queue = nil
+		s.signal()
+		return false
+	}
+	s.queue = append(s.queue, ev)
+	s.signal()
+	return true
+}
+
+// end the Subscription once its queued events have been sent.
+func (s *Subscription) end(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.ended = true
+		s.err = err
+	}
+	s.signal()
+}
+
+func (s *Subscription) signal() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// replay sends the archived events after offset directly, until it reaches
+// the Hub's retained events, then attaches to the Hub and pumps as usual.
+// Events aren't queued while replaying so a slow replay can't overflow the
+// Subscription's buffer.
+func (s *Subscription) replay(after uint64) {
+	h := s.h
+	for {
+		h.mu.Lock()
+		if !h.trimmed || after >= h.floor {
+			h.attach(s, &after)
+			h.mu.Unlock()
+			s.pump()
+			return
+		}
+		floor := h.floor
+		h.mu.Unlock()
+
+		reached := false
+		err := h.Archive.Replay(after, func(ev *events.Event) error {
+			if ev.Offset > floor {
+				return errReplayed
+			}
+			after = ev.Offset
+			reached = ev.Offset == floor
+			if !events.MatchFilters(s.filters, ev) {
+				return nil
+			}
+			select {
+			case s.out <- ev:
+				return nil
+			case <-s.closed:
+				return errUnsubscribed
+			}
+		})
+		switch {
+		case err == errUnsubscribed:
+			close(s.out)
+			return
+		case err == errReplayed:
+			err = nil
+		case err == nil && !reached:
+			// The archive ends before the discarded events do
+			err = ErrOffsetUnavailable
+		}
+		if err != nil {
+			s.end(err)
+			close(s.out)
+			return
+		}
+		// The archive covers the discarded events, though more may have been
+		// discarded while replaying
+		after = floor
+	}
+}
+
+// pump sends queued events until the Subscription ends or is closed.
+func (s *Subscription) pump() {
+	defer close(s.out)
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			ended := s.ended
+			s.mu.Unlock()
+			if ended {
+				return
+			}
+			select {
+			case <-s.notify:
+				continue
+			case <-s.closed:
+				return
+			}
+		}
+		ev := s.queue[0]
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		select {
+		case s.out <- ev:
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// Events returns a chan that emits matching events until the Subscription is
+// closed or ends.
+func (s *Subscription) Events() <-chan *events.Event { return s.out }
+
+// Close the Subscription. Safe to call concurrently.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		close(s.closed)
+		s.h.remove(s)
+	})
+}
+
+// Err returns ErrSlowSubscriber if the Subscription fell behind, otherwise the
+// error which ended the Hub's Source or nil. May be checked once the chan
+// returned by Events() is closed.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
@@ -0,0 +1,190 @@
+package relay_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/relay"
+)
+
+// source is an events.Source fed by tests.
+type source struct {
+	c   chan *events.Event
+	err error
+}
+
+func newSource() *source { return &source{c: make(chan *events.Event), err: io.EOF} }
+
+func (s *source) Events() <-chan *events.Event { return s.c }
+func (s *source) Close()                       {}
+func (s *source) Err() error                   { return s.err }
+
+func event(offset uint64, typ events.Type) *events.Event {
+	return &events.Event{ID: "id", Type: typ, Offset: offset}
+}
+
+func receive(t *testing.T, sub *relay.Subscription, expected ...uint64) {
+	for _, offset := range expected {
+		select {
+		case ev, ok := <-sub.Events():
+			if !ok {
+				t.Fatalf("Expected offset %d but subscription ended: %v", offset, sub.Err())
+			}
+			if ev.Offset != offset {
+				t.Fatalf("Expected offset %d but received %d", offset, ev.Offset)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Timed out waiting for offset %d", offset)
+		}
+	}
+}
+
+func TestHub(t *testing.T) {
+	src := newSource()
+	hub := relay.NewHub(src)
+	hub.Retain = 2
+
+	all, err := hub.Subscribe(nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	opens, err := hub.Subscribe([]*events.Filter{{Types: []events.Type{events.TypeOpen}}}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := hub.Subscribe([]*events.Filter{{Types: []events.Type{"nope"}}}, nil); err == nil {
+		t.Errorf("Expected error subscribing with an invalid filter")
+	}
+
+	src.c <- event(1, events.TypeOpen)
+	src.c <- event(2, events.TypeSend)
+	src.c <- event(3, events.TypeOpen)
+	receive(t, all, 1, 2, 3)
+	receive(t, opens, 1, 3)
+
+	// Resume from retained events
+	after := uint64(1)
+	resumed, err := hub.Subscribe(nil, &after)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	receive(t, resumed, 2, 3)
+	after = 0
+	if _, err := hub.Subscribe(nil, &after); err != relay.ErrOffsetUnavailable {
+		t.Errorf("Expected ErrOffsetUnavailable but found %v", err)
+	}
+
+	// Closed subscriptions stop receiving
+	resumed.Close()
+	for range resumed.Events() {
+	}
+	if n := hub.Subscribers(); n != 2 {
+		t.Errorf("Expected 2 subscribers but found %d", n)
+	}
+
+	close(src.c)
+	for _, sub := range []*relay.Subscription{all, opens} {
+		for range sub.Events() {
+		}
+		if err := sub.Err(); err != io.EOF {
+			t.Errorf("Expected io.EOF but found %v", err)
+		}
+	}
+	hub.Close()
+	if _, err := hub.Subscribe(nil, nil); err != relay.ErrClosed {
+		t.Errorf("Expected ErrClosed but found %v", err)
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	src := newSource()
+	hub := relay.NewHub(src)
+	hub.Buffer = 2
+	slow, err := hub.Subscribe(nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fast, err := hub.Subscribe(nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The slow subscriber's pump holds one event and queues Buffer more
+	for i := uint64(1); i <= 5; i++ {
+		src.c <- event(i, events.TypeSend)
+		receive(t, fast, i)
+	}
+	for range slow.Events() {
+	}
+	if err := slow.Err(); err != relay.ErrSlowSubscriber {
+		t.Errorf("Expected ErrSlowSubscriber but found %v", err)
+	}
+	if n := hub.Subscribers(); n != 1 {
+		t.Errorf("Expected 1 subscriber but found %d", n)
+	}
+	close(src.c)
+	hub.Close()
+}
+
+// archive is a relay.Archive of the events a Hub discarded.
+type archive []*events.Event
+
+func (a archive) Replay(after uint64, fn func(*events.Event) error) error {
+	for _, ev := range a {
+		if ev.Offset <= after {
+			continue
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestArchiveReplay(t *testing.T) {
+	src := newSource()
+	hub := relay.NewHub(src)
+	hub.Retain = 2
+	// 4 isn't archived so once it's discarded resuming from before it fails
+	hub.Archive = archive{event(2, events.TypeSend), event(3, events.TypeOpen)}
+
+	// Subscribe to start the Hub and wait for events to be discarded
+	live, err := hub.Subscribe(nil, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := uint64(1); i <= 5; i++ {
+		src.c <- event(i, events.TypeSend)
+		receive(t, live, i)
+	}
+
+	after := uint64(1)
+	all, err := hub.Subscribe(nil, &after)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	receive(t, all, 2, 3, 4, 5)
+	src.c <- event(6, events.TypeSend)
+	receive(t, all, 6)
+	receive(t, live, 6)
+
+	after = 0
+	gap, err := hub.Subscribe(nil, &after)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for range gap.Events() {
+	}
+	if err := gap.Err(); err != relay.ErrOffsetUnavailable {
+		t.Errorf("Expected ErrOffsetUnavailable but found %v", err)
+	}
+
+	close(src.c)
+	for _, sub := range []*relay.Subscription{live, all} {
+		for range sub.Events() {
+		}
+	}
+	hub.Close()
+}
@@ -0,0 +1,141 @@
+package reports
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultDevicesURL lists channels and their opt-in state.
+const DefaultDevicesURL = "https://go.urbanairship.com/api/channels"
+
+// Client used to fetch reports. Usually *gobyairship.Client.
+type Client interface {
+	Get(url string, extra http.Header) (*http.Response, error)
+}
+
+// Device is the state of a single channel.
+type Device struct {
+	ChannelID        string   `json:"channel_id"`
+	DeviceType       string   `json:"device_type"`
+	Installed        bool     `json:"installed"`
+	OptIn            bool     `json:"opt_in"`
+	Background       bool     `json:"background"`
+	PushAddress      string   `json:"push_address,omitempty"`
+	Created          string   `json:"created,omitempty"`
+	LastRegistration string   `json:"last_registration,omitempty"`
+	NamedUser        string   `json:"named_user_id,omitempty"`
+	Alias            string   `json:"alias,omitempty"`
+	Tags             []string `json:"tags,omitempty"`
+}
+
+type devicesPage struct {
+	OK       bool      `json:"ok"`
+	Channels []*Device `json:"channels"`
+	NextPage string    `json:"next_page"`
+}
+
+// DevicePager pages through the device listing one page at a time so the
+// full listing, which may contain millions of channels, is never held in
+// memory.
+type DevicePager struct {
+	c    Client
+	next string
+}
+
+// Devices returns a DevicePager starting at the first page of the listing.
+func Devices(c Client) *DevicePager {
+	return DevicesURL(c, DefaultDevicesURL)
+}
+
+// DevicesURL returns a DevicePager starting at url, such as a next page URL
+// saved from a previous export.
+func DevicesURL(c Client, url string) *DevicePager {
+	return &DevicePager{c: c, next: url}
+}
+
+// NextURL returns the URL of the next page or an empty string if there are
+// no more pages.
+func (p *DevicePager) NextURL() string { return p.next }
+
+// Next returns the next page of devices. io.EOF is returned once every page
+// has been read.
+func (p *DevicePager) Next() ([]*Device, error) {
+	if p.next == "" {
+		return nil, io.EOF
+	}
+	resp, err := p.c.Get(p.next, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil, fmt.Errorf("unexpected non-200 response: %d", resp.StatusCode)
+	}
+	page := devicesPage{}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, err
+	}
+	p.next = page.NextPage
+	return page.Channels, nil
+}
+
+// Each calls fn for every remaining device, stopping at the first error. A nil
+// error is returned once every page has been read.
+func (p *DevicePager) Each(fn func(*Device) error) error {
+	for {
+		devices, err := p.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, d := range devices {
+			if err := fn(d); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// DeviceCSVHeader is the header row written by WriteDevicesCSV.
+var DeviceCSVHeader = []string{
+	"channel_id", "device_type", "installed", "opt_in", "background",
+	"push_address", "created", "last_registration", "named_user_id", "alias", "tags",
+}
+
+// WriteDevicesCSV writes every remaining device from p as CSV with a
+// DeviceCSVHeader row. Tags are joined with spaces.
+func WriteDevicesCSV(w io.Writer, p *DevicePager) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(DeviceCSVHeader); err != nil {
+		return err
+	}
+	err := p.Each(func(d *Device) error {
+		return cw.Write([]string{
+			d.ChannelID, d.DeviceType,
+			strconv.FormatBool(d.Installed), strconv.FormatBool(d.OptIn), strconv.FormatBool(d.Background),
+			d.PushAddress, d.Created, d.LastRegistration, d.NamedUser, d.Alias,
+			strings.Join(d.Tags, " "),
+		})
+	})
+	cw.Flush()
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// WriteDevicesNDJSON writes every remaining device from p as newline
+// delimited JSON.
+func WriteDevicesNDJSON(w io.Writer, p *DevicePager) error {
+	enc := json.NewEncoder(w)
+	return p.Each(func(d *Device) error { return enc.Encode(d) })
+}
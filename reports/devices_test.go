@@ -0,0 +1,90 @@
+package reports_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/reports"
+)
+
+func devicesServer() *httptest.Server {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := map[string]interface{}{"ok": true}
+		switch r.URL.Query().Get("start") {
+		case "":
+			page["channels"] = []map[string]interface{}{
+				{"channel_id": "a", "device_type": "ios", "installed": true, "opt_in": true, "tags": []string{"x", "y"}},
+				{"channel_id": "b", "device_type": "android", "installed": true},
+			}
+			page["next_page"] = ts.URL + "/api/channels?start=b"
+		case "b":
+			page["channels"] = []map[string]interface{}{
+				{"channel_id": "c", "device_type": "amazon", "named_user_id": "u,1"},
+			}
+		default:
+			w.WriteHeader(404)
+			return
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	return ts
+}
+
+func TestDevicesCSV(t *testing.T) {
+	t.Parallel()
+	ts := devicesServer()
+	defer ts.Close()
+
+	buf := &bytes.Buffer{}
+	p := reports.DevicesURL(gobyairship.NewClient("", ""), ts.URL+"/api/channels")
+	if err := reports.WriteDevicesCSV(buf, p); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp := strings.Join(reports.DeviceCSVHeader, ",") + "\n" +
+		"a,ios,true,true,false,,,,,,x y\n" +
+		"b,android,true,false,false,,,,,,\n" +
+		`c,amazon,false,false,false,,,,"u,1",,` + "\n"
+	if buf.String() != exp {
+		t.Errorf("Expected:\n%s\nFound:\n%s", exp, buf.String())
+	}
+	if p.NextURL() != "" {
+		t.Errorf("Expected pager to be exhausted but found %q", p.NextURL())
+	}
+}
+
+func TestDevicesNDJSON(t *testing.T) {
+	t.Parallel()
+	ts := devicesServer()
+	defer ts.Close()
+
+	buf := &bytes.Buffer{}
+	p := reports.DevicesURL(gobyairship.NewClient("", ""), ts.URL+"/api/channels")
+	if err := reports.WriteDevicesNDJSON(buf, p); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ids := []string{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		d := reports.Device{}
+		if err := dec.Decode(&d); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, d.ChannelID)
+	}
+	if fmt.Sprint(ids) != "[a b c]" {
+		t.Errorf("Unexpected devices: %v", ids)
+	}
+
+	// Errors stop the export
+	p = reports.DevicesURL(gobyairship.NewClient("", ""), ts.URL+"/api/channels?start=missing")
+	if err := reports.WriteDevicesNDJSON(buf, p); err == nil {
+		t.Errorf("Expected error for missing page")
+	}
+}
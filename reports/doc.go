@@ -0,0 +1,23 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package exports reports from Urban Airship's API using a
+// gobyairship.Client.
+//
+// Devices pages through every channel's opt-in state, and WriteDevicesCSV
+// and WriteDevicesNDJSON stream the listing to a writer for reconciliation
+// against other systems:
+//
+//	err := reports.WriteDevicesCSV(f, reports.Devices(client))
+package reports
@@ -0,0 +1,30 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package manages scheduled pushes with Urban Airship's Schedules API.
+//
+// Schedules send a push.Push at an absolute time, at a time in each device's
+// local time zone with LocalScheduledTime, or with BestTime at the time on a
+// given day each device is most likely to engage:
+//
+//	api := schedules.New(client)
+//	resp, err := api.Create(&schedules.Schedule{
+//		Name:     "Game day",
+//		Schedule: schedules.When{ScheduledTime: kickoff.Add(-time.Hour)},
+//		Push:     p,
+//	})
+//
+// List pages through every schedule like reports.Devices, and Get, Update,
+// and Delete manage a single schedule by its ID.
+package schedules
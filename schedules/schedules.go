@@ -0,0 +1,331 @@
+package schedules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lytics/gobyairship/push"
+)
+
+// DefaultURL is the Schedules API endpoint.
+const DefaultURL = "https://go.urbanairship.com/api/schedules"
+
+// Layouts of the API's times, which are in UTC, and dates.
+const (
+	timeLayout = "2006-01-02T15:04:05"
+	dateLayout = "2006-01-02"
+)
+
+// Client used to manage schedules. Usually *gobyairship.Client.
+type Client interface {
+	Get(url string, extra http.Header) (*http.Response, error)
+	Post(url string, body interface{}, extra http.Header) (*http.Response, error)
+	Put(url string, body interface{}, extra http.Header) (*http.Response, error)
+	Delete(url string, extra http.Header) (*http.Response, error)
+}
+
+// BestTime sends a push on SendDate at the time each device is most likely to
+// engage, which Urban Airship calls Send Time Optimization. Only the date of
+// SendDate is used.
+type BestTime struct {
+	SendDate time.Time
+}
+
+// When a scheduled push is sent. Set exactly one of ScheduledTime,
+// LocalScheduledTime, or BestTime.
+type When struct {
+	// ScheduledTime is when the push is sent. It's sent to the API in UTC
+	// with second precision.
+	ScheduledTime time.Time
+
+	// LocalScheduledTime sends the push when the time is reached in each
+	// device's own time zone, so a 9am push reaches every device at 9am
+	// local time. Only its date and clock time are used: its Location is
+	// ignored rather than converted to UTC.
+	LocalScheduledTime time.Time
+
+	BestTime *BestTime
+}
+
+type when struct {
+	ScheduledTime      string `json:"scheduled_time,omitempty"`
+	LocalScheduledTime string `json:"local_scheduled_time,omitempty"`
+	BestTime           *struct {
+		SendDate string `json:"send_date"`
+	} `json:"best_time,omitempty"`
+}
+
+// MarshalJSON encodes times in the API's layouts.
+func (w When) MarshalJSON() ([]byte, error) {
+	out := when{}
+	if !w.ScheduledTime.IsZero() {
+		out.ScheduledTime = w.ScheduledTime.UTC().Format(timeLayout)
+	}
+	if !w.LocalScheduledTime.IsZero() {
+		out.LocalScheduledTime = w.LocalScheduledTime.Format(timeLayout)
+	}
+	if w.BestTime != nil {
+		out.BestTime = &struct {
+			SendDate string `json:"send_date"`
+		}{w.BestTime.SendDate.Format(dateLayout)}
+	}
+	return json.Marshal(&out)
+}
+
+// UnmarshalJSON decodes times in the API's layouts. LocalScheduledTime is in
+// UTC though it's a local time.
+func (w *When) UnmarshalJSON(b []byte) error {
+	in := when{}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	*w = When{}
+	var err error
+	if in.ScheduledTime != "" {
+		if w.ScheduledTime, err = time.Parse(timeLayout, in.ScheduledTime); err != nil {
+			return err
+		}
+	}
+	if in.LocalScheduledTime != "" {
+		if w.LocalScheduledTime, err = time.Parse(timeLayout, in.LocalScheduledTime); err != nil {
+			return err
+		}
+	}
+	if in.BestTime != nil {
+		d, err := time.Parse(dateLayout, in.BestTime.SendDate)
+		if err != nil {
+			return err
+		}
+		w.BestTime = &BestTime{SendDate: d}
+	}
+	return nil
+}
+
+// Validate returns nil if exactly one of ScheduledTime, LocalScheduledTime,
+// or BestTime is set.
+func (w *When) Validate() error {
+	var set []string
+	if !w.ScheduledTime.IsZero() {
+		set = append(set, "scheduled time")
+	}
+	if !w.LocalScheduledTime.IsZero() {
+		set = append(set, "local scheduled time")
+	}
+	if w.BestTime != nil {
+		if w.BestTime.SendDate.IsZero() {
+			return errors.New("best time without a send date")
+		}
+		set = append(set, "best time")
+	}
+	switch len(set) {
+	case 0:
+		return errors.New("missing scheduled time, local scheduled time, or best time")
+	case 1:
+		return nil
+	}
+	return fmt.Errorf("only specify one of %s", strings.Join(set, " or "))
+}
+
+// Schedule is a push sent at a later time.
+type Schedule struct {
+	// URL of the schedule, set by the API.
+	URL string `json:"url,omitempty"`
+
+	Name     string     `json:"name,omitempty"`
+	Schedule When       `json:"schedule"`
+	Push     *push.Push `json:"push"`
+}
+
+// ID of the schedule, the last element of its URL.
+func (s *Schedule) ID() string {
+	return s.URL[strings.LastIndex(s.URL, "/")+1:]
+}
+
+// Validate returns nil if the schedule is valid or an error if there's an
+// issue.
+func (s *Schedule) Validate() error {
+	if err := s.Schedule.Validate(); err != nil {
+		return err
+	}
+	if s.Push == nil {
+		return errors.New("missing push")
+	}
+	if err := s.Push.Validate(); err != nil {
+		return fmt.Errorf("invalid push: %v", err)
+	}
+	return nil
+}
+
+// Response to a created or updated schedule.
+type Response struct {
+	OK          bool   `json:"ok"`
+	OperationID string `json:"operation_id"`
+
+	// ScheduleURLs and ScheduleIDs identify each created schedule in request
+	// order, and Schedules are the schedules as stored.
+	ScheduleURLs []string    `json:"schedule_urls"`
+	ScheduleIDs  []string    `json:"schedule_ids"`
+	Schedules    []*Schedule `json:"schedules"`
+}
+
+// API manages the schedules of an app.
+type API struct {
+	c   Client
+	url string
+}
+
+// New creates an API using DefaultURL.
+func New(c Client) *API {
+	return NewURL(c, DefaultURL)
+}
+
+// NewURL creates an API using the Schedules API at url.
+func NewURL(c Client, url string) *API {
+	return &API{c: c, url: strings.TrimSuffix(url, "/")}
+}
+
+// scheduleURL returns the URL of the schedule with id.
+func (a *API) scheduleURL(id string) (string, error) {
+	if id == "" || strings.Contains(id, "/") {
+		return "", fmt.Errorf("invalid schedule ID %q", id)
+	}
+	return a.url + "/" + id, nil
+}
+
+// respond decodes a Response filling in its OperationID from the response's
+// headers if the body has none.
+func respond(resp *http.Response, err error) (*Response, error) {
+	if err != nil {
+		return nil, err
+	}
+	out := &Response{}
+	if err := push.DecodeResult(resp, out, &out.OperationID); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Create schedules. Invalid schedules return an error without being sent.
+func (a *API) Create(schedules ...*Schedule) (*Response, error) {
+	return a.CreateHeader(nil, schedules...)
+}
+
+// CreateHeader is like Create but adds extra headers to the request, such as a
+// gobyairship.IdempotencyHeader to reuse when retrying.
+func (a *API) CreateHeader(extra http.Header, schedules ...*Schedule) (*Response, error) {
+	if len(schedules) == 0 {
+		return nil, errors.New("no schedules to create")
+	}
+	for i, s := range schedules {
+		if err := s.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid schedule %d: %v", i, err)
+		}
+	}
+	var body interface{} = schedules
+	if len(schedules) == 1 {
+		body = schedules[0]
+	}
+	return respond(a.c.Post(a.url, body, extra))
+}
+
+// Get the schedule with id.
+func (a *API) Get(id string) (*Schedule, error) {
+	url, err := a.scheduleURL(id)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.c.Get(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	s := &Schedule{}
+	if err := push.DecodeResponse(resp, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Update replaces the schedule with id. Invalid schedules return an error
+// without being sent.
+func (a *API) Update(id string, s *Schedule) (*Response, error) {
+	url, err := a.scheduleURL(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	// The URL is read-only
+	cp := *s
+	cp.URL = ""
+	return respond(a.c.Put(url, &cp, nil))
+}
+
+// Delete the schedule with id so it's never sent.
+func (a *API) Delete(id string) error {
+	url, err := a.scheduleURL(id)
+	if err != nil {
+		return err
+	}
+	resp, err := a.c.Delete(url, nil)
+	if err != nil {
+		return err
+	}
+	return push.DecodeResponse(resp, nil)
+}
+
+// Pager pages through the schedules of an app.
+type Pager struct {
+	pages *push.Pages
+}
+
+// List returns a Pager starting at the first page of schedules.
+func (a *API) List() *Pager {
+	return a.ListURL(a.url)
+}
+
+// ListURL returns a Pager starting at url, such as a next page URL saved from
+// a previous listing.
+func (a *API) ListURL(url string) *Pager {
+	return &Pager{pages: push.NewPages(a.c, url)}
+}
+
+// NextURL returns the URL of the next page or an empty string if there are
+// no more pages.
+func (p *Pager) NextURL() string { return p.pages.NextURL() }
+
+// Next returns the next page of schedules. io.EOF is returned once every page
+// has been read.
+func (p *Pager) Next() ([]*Schedule, error) {
+	pg := struct {
+		Schedules []*Schedule `json:"schedules"`
+	}{}
+	if err := p.pages.Next(&pg); err != nil {
+		return nil, err
+	}
+	return pg.Schedules, nil
+}
+
+// Each calls fn for every remaining schedule, stopping at the first error. A
+// nil error is returned once every page has been read.
+func (p *Pager) Each(fn func(*Schedule) error) error {
+	for {
+		schedules, err := p.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, s := range schedules {
+			if err := fn(s); err != nil {
+				return err
+			}
+		}
+	}
+}
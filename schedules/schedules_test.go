@@ -0,0 +1,215 @@
+package schedules_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/audience"
+	"github.com/lytics/gobyairship/push"
+	"github.com/lytics/gobyairship/schedules"
+)
+
+// server is a fake Schedules API storing schedules in memory.
+type server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	schedules map[string]json.RawMessage
+	ids       []string
+	keys      []string // idempotency keys of created schedules
+}
+
+func newServer(t *testing.T) *server {
+	s := &server{schedules: map[string]json.RawMessage{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		id := strings.TrimPrefix(r.URL.Path, "/api/schedules")
+		id = strings.TrimPrefix(id, "/")
+		body, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case r.Method == "POST" && id == "":
+			var sched map[string]interface{}
+			if err := json.Unmarshal(body, &sched); err != nil {
+				t.Errorf("Expected a single schedule but found %s", body)
+			}
+			s.keys = append(s.keys, r.Header.Get(gobyairship.IdempotencyHeader))
+			id = fmt.Sprintf("s%d", len(s.ids)+1)
+			sched["url"] = s.URL + "/api/schedules/" + id
+			stored, _ := json.Marshal(sched)
+			s.schedules[id] = stored
+			s.ids = append(s.ids, id)
+			w.Header().Set("UA-Operation-Id", "op")
+			w.WriteHeader(201)
+			fmt.Fprintf(w, `{"ok":true,"schedule_urls":[%q],"schedule_ids":[%q],"schedules":[%s]}`, sched["url"], id, stored)
+		case r.Method == "GET" && id == "":
+			// One schedule per page
+			start := 0
+			fmt.Sscan(r.URL.Query().Get("start"), &start)
+			page := map[string]interface{}{"ok": true, "count": 0, "total_count": len(s.ids)}
+			if start < len(s.ids) {
+				page["count"] = 1
+				page["schedules"] = []json.RawMessage{s.schedules[s.ids[start]]}
+			}
+			if start+1 < len(s.ids) {
+				page["next_page"] = fmt.Sprintf("%s/api/schedules?start=%d", s.URL, start+1)
+			}
+			json.NewEncoder(w).Encode(page)
+		case s.schedules[id] == nil:
+			w.WriteHeader(404)
+			w.Write([]byte(`{"ok":false,"error":"Could not find schedule","error_code":40401}`))
+		case r.Method == "GET":
+			w.Write(s.schedules[id])
+		case r.Method == "PUT":
+			var sched map[string]interface{}
+			json.Unmarshal(body, &sched)
+			if _, ok := sched["url"]; ok {
+				t.Errorf("Expected update without a URL but found %s", body)
+			}
+			sched["url"] = s.URL + "/api/schedules/" + id
+			s.schedules[id], _ = json.Marshal(sched)
+			fmt.Fprintf(w, `{"ok":true,"operation_id":"op2","schedule_urls":[%q]}`, sched["url"])
+		case r.Method == "DELETE":
+			delete(s.schedules, id)
+			w.WriteHeader(204)
+		default:
+			w.WriteHeader(405)
+		}
+	}))
+	return s
+}
+
+func TestSchedules(t *testing.T) {
+	t.Parallel()
+	srv := newServer(t)
+	defer srv.Close()
+	api := schedules.NewURL(gobyairship.NewClient("key", "token"), srv.URL+"/api/schedules")
+
+	at := time.Date(2030, 1, 2, 15, 4, 5, 0, time.FixedZone("PST", -8*3600))
+	p := &push.Push{Audience: audience.Tag("vip"), Notification: &push.Notification{Alert: "Hello"}}
+	resp, err := api.Create(&schedules.Schedule{Name: "first", Schedule: schedules.When{ScheduledTime: at}, Push: p})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !resp.OK || resp.OperationID != "op" || len(resp.ScheduleIDs) != 1 || resp.ScheduleIDs[0] != "s1" {
+		t.Fatalf("Unexpected response: %#v", resp)
+	}
+	if st := resp.Schedules[0].Schedule.ScheduledTime; !st.Equal(at) {
+		t.Errorf("Expected scheduled time %s but found %s", at, st)
+	}
+	day := time.Date(2030, 1, 3, 0, 0, 0, 0, time.UTC)
+	extra := http.Header{gobyairship.IdempotencyHeader: []string{"k2"}}
+	if _, err := api.CreateHeader(extra, &schedules.Schedule{Name: "second", Schedule: schedules.When{BestTime: &schedules.BestTime{SendDate: day}}, Push: p}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	srv.mu.Lock()
+	if fmt.Sprint(srv.keys) != "[ k2]" {
+		t.Errorf("Expected only the second schedule to have an idempotency key but found %q", srv.keys)
+	}
+	srv.mu.Unlock()
+
+	s, err := api.Get("s2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s.ID() != "s2" || s.Name != "second" || s.Schedule.BestTime == nil || !s.Schedule.BestTime.SendDate.Equal(day) {
+		t.Errorf("Unexpected schedule: %+v", s)
+	}
+	if s.Push == nil || s.Push.Notification == nil || s.Push.Notification.Alert != "Hello" {
+		t.Errorf("Unexpected push: %+v", s.Push)
+	}
+
+	s.Name = "renamed"
+	if resp, err = api.Update(s.ID(), s); err != nil || resp.OperationID != "op2" {
+		t.Fatalf("Unexpected update response %#v: %v", resp, err)
+	}
+	var names []string
+	err = api.List().Each(func(s *schedules.Schedule) error {
+		names = append(names, s.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Join(names, ",") != "first,renamed" {
+		t.Errorf("Expected schedules first and renamed but found %v", names)
+	}
+
+	if err := api.Delete("s1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = api.Get("s1")
+	if apiErr, ok := err.(*push.APIError); !ok || apiErr.StatusCode != 404 {
+		t.Errorf("Expected a 404 *push.APIError but found %v", err)
+	}
+}
+
+func TestWhen(t *testing.T) {
+	t.Parallel()
+	pst := time.FixedZone("PST", -8*3600)
+	tests := []struct {
+		when     schedules.When
+		expected string
+	}{
+		{schedules.When{ScheduledTime: time.Date(2030, 1, 2, 9, 0, 0, 0, pst)}, `{"scheduled_time":"2030-01-02T17:00:00"}`},
+		// Local times keep their clock time rather than being converted to UTC
+		{schedules.When{LocalScheduledTime: time.Date(2030, 1, 2, 9, 0, 0, 0, pst)}, `{"local_scheduled_time":"2030-01-02T09:00:00"}`},
+		{schedules.When{BestTime: &schedules.BestTime{SendDate: time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)}}, `{"best_time":{"send_date":"2030-01-02"}}`},
+	}
+	for _, tc := range tests {
+		if err := tc.when.Validate(); err != nil {
+			t.Errorf("Unexpected error validating %s: %v", tc.expected, err)
+		}
+		buf, err := json.Marshal(tc.when)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(buf) != tc.expected {
+			t.Errorf("Expected %s but found %s", tc.expected, buf)
+		}
+		var w schedules.When
+		if err := json.Unmarshal(buf, &w); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if again, _ := json.Marshal(w); string(again) != tc.expected {
+			t.Errorf("Expected %s after decoding but found %s", tc.expected, again)
+		}
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	t.Parallel()
+	p := &push.Push{Audience: audience.All, Notification: &push.Notification{Alert: "Hello"}}
+	now := time.Now()
+	invalid := map[string]*schedules.Schedule{
+		"missing time":         {Push: p},
+		"missing push":         {Schedule: schedules.When{ScheduledTime: now}},
+		"invalid push":         {Schedule: schedules.When{ScheduledTime: now}, Push: &push.Push{}},
+		"both times":           {Schedule: schedules.When{ScheduledTime: now, BestTime: &schedules.BestTime{SendDate: now}}, Push: p},
+		"best time empty date": {Schedule: schedules.When{BestTime: &schedules.BestTime{}}, Push: p},
+		"local and best time":  {Schedule: schedules.When{LocalScheduledTime: now, BestTime: &schedules.BestTime{SendDate: now}}, Push: p},
+		"absolute and local":   {Schedule: schedules.When{ScheduledTime: now, LocalScheduledTime: now}, Push: p},
+	}
+	for name, s := range invalid {
+		if err := s.Validate(); err == nil {
+			t.Errorf("Expected an error with %s", name)
+		}
+	}
+
+	// Invalid schedules aren't sent
+	api := schedules.NewURL(gobyairship.NewClient("key", "token"), "http://127.0.0.1:0/api/schedules")
+	if _, err := api.Create(invalid["missing push"]); err == nil {
+		t.Errorf("Expected an error creating an invalid schedule")
+	}
+	if _, err := api.Get("../push"); err == nil {
+		t.Errorf("Expected an error getting an invalid schedule ID")
+	}
+}
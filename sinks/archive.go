@@ -0,0 +1,249 @@
+package sinks
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/events"
+)
+
+// ObjectStore stores objects in a bucket such as S3 or Google Cloud Storage.
+type ObjectStore interface {
+	// Put stores body at key, replacing any existing object. It must not
+	// return until the object is durably stored.
+	Put(key string, body []byte) error
+}
+
+// Default Archive limits.
+const (
+	DefaultMaxObjectBytes = 64 << 20
+	DefaultMaxObjectAge   = time.Hour
+)
+
+// Archive is a Sink which writes events as gzipped newline delimited JSON
+// objects to an ObjectStore. Objects are named after the processed time and
+// offset of their first event:
+//
+//	<prefix>dt=2017-04-25/hour=09/part-00000000000000000602.json.gz
+//
+// so retrying an upload replaces the object instead of duplicating its events.
+// Object boundaries depend on MaxBytes, MaxAge, and when Flush is called, so
+// after a restart objects may start at different offsets and overlap those
+// already uploaded with events after the last checkpoint.
+//
+// Flush uploads the current object so how often callers flush bounds the
+// delay before events are archived and checkpointed. Objects are also rotated
+// during Write once they reach MaxBytes of uncompressed events or have been
+// open for MaxAge. An upload which fails during Write is retried by the next
+// Write or Flush.
+type Archive struct {
+	// MaxBytes and MaxAge limit the size and age of objects. Default to
+	// DefaultMaxObjectBytes and DefaultMaxObjectAge. Set them before the
+	// first Write.
+	MaxBytes int
+	MaxAge   time.Duration
+
+	// Clock used to age objects for rotation. Defaults to
+	// gobyairship.RealClock.
+	Clock gobyairship.Clock
+
+	// Key, if non-nil, names the object starting with an event instead of
+	// ObjectKey. Keys must be unique per offset so retried uploads replace
+	// objects rather than duplicating events. See KeyTemplate.
+	Key func(first *events.Event) string
+
+	store  ObjectStore
+	prefix string
+
+	mu     sync.Mutex
+	mark   Watermark
+	cur    *object
+	ready  []*object // finished objects not yet uploaded
+	closed bool
+}
+
+// object being written to an Archive.
+type object struct {
+	key    string
+	opened time.Time
+	size   int // uncompressed
+	buf    bytes.Buffer
+	gz     *gzip.Writer
+}
+
+// NewArchive creates an Archive which writes objects under prefix in store.
+// Set Key to customize object names.
+func NewArchive(store ObjectStore, prefix string) *Archive {
+	return &Archive{store: store, prefix: prefix}
+}
+
+// ObjectKey returns the key of the object starting with ev under prefix.
+func ObjectKey(prefix string, ev *events.Event) string {
+	return fmt.Sprintf("%s%s/part-%020d.json.gz", prefix, ev.Processed.UTC().Format("dt=2006-01-02/hour=15"), ev.Offset)
+}
+
+// KeyData is passed to templates parsed by KeyTemplate.
+type KeyData struct {
+	// Date (2006-01-02) and Hour (15) are when the first event was processed
+	// in UTC.
+	Date string
+	Hour string
+
+	// Offset is the zero padded offset of the first event.
+	Offset string
+
+	// Type and Event are the first event's Type and the event itself.
+	Type  events.Type
+	Event *events.Event
+}
+
+// KeyTemplate parses a text/template for Archive.Key which is executed with
+// KeyData. The template must include {{.Offset}}. For example:
+//
+//	{{.Type}}/{{.Date}}/{{.Hour}}/{{.Offset}}.json.gz
+func KeyTemplate(tmpl string) (func(first *events.Event) string, error) {
+	if !strings.Contains(tmpl, "{{.Offset}}") {
+		return nil, errors.New("key template must include {{.Offset}}")
+	}
+	t, err := template.New("key").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	// Check the template executes before it's used
+	if err := t.Execute(ioutil.Discard, keyData(&events.Event{})); err != nil {
+		return nil, err
+	}
+	return func(ev *events.Event) string {
+		buf := &bytes.Buffer{}
+		t.Execute(buf, keyData(ev))
+		return buf.String()
+	}, nil
+}
+
+func keyData(ev *events.Event) KeyData {
+	p := ev.Processed.UTC()
+	return KeyData{
+		Date:   p.Format("2006-01-02"),
+		Hour:   p.Format("15"),
+		Offset: fmt.Sprintf("%020d", ev.Offset),
+		Type:   ev.Type,
+		Event:  ev,
+	}
+}
+
+func (a *Archive) key(ev *events.Event) string {
+	if a.Key != nil {
+		return a.Key(ev)
+	}
+	return ObjectKey(a.prefix, ev)
+}
+
+func (a *Archive) now() time.Time {
+	if a.Clock != nil {
+		return a.Clock.Now()
+	}
+	return gobyairship.RealClock.Now()
+}
+
+// Write implements Sink.
+func (a *Archive) Write(evs []*events.Event) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return ErrClosed
+	}
+	maxBytes, maxAge := a.MaxBytes, a.MaxAge
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxObjectBytes
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMaxObjectAge
+	}
+	for _, ev := range a.mark.Filter(evs) {
+		line, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		if a.cur == nil {
+			a.cur = &object{key: a.key(ev), opened: a.now()}
+			a.cur.gz = gzip.NewWriter(&a.cur.buf)
+		}
+		a.cur.gz.Write(append(line, '\n'))
+		a.cur.size += len(line) + 1
+		if a.cur.size >= maxBytes || a.now().Sub(a.cur.opened) >= maxAge {
+			a.rotate()
+		}
+	}
+	return a.upload()
+}
+
+// Flush implements Sink.
+func (a *Archive) Flush() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rotate()
+	return a.upload()
+}
+
+// Close implements Sink.
+func (a *Archive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+	a.rotate()
+	return a.upload()
+}
+
+// rotate finishes the current object. a.mu must be held.
+func (a *Archive) rotate() {
+	if a.cur == nil {
+		return
+	}
+	a.cur.gz.Close()
+	a.ready = append(a.ready, a.cur)
+	a.cur = nil
+}
+
+// upload finished objects in order, stopping at the first error. a.mu must be
+// held.
+func (a *Archive) upload() error {
+	for len(a.ready) > 0 {
+		o := a.ready[0]
+		if err := a.store.Put(o.key, o.buf.Bytes()); err != nil {
+			return fmt.Errorf("error uploading %s: %v", o.key, err)
+		}
+		a.ready = a.ready[1:]
+	}
+	return nil
+}
+
+// ReadObject decodes the events in an object written by an Archive.
+func ReadObject(body []byte) ([]*events.Event, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	var evs []*events.Event
+	dec := json.NewDecoder(gz)
+	for dec.More() {
+		ev := &events.Event{}
+		if err := dec.Decode(ev); err != nil {
+			return nil, err
+		}
+		evs = append(evs, ev)
+	}
+	return evs, nil
+}
@@ -0,0 +1,104 @@
+package bigquery
+
+import (
+	"context"
+	"sync"
+
+	bq "cloud.google.com/go/bigquery"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/sinks"
+)
+
+// Inserter streams rows into a table. It's implemented by *bigquery.Inserter.
+type Inserter interface {
+	Put(ctx context.Context, src interface{}) error
+}
+
+// DatasetTables returns the Inserters for the tables named by TableName in
+// ds.
+func DatasetTables(ds *bq.Dataset) func(events.Type) Inserter {
+	return func(t events.Type) Inserter { return ds.Table(TableName(t)).Inserter() }
+}
+
+// Saver is a row passed to Inserters in slices. It implements
+// bigquery.ValueSaver with the event's ID as the insert ID.
+type Saver struct {
+	id  string
+	row map[string]bq.Value
+}
+
+// Save implements bigquery.ValueSaver.
+func (s *Saver) Save() (map[string]bq.Value, string, error) { return s.row, s.id, nil }
+
+// Sink is a sinks.Sink which streams events into a BigQuery table per event
+// type. Rows are buffered until Flush.
+type Sink struct {
+	ctx    context.Context
+	tables func(events.Type) Inserter
+
+	mu      sync.Mutex
+	mark    sinks.Watermark
+	pending map[events.Type][]*Saver
+	order   []events.Type // types in pending in the order first written
+	closed  bool
+}
+
+// NewSink creates a Sink which inserts events into the tables returned by
+// tables, such as DatasetTables. Inserts use ctx.
+func NewSink(ctx context.Context, tables func(events.Type) Inserter) *Sink {
+	return &Sink{ctx: ctx, tables: tables, pending: map[events.Type][]*Saver{}}
+}
+
+// Write implements sinks.Sink. Events which can't be mapped onto rows are
+// errors.
+func (s *Sink) Write(evs []*events.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return sinks.ErrClosed
+	}
+	for _, ev := range s.mark.Filter(evs) {
+		row, err := Row(ev)
+		if err != nil {
+			return err
+		}
+		if _, ok := s.pending[ev.Type]; !ok {
+			s.order = append(s.order, ev.Type)
+		}
+		s.pending[ev.Type] = append(s.pending[ev.Type], &Saver{id: ev.ID, row: row})
+	}
+	return nil
+}
+
+// Flush implements sinks.Sink. Rows for each type are inserted in the order
+// their types were first written. If an insert fails the rows of it and any
+// later types are retried by the next Flush.
+func (s *Sink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+func (s *Sink) flush() error {
+	for len(s.order) > 0 {
+		t := s.order[0]
+		if err := s.tables(t).Put(s.ctx, s.pending[t]); err != nil {
+			return err
+		}
+		delete(s.pending, t)
+		s.order = s.order[1:]
+	}
+	return nil
+}
+
+// Close implements sinks.Sink.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.flush()
+}
@@ -0,0 +1,128 @@
+package bigquery_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	bq "cloud.google.com/go/bigquery"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/sinks"
+	"github.com/lytics/gobyairship/sinks/bigquery"
+	"github.com/lytics/gobyairship/sinks/sinktest"
+)
+
+// fakeTables stores inserted rows in memory by insert ID.
+type fakeTables struct {
+	mu   sync.Mutex
+	rows map[string]map[string]bq.Value
+	fail bool
+}
+
+type fakeInserter struct{ f *fakeTables }
+
+func (i fakeInserter) Put(_ context.Context, src interface{}) error {
+	i.f.mu.Lock()
+	defer i.f.mu.Unlock()
+	if i.f.fail {
+		return errors.New("unavailable")
+	}
+	for _, s := range src.([]*bigquery.Saver) {
+		row, id, err := s.Save()
+		if err != nil {
+			return err
+		}
+		i.f.rows[id] = row
+	}
+	return nil
+}
+
+func (f *fakeTables) tables(events.Type) bigquery.Inserter { return fakeInserter{f} }
+
+func (f *fakeTables) events() ([]*events.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var evs []*events.Event
+	for id, row := range f.rows {
+		offset, err := events.ParseOffset(row["offset"].(string))
+		if err != nil {
+			return nil, err
+		}
+		evs = append(evs, &events.Event{ID: id, Type: events.Type(row["type"].(string)), Offset: uint64(offset)})
+	}
+	return evs, nil
+}
+
+func TestSink(t *testing.T) {
+	sinktest.Run(t, func(t *testing.T) (sinks.Sink, sinktest.StoredFunc) {
+		f := &fakeTables{rows: map[string]map[string]bq.Value{}}
+		return bigquery.NewSink(context.Background(), f.tables), f.events
+	})
+
+	// Failed inserts are retried
+	f := &fakeTables{rows: map[string]map[string]bq.Value{}, fail: true}
+	s := bigquery.NewSink(context.Background(), f.tables)
+	s.Write(sinktest.Events(0, 2))
+	if err := s.Flush(); err == nil {
+		t.Fatalf("Expected insert error")
+	}
+	f.fail = false
+	if err := s.Close(); err != nil || len(f.rows) != 2 {
+		t.Errorf("Expected 2 rows but found %d (%v)", len(f.rows), err)
+	}
+}
+
+func TestRow(t *testing.T) {
+	ts := time.Date(2017, 4, 25, 9, 0, 0, 0, time.UTC)
+	ev := &events.Event{
+		ID:        "a",
+		Type:      events.TypeOpen,
+		Offset:    1<<64 - 1,
+		Occurred:  ts,
+		Processed: ts,
+		Device:    &events.Device{IOS: "c", NamedUser: "u"},
+		Body:      []byte(`{"session_id":"s","last_delivered":{"push_id":"p"},"ignored":[1]}`),
+	}
+	row, err := bigquery.Row(ev)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]bq.Value{
+		"id": "a", "type": "OPEN", "offset": "18446744073709551615", "occurred": ts, "processed": ts,
+		"ios_channel": "c", "named_user_id": "u", "body": string(ev.Body),
+		"body_session_id": "s", "body_last_delivered_push_id": "p",
+	}
+	if len(row) != len(expected) {
+		t.Errorf("Expected %d columns but found %d: %v", len(expected), len(row), row)
+	}
+	for k, v := range expected {
+		if row[k] != v {
+			t.Errorf("Expected %s=%v but found %v", k, v, row[k])
+		}
+	}
+
+	// Every column is in the type's schema with a matching Go type
+	ev = &events.Event{Type: events.TypeLocation, Body: []byte(`{"latitude":"45.5","longitude":-122.6,"foreground":true}`)}
+	if row, err = bigquery.Row(ev); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if row["body_latitude"] != 45.5 || row["body_longitude"] != -122.6 || row["body_foreground"] != true {
+		t.Errorf("Unexpected location row: %v", row)
+	}
+	schema := map[string]bq.FieldType{}
+	for _, f := range bigquery.Schema(events.TypeLocation) {
+		schema[f.Name] = f.Type
+	}
+	for k := range row {
+		if _, ok := schema[k]; !ok {
+			t.Errorf("Column %s is not in the schema", k)
+		}
+	}
+
+	if len(bigquery.Types()) == 0 || bigquery.TableName(events.TypeTagChange) != "tag_change" {
+		t.Errorf("Unexpected types %v or table name", bigquery.Types())
+	}
+}
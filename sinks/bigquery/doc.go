@@ -0,0 +1,34 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package loads events into BigQuery with one table per event type.
+// Each row has the event's envelope (id, type, offset, timestamps, and device
+// IDs), the raw body as JSON, and the body's fields flattened into columns
+// described by Schema:
+//
+//	ds := client.Dataset("airship")
+//	for _, t := range bigquery.Types() {
+//		md := &bq.TableMetadata{Schema: bigquery.Schema(t)}
+//		if err := ds.Table(bigquery.TableName(t)).Create(ctx, md); err != nil {
+//			return err
+//		}
+//	}
+//	sink := bigquery.NewSink(ctx, bigquery.DatasetTables(ds))
+//
+// Rows are streamed with each event's ID as the insert ID so BigQuery
+// deduplicates rows retried after a failed Flush on a best-effort basis.
+//
+// Unlike the core packages this package depends on
+// cloud.google.com/go/bigquery.
+package bigquery
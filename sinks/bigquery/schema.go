@@ -0,0 +1,213 @@
+package bigquery
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	bq "cloud.google.com/go/bigquery"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+// column is a body field flattened into a column. Path is the dotted path of
+// the field in the body.
+type column struct {
+	name string
+	path string
+	typ  bq.FieldType
+}
+
+func str(path string) column {
+	return column{name: strings.Replace(path, ".", "_", -1), path: path, typ: bq.StringFieldType}
+}
+
+func integer(path string) column {
+	c := str(path)
+	c.typ = bq.IntegerFieldType
+	return c
+}
+
+func float(path string) column {
+	c := str(path)
+	c.typ = bq.FloatFieldType
+	return c
+}
+
+func boolean(path string) column {
+	c := str(path)
+	c.typ = bq.BooleanFieldType
+	return c
+}
+
+// push columns are shared by events about a push.
+var push = []column{str("push_id"), str("group_id"), integer("variant_id")}
+
+// bodyColumns are the flattened body columns of each event type. Fields not
+// listed, such as maps of tags, are only available in the body column.
+var bodyColumns = map[events.Type][]column{
+	events.TypeOpen: {
+		str("session_id"),
+		str("last_delivered.push_id"), str("last_delivered.group_id"),
+		str("triggering_push.push_id"), str("triggering_push.group_id"),
+	},
+	events.TypeSend:                   push,
+	events.TypeClose:                  {str("session_id")},
+	events.TypeTagChange:              nil,
+	events.TypeUninstall:              nil,
+	events.TypeFirst:                  {str("session_id")},
+	events.TypeLocation:               {float("latitude"), float("longitude"), boolean("foreground"), str("session_id")},
+	events.TypePush:                   {str("push_id"), str("group_id")},
+	events.TypeRichDelivery:           append(push, str("message_id")),
+	events.TypeRichRead:               append(push, str("message_id"), str("session_id")),
+	events.TypeRichDelete:             append(push, str("message_id"), str("session_id")),
+	events.TypeInAppMessageDisplay:    {str("push_id"), str("group_id"), str("triggering_push.push_id"), str("session_id")},
+	events.TypeInAppMessageResolution: {str("push_id"), str("group_id"), str("type"), integer("duration"), str("button_id"), str("session_id")},
+	events.TypeInAppMessageExpiration: {str("push_id"), str("group_id"), str("type"), str("session_id")},
+	events.TypeScreenViewed:           {str("viewed_screen"), str("previous_screen"), integer("duration"), str("session_id")},
+	events.TypeRegion:                 {str("region_id"), str("name"), str("source"), str("action"), str("session_id")},
+	events.TypeControl:                push,
+	events.TypeSendAborted:            append(push, str("reason")),
+	events.TypeEmailDelivery:          append(push, str("message_type")),
+	events.TypeEmailOpen:              append(push, str("message_type")),
+	events.TypeEmailClick:             append(push, str("message_type"), str("link_url")),
+	events.TypeEmailBounce:            append(push, str("message_type"), str("bounce_reason")),
+	events.TypeEmailUnsubscribe:       append(push, str("message_type")),
+	events.TypeSMSDelivery:            append(push, str("delivery_status"), str("error_code"), boolean("mms")),
+	events.TypeMobileOriginated:       {str("keyword"), str("inbound_message")},
+	events.TypeSMSOptIn:               {str("keyword"), str("source")},
+	events.TypeSMSOptOut:              {str("keyword"), str("source")},
+	events.TypeWebClick:               append(push, str("url"), str("browser_name"), str("session_id")),
+	events.TypeCustom:                 {str("name"), float("value"), str("interaction_id"), str("interaction_type"), str("session_id")},
+	events.TypeAttributeOperation:     nil,
+	events.TypeSubscriptionListChange: {str("list_id"), str("action"), str("scope")},
+	events.TypeCompliance:             {str("action"), str("request_id"), str("reason")},
+}
+
+// deviceColumns are the envelope's device ID columns named like the fields
+// of events.Device.
+var deviceColumns = []string{
+	"ios_channel", "android_channel", "amazon_channel", "named_user_id",
+	"email_channel", "sms_channel", "web_channel",
+}
+
+// deviceIDs returns the IDs of d in deviceColumns order.
+func deviceIDs(d *events.Device) []string {
+	return []string{d.IOS, d.Android, d.Amazon, d.NamedUser, d.Email, d.SMS, d.Web}
+}
+
+// Types returns the event types with schemas in a stable order.
+func Types() []events.Type {
+	types := make([]string, 0, len(bodyColumns))
+	for t := range bodyColumns {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+	out := make([]events.Type, len(types))
+	for i, t := range types {
+		out[i] = events.Type(t)
+	}
+	return out
+}
+
+// TableName returns the table for events of type t, such as "tag_change"
+// for TAG_CHANGE.
+func TableName(t events.Type) string { return strings.ToLower(string(t)) }
+
+// Schema returns the table schema for events of type t. Unknown types only
+// have envelope and body columns.
+func Schema(t events.Type) bq.Schema {
+	s := bq.Schema{
+		{Name: "id", Type: bq.StringFieldType, Required: true},
+		{Name: "type", Type: bq.StringFieldType, Required: true},
+		{Name: "offset", Type: bq.StringFieldType, Required: true, Description: "Offsets may exceed the range of INTEGER"},
+		{Name: "occurred", Type: bq.TimestampFieldType, Required: true},
+		{Name: "processed", Type: bq.TimestampFieldType, Required: true},
+	}
+	for _, name := range deviceColumns {
+		s = append(s, &bq.FieldSchema{Name: name, Type: bq.StringFieldType})
+	}
+	s = append(s, &bq.FieldSchema{Name: "body", Type: bq.StringFieldType, Description: "Raw JSON body"})
+	for _, c := range bodyColumns[t] {
+		s = append(s, &bq.FieldSchema{Name: "body_" + c.name, Type: c.typ})
+	}
+	return s
+}
+
+// Row maps an event onto a row of its type's Schema.
+func Row(ev *events.Event) (map[string]bq.Value, error) {
+	row := map[string]bq.Value{
+		"id":        ev.ID,
+		"type":      string(ev.Type),
+		"offset":    events.Offset(ev.Offset).String(),
+		"occurred":  ev.Occurred,
+		"processed": ev.Processed,
+		"body":      string(ev.Body),
+	}
+	if ev.Device != nil {
+		for i, id := range deviceIDs(ev.Device) {
+			if id != "" {
+				row[deviceColumns[i]] = id
+			}
+		}
+	}
+	cols := bodyColumns[ev.Type]
+	if len(cols) == 0 || len(ev.Body) == 0 {
+		return row, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(ev.Body))
+	dec.UseNumber()
+	body := map[string]interface{}{}
+	if err := dec.Decode(&body); err != nil {
+		return nil, err
+	}
+	for _, c := range cols {
+		if v, ok := value(body, c); ok {
+			row["body_"+c.name] = v
+		}
+	}
+	return row, nil
+}
+
+// value returns the body field at c's path converted to c's type.
+func value(body map[string]interface{}, c column) (bq.Value, bool) {
+	var v interface{} = body
+	for _, key := range strings.Split(c.path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok || v == nil {
+			return nil, false
+		}
+	}
+	switch c.typ {
+	case bq.IntegerFieldType:
+		if n, ok := v.(json.Number); ok {
+			i, err := n.Int64()
+			return i, err == nil
+		}
+	case bq.FloatFieldType:
+		switch n := v.(type) {
+		case json.Number:
+			f, err := n.Float64()
+			return f, err == nil
+		case string:
+			// Some APIs send numbers as strings
+			f, err := json.Number(n).Float64()
+			return f, err == nil
+		}
+	case bq.BooleanFieldType:
+		b, ok := v.(bool)
+		return b, ok
+	default:
+		switch s := v.(type) {
+		case string:
+			return s, true
+		case json.Number:
+			return s.String(), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,35 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package defines the Sink interface for durably storing events from
+// Urban Airship's Event API.
+//
+// Sinks implementations should verify they meet the Sink contract with the
+// conformance tests in the sinktest package.
+//
+// An Archive batches events into compressed objects in an ObjectStore.
+// DirStore keeps them in a local directory and, with ArchiveReader, they can
+// be replayed, such as to a relay.Hub's subscribers resuming from offsets the
+// Hub no longer retains.
+//
+// # Dependencies
+//
+// The gobyairship, events, and sinks packages only depend on the standard
+// library. Sinks and other integrations which need third-party libraries
+// (cloud SDKs, Kafka clients, etc.) live in their own subpackages such as
+// sinks/s3 so users only pull in the dependencies of the integrations they
+// import. Such packages must be listed in the integrations set in
+// deps_test.go, which fails if any other package imports a third-party
+// library or an integration package.
+package sinks
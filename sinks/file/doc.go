@@ -0,0 +1,23 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package implements a Sink which appends events as newline delimited
+// JSON to local files, rotating them by size and age and optionally gzipping
+// them. It's useful for archiving events on machines without access to cloud
+// storage and as a reference implementation of the sinks.Sink contract.
+//
+// Files are named after the offset of their first event, such as
+// part-00000000000000000602.ndjson.gz, so they sort in stream order. The file
+// being written has a .partial suffix until it's rotated.
+package file
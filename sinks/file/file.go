@@ -0,0 +1,318 @@
+package file
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/sinks"
+)
+
+// Default rotation limits.
+const (
+	DefaultMaxBytes = 128 << 20
+	DefaultMaxAge   = time.Hour
+)
+
+const (
+	prefix     = "part-"
+	ext        = ".ndjson"
+	gzExt      = ".gz"
+	partialExt = ".partial"
+)
+
+// Sink appends events to files in a directory. Flush syncs the current file
+// to disk. Files are rotated during Write once they reach MaxBytes of
+// uncompressed events or have been open for MaxAge.
+//
+// When a Sink first writes to a directory it recovers the files left by a
+// previous Sink: partial files are truncated to their last complete event and
+// rotated, and events at or below the highest offset already stored are
+// skipped. So after a crash, resuming from the last checkpoint doesn't store
+// events twice.
+type Sink struct {
+	// MaxBytes and MaxAge limit the size and age of files. Default to
+	// DefaultMaxBytes and DefaultMaxAge.
+	MaxBytes int64
+	MaxAge   time.Duration
+
+	// Compress gzips files.
+	Compress bool
+
+	// Clock used to age files for rotation. Defaults to
+	// gobyairship.RealClock.
+	Clock gobyairship.Clock
+
+	dir string
+
+	mu        sync.Mutex
+	mark      sinks.Watermark
+	recovered bool
+	closed    bool
+
+	// current file
+	f      *os.File
+	w      io.Writer
+	gz     *gzip.Writer
+	name   string // path once rotated
+	size   int64
+	opened time.Time
+}
+
+// NewSink creates a Sink which writes files to dir. The directory must exist.
+// Set options before the first Write.
+func NewSink(dir string) *Sink {
+	return &Sink{dir: dir}
+}
+
+func (s *Sink) now() time.Time {
+	if s.Clock != nil {
+		return s.Clock.Now()
+	}
+	return gobyairship.RealClock.Now()
+}
+
+// Write implements sinks.Sink.
+func (s *Sink) Write(evs []*events.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return sinks.ErrClosed
+	}
+	if !s.recovered {
+		if err := s.recover(); err != nil {
+			return err
+		}
+		s.recovered = true
+	}
+	maxBytes, maxAge := s.MaxBytes, s.MaxAge
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	for _, ev := range s.mark.Filter(evs) {
+		line, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		if s.f == nil {
+			if err := s.open(ev.Offset); err != nil {
+				return err
+			}
+		}
+		n, err := s.w.Write(append(line, '\n'))
+		s.size += int64(n)
+		if err != nil {
+			return err
+		}
+		if s.size >= maxBytes || s.now().Sub(s.opened) >= maxAge {
+			if err := s.rotate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Flush implements sinks.Sink.
+func (s *Sink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+// Close implements sinks.Sink. The current file is rotated.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.rotate()
+}
+
+// open a new partial file starting at offset. s.mu must be held.
+func (s *Sink) open(offset uint64) error {
+	name := fmt.Sprintf("%s%020d%s", prefix, offset, ext)
+	if s.Compress {
+		name += gzExt
+	}
+	s.name = filepath.Join(s.dir, name)
+	f, err := os.OpenFile(s.name+partialExt, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	s.f, s.w, s.gz = f, f, nil
+	if s.Compress {
+		s.gz = gzip.NewWriter(f)
+		s.w = s.gz
+	}
+	s.size = 0
+	s.opened = s.now()
+	return nil
+}
+
+// flush syncs the current file. s.mu must be held.
+func (s *Sink) flush() error {
+	if s.f == nil {
+		return nil
+	}
+	if s.gz != nil {
+		if err := s.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	return s.f.Sync()
+}
+
+// rotate closes the current file and removes its partial suffix. s.mu must be
+// held.
+func (s *Sink) rotate() error {
+	if s.f == nil {
+		return nil
+	}
+	if s.gz != nil {
+		if err := s.gz.Close(); err != nil {
+			return err
+		}
+	}
+	if err := s.f.Sync(); err != nil {
+		return err
+	}
+	if err := s.f.Close(); err != nil {
+		return err
+	}
+	s.f = nil
+	return os.Rename(s.name+partialExt, s.name)
+}
+
+// recover rotates partial files left by a previous Sink and restores the
+// watermark from the newest file. s.mu must be held.
+func (s *Sink) recover() error {
+	fis, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, fi := range fis {
+		if name := fi.Name(); strings.HasPrefix(name, prefix) && strings.Contains(name, ext) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	// Zero padded offsets sort in stream order
+	sort.Strings(names)
+	for i, name := range names {
+		if !strings.HasSuffix(name, partialExt) {
+			continue
+		}
+		fn := filepath.Join(s.dir, name)
+		names[i] = strings.TrimSuffix(name, partialExt)
+		// Keep the complete events before any torn write
+		evs, _ := ReadFile(fn)
+		if len(evs) > 0 {
+			if err := rewrite(filepath.Join(s.dir, names[i]), evs); err != nil {
+				return err
+			}
+		}
+		if err := os.Remove(fn); err != nil {
+			return err
+		}
+	}
+	for i := len(names) - 1; i >= 0; i-- {
+		evs, err := ReadFile(filepath.Join(s.dir, names[i]))
+		if os.IsNotExist(err) {
+			// An empty partial file
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if len(evs) > 0 {
+			s.mark.Filter(evs[len(evs)-1:])
+			return nil
+		}
+	}
+	return nil
+}
+
+// rewrite the complete events recovered from a partial file to fn.
+func rewrite(fn string, evs []*events.Event) error {
+	f, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if strings.HasSuffix(fn, gzExt) {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+	enc := json.NewEncoder(w)
+	for _, ev := range evs {
+		if err = enc.Encode(ev); err != nil {
+			break
+		}
+	}
+	if gz != nil && err == nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ReadFile decodes the events in a file written by a Sink, including partial
+// files. If the file ends with an incomplete event, such as after a crash,
+// the complete events are returned along with an error.
+func ReadFile(fn string) ([]*events.Event, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(strings.TrimSuffix(fn, partialExt), gzExt) {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		r = gz
+	}
+	br := bufio.NewReader(r)
+	var evs []*events.Event
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return evs, nil
+		}
+		if err != nil {
+			return evs, fmt.Errorf("incomplete event in %s: %v", fn, err)
+		}
+		ev := &events.Event{}
+		if err := json.Unmarshal(line, ev); err != nil {
+			return evs, fmt.Errorf("invalid event in %s: %v", fn, err)
+		}
+		evs = append(evs, ev)
+	}
+}
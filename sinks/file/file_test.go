@@ -0,0 +1,160 @@
+package file_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/sinks"
+	"github.com/lytics/gobyairship/sinks/file"
+	"github.com/lytics/gobyairship/sinks/sinktest"
+	"github.com/lytics/gobyairship/uatest"
+)
+
+// stored reads the events in every file in dir. Files being written may end
+// in an incomplete gzip stream so their errors are ignored.
+func stored(dir string) ([]*events.Event, error) {
+	fns, err := filepath.Glob(filepath.Join(dir, "part-*"))
+	if err != nil {
+		return nil, err
+	}
+	var evs []*events.Event
+	for _, fn := range fns {
+		fevs, err := file.ReadFile(fn)
+		if err != nil && !strings.HasSuffix(fn, ".partial") {
+			return nil, err
+		}
+		evs = append(evs, fevs...)
+	}
+	return evs, nil
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "filesink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestSink(t *testing.T) {
+	var dirs []string
+	defer func() {
+		for _, dir := range dirs {
+			os.RemoveAll(dir)
+		}
+	}()
+	for _, compress := range []bool{false, true} {
+		sinktest.Run(t, func(t *testing.T) (sinks.Sink, sinktest.StoredFunc) {
+			dir := tempDir(t)
+			dirs = append(dirs, dir)
+			s := file.NewSink(dir)
+			s.Compress = compress
+			return s, func() ([]*events.Event, error) { return stored(dir) }
+		})
+		sinktest.RunRestart(t, func(t *testing.T) (func() sinks.Sink, sinktest.StoredFunc) {
+			dir := tempDir(t)
+			dirs = append(dirs, dir)
+			open := func() sinks.Sink {
+				s := file.NewSink(dir)
+				s.Compress = compress
+				return s
+			}
+			return open, func() ([]*events.Event, error) { return stored(dir) }
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	clock := uatest.NewClock(time.Date(2017, 4, 25, 9, 0, 0, 0, time.UTC))
+	s := file.NewSink(dir)
+	s.Compress = true
+	s.MaxBytes = 500
+	s.MaxAge = time.Minute
+	s.Clock = clock
+
+	evs := sinktest.Events(0, 12)
+	if err := s.Write(evs[:10]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	clock.Advance(time.Minute)
+	if err := s.Write(evs[10:]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fns, _ := filepath.Glob(filepath.Join(dir, "part-*.ndjson.gz"))
+	if len(fns) < 3 {
+		t.Errorf("Expected files to rotate by size and age but found %v", fns)
+	}
+	if filepath.Base(fns[0]) != "part-00000000000000000000.ndjson.gz" {
+		t.Errorf("Unexpected first file %s", fns[0])
+	}
+	if got, err := stored(dir); err != nil || len(got) != len(evs) {
+		t.Errorf("Expected %d events but found %d (%v)", len(evs), len(got), err)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+		evs := sinktest.Events(0, 20)
+
+		// Crash after flushing some events and writing others, one of them torn
+		s := file.NewSink(dir)
+		s.Compress = compress
+		if err := s.Write(evs[:10]); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := s.Flush(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := s.Write(evs[10:15]); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		s.Flush()
+		if !compress {
+			fns, _ := filepath.Glob(filepath.Join(dir, "*.partial"))
+			f, err := os.OpenFile(fns[0], os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			f.WriteString(`{"id":"torn`)
+			f.Close()
+		}
+
+		// Resume from the checkpoint after the first flush
+		s = file.NewSink(dir)
+		s.Compress = compress
+		if err := s.Write(evs[10:]); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got, err := stored(dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		seen := map[uint64]int{}
+		for _, ev := range got {
+			seen[ev.Offset]++
+		}
+		for _, ev := range evs {
+			if seen[ev.Offset] != 1 {
+				t.Errorf("compress=%t: expected offset %d once but found it %d times", compress, ev.Offset, seen[ev.Offset])
+			}
+		}
+		if fns, _ := filepath.Glob(filepath.Join(dir, "*.partial")); len(fns) > 0 {
+			t.Errorf("Expected partial files to be rotated but found %v", fns)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package archives events to Google Cloud Storage as gzipped newline
+// delimited JSON objects rotated by size and age, mirroring sinks/s3:
+//
+//	client, err := storage.NewClient(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	sink := gcs.NewArchive(ctx, client.Bucket("my-bucket"), "events/")
+//
+// Object names may be templated with sinks.KeyTemplate:
+//
+//	sink.Key, err = sinks.KeyTemplate("events/{{.Type}}/{{.Date}}/{{.Offset}}.json.gz")
+//
+// See sinks.Archive for rotation and retry behavior. Since Flush only returns
+// once the current object is uploaded, checkpointing offsets after Flush
+// never skips events which weren't archived.
+//
+// Unlike the core packages this package depends on
+// cloud.google.com/go/storage.
+package gcs
@@ -0,0 +1,42 @@
+package gcs
+
+import (
+	"context"
+	"io"
+
+	"cloud.google.com/go/storage"
+
+	"github.com/lytics/gobyairship/sinks"
+)
+
+// Store is a sinks.ObjectStore which uploads objects to a GCS bucket.
+type Store struct {
+	open func(key string) io.WriteCloser
+}
+
+// NewStore creates a Store which uploads objects to bucket. Uploads are
+// canceled when ctx is done.
+func NewStore(ctx context.Context, bucket *storage.BucketHandle) *Store {
+	return &Store{open: func(key string) io.WriteCloser {
+		w := bucket.Object(key).NewWriter(ctx)
+		w.ContentType = "application/gzip"
+		return w
+	}}
+}
+
+// Put implements sinks.ObjectStore. The object is only stored once the writer
+// is closed successfully.
+func (s *Store) Put(key string, body []byte) error {
+	w := s.open(key)
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// NewArchive creates a sinks.Archive which writes objects under prefix in
+// bucket.
+func NewArchive(ctx context.Context, bucket *storage.BucketHandle, prefix string) *sinks.Archive {
+	return sinks.NewArchive(NewStore(ctx, bucket), prefix)
+}
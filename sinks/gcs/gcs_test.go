@@ -0,0 +1,93 @@
+package gcs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/sinks"
+	"github.com/lytics/gobyairship/sinks/sinktest"
+)
+
+// fakeBucket stores objects in memory once their writers are closed.
+type fakeBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	fail    bool
+}
+
+type fakeWriter struct {
+	bytes.Buffer
+	b   *fakeBucket
+	key string
+}
+
+func (w *fakeWriter) Close() error {
+	w.b.mu.Lock()
+	defer w.b.mu.Unlock()
+	if w.b.fail {
+		return errors.New("unavailable")
+	}
+	w.b.objects[w.key] = w.Bytes()
+	return nil
+}
+
+func (b *fakeBucket) store() *Store {
+	return &Store{open: func(key string) io.WriteCloser { return &fakeWriter{b: b, key: key} }}
+}
+
+func (b *fakeBucket) events() ([]*events.Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var evs []*events.Event
+	for _, body := range b.objects {
+		objEvs, err := sinks.ReadObject(body)
+		if err != nil {
+			return nil, err
+		}
+		evs = append(evs, objEvs...)
+	}
+	return evs, nil
+}
+
+func TestArchive(t *testing.T) {
+	sinktest.Run(t, func(t *testing.T) (sinks.Sink, sinktest.StoredFunc) {
+		b := &fakeBucket{objects: map[string][]byte{}}
+		return sinks.NewArchive(b.store(), "events/"), b.events
+	})
+}
+
+func TestKeyTemplate(t *testing.T) {
+	b := &fakeBucket{objects: map[string][]byte{}}
+	a := sinks.NewArchive(b.store(), "")
+	var err error
+	if a.Key, err = sinks.KeyTemplate("events/{{.Type}}/{{.Date}}/{{.Hour}}/{{.Offset}}.json.gz"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Failed uploads are retried by Flush
+	b.fail = true
+	if err := a.Write(sinktest.Events(42, 1)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := a.Flush(); err == nil {
+		t.Fatalf("Expected upload error")
+	}
+	b.fail = false
+	if err := a.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	key := "events/CLOSE/2015-05-27/11/00000000000000000042.json.gz"
+	if _, ok := b.objects[key]; !ok {
+		t.Errorf("Expected object %s but found %v", key, b.objects)
+	}
+
+	for _, tmpl := range []string{"events/{{.Date}}.json.gz", "{{.Offset}}{{.Nope}}", "{{.Offset}}{{"} {
+		if _, err := sinks.KeyTemplate(tmpl); err == nil {
+			t.Errorf("Expected error parsing %q", tmpl)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package sinks
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+// ObjectReader reads back objects from an ObjectStore, such as to replay an
+// Archive.
+type ObjectReader interface {
+	// List returns the keys of every object whose key starts with prefix.
+	List(prefix string) ([]string, error)
+
+	// Get returns the body of the object at key.
+	Get(key string) ([]byte, error)
+}
+
+// ArchiveReader replays the events in objects written by an Archive. It
+// implements relay.Archive so a relay.Hub can serve subscribers resuming from
+// offsets older than it retains.
+type ArchiveReader struct {
+	store  ObjectReader
+	prefix string
+}
+
+// NewArchiveReader creates an ArchiveReader for the objects under prefix in
+// store.
+func NewArchiveReader(store ObjectReader, prefix string) *ArchiveReader {
+	return &ArchiveReader{store: store, prefix: prefix}
+}
+
+// archived is an object and the offset of its first event.
+type archived struct {
+	key   string
+	first uint64
+}
+
+type byFirst []archived
+
+func (b byFirst) Len() int           { return len(b) }
+func (b byFirst) Less(i, j int) bool { return b[i].first < b[j].first }
+func (b byFirst) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// firstOffset returns the zero padded offset in an object's key, which
+// ObjectKey and KeyTemplate always include, or false if there is none.
+func firstOffset(key string) (uint64, bool) {
+	run := 0
+	for i := len(key) - 1; i >= 0; i-- {
+		if key[i] < '0' || key[i] > '9' {
+			run = 0
+			continue
+		}
+		if run++; run == 20 && (i == 0 || key[i-1] < '0' || key[i-1] > '9') {
+			n, err := strconv.ParseUint(key[i:i+20], 10, 64)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// Replay calls fn with each archived event after offset in offset order. It
+// stops at the first error from fn, which is returned. Objects are read
+// starting with the one containing the first event after offset, and keys
+// without an offset are ignored.
+func (r *ArchiveReader) Replay(after uint64, fn func(*events.Event) error) error {
+	keys, err := r.store.List(r.prefix)
+	if err != nil {
+		return err
+	}
+	var objs []archived
+	for _, k := range keys {
+		if first, ok := firstOffset(k); ok {
+			objs = append(objs, archived{key: k, first: first})
+		}
+	}
+	// An Archive writes one object at a time so each holds the events from
+	// its first offset up to the next object's
+	sort.Sort(byFirst(objs))
+	for i, o := range objs {
+		if i+1 < len(objs) && objs[i+1].first <= after {
+			continue
+		}
+		body, err := r.store.Get(o.key)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %v", o.key, err)
+		}
+		evs, err := ReadObject(body)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %v", o.key, err)
+		}
+		for _, ev := range evs {
+			if ev.Offset <= after {
+				continue
+			}
+			if err := fn(ev); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// DirStore is an ObjectStore and ObjectReader which keeps objects as files in
+// a local directory, such as an Archive kept on disk by a relay. Keys are
+// paths relative to Dir using forward slashes.
+type DirStore struct {
+	Dir string
+}
+
+// tmpPrefix names files being written by Put.
+const tmpPrefix = ".tmp-"
+
+func (d *DirStore) path(key string) (string, error) {
+	clean := filepath.FromSlash(key)
+	if key == "" || filepath.IsAbs(clean) || strings.HasPrefix(filepath.Clean(clean), "..") {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(d.Dir, clean), nil
+}
+
+// Put implements ObjectStore. The object is written to a temporary file which
+// is synced and then renamed, so readers never see partial objects.
+func (d *DirStore) Put(key string, body []byte) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), tmpPrefix)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(body)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// List implements ObjectReader. Keys are returned in lexical order.
+func (d *DirStore) List(prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.Walk(d.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == d.Dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), tmpPrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(d.Dir, path)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Get implements ObjectReader.
+func (d *DirStore) Get(key string) ([]byte, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, errors.New("no object at " + key)
+	}
+	return body, err
+}
@@ -0,0 +1,109 @@
+package sinks_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/sinks"
+)
+
+func TestDirStore(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "dirstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := &sinks.DirStore{Dir: dir + "/missing"}
+	if keys, err := store.List(""); err != nil || len(keys) != 0 {
+		t.Errorf("Expected no keys in a missing directory but found %v (%v)", keys, err)
+	}
+
+	store = &sinks.DirStore{Dir: dir}
+	for _, key := range []string{"b/2", "a/1", "b/1"} {
+		if err := store.Put(key, []byte(key)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	keys, err := store.List("b/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(keys) != 2 || keys[0] != "b/1" || keys[1] != "b/2" {
+		t.Errorf("Expected [b/1 b/2] but found %v", keys)
+	}
+	body, err := store.Get("a/1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(body) != "a/1" {
+		t.Errorf("Expected a/1 but found %q", body)
+	}
+	if _, err := store.Get("a/2"); err == nil {
+		t.Errorf("Expected an error getting a missing object")
+	}
+	for _, key := range []string{"", "/etc/passwd", "../x", "a/../../x"} {
+		if err := store.Put(key, nil); err == nil {
+			t.Errorf("Expected an error with key %q", key)
+		}
+	}
+}
+
+func TestArchiveReader(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "archivereader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := &sinks.DirStore{Dir: dir}
+	a := sinks.NewArchive(store, "events/")
+	// Every event is in its own object
+	a.MaxBytes = 1
+	var evs []*events.Event
+	for i := uint64(1); i <= 5; i++ {
+		evs = append(evs, &events.Event{ID: "id", Type: events.TypeSend, Offset: i, Processed: time.Unix(0, 0)})
+	}
+	if err := a.Write(evs); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Keys without an offset are ignored
+	if err := store.Put("events/README", []byte("not an object")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	r := sinks.NewArchiveReader(store, "events/")
+	var offsets []uint64
+	err = r.Replay(2, func(ev *events.Event) error {
+		offsets = append(offsets, ev.Offset)
+		if ev.Offset == 4 {
+			return sinks.ErrClosed
+		}
+		return nil
+	})
+	if err != sinks.ErrClosed {
+		t.Errorf("Expected the error from fn but found %v", err)
+	}
+	if len(offsets) != 2 || offsets[0] != 3 || offsets[1] != 4 {
+		t.Errorf("Expected offsets [3 4] but found %v", offsets)
+	}
+
+	offsets = nil
+	if err := r.Replay(0, func(ev *events.Event) error {
+		offsets = append(offsets, ev.Offset)
+		return nil
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(offsets) != 5 {
+		t.Errorf("Expected 5 events but found %v", offsets)
+	}
+}
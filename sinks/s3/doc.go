@@ -0,0 +1,28 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package archives events to Amazon S3 as gzipped newline delimited JSON
+// objects rotated by size and age:
+//
+//	sess := session.Must(session.NewSession())
+//	sink := s3.NewArchive(awss3.New(sess), "my-bucket", "events/")
+//
+// Objects are named like events/dt=2017-04-25/hour=09/part-<offset>.json.gz.
+// See sinks.Archive for rotation and retry behavior. Since Flush only returns
+// once the current object is uploaded, checkpointing offsets after Flush
+// never skips events which weren't archived.
+//
+// Unlike the core packages this package depends on
+// github.com/aws/aws-sdk-go.
+package s3
@@ -0,0 +1,39 @@
+package s3
+
+import (
+	"bytes"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/lytics/gobyairship/sinks"
+)
+
+// PutObjectAPI is the subset of the S3 API used to upload objects. It's
+// implemented by *s3.S3 from github.com/aws/aws-sdk-go/service/s3.
+type PutObjectAPI interface {
+	PutObject(*awss3.PutObjectInput) (*awss3.PutObjectOutput, error)
+}
+
+// Store is a sinks.ObjectStore which uploads objects to an S3 bucket.
+type Store struct {
+	Client PutObjectAPI
+	Bucket string
+}
+
+// Put implements sinks.ObjectStore.
+func (s *Store) Put(key string, body []byte) error {
+	_, err := s.Client.PutObject(&awss3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/gzip"),
+	})
+	return err
+}
+
+// NewArchive creates a sinks.Archive which writes objects under prefix in
+// bucket.
+func NewArchive(client PutObjectAPI, bucket, prefix string) *sinks.Archive {
+	return sinks.NewArchive(&Store{Client: client, Bucket: bucket}, prefix)
+}
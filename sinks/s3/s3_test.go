@@ -0,0 +1,66 @@
+package s3_test
+
+import (
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/sinks"
+	"github.com/lytics/gobyairship/sinks/s3"
+	"github.com/lytics/gobyairship/sinks/sinktest"
+)
+
+// fakeS3 stores objects from PutObject in memory.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) PutObject(in *awss3.PutObjectInput) (*awss3.PutObjectOutput, error) {
+	body, err := ioutil.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[aws.StringValue(in.Bucket)+"/"+aws.StringValue(in.Key)] = body
+	return &awss3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) events() ([]*events.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var evs []*events.Event
+	for _, body := range f.objects {
+		objEvs, err := sinks.ReadObject(body)
+		if err != nil {
+			return nil, err
+		}
+		evs = append(evs, objEvs...)
+	}
+	return evs, nil
+}
+
+func TestArchive(t *testing.T) {
+	sinktest.Run(t, func(t *testing.T) (sinks.Sink, sinktest.StoredFunc) {
+		f := &fakeS3{objects: map[string][]byte{}}
+		return s3.NewArchive(f, "bucket", "events/"), f.events
+	})
+
+	f := &fakeS3{objects: map[string][]byte{}}
+	a := s3.NewArchive(f, "bucket", "events/")
+	if err := a.Write(sinktest.Events(7, 1)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	key := "bucket/events/dt=2015-05-27/hour=11/part-00000000000000000007.json.gz"
+	if _, ok := f.objects[key]; !ok {
+		t.Errorf("Expected object %s but found %v", key, f.objects)
+	}
+}
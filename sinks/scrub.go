@@ -0,0 +1,33 @@
+package sinks
+
+import "github.com/lytics/gobyairship/events"
+
+// scrubbed is a Sink which scrubs events before writing them.
+type scrubbed struct {
+	Sink
+	s *events.Scrubber
+}
+
+// Scrubbed wraps sink so events are scrubbed by s before they're written,
+// keeping raw identifiers out of storage. Events whose bodies must be
+// scrubbed but aren't valid JSON are written without their bodies.
+func Scrubbed(sink Sink, s *events.Scrubber) Sink {
+	return &scrubbed{Sink: sink, s: s}
+}
+
+// Write implements Sink.
+func (w *scrubbed) Write(evs []*events.Event) error {
+	out := make([]*events.Event, len(evs))
+	for i, ev := range evs {
+		cp, err := w.s.Scrub(ev)
+		if err != nil {
+			body := *ev
+			body.Body = nil
+			if cp, err = w.s.Scrub(&body); err != nil {
+				return err
+			}
+		}
+		out[i] = cp
+	}
+	return w.Sink.Write(out)
+}
@@ -0,0 +1,115 @@
+package sinks
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+// ErrClosed is returned when writing to a closed Sink.
+var ErrClosed = errors.New("sink closed")
+
+// Sink durably stores events. Callers write batches of events in offset
+// order, call Flush, and only then checkpoint the offset of the last event
+// written. Together this provides at-least-once storage with idempotent
+// retries within a process:
+//
+//   - Events written before a successful Flush must be durably stored.
+//   - Writing events with offsets at or below the highest offset already
+//     written must not store them again, so a failed Write or Flush may
+//     simply be retried with the same batch.
+//
+// Use Watermark to implement the second requirement. After a restart the
+// stream resumes from the last checkpoint, so events flushed after it may be
+// stored again.
+type Sink interface {
+	// Write a batch of events. Sinks may buffer events until Flush.
+	Write(evs []*events.Event) error
+
+	// Flush durably stores all written events.
+	Flush() error
+
+	// Close flushes and releases any resources. Writes after Close return
+	// ErrClosed.
+	Close() error
+}
+
+// Watermark tracks the highest offset written to a Sink so redelivered events
+// may be skipped. Safe for concurrent use.
+type Watermark struct {
+	mu     sync.Mutex
+	offset uint64
+	set    bool
+}
+
+// Filter returns the events in evs with offsets above the watermark and
+// advances the watermark to the highest offset. The watermark is only kept in
+// memory so it doesn't skip events redelivered after a restart.
+func (w *Watermark) Filter(evs []*events.Event) []*events.Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := evs[:0:0]
+	for _, ev := range evs {
+		if w.set && ev.Offset <= w.offset {
+			continue
+		}
+		w.offset = ev.Offset
+		w.set = true
+		out = append(out, ev)
+	}
+	return out
+}
+
+// Offset returns the highest offset seen and false if no events have been
+// seen.
+func (w *Watermark) Offset() (uint64, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.offset, w.set
+}
+
+// Memory is a Sink which stores events in memory. Useful for testing.
+type Memory struct {
+	mu      sync.Mutex
+	mark    Watermark
+	pending []*events.Event
+	stored  []*events.Event
+	closed  bool
+}
+
+// Write implements Sink.
+func (m *Memory) Write(evs []*events.Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.pending = append(m.pending, m.mark.Filter(evs)...)
+	return nil
+}
+
+// Flush implements Sink.
+func (m *Memory) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stored = append(m.stored, m.pending...)
+	m.pending = nil
+	return nil
+}
+
+// Close implements Sink.
+func (m *Memory) Close() error {
+	m.Flush()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+// Events returns the flushed events.
+func (m *Memory) Events() []*events.Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*events.Event(nil), m.stored...)
+}
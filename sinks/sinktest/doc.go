@@ -0,0 +1,29 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package provides conformance tests for sinks.Sink implementations.
+//
+// Sink packages should call Run from a test:
+//
+//	func TestConformance(t *testing.T) {
+//		sinktest.Run(t, func(t *testing.T) (sinks.Sink, sinktest.StoredFunc) {
+//			s := NewMySink(...)
+//			return s, func() ([]*events.Event, error) { return readBack(s) }
+//		})
+//	}
+//
+// Sinks which store to a place outside the process, such as a directory or a
+// bucket, should also call RunRestart to test resuming from a checkpoint with a
+// new Sink.
+package sinktest
@@ -0,0 +1,245 @@
+package sinktest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/sinks"
+)
+
+// StoredFunc returns the events durably stored by a Sink in any order. Only
+// the ID, Type, and Offset of events are compared.
+type StoredFunc func() ([]*events.Event, error)
+
+// NewFunc creates an empty Sink for a single test along with a function to read
+// back what it stored.
+type NewFunc func(t *testing.T) (sinks.Sink, StoredFunc)
+
+// OpenFunc creates a Sink for a single test along with functions to open it
+// again, storing to the same place as after a process restart, and to read back
+// what every opened Sink stored.
+type OpenFunc func(t *testing.T) (open func() sinks.Sink, stored StoredFunc)
+
+// Run the conformance tests against Sinks created by newSink.
+func Run(t *testing.T, newSink NewFunc) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, NewFunc)
+	}{
+		{"Batches", testBatches},
+		{"EmptyBatch", testEmptyBatch},
+		{"Retry", testRetry},
+		{"Checkpoint", testCheckpoint},
+		{"Close", testClose},
+	}
+	for _, test := range tests {
+		fn := test.fn
+		t.Run(test.name, func(t *testing.T) { fn(t, newSink) })
+	}
+}
+
+// RunRestart tests that a Sink opened again mid-stream and resumed from the
+// last checkpoint stores every event at least once. Events after the
+// checkpoint may be stored twice.
+func RunRestart(t *testing.T, open OpenFunc) {
+	t.Run("Restart", func(t *testing.T) { testRestart(t, open) })
+}
+
+// Events returns n CLOSE events starting at offset.
+func Events(offset uint64, n int) []*events.Event {
+	ts := time.Date(2015, 5, 27, 11, 32, 7, 0, time.UTC)
+	evs := make([]*events.Event, n)
+	for i := range evs {
+		off := offset + uint64(i)
+		evs[i] = &events.Event{
+			ID:        fmt.Sprintf("event-%d", off),
+			Type:      events.TypeClose,
+			Occurred:  ts,
+			Processed: ts,
+			Offset:    off,
+			Body:      []byte(`{"session_id":"session"}`),
+			Device:    &events.Device{IOS: "channel"},
+		}
+	}
+	return evs
+}
+
+// check that exactly the expected events were stored once each.
+func check(t *testing.T, stored StoredFunc, expected []*events.Event) {
+	evs, err := stored()
+	if err != nil {
+		t.Fatalf("Error reading stored events: %v", err)
+	}
+	seen := map[string]int{}
+	for _, ev := range evs {
+		seen[ev.ID]++
+	}
+	for _, ev := range expected {
+		switch n := seen[ev.ID]; n {
+		case 0:
+			t.Errorf("Event %s (offset %d) was not stored", ev.ID, ev.Offset)
+		case 1:
+		default:
+			t.Errorf("Event %s (offset %d) was stored %d times", ev.ID, ev.Offset, n)
+		}
+		delete(seen, ev.ID)
+	}
+	for id := range seen {
+		t.Errorf("Unexpected event stored: %s", id)
+	}
+}
+
+// checkAtLeastOnce checks that the expected events were stored, those up to
+// and including offset checkpoint exactly once.
+func checkAtLeastOnce(t *testing.T, stored StoredFunc, expected []*events.Event, checkpoint uint64) {
+	evs, err := stored()
+	if err != nil {
+		t.Fatalf("Error reading stored events: %v", err)
+	}
+	seen := map[string]int{}
+	for _, ev := range evs {
+		seen[ev.ID]++
+	}
+	for _, ev := range expected {
+		switch n := seen[ev.ID]; {
+		case n == 0:
+			t.Errorf("Event %s (offset %d) was not stored", ev.ID, ev.Offset)
+		case n > 1 && ev.Offset <= checkpoint:
+			t.Errorf("Event %s (offset %d) before the checkpoint was stored %d times", ev.ID, ev.Offset, n)
+		}
+		delete(seen, ev.ID)
+	}
+	for id := range seen {
+		t.Errorf("Unexpected event stored: %s", id)
+	}
+}
+
+func testBatches(t *testing.T, newSink NewFunc) {
+	s, stored := newSink(t)
+	defer s.Close()
+	evs := Events(0, 250)
+	for i := 0; i < len(evs); i += 100 {
+		end := i + 100
+		if end > len(evs) {
+			end = len(evs)
+		}
+		if err := s.Write(evs[i:end]); err != nil {
+			t.Fatalf("Error writing batch: %v", err)
+		}
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Error flushing: %v", err)
+	}
+	check(t, stored, evs)
+}
+
+func testEmptyBatch(t *testing.T, newSink NewFunc) {
+	s, stored := newSink(t)
+	defer s.Close()
+	if err := s.Write(nil); err != nil {
+		t.Fatalf("Error writing empty batch: %v", err)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Error flushing empty sink: %v", err)
+	}
+	check(t, stored, nil)
+}
+
+// testRetry ensures batches may be retried without duplicating events.
+func testRetry(t *testing.T, newSink NewFunc) {
+	s, stored := newSink(t)
+	defer s.Close()
+	evs := Events(10, 20)
+	if err := s.Write(evs[:10]); err != nil {
+		t.Fatalf("Error writing batch: %v", err)
+	}
+
+	// Retry the first batch before flushing and with an overlapping batch
+	if err := s.Write(evs[:10]); err != nil {
+		t.Fatalf("Error retrying batch: %v", err)
+	}
+	if err := s.Write(evs[5:]); err != nil {
+		t.Fatalf("Error writing overlapping batch: %v", err)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Error flushing: %v", err)
+	}
+
+	// Retry after flushing
+	if err := s.Write(evs); err != nil {
+		t.Fatalf("Error retrying flushed batch: %v", err)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Error flushing: %v", err)
+	}
+	check(t, stored, evs)
+}
+
+// testCheckpoint ensures everything up to the last written offset is stored
+// once Flush returns, so the offset may be checkpointed.
+func testCheckpoint(t *testing.T, newSink NewFunc) {
+	s, stored := newSink(t)
+	defer s.Close()
+	evs := Events(100, 30)
+	for i := 0; i < len(evs); i += 10 {
+		if err := s.Write(evs[i : i+10]); err != nil {
+			t.Fatalf("Error writing batch: %v", err)
+		}
+		if err := s.Flush(); err != nil {
+			t.Fatalf("Error flushing: %v", err)
+		}
+		check(t, stored, evs[:i+10])
+	}
+}
+
+func testClose(t *testing.T, newSink NewFunc) {
+	s, stored := newSink(t)
+	evs := Events(0, 10)
+	if err := s.Write(evs); err != nil {
+		t.Fatalf("Error writing batch: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Error closing: %v", err)
+	}
+	check(t, stored, evs)
+	if err := s.Write(Events(10, 1)); err == nil {
+		t.Errorf("Expected an error writing to a closed sink")
+	}
+}
+
+// testRestart stops a Sink after flushing events which weren't checkpointed
+// and resumes from the checkpoint with a new Sink.
+func testRestart(t *testing.T, open OpenFunc) {
+	reopen, stored := open(t)
+	evs := Events(0, 30)
+	s := reopen()
+	if err := s.Write(evs[:10]); err != nil {
+		t.Fatalf("Error writing batch: %v", err)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Error flushing: %v", err)
+	}
+	checkpoint := evs[9].Offset
+
+	// Stop before the next batch is checkpointed
+	if err := s.Write(evs[10:20]); err != nil {
+		t.Fatalf("Error writing batch: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Error closing: %v", err)
+	}
+
+	s = reopen()
+	defer s.Close()
+	for i := 10; i < len(evs); i += 5 {
+		if err := s.Write(evs[i : i+5]); err != nil {
+			t.Fatalf("Error writing batch after restart: %v", err)
+		}
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Error flushing after restart: %v", err)
+	}
+	checkAtLeastOnce(t, stored, evs, checkpoint)
+}
@@ -0,0 +1,141 @@
+package sinktest_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/sinks"
+	"github.com/lytics/gobyairship/sinks/sinktest"
+	"github.com/lytics/gobyairship/uatest"
+)
+
+func TestMemory(t *testing.T) {
+	sinktest.Run(t, func(t *testing.T) (sinks.Sink, sinktest.StoredFunc) {
+		m := &sinks.Memory{}
+		return m, func() ([]*events.Event, error) { return m.Events(), nil }
+	})
+	sinktest.RunRestart(t, func(t *testing.T) (func() sinks.Sink, sinktest.StoredFunc) {
+		var opened []*sinks.Memory
+		open := func() sinks.Sink {
+			m := &sinks.Memory{}
+			opened = append(opened, m)
+			return m
+		}
+		return open, func() ([]*events.Event, error) {
+			var evs []*events.Event
+			for _, m := range opened {
+				evs = append(evs, m.Events()...)
+			}
+			return evs, nil
+		}
+	})
+}
+
+func TestScrubbed(t *testing.T) {
+	sinktest.Run(t, func(t *testing.T) (sinks.Sink, sinktest.StoredFunc) {
+		m := &sinks.Memory{}
+		s := sinks.Scrubbed(m, &events.Scrubber{Channels: events.ScrubRedact})
+		return s, func() ([]*events.Event, error) {
+			evs := m.Events()
+			for _, ev := range evs {
+				if ev.Device != nil {
+					t.Errorf("Expected device of %s to be scrubbed but found %+v", ev.ID, ev.Device)
+				}
+			}
+			return evs, nil
+		}
+	})
+}
+
+// memoryStore is an ObjectStore backed by a map which fails Puts while fail is
+// set.
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	fail    bool
+}
+
+func (m *memoryStore) Put(key string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail {
+		return errors.New("unavailable")
+	}
+	if m.objects == nil {
+		m.objects = map[string][]byte{}
+	}
+	m.objects[key] = append([]byte(nil), body...)
+	return nil
+}
+
+func (m *memoryStore) events() ([]*events.Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var evs []*events.Event
+	for _, body := range m.objects {
+		objEvs, err := sinks.ReadObject(body)
+		if err != nil {
+			return nil, err
+		}
+		evs = append(evs, objEvs...)
+	}
+	return evs, nil
+}
+
+func TestArchive(t *testing.T) {
+	sinktest.Run(t, func(t *testing.T) (sinks.Sink, sinktest.StoredFunc) {
+		store := &memoryStore{}
+		return sinks.NewArchive(store, "events/"), store.events
+	})
+	sinktest.RunRestart(t, func(t *testing.T) (func() sinks.Sink, sinktest.StoredFunc) {
+		store := &memoryStore{}
+		return func() sinks.Sink { return sinks.NewArchive(store, "events/") }, store.events
+	})
+}
+
+func TestArchiveRotation(t *testing.T) {
+	store := &memoryStore{}
+	a := sinks.NewArchive(store, "events/")
+	clock := uatest.NewClock(time.Date(2017, 4, 25, 9, 0, 0, 0, time.UTC))
+	a.Clock = clock
+	a.MaxBytes = 500
+	a.MaxAge = time.Minute
+
+	// Rotate by size
+	evs := sinktest.Events(0, 20)
+	if err := a.Write(evs[:10]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(store.objects) < 2 {
+		t.Errorf("Expected objects to rotate by size but found %d", len(store.objects))
+	}
+	key := "events/dt=2015-05-27/hour=11/part-00000000000000000000.json.gz"
+	if _, ok := store.objects[key]; !ok {
+		t.Errorf("Expected object %s but found %v", key, store.objects)
+	}
+
+	// Failed uploads are retried
+	a.MaxBytes = 1 << 20
+	store.fail = true
+	if err := a.Write(evs[10:11]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	clock.Advance(time.Minute)
+	if err := a.Write(evs[11:12]); err == nil {
+		t.Fatalf("Expected upload error")
+	}
+	store.fail = false
+	if err := a.Write(evs[12:]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stored, err := store.events()
+	if err != nil || len(stored) != len(evs) {
+		t.Errorf("Expected %d events but found %d (%v)", len(evs), len(stored), err)
+	}
+}
@@ -0,0 +1,35 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package relays events to HTTP endpoints as signed JSON POSTs so teams
+// can receive events without running their own Event API consumer. The Sink
+// plugs into a Stream like any other sinks.Sink, delivering each batch to
+// every Endpoint whose filter matches it and retrying failed deliveries.
+//
+// Each POST's body is a JSON array of events. Receivers should check the
+// signature with Verify:
+//
+//	func handle(w http.ResponseWriter, r *http.Request) {
+//		body, err := ioutil.ReadAll(r.Body)
+//		if err != nil || !webhook.Verify(secret, r.Header, body, 5*time.Minute) {
+//			http.Error(w, "invalid signature", http.StatusUnauthorized)
+//			return
+//		}
+//		// decode and process body
+//	}
+//
+// Deliveries are at-least-once: a delivery may be retried after the endpoint
+// processed it but before its response arrived. Retries carry the same
+// DeliveryHeader so receivers may deduplicate them.
+package webhook
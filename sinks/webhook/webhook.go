@@ -0,0 +1,293 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/sinks"
+)
+
+// Headers set on each delivery.
+const (
+	// SignatureHeader is "sha256=" followed by the hex encoded HMAC-SHA256 of
+	// the TimestampHeader value, a period, and the body.
+	SignatureHeader = "X-Gobyairship-Signature"
+
+	// TimestampHeader is when the delivery was sent in Unix seconds.
+	TimestampHeader = "X-Gobyairship-Timestamp"
+
+	// DeliveryHeader identifies a delivery by the offsets of its first and
+	// last events. It's the same for every attempt.
+	DeliveryHeader = "X-Gobyairship-Delivery"
+)
+
+// Endpoint receives events.
+type Endpoint struct {
+	// URL events are POSTed to.
+	URL string
+
+	// Secret signs deliveries. Deliveries are unsigned if it's empty.
+	Secret []byte
+
+	// Types, if non-empty, limits the events sent to the Endpoint.
+	Types []events.Type
+
+	// Filter, if non-nil, must return true for events to be sent to the
+	// Endpoint. It's checked after Types.
+	Filter func(*events.Event) bool
+
+	// Header is added to each delivery.
+	Header http.Header
+}
+
+// match returns true if ev should be sent to the Endpoint.
+func (e *Endpoint) match(ev *events.Event) bool {
+	if len(e.Types) > 0 {
+		found := false
+		for _, t := range e.Types {
+			if t == ev.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return e.Filter == nil || e.Filter(ev)
+}
+
+// DeliveryError is returned by Flush when an Endpoint fails to accept a
+// delivery after every retry.
+type DeliveryError struct {
+	URL        string
+	StatusCode int // zero if no response was received
+	Err        error
+}
+
+func (e *DeliveryError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("webhook %s responded %d", e.URL, e.StatusCode)
+	}
+	return fmt.Sprintf("webhook %s failed: %v", e.URL, e.Err)
+}
+
+// Sink delivers events to Endpoints. Write buffers matching events for each
+// Endpoint and Flush delivers them in batches, retrying failed deliveries. If
+// an Endpoint still fails Flush returns a *DeliveryError and the next Flush
+// resumes with its undelivered events, so Endpoints which already succeeded
+// don't receive events twice.
+//
+// Since the offset is only checkpointed after a successful Flush an Endpoint
+// which stays down stalls the Stream. Use Filter and separate Sinks to
+// isolate unreliable Endpoints.
+type Sink struct {
+	// Client sends deliveries. Defaults to http.DefaultClient.
+	Client *http.Client
+
+	// MaxBatch is the most events sent in a single delivery. Defaults to 100.
+	MaxBatch int
+
+	// Retries is how many times a failed delivery is retried within a Flush,
+	// waiting according to Backoff between attempts. Default to 3 and an
+	// exponential backoff from 100ms to 5s.
+	Retries int
+	Backoff gobyairship.Backoff
+
+	// Clock defaults to gobyairship.RealClock.
+	Clock gobyairship.Clock
+
+	mu        sync.Mutex
+	mark      sinks.Watermark
+	endpoints []*endpoint
+	closed    bool
+}
+
+type endpoint struct {
+	Endpoint
+	pending []*events.Event
+}
+
+// NewSink creates a Sink delivering to endpoints. An error is returned if an
+// Endpoint's URL isn't an absolute http or https URL.
+func NewSink(endpoints ...Endpoint) (*Sink, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no endpoints")
+	}
+	s := &Sink{MaxBatch: 100, Retries: 3}
+	for _, e := range endpoints {
+		u, err := url.Parse(e.URL)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL %q", e.URL)
+		}
+		s.endpoints = append(s.endpoints, &endpoint{Endpoint: e})
+	}
+	return s, nil
+}
+
+// Write implements sinks.Sink.
+func (s *Sink) Write(evs []*events.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return sinks.ErrClosed
+	}
+	for _, ev := range s.mark.Filter(evs) {
+		for _, e := range s.endpoints {
+			if e.match(ev) {
+				e.pending = append(e.pending, ev)
+			}
+		}
+	}
+	return nil
+}
+
+// Flush implements sinks.Sink. Endpoints are delivered to in order and Flush
+// stops at the first Endpoint which fails.
+func (s *Sink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+// Close implements sinks.Sink.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.flush()
+}
+
+func (s *Sink) flush() error {
+	max := s.MaxBatch
+	if max <= 0 {
+		max = 100
+	}
+	for _, e := range s.endpoints {
+		for len(e.pending) > 0 {
+			n := len(e.pending)
+			if n > max {
+				n = max
+			}
+			if err := s.deliver(e, e.pending[:n]); err != nil {
+				return err
+			}
+			e.pending = e.pending[n:]
+		}
+		e.pending = nil
+	}
+	return nil
+}
+
+// deliver a batch to an endpoint with retries.
+func (s *Sink) deliver(e *endpoint, evs []*events.Event) error {
+	body, err := json.Marshal(evs)
+	if err != nil {
+		return err
+	}
+	id := fmt.Sprintf("%d-%d", evs[0].Offset, evs[len(evs)-1].Offset)
+	clock := s.Clock
+	if clock == nil {
+		clock = gobyairship.RealClock
+	}
+	backoff := s.Backoff
+	if backoff == nil {
+		backoff = gobyairship.ExponentialBackoff{Min: 100 * time.Millisecond, Max: 5 * time.Second}
+	}
+	var last time.Duration
+	for attempt := 0; ; attempt++ {
+		err := s.post(e, id, body, clock.Now())
+		if err == nil {
+			return nil
+		}
+		derr, ok := err.(*DeliveryError)
+		if !ok || !retryable(derr.StatusCode) || attempt >= s.Retries {
+			return err
+		}
+		last = backoff.Next(attempt+1, last)
+		<-clock.After(last)
+	}
+}
+
+// retryable returns true for statuses which may succeed if retried.
+func retryable(code int) bool {
+	return code == 0 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+}
+
+// post a single delivery attempt.
+func (s *Sink) post(e *endpoint, id string, body []byte, now time.Time) error {
+	req, err := http.NewRequest("POST", e.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, vs := range e.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, id)
+	if len(e.Secret) > 0 {
+		ts := strconv.FormatInt(now.Unix(), 10)
+		req.Header.Set(TimestampHeader, ts)
+		req.Header.Set(SignatureHeader, Sign(e.Secret, ts, body))
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return &DeliveryError{URL: e.URL, Err: err}
+	}
+	// Drain the body so the connection may be reused
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &DeliveryError{URL: e.URL, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// Sign returns the SignatureHeader value for a delivery.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns true if the delivery with header and body was signed with
+// secret no more than maxAge ago. Pass a maxAge of zero to skip the age check.
+func Verify(secret []byte, header http.Header, body []byte, maxAge time.Duration) bool {
+	ts := header.Get(TimestampHeader)
+	sent, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(sent, 0)); maxAge > 0 && (age > maxAge || age < -maxAge) {
+		return false
+	}
+	return hmac.Equal([]byte(header.Get(SignatureHeader)), []byte(Sign(secret, ts, body)))
+}
@@ -0,0 +1,140 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/sinks"
+	"github.com/lytics/gobyairship/sinks/sinktest"
+	"github.com/lytics/gobyairship/sinks/webhook"
+)
+
+// receiver records verified deliveries and fails the next fail requests.
+type receiver struct {
+	*httptest.Server
+	secret []byte
+
+	mu         sync.Mutex
+	evs        []*events.Event
+	deliveries []string
+	fail       int
+	status     int
+}
+
+func newReceiver(t *testing.T, secret string) *receiver {
+	r := &receiver{secret: []byte(secret), status: http.StatusInternalServerError}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("Error reading delivery: %v", err)
+		}
+		if len(r.secret) > 0 && !webhook.Verify(r.secret, req.Header, body, time.Minute) {
+			t.Errorf("Invalid signature %q", req.Header.Get(webhook.SignatureHeader))
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.fail > 0 {
+			r.fail--
+			w.WriteHeader(r.status)
+			return
+		}
+		var evs []*events.Event
+		if err := json.Unmarshal(body, &evs); err != nil {
+			t.Errorf("Error decoding delivery: %v", err)
+		}
+		r.evs = append(r.evs, evs...)
+		r.deliveries = append(r.deliveries, req.Header.Get(webhook.DeliveryHeader))
+	}))
+	return r
+}
+
+func (r *receiver) events() ([]*events.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*events.Event(nil), r.evs...), nil
+}
+
+func TestSink(t *testing.T) {
+	sinktest.Run(t, func(t *testing.T) (sinks.Sink, sinktest.StoredFunc) {
+		r := newReceiver(t, "secret")
+		s, err := webhook.NewSink(webhook.Endpoint{URL: r.URL, Secret: r.secret})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		s.MaxBatch = 7
+		return s, r.events
+	})
+}
+
+func TestRetries(t *testing.T) {
+	all := newReceiver(t, "a")
+	defer all.Close()
+	opens := newReceiver(t, "")
+	defer opens.Close()
+	s, err := webhook.NewSink(
+		webhook.Endpoint{URL: all.URL, Secret: all.secret},
+		webhook.Endpoint{URL: opens.URL, Types: []events.Type{events.TypeOpen}},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.Backoff = gobyairship.ConstantBackoff(time.Millisecond)
+	s.Retries = 2
+
+	evs := sinktest.Events(0, 3)
+	evs[1].Type = events.TypeOpen
+	if err := s.Write(evs); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Server errors are retried within a Flush
+	all.fail = 2
+	opens.fail, opens.status = 1, http.StatusBadRequest
+	err = s.Flush()
+	derr, ok := err.(*webhook.DeliveryError)
+	if !ok || derr.URL != opens.URL || derr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected a 400 delivery error from %s but found %v", opens.URL, err)
+	}
+	if len(all.evs) != 3 || all.fail != 0 {
+		t.Errorf("Expected 3 events after retries but found %d", len(all.evs))
+	}
+
+	// The next Flush only retries the failed endpoint
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(all.evs) != 3 || len(opens.evs) != 1 || opens.evs[0].Type != events.TypeOpen {
+		t.Errorf("Unexpected deliveries: %d to all, %v to opens", len(all.evs), opens.evs)
+	}
+	if opens.deliveries[0] != "1-1" {
+		t.Errorf("Unexpected delivery ID %q", opens.deliveries[0])
+	}
+
+	if _, err := webhook.NewSink(webhook.Endpoint{URL: "ftp://example.com"}); err == nil {
+		t.Errorf("Expected an error for a non-HTTP URL")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret, body := []byte("secret"), []byte("[]")
+	ts := "1493110800"
+	h := http.Header{}
+	h.Set(webhook.TimestampHeader, ts)
+	h.Set(webhook.SignatureHeader, webhook.Sign(secret, ts, body))
+	if !webhook.Verify(secret, h, body, 0) {
+		t.Errorf("Expected signature to verify")
+	}
+	if webhook.Verify(secret, h, body, time.Minute) {
+		t.Errorf("Expected old signature to fail")
+	}
+	if webhook.Verify([]byte("other"), h, body, 0) || webhook.Verify(secret, h, []byte("{}"), 0) {
+		t.Errorf("Expected signature to fail with a different secret or body")
+	}
+}
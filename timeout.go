@@ -0,0 +1,65 @@
+package gobyairship
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TimeoutPolicy determines how long requests may take. A timeout covers the
+// entire request including reading the response body, so streaming endpoints
+// such as the Event API should not have a timeout.
+type TimeoutPolicy struct {
+	// Default timeout for requests. Zero means no timeout.
+	Default time.Duration
+
+	// Paths overrides Default for requests whose URL path begins with a key.
+	// The longest matching prefix wins. A zero duration disables the timeout.
+	Paths map[string]time.Duration
+}
+
+// DefaultTimeoutPolicy returns a new TimeoutPolicy with a 30 second default
+// and no timeout for the Event API.
+func DefaultTimeoutPolicy() *TimeoutPolicy {
+	return &TimeoutPolicy{
+		Default: 30 * time.Second,
+		Paths:   map[string]time.Duration{"/api/events/": 0},
+	}
+}
+
+// Timeout returns the timeout for a URL.
+func (p *TimeoutPolicy) Timeout(u *url.URL) time.Duration {
+	timeout := p.Default
+	match := ""
+	for prefix, d := range p.Paths {
+		if strings.HasPrefix(u.Path, prefix) && len(prefix) > len(match) {
+			timeout = d
+			match = prefix
+		}
+	}
+	return timeout
+}
+
+// timeout returns the timeout for a request. A nil policy never times out.
+func (p *TimeoutPolicy) timeout(ctx context.Context, rawurl string) time.Duration {
+	if d, ok := ctx.Value(timeoutKey{}).(time.Duration); ok {
+		return d
+	}
+	if p == nil {
+		return 0
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return p.Default
+	}
+	return p.Timeout(u)
+}
+
+type timeoutKey struct{}
+
+// WithTimeout returns a Context which overrides the Client's TimeoutPolicy
+// for requests made with it. A zero duration disables the timeout.
+func WithTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, d)
+}
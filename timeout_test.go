@@ -0,0 +1,76 @@
+package gobyairship_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/lytics/gobyairship"
+)
+
+func TestTimeoutPolicy(t *testing.T) {
+	t.Parallel()
+	p := &TimeoutPolicy{
+		Default: time.Second,
+		Paths: map[string]time.Duration{
+			"/api/":        2 * time.Second,
+			"/api/events/": 0,
+		},
+	}
+	tests := map[string]time.Duration{
+		"https://go.urbanairship.com/other":              time.Second,
+		"https://go.urbanairship.com/api/channels":       2 * time.Second,
+		"https://connect.urbanairship.com/api/events/":   0,
+		"https://connect.urbanairship.com/api/events/x/": 0,
+	}
+	for raw, expected := range tests {
+		u, _ := url.Parse(raw)
+		if d := p.Timeout(u); d != expected {
+			t.Errorf("%s: expected %s but found %s", raw, expected, d)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		w.(http.Flusher).Flush()
+		select {
+		case <-time.After(200 * time.Millisecond):
+			w.Write([]byte("done"))
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+
+	c := NewClient("", "")
+	c.Timeouts = &TimeoutPolicy{Default: 50 * time.Millisecond, Paths: map[string]time.Duration{"/api/events/": 0}}
+	read := func(ctx context.Context, path string) error {
+		resp, err := c.PostContext(ctx, ts.URL+path, nil, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, err = ioutil.ReadAll(resp.Body)
+		return err
+	}
+
+	if err := read(context.Background(), "/api/channels"); err == nil {
+		t.Errorf("Expected request to time out")
+	}
+	if err := read(context.Background(), "/api/events/"); err != nil {
+		t.Errorf("Unexpected error reading from endpoint without timeout: %v", err)
+	}
+	if err := read(WithTimeout(context.Background(), 0), "/api/channels"); err != nil {
+		t.Errorf("Unexpected error overriding timeout: %v", err)
+	}
+	if err := read(WithTimeout(context.Background(), 10*time.Millisecond), "/api/events/"); err == nil {
+		t.Errorf("Expected request with overridden timeout to time out")
+	}
+}
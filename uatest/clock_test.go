@@ -0,0 +1,47 @@
+package uatest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/uatest"
+)
+
+func TestClock(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2015, 5, 27, 12, 0, 0, 0, time.UTC)
+	var c gobyairship.Clock = uatest.NewClock(start)
+	clock := c.(*uatest.Clock)
+
+	short := c.After(time.Second)
+	long := c.After(time.Minute)
+	if n := clock.Timers(); n != 2 {
+		t.Fatalf("Expected 2 timers but found %d", n)
+	}
+
+	clock.Advance(2 * time.Second)
+	select {
+	case now := <-short:
+		if !now.Equal(start.Add(2 * time.Second)) {
+			t.Errorf("Unexpected timer time: %s", now)
+		}
+	default:
+		t.Fatal("Expected short timer to fire")
+	}
+	select {
+	case <-long:
+		t.Fatal("Long timer fired early")
+	default:
+	}
+
+	clock.Advance(time.Minute)
+	select {
+	case <-long:
+	default:
+		t.Fatal("Expected long timer to fire")
+	}
+	if !c.Now().Equal(start.Add(time.Minute + 2*time.Second)) {
+		t.Errorf("Unexpected time: %s", c.Now())
+	}
+}
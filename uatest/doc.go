@@ -0,0 +1,28 @@
+//   Copyright 2015 Lytics
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+// This package provides a fake Urban Airship API server for testing code that
+// uses gobyairship without real credentials.
+//
+// The Server serves canned NDJSON event streams, honors the filters, subsets,
+// and offsets of events.Request bodies, and can simulate the 307 + Set-Cookie
+// redirects and 402 rate limits returned by the real Event API.
+//
+// The Recorder is an http.RoundTripper which records interactions with the
+// real API (with credentials scrubbed) and replays them deterministically in
+// tests.
+//
+// Clock is a fake gobyairship.Clock which lets tests advance time without
+// sleeping.
+package uatest
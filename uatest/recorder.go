@@ -0,0 +1,235 @@
+package uatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// Mode of a Recorder.
+type Mode int
+
+const (
+	// ModeRecord sends requests to the real API and records them.
+	ModeRecord Mode = iota
+
+	// ModeReplay serves previously recorded responses without making requests.
+	ModeReplay
+)
+
+// Redacted replaces credentials in recorded headers.
+const Redacted = "REDACTED"
+
+// scrubbed headers are replaced with Redacted when recording.
+var scrubbed = []string{"Authorization", "X-UA-Appkey", "Cookie", "Set-Cookie"}
+
+// Interaction is a single recorded request and response pair.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Recorder is an http.RoundTripper which records requests and responses to a
+// cassette file or deterministically replays them. Use it as the Transport of
+// a gobyairship.Client's HTTPClient.
+//
+// Response bodies are recorded as they are read, so streaming responses such
+// as the Event API only record the events actually consumed before the body
+// was closed.
+type Recorder struct {
+	// Transport makes requests when recording. Defaults to
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+
+	mode Mode
+	path string
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// NewRecorder creates a Recorder for the cassette at path. In ModeReplay the
+// cassette is loaded immediately.
+func NewRecorder(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{mode: mode, path: path}
+	if mode == ModeReplay {
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(buf, &r.interactions); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s: %v", path, err)
+		}
+		r.used = make([]bool, len(r.interactions))
+	}
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	if r.mode == ModeReplay {
+		return r.replay(req, body)
+	}
+	return r.record(req, body)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	t := r.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	resp, err := t.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	in := &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: scrub(req.Header),
+			Body:   string(body),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     scrub(resp.Header),
+		},
+	}
+	r.mu.Lock()
+	r.interactions = append(r.interactions, in)
+	r.mu.Unlock()
+
+	resp.Body = &recordingBody{r: r, in: in, body: resp.Body}
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.interactions {
+		if r.used[i] {
+			continue
+		}
+		if in.Request.Method != req.Method || in.Request.URL != req.URL.String() || in.Request.Body != string(body) {
+			continue
+		}
+		r.used[i] = true
+		header := http.Header{}
+		for k, v := range in.Response.Header {
+			header[k] = v
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(bytes.NewBufferString(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded interaction for %s %s", req.Method, req.URL)
+}
+
+// Interactions returns the recorded or loaded interactions.
+func (r *Recorder) Interactions() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Interaction(nil), r.interactions...)
+}
+
+// Save writes the recorded interactions to the cassette file. Response bodies
+// should be closed before calling Save.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	buf, err := json.MarshalIndent(r.interactions, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, buf, 0644)
+}
+
+func scrub(h http.Header) http.Header {
+	out := http.Header{}
+	for k, v := range h {
+		out[k] = append([]string(nil), v...)
+	}
+	for _, k := range scrubbed {
+		k = http.CanonicalHeaderKey(k)
+		if _, ok := out[k]; ok {
+			out[k] = []string{Redacted}
+		}
+	}
+	return out
+}
+
+// recordingBody captures the bytes of a response body as they're read.
+type recordingBody struct {
+	r    *Recorder
+	in   *Interaction
+	body io.ReadCloser
+
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	done bool
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.mu.Lock()
+	if !b.done {
+		b.buf.Write(p[:n])
+	}
+	b.mu.Unlock()
+	if err == io.EOF {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.finish()
+	return b.body.Close()
+}
+
+func (b *recordingBody) finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.done {
+		return
+	}
+	b.done = true
+	b.r.mu.Lock()
+	b.in.Response.Body = b.buf.String()
+	b.r.mu.Unlock()
+}
@@ -0,0 +1,86 @@
+package uatest_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/uatest"
+)
+
+// TestRecorder records a stream from a Server and replays it after the Server
+// is gone.
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "uatest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cassette := filepath.Join(dir, "events.json")
+
+	s := newServer(t)
+	s.Redirects = 1
+	url := s.EventsURL()
+	req := &events.Request{Start: events.StartFirst, Filters: []*events.Filter{{Types: []events.Type{events.TypeOpen}}}}
+
+	rec, err := uatest.NewRecorder(cassette, uatest.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := gobyairship.NewClient("secret-key", "secret-token")
+	c.HTTPClient = &http.Client{Transport: rec}
+	resp, err := c.Post(url, req, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error recording: %v", err)
+	}
+	r, err := events.NewResponse(resp)
+	if err != nil {
+		t.Fatalf("Unexpected error creating response: %v", err)
+	}
+	recorded, _ := count(t, r)
+	r.Close()
+	s.Close()
+	if err := rec.Save(); err != nil {
+		t.Fatalf("Error saving cassette: %v", err)
+	}
+
+	raw, err := ioutil.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "secret-") {
+		t.Errorf("Credentials were not scrubbed from cassette")
+	}
+
+	rep, err := uatest.NewRecorder(cassette, uatest.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rep.Interactions()); n != 2 {
+		t.Errorf("Expected 2 interactions (redirect + stream) but found %d", n)
+	}
+	c.HTTPClient = &http.Client{Transport: rep}
+	resp, err = c.Post(url, req, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error replaying: %v", err)
+	}
+	r, err = events.NewResponse(resp)
+	if err != nil {
+		t.Fatalf("Unexpected error creating response: %v", err)
+	}
+	replayed, _ := count(t, r)
+	if replayed == 0 || replayed != recorded {
+		t.Errorf("Replayed %d events but recorded %d", replayed, recorded)
+	}
+
+	// Everything has been replayed
+	if _, err := c.Post(url, req, nil); err == nil {
+		t.Errorf("Expected an error after exhausting cassette")
+	}
+}
@@ -0,0 +1,257 @@
+package uatest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lytics/gobyairship/events"
+)
+
+// EventsPath is the path the Server serves the Event API on.
+const EventsPath = "/api/events/"
+
+const redirectCookie = "uatest-redirect"
+
+// Server is a fake Urban Airship API backed by an httptest.Server. Exported
+// fields should be set before making requests against the Server.
+type Server struct {
+	*httptest.Server
+
+	// AppKey and AccessToken, if non-empty, must match the credentials sent by
+	// clients or the Server responds with 401 Unauthorized.
+	AppKey      string
+	AccessToken string
+
+	// Redirects is the number of 307 + Set-Cookie responses sent before an event
+	// stream is served. Clients must return the cookie on each redirect.
+	Redirects int
+
+	// MaxStreams limits the number of concurrent event streams. Requests in
+	// excess of the limit receive 402 Payment Required. Zero means unlimited.
+	MaxStreams int
+
+	// RetryAfter, if positive, is sent in the Retry-After header of 402
+	// responses rounded up to the nearest second.
+	RetryAfter time.Duration
+
+	mu      sync.Mutex
+	records []*record
+	streams int
+	limit   int
+	ops     int
+}
+
+type record struct {
+	ev  *events.Event
+	raw []byte
+}
+
+// NewServer starts and returns a new Server with no events. Callers should
+// call Close when finished.
+func NewServer() *Server {
+	s := &Server{}
+	mux := http.NewServeMux()
+	mux.HandleFunc(EventsPath, s.serveEvents)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// EventsURL returns the URL of the Server's Event API. Pass it to
+// events.SetURL to point the events package at the Server.
+func (s *Server) EventsURL() string { return s.URL + EventsPath }
+
+// Add events to the end of the Server's event stream.
+func (s *Server) Add(evs ...*events.Event) error {
+	for _, ev := range evs {
+		raw, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		s.add(ev, raw)
+	}
+	return nil
+}
+
+// Load newline delimited JSON events from a reader and add them to the end of
+// the Server's event stream.
+func (s *Server) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 10*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		ev := &events.Event{}
+		if err := json.Unmarshal(line, ev); err != nil {
+			return err
+		}
+		s.add(ev, append([]byte(nil), line...))
+	}
+	return scanner.Err()
+}
+
+// LoadFile loads newline delimited JSON events from a file such as the
+// fixtures in events/testdata.
+func (s *Server) LoadFile(fn string) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.Load(f)
+}
+
+func (s *Server) add(ev *events.Event, raw []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, &record{ev: ev, raw: raw})
+}
+
+// RateLimit causes the next n requests to receive a 402 Payment Required
+// response regardless of MaxStreams.
+func (s *Server) RateLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = n
+}
+
+// Streams returns the number of event streams currently being served.
+func (s *Server) Streams() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.streams
+}
+
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	// Redirect with a cookie until the client has followed enough redirects
+	if s.Redirects > 0 {
+		hops := 0
+		if c, err := r.Cookie(redirectCookie); err == nil {
+			hops, _ = strconv.Atoi(c.Value)
+		}
+		if hops < s.Redirects {
+			http.SetCookie(w, &http.Cookie{Name: redirectCookie, Value: strconv.Itoa(hops + 1)})
+			w.Header().Set("Location", r.URL.Path)
+			w.WriteHeader(http.StatusTemporaryRedirect)
+			return
+		}
+	}
+
+	req := &events.Request{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "could not parse request body: "+err.Error())
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Check rate limits and register the stream
+	s.mu.Lock()
+	if s.limit > 0 || (s.MaxStreams > 0 && s.streams >= s.MaxStreams) {
+		if s.limit > 0 {
+			s.limit--
+		}
+		retry := s.RetryAfter
+		s.mu.Unlock()
+		if retry > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int((retry+time.Second-1)/time.Second)))
+		}
+		writeError(w, http.StatusPaymentRequired, "too many connections")
+		return
+	}
+	s.streams++
+	s.ops++
+	opid := fmt.Sprintf("uatest-%d", s.ops)
+	recs := s.records
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.streams--
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "application/vnd.urbanairship+x-ndjson;version=3;")
+	w.Header().Set("UA-Operation-Id", opid)
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	if req.Start == events.StartLast {
+		// Only events added after the request would be sent; the canned stream is
+		// finite so there is nothing to send.
+		return
+	}
+	for _, rec := range recs {
+		if req.Offset != nil && rec.ev.Offset < *req.Offset {
+			continue
+		}
+		if !events.MatchFilters(req.Filters, rec.ev) || !matchSubset(req.Subset, rec.ev) {
+			continue
+		}
+		if _, err := w.Write(rec.raw); err != nil {
+			return
+		}
+		if _, err := w.Write([]byte("\n")); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if r.Header.Get("X-UA-Appkey") != s.AppKey && s.AppKey != "" {
+		return false
+	}
+	if s.AccessToken == "" {
+		return true
+	}
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ") == s.AccessToken
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error": msg})
+}
+
+// matchSubset deterministically assigns events to partitions and samples by
+// hashing their IDs.
+func matchSubset(s *events.Subset, ev *events.Event) bool {
+	if s == nil {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(ev.ID))
+	sum := h.Sum32()
+	switch s.Type {
+	case events.SubsetTypePartition:
+		return int(sum%uint32(*s.Count)) == *s.Selection
+	case events.SubsetTypeSample:
+		return float64(sum)/float64(^uint32(0)) < *s.Proportion
+	}
+	return true
+}
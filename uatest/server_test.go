@@ -0,0 +1,137 @@
+package uatest_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/lytics/gobyairship"
+	"github.com/lytics/gobyairship/events"
+	"github.com/lytics/gobyairship/uatest"
+)
+
+const fixture = "../events/testdata/all.json"
+
+func newServer(t *testing.T) *uatest.Server {
+	s := uatest.NewServer()
+	if err := s.LoadFile(fixture); err != nil {
+		s.Close()
+		t.Fatalf("Error loading %s: %v", fixture, err)
+	}
+	return s
+}
+
+func count(t *testing.T, resp *events.Response) (n int, last uint64) {
+	for ev := range resp.Events() {
+		n++
+		last = ev.Offset
+	}
+	if err := resp.Err(); err != io.EOF {
+		t.Errorf("Unexpected error reading events: %v", err)
+	}
+	return n, last
+}
+
+// TestServerRedirects ensures the Server requires clients to follow redirects
+// and return cookies before serving events.
+func TestServerRedirects(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	defer s.Close()
+	s.AppKey = "key"
+	s.AccessToken = "token"
+	s.Redirects = 3
+
+	c := gobyairship.NewClient("key", "token")
+	resp, err := c.Post(s.EventsURL(), &events.Request{Start: events.StartFirst}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	r, err := events.NewResponse(resp)
+	if err != nil {
+		t.Fatalf("Unexpected error creating response: %v", err)
+	}
+	if r.ID == "" {
+		t.Error("Expected UA-Operation-Id to be set")
+	}
+	if n, _ := count(t, r); n != 100 {
+		t.Errorf("Expected 100 events but received %d", n)
+	}
+
+	// Bad credentials should be rejected
+	c = gobyairship.NewClient("key", "wrong")
+	resp, err = c.Post(s.EventsURL(), &events.Request{Start: events.StartFirst}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 401 {
+		t.Errorf("Expected 401 for bad credentials but received %d", resp.StatusCode)
+	}
+}
+
+// TestServerFilters ensures the Server honors filters, subsets, and offsets.
+func TestServerFilters(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	defer s.Close()
+	c := gobyairship.NewClient("", "")
+
+	fetch := func(req *events.Request) *events.Response {
+		resp, err := c.Post(s.EventsURL(), req, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		r, err := events.NewResponse(resp)
+		if err != nil {
+			t.Fatalf("Unexpected error creating response: %v", err)
+		}
+		return r
+	}
+
+	r := fetch(&events.Request{Start: events.StartFirst, Filters: []*events.Filter{{Types: []events.Type{events.TypeClose}}}})
+	for ev := range r.Events() {
+		if ev.Type != events.TypeClose {
+			t.Errorf("Expected only %s events but received %s", events.TypeClose, ev.Type)
+		}
+	}
+
+	offset := uint64(50)
+	n, _ := count(t, fetch(&events.Request{Offset: &offset}))
+	if n != 50 {
+		t.Errorf("Expected 50 events after offset %d but received %d", offset, n)
+	}
+
+	total := 0
+	for i := 0; i < 4; i++ {
+		n, _ := count(t, fetch(&events.Request{Start: events.StartFirst, Subset: events.SubsetPartition(4, i)}))
+		total += n
+	}
+	if total != 100 {
+		t.Errorf("Expected partitions to cover 100 events but covered %d", total)
+	}
+
+	if n, _ := count(t, fetch(&events.Request{Start: events.StartLast})); n != 0 {
+		t.Errorf("Expected no events when starting from latest but received %d", n)
+	}
+}
+
+// TestServerRateLimit ensures the Server can simulate 402 responses.
+func TestServerRateLimit(t *testing.T) {
+	t.Parallel()
+	s := newServer(t)
+	defer s.Close()
+	s.RateLimit(1)
+
+	events.SetURL(s.EventsURL())
+	defer events.SetURL(events.DefaultEventsURL)
+
+	c := gobyairship.NewClient("", "")
+	if _, err := events.Fetch(c, events.StartFirst, 0, nil); err != events.LimitExceeded {
+		t.Fatalf("Expected LimitExceeded but received: %v", err)
+	}
+	resp, err := events.Fetch(c, events.StartFirst, 0, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error after rate limit: %v", err)
+	}
+	resp.Close()
+}